@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIKeyHeader carries the API key of a request, as an alternative to
+// presenting it as a bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates requests bearing one of a fixed set of
+// API keys, each issued to a tenant. The subject and the tenant of a request
+// are both the tenant of its key.
+type APIKeyAuthenticator struct {
+	// keys maps the SHA-256 digests of keys to their grants. Keys are looked
+	// up by digest so that the time taken reveals nothing of them.
+	keys map[[sha256.Size]byte]APIKey
+}
+
+// APIKey is what an API key grants its bearer.
+type APIKey struct {
+	// Tenant names the tenant the key was issued to.
+	Tenant string
+
+	// Quota, when set, bounds the captures made with the key in place of
+	// the server's default quota.
+	Quota *Quota
+}
+
+// NewAPIKeyAuthenticator creates an APIKeyAuthenticator accepting the keys
+// of keys, which maps each to what it grants.
+func NewAPIKeyAuthenticator(keys map[string]APIKey) (*APIKeyAuthenticator, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("auth: at least one API key is required")
+	}
+	digests := make(map[[sha256.Size]byte]APIKey, len(keys))
+	for key, k := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("auth: empty API key for tenant %q", k.Tenant)
+		}
+		if !ValidTenant(k.Tenant) {
+			return nil, fmt.Errorf("auth: invalid tenant %q: must be lowercase letters, digits and hyphens", k.Tenant)
+		}
+		digests[sha256.Sum256([]byte(key))] = k
+	}
+	return &APIKeyAuthenticator{keys: digests}, nil
+}
+
+// ReadAPIKeys reads API keys from r, one per line as a tenant and a key
+// separated by whitespace, returning them keyed by key. A key may be
+// followed by its quota, as max_concurrent=N and daily=N. Blank lines and
+// lines beginning with # are ignored.
+func ReadAPIKeys(r io.Reader) (map[string]APIKey, error) {
+	keys := make(map[string]APIKey)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("auth: line %d: want a tenant and a key", n)
+		}
+		tenant, key := fields[0], fields[1]
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("auth: line %d: key is already issued", n)
+		}
+		k := APIKey{Tenant: tenant}
+		if len(fields) > 2 {
+			quota, err := parseQuota(fields[2:])
+			if err != nil {
+				return nil, fmt.Errorf("auth: line %d: %w", n, err)
+			}
+			k.Quota = &quota
+		}
+		keys[key] = k
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("auth: failed to read API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// parseQuota parses the limits of a quota, given as name=value.
+func parseQuota(fields []string) (Quota, error) {
+	var q Quota
+	for _, f := range fields {
+		name, value, _ := strings.Cut(f, "=")
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return Quota{}, fmt.Errorf("invalid quota %q: must be a name and a non-negative number", f)
+		}
+		switch name {
+		case "max_concurrent":
+			q.MaxConcurrent = n
+		case "daily":
+			q.Daily = n
+		default:
+			return Quota{}, fmt.Errorf("unknown quota %q: must be max_concurrent or daily", name)
+		}
+	}
+	return q, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key, _ = bearerToken(r)
+	}
+	if key == "" {
+		return Identity{}, fmt.Errorf("auth: no API key: %w", ErrUnauthenticated)
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	k, ok := a.keys[digest]
+	if !ok {
+		return Identity{}, fmt.Errorf("auth: unknown API key: %w", ErrUnauthenticated)
+	}
+	return Identity{
+		Subject: k.Tenant,
+		Tenant:  k.Tenant,
+		Key:     hex.EncodeToString(digest[:8]),
+		Quota:   k.Quota,
+	}, nil
+}
@@ -0,0 +1,117 @@
+// Package auth authenticates requests to the API, by JWT or by API key.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthenticated is returned by an Authenticator when a request carries
+// no credentials, or credentials that are not valid.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator establishes who made a request.
+type Authenticator interface {
+	// Authenticate returns the identity on whose behalf r was made, or an
+	// error wrapping ErrUnauthenticated.
+	Authenticate(r *http.Request) (Identity, error)
+}
+
+// Identity is who made a request.
+type Identity struct {
+	// Subject identifies the caller.
+	Subject string
+
+	// Tenant names the team the caller belongs to. Each tenant sees only
+	// its own operations. Empty when the server is not multi-tenant.
+	Tenant string
+
+	// Key identifies the API key the caller presented, without revealing
+	// it. Empty for callers authenticated otherwise.
+	Key string
+
+	// Quota, when set, bounds the captures of the caller in place of the
+	// server's default quota.
+	Quota *Quota
+}
+
+// Quota bounds the captures a caller may make. Zero limits are not enforced.
+type Quota struct {
+	// MaxConcurrent limits the captures of the caller unfinished at once.
+	MaxConcurrent int
+
+	// Daily limits the captures the caller may make each day, from midnight
+	// UTC.
+	Daily int
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity carried by ctx, which is empty for
+// requests that were not authenticated.
+func FromContext(ctx context.Context) Identity {
+	id, _ := ctx.Value(identityKey{}).(Identity)
+	return id
+}
+
+// Subject returns the subject carried by ctx, or "" for requests that were
+// not authenticated.
+func Subject(ctx context.Context) string {
+	id, _ := ctx.Value(identityKey{}).(Identity)
+	return id.Subject
+}
+
+// Tenant returns the tenant carried by ctx, or "" for requests that were not
+// authenticated or belong to no tenant.
+func Tenant(ctx context.Context) string {
+	id, _ := ctx.Value(identityKey{}).(Identity)
+	return id.Tenant
+}
+
+// ValidTenant reports whether name may be used as a tenant: between 1 and 63
+// lowercase letters, digits and hyphens, beginning with a letter or digit.
+// Tenants name the storage prefixes of their artefacts, so are restricted to
+// characters safe in object names.
+func ValidTenant(name string) bool {
+	if name == "" || len(name) > 63 || name[0] == '-' {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware rejects requests that a does not authenticate with 401
+// Unauthorized, and passes the identity of the rest to next in their context.
+func Middleware(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthenticated"}` + "\n"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+	})
+}
+
+// bearerToken returns the token of the Bearer authorization of r.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
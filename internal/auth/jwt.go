@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// signatureAlgorithms are those accepted for JWTs. Symmetric algorithms are
+// excluded, as the keys are public.
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+const (
+	// defaultJWKSCacheTTL is how long keys are used before being fetched
+	// afresh.
+	defaultJWKSCacheTTL = time.Hour
+
+	// minJWKSRefresh limits how often keys are fetched early, when a token
+	// is signed with a key not yet known, e.g. after the identity provider
+	// rotated them.
+	minJWKSRefresh = time.Minute
+)
+
+// JWTConfig configures a JWTAuthenticator.
+type JWTConfig struct {
+	// Issuer must match the iss claim of tokens. Required.
+	Issuer string
+
+	// Audience, if set, must be among the aud claim of tokens.
+	Audience string
+
+	// JWKSURL locates the keys that sign tokens. When empty, it is
+	// discovered from the OpenID configuration of the Issuer.
+	JWKSURL string
+
+	// TenantClaim, if set, names the claim holding the tenant of the caller.
+	// Tokens without it, or with a tenant that is not valid, are rejected.
+	TenantClaim string
+
+	// CacheTTL is how long fetched keys are used. Defaults to an hour.
+	CacheTTL time.Duration
+
+	// Client fetches keys. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// JWTAuthenticator authenticates requests bearing a JWT issued by an OpenID
+// Connect identity provider. The subject of a request is the sub claim of
+// its token, and its tenant the claim named by JWTConfig.TenantClaim.
+type JWTAuthenticator struct {
+	config JWTConfig
+
+	mu        sync.Mutex
+	keys      *jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// NewJWTAuthenticator creates a JWTAuthenticator. Keys are fetched when the
+// first token is presented.
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("auth: issuer is required")
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaultJWKSCacheTTL
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &JWTAuthenticator{config: config}, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Identity{}, fmt.Errorf("auth: no bearer token: %w", ErrUnauthenticated)
+	}
+	parsed, err := jwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil || len(parsed.Headers) == 0 {
+		return Identity{}, fmt.Errorf("auth: malformed token: %w", ErrUnauthenticated)
+	}
+
+	key, err := a.key(r.Context(), parsed.Headers[0].KeyID)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	var claims jwt.Claims
+	var custom map[string]any
+	if err := parsed.Claims(key, &claims, &custom); err != nil {
+		return Identity{}, fmt.Errorf("auth: invalid signature: %w", ErrUnauthenticated)
+	}
+	expected := jwt.Expected{Issuer: a.config.Issuer, Time: time.Now()}
+	if a.config.Audience != "" {
+		expected.AnyAudience = jwt.Audience{a.config.Audience}
+	}
+	if err := claims.Validate(expected); err != nil {
+		return Identity{}, fmt.Errorf("auth: %w: %w", err, ErrUnauthenticated)
+	}
+	if claims.Expiry == nil {
+		return Identity{}, fmt.Errorf("auth: token does not expire: %w", ErrUnauthenticated)
+	}
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("auth: token has no subject: %w", ErrUnauthenticated)
+	}
+
+	id := Identity{Subject: claims.Subject}
+	if a.config.TenantClaim != "" {
+		tenant, _ := custom[a.config.TenantClaim].(string)
+		if !ValidTenant(tenant) {
+			return Identity{}, fmt.Errorf("auth: token has no valid %s claim: %w", a.config.TenantClaim, ErrUnauthenticated)
+		}
+		id.Tenant = tenant
+	}
+	return id, nil
+}
+
+// key returns the public key with ID kid, fetching the key set when it has
+// expired or, at most once a minute, when it does not contain the key.
+func (a *JWTAuthenticator) key(ctx context.Context, kid string) (any, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	stale := a.keys == nil || now.Sub(a.fetchedAt) > a.config.CacheTTL
+	if !stale && len(a.keys.Key(kid)) == 0 && now.Sub(a.fetchedAt) > minJWKSRefresh {
+		stale = true
+	}
+	if stale {
+		keys, err := a.fetchKeys(ctx)
+		if err != nil && a.keys == nil {
+			return nil, err
+		}
+		// Keep using the keys we have should the identity provider be
+		// unavailable.
+		if err == nil {
+			a.keys, a.fetchedAt = keys, now
+		}
+	}
+
+	for _, k := range a.keys.Key(kid) {
+		if k.Use == "" || k.Use == "sig" {
+			return k.Key, nil
+		}
+	}
+	return nil, fmt.Errorf("auth: unknown signing key %q: %w", kid, ErrUnauthenticated)
+}
+
+func (a *JWTAuthenticator) fetchKeys(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	jwksURL := a.config.JWKSURL
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		wellKnown := strings.TrimSuffix(a.config.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := a.getJSON(ctx, wellKnown, &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("auth: %s has no jwks_uri", wellKnown)
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := a.getJSON(ctx, jwksURL, &keys); err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+func (a *JWTAuthenticator) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	resp, err := a.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("auth: failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth: failed to fetch %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("auth: failed to decode %s: %w", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package chat posts messages about finished captures to Slack or Microsoft
+// Teams through their incoming webhooks, so that a team can follow the
+// captures of a schedule without polling for them.
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/internal/webhook"
+)
+
+// Format is the kind of incoming webhook messages are posted to.
+type Format string
+
+const (
+	FormatSlack Format = "slack"
+	FormatTeams Format = "teams"
+)
+
+// DefaultTemplate renders the message posted for an operation unless
+// configured otherwise.
+const DefaultTemplate = `Capture of {{.URL}} {{.Status}}{{with .Error}}: {{.}}{{end}}
+{{- if eq .Status "complete"}}
+TTFB: {{.TTFB}}{{if .TimedOut}} (timed out before the network was idle){{end}}
+{{- range .Artefacts}}
+{{.Name}}: {{.SignedURL}}
+{{- end}}
+{{- end}}`
+
+// Config configures a Notifier.
+type Config struct {
+	// URL is the incoming webhook to post to.
+	URL string
+
+	// Format is the kind of webhook URL is. Defaults to FormatSlack.
+	Format Format
+
+	// Channel, if set, overrides the channel a Slack webhook posts to.
+	// Teams webhooks post to a fixed channel.
+	Channel string
+
+	// Template renders the message posted for each operation, given the
+	// *operation.Operation. Defaults to DefaultTemplate.
+	Template string
+}
+
+// Notifier posts a message for each capture that completes or fails.
+type Notifier struct {
+	config   Config
+	template *template.Template
+	webhook  *webhook.Notifier
+}
+
+// New creates a Notifier posting as config describes, delivering messages
+// through w.
+func New(config Config, w *webhook.Notifier) (*Notifier, error) {
+	if config.URL == "" {
+		return nil, errors.New("chat: webhook URL is required")
+	}
+	switch config.Format {
+	case "":
+		config.Format = FormatSlack
+	case FormatSlack:
+	case FormatTeams:
+		if config.Channel != "" {
+			return nil, errors.New("chat: Teams webhooks post to a fixed channel")
+		}
+	default:
+		return nil, fmt.Errorf("chat: invalid format %q: must be slack or teams", config.Format)
+	}
+	if config.Template == "" {
+		config.Template = DefaultTemplate
+	}
+
+	// Templates are tried on an empty operation, so that one naming fields
+	// operations lack is rejected now rather than with every capture.
+	tmpl, err := template.New("message").Parse(config.Template)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, &operation.Operation{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("chat: invalid template: %w", err)
+	}
+	return &Notifier{config: config, template: tmpl, webhook: w}, nil
+}
+
+// Notify posts a message about op, if it has completed or failed. Operations
+// that were cancelled or interrupted are not reported.
+func (n *Notifier) Notify(ctx context.Context, op *operation.Operation) error {
+	if op.Status != operation.StatusComplete && op.Status != operation.StatusFailed {
+		return nil
+	}
+
+	var text strings.Builder
+	if err := n.template.Execute(&text, op); err != nil {
+		return fmt.Errorf("chat: failed to render message: %w", err)
+	}
+
+	if err := n.webhook.Notify(ctx, n.config.URL, n.message(text.String())); err != nil {
+		return fmt.Errorf("chat: %w", err)
+	}
+	return nil
+}
+
+// message returns the body posted to the webhook for text.
+func (n *Notifier) message(text string) any {
+	switch n.config.Format {
+	case FormatTeams:
+		return teamsMessage{Type: "MessageCard", Context: "https://schema.org/extensions", Text: text}
+	default:
+		return slackMessage{Text: text, Channel: n.config.Channel}
+	}
+}
+
+// slackMessage is posted to Slack incoming webhooks.
+type slackMessage struct {
+	Text    string `json:"text"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// teamsMessage is a message card posted to Teams incoming webhooks.
+type teamsMessage struct {
+	Type    string `json:"@type"`
+	Context string `json:"@context"`
+	Text    string `json:"text"`
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	cliflag "github.com/tomasbasham/cli-runtime/flag"
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+)
+
+// AnalyzeOptions defines the options for the `analyze` command.
+type AnalyzeOptions struct {
+	iooption.IOStreams
+
+	PrintFlags *cliflag.PrinterFlags
+
+	Path string
+	Top  int
+}
+
+var (
+	analyzeLong = templates.LongDesc(`
+		Summarise a HAR file: the number of requests, bytes by resource type,
+		the slowest requests, the largest responses, the domains contacted and
+		the HTTP protocols used.`)
+
+	analyzeExample = templates.Examples(`
+		# Summarise a capture
+		har analyze capture.har
+
+		# Output the summary as JSON for scripting
+		har analyze capture.har --format json`)
+)
+
+func NewAnalyzeOptions(streams iooption.IOStreams) *AnalyzeOptions {
+	accepted := cliflag.FormatTextFlag | cliflag.FormatJSONFlag | cliflag.FormatPrettyJSONFlag
+	return &AnalyzeOptions{
+		IOStreams:  streams,
+		PrintFlags: cliflag.NewPrinterFlags(accepted, cliflag.FormatText),
+		Top:        10,
+	}
+}
+
+func NewAnalyzeCommand(o *AnalyzeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "analyze [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Summarise the requests of a HAR file",
+		Long:                  analyzeLong,
+		Example:               analyzeExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd.Flags())
+	cmd.Flags().IntVar(&o.Top, "top", o.Top, "Number of slowest and largest requests to list")
+
+	return cmd
+}
+
+func (o *AnalyzeOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+	return nil
+}
+
+func (o *AnalyzeOptions) Validate() error {
+	if o.Top < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+	return nil
+}
+
+func (o *AnalyzeOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	p, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	return p.Print(o.Out, hario.Analyze(h, o.Top))
+}
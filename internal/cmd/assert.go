@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	cliflag "github.com/tomasbasham/cli-runtime/flag"
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// AssertOptions defines the options for the `assert` command.
+type AssertOptions struct {
+	iooption.IOStreams
+
+	PrintFlags *cliflag.PrinterFlags
+
+	budget hario.Budget
+	vitals *capture.WebVitals
+
+	Path       string
+	BudgetPath string
+	VitalsPath string
+}
+
+var (
+	assertLong = templates.LongDesc(`
+		Check a HAR file against a performance budget and exit non-zero if any
+		limit is exceeded, so that a capture can gate a CI pipeline. The budget
+		is a YAML file which may limit the number of requests, total bytes, the
+		time to first byte of the main document, bytes per resource type and
+		the Largest Contentful Paint. LCP is not recorded in the HAR, so
+		limiting it requires the web vitals of the capture as JSON. Byte limits
+		are checked against the transfer sizes recorded in the HAR; should a
+		response they cover have no recorded size, the limit cannot be checked
+		and the command fails.
+
+		    requests: 80
+		    total_bytes: 2000000
+		    ttfb_ms: 600
+		    lcp_ms: 2500
+		    bytes_by_type:
+		      script: 500000
+		      image: 1000000`)
+
+	assertExample = templates.Examples(`
+		# Fail if a capture exceeds its budget
+		har assert capture.har --budget budget.yaml
+
+		# Include the LCP recorded by the capture server
+		har assert capture.har --budget budget.yaml --vitals web_vitals.json`)
+)
+
+func NewAssertOptions(streams iooption.IOStreams) *AssertOptions {
+	accepted := cliflag.FormatTextFlag | cliflag.FormatJSONFlag | cliflag.FormatPrettyJSONFlag
+	return &AssertOptions{
+		IOStreams:  streams,
+		PrintFlags: cliflag.NewPrinterFlags(accepted, cliflag.FormatText),
+	}
+}
+
+func NewAssertCommand(o *AssertOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "assert [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Check a HAR file against a performance budget",
+		Long:                  assertLong,
+		Example:               assertExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.BudgetPath, "budget", "", "YAML file of performance limits (required)")
+	cmd.Flags().StringVar(&o.VitalsPath, "vitals", "", "JSON file of the web vitals of the capture")
+
+	return cmd
+}
+
+func (o *AssertOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+
+	if o.BudgetPath == "" {
+		return fmt.Errorf("--budget is required")
+	}
+	f, err := os.Open(o.BudgetPath)
+	if err != nil {
+		return fmt.Errorf("failed to open budget: %w", err)
+	}
+	defer f.Close()
+	if o.budget, err = hario.ReadBudget(f); err != nil {
+		return err
+	}
+
+	if o.VitalsPath != "" {
+		data, err := os.ReadFile(o.VitalsPath)
+		if err != nil {
+			return fmt.Errorf("failed to read web vitals: %w", err)
+		}
+		o.vitals = &capture.WebVitals{}
+		if err := json.Unmarshal(data, o.vitals); err != nil {
+			return fmt.Errorf("invalid web vitals: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *AssertOptions) Validate() error {
+	if o.budget.LCP > 0 && o.vitals == nil {
+		return fmt.Errorf("the budget limits lcp_ms, which requires --vitals")
+	}
+	return nil
+}
+
+func (o *AssertOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	report := hario.CheckBudget(h, o.budget, o.vitals)
+
+	p, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	if err := p.Print(o.Out, report); err != nil {
+		return err
+	}
+	if len(report.Violations) > 0 {
+		return fmt.Errorf("%s: %d budget violations", o.Path, len(report.Violations))
+	}
+	if len(report.Unchecked) > 0 {
+		return fmt.Errorf("%s: %d budget limits could not be checked", o.Path, len(report.Unchecked))
+	}
+	return nil
+}
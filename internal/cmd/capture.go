@@ -4,28 +4,91 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/chromedp/cdproto/network"
 	"github.com/spf13/cobra"
 
 	"github.com/tomasbasham/cli-runtime/iooption"
 	"github.com/tomasbasham/cli-runtime/templates"
 
-	"github.com/tomasbasham/har-capture/internal/capture"
+	"github.com/tomasbasham/har-capture/internal/compress"
+	"github.com/tomasbasham/har-capture/internal/hario"
 	"github.com/tomasbasham/har-capture/internal/storage"
+	"github.com/tomasbasham/har-capture/pkg/capture"
 )
 
 type CaptureOptions struct {
-	outFile *os.File
+	outFile     *os.File
+	cookies     []capture.CookieSeed
+	geolocation *capture.LatLng
+	actions     []capture.Action
+	hostRules   map[string]string
+	compression compress.Format
 
 	URL               string
 	NavigationTimeout time.Duration
 	TotalTimeout      time.Duration
+	IncludePending    bool
+	IdleDuration      time.Duration
+	MaxInflight       int
+	Warm              bool
 	OutPath           string
+	Storage           string
+	Compress          string
+	Cookies           []string
+	UserAgent         string
+	AcceptLanguage    string
+	DeviceScaleFactor float64
+	Mobile            bool
+	Orientation       string
+	DisableCache      bool
+	BlockURLs         []string
+	Geolocation       string
+	WaitForSelector   string
+	WaitForExpression string
+	ScrollToBottom    bool
+	ScrollStep        int64
+	ScrollDelay       time.Duration
+
+	ScreenshotInterval time.Duration
+
+	CaptureBodies     bool
+	BodyMIMEAllowlist []string
+	MaxBodyBytes      int64
+	Actions           []string
+	FollowNavigations int
+	FailOnException   bool
+	Trace             bool
+	Coverage          bool
+	CapturePDF        bool
+	CaptureSnapshot   bool
+	Screencast        bool
+
+	RemoteDebuggingURL string
+	ChromePath         string
+	ChromeFlags        []string
+	UserDataDir        string
+	Extensions         []string
+	HostRules          []string
+
+	FilterExtensionRequests bool
+
+	IncludeResourceTypes []string
+	ExcludeResourceTypes []string
+	IncludeDomains       []string
+	ExcludeDomains       []string
+
+	Debug bool
 
 	iooption.IOStreams
 }
@@ -68,7 +131,51 @@ func NewCaptureCommand(o *CaptureOptions) *cobra.Command {
 
 	pflags.DurationVarP(&o.NavigationTimeout, "navigation-timeout", "n", 10*time.Second, "Navigation timeout duration")
 	pflags.DurationVarP(&o.TotalTimeout, "total-timeout", "t", 30*time.Second, "Total capture timeout duration")
+	pflags.BoolVar(&o.IncludePending, "include-pending", false, "Record requests still in flight at the total timeout as pending entries")
+	pflags.BoolVar(&o.Warm, "warm", false, "Capture the page a second time with a warm cache to capture_warm.har")
+	pflags.DurationVar(&o.IdleDuration, "idle-duration", 0, "Quiet period after which the network is considered idle (default: Chrome's networkIdle)")
+	pflags.IntVar(&o.MaxInflight, "max-inflight", 0, "Requests that may remain in flight while the network is considered idle")
 	pflags.StringVarP(&o.OutPath, "out", "o", "", "Output file (default: stdout)")
+	pflags.StringVar(&o.Compress, "compress", "", "Compress HAR output: gzip or zstd")
+	pflags.StringVar(&o.Storage, "storage", "", "URL of the storage for artefacts other than the HAR, one of "+storageSchemes+" (default: file:// of the current directory)")
+	pflags.StringVar(&o.UserAgent, "user-agent", "", "Override the browser User-Agent")
+	pflags.StringVar(&o.AcceptLanguage, "accept-language", "", "Override the browser Accept-Language")
+	pflags.Float64Var(&o.DeviceScaleFactor, "device-scale-factor", 0, "Ratio of device pixels to CSS pixels (default 1)")
+	pflags.BoolVar(&o.Mobile, "mobile", false, "Emulate a mobile device with touch input")
+	pflags.StringVar(&o.Orientation, "orientation", "", "Emulate a screen orientation: portrait or landscape")
+	pflags.BoolVar(&o.DisableCache, "disable-cache", false, "Disable the browser cache for a cold-cache capture")
+	pflags.StringArrayVar(&o.BlockURLs, "block-url", nil, "URL pattern to block during capture, '*' is a wildcard (repeatable)")
+	pflags.StringVar(&o.Geolocation, "geolocation", "", "Override the reported position as latitude,longitude")
+	pflags.StringVar(&o.WaitForSelector, "wait-for-selector", "", "Wait until a CSS selector matches before completing")
+	pflags.StringVar(&o.WaitForExpression, "wait-for-expression", "", "Wait until a JavaScript expression is truthy before completing")
+	pflags.BoolVar(&o.ScrollToBottom, "scroll", false, "Scroll to the bottom of the page after load to trigger lazy loading")
+	pflags.Int64Var(&o.ScrollStep, "scroll-step", 0, "Pixels to scroll per step (default: viewport height)")
+	pflags.DurationVar(&o.ScrollDelay, "scroll-delay", 250*time.Millisecond, "Delay between scroll steps")
+	pflags.BoolVar(&o.CaptureBodies, "bodies", false, "Record response bodies in the HAR")
+	pflags.StringArrayVar(&o.BodyMIMEAllowlist, "body-mime", nil, "Only record bodies of this MIME type, e.g. text/* (repeatable)")
+	pflags.Int64Var(&o.MaxBodyBytes, "max-body-bytes", 0, "Omit response bodies larger than this many bytes (0 for no limit)")
+	pflags.DurationVar(&o.ScreenshotInterval, "screenshot-interval", 0, "Also take a screenshot at this interval throughout the capture")
+	pflags.StringArrayVar(&o.Actions, "action", nil, "Scripted action after load: click:SELECTOR, type:SELECTOR=TEXT, wait:DURATION or eval:EXPRESSION (repeatable)")
+	pflags.IntVar(&o.FollowNavigations, "follow-navigations", 0, "Number of navigations after the initial load to keep collecting across")
+	pflags.BoolVar(&o.FailOnException, "fail-on-exception", false, "Fail the capture if the page throws an uncaught exception")
+	pflags.BoolVar(&o.Trace, "trace", false, "Record a Chrome performance trace to trace.json")
+	pflags.BoolVar(&o.Coverage, "coverage", false, "Record JavaScript and CSS coverage to coverage.json")
+	pflags.BoolVar(&o.CapturePDF, "pdf", false, "Render the final page state to page.pdf")
+	pflags.BoolVar(&o.CaptureSnapshot, "snapshot", false, "Save the final page state as MHTML to page.mhtml")
+	pflags.BoolVar(&o.Screencast, "filmstrip", false, "Record a filmstrip of frames throughout the capture")
+	pflags.StringVar(&o.RemoteDebuggingURL, "remote-debugging-url", "", "Connect to a running browser at this CDP endpoint instead of launching Chrome")
+	pflags.StringVar(&o.ChromePath, "chrome-path", "", "Path to the Chrome or Chromium executable to launch")
+	pflags.StringArrayVar(&o.ChromeFlags, "chrome-flag", nil, "Extra flag to pass to Chrome, e.g. --no-sandbox (repeatable)")
+	pflags.StringVar(&o.UserDataDir, "user-data-dir", "", "Chrome profile directory to capture with, reusing its cookies and storage")
+	pflags.StringArrayVar(&o.Extensions, "extension", nil, "Unpacked extension directory or CRX file to load into Chrome (repeatable)")
+	pflags.BoolVar(&o.FilterExtensionRequests, "filter-extension-requests", false, "Omit requests issued by extensions from the HAR")
+	pflags.StringArrayVar(&o.HostRules, "host-rule", nil, "Resolve a host to another address as host=address, e.g. example.com=127.0.0.1 (repeatable)")
+	pflags.StringArrayVar(&o.IncludeResourceTypes, "include-type", nil, "Only record entries of this resource type, e.g. XHR or Fetch (repeatable)")
+	pflags.StringArrayVar(&o.ExcludeResourceTypes, "exclude-type", nil, "Omit entries of this resource type, e.g. Image (repeatable)")
+	pflags.StringArrayVar(&o.IncludeDomains, "include-domain", nil, "Only record entries for hosts matching this glob, e.g. *.example.com (repeatable)")
+	pflags.StringArrayVar(&o.ExcludeDomains, "exclude-domain", nil, "Omit entries for hosts matching this glob (repeatable)")
+	pflags.BoolVar(&o.Debug, "debug", false, "Log capture diagnostics to stderr")
+	pflags.StringArrayVar(&o.Cookies, "cookie", nil, "Cookie to set before navigation as name=value (repeatable)")
 
 	return cmd
 }
@@ -78,9 +185,88 @@ func (o *CaptureOptions) Complete(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("URL is required")
 	}
 	o.URL = args[0]
+
+	for _, c := range o.Cookies {
+		name, value, ok := strings.Cut(c, "=")
+		if !ok {
+			return fmt.Errorf("invalid cookie %q: expected name=value", c)
+		}
+		o.cookies = append(o.cookies, capture.CookieSeed{Name: name, Value: value})
+	}
+
+	for _, r := range o.HostRules {
+		host, addr, ok := strings.Cut(r, "=")
+		if !ok {
+			return fmt.Errorf("invalid host rule %q: expected host=address", r)
+		}
+		if o.hostRules == nil {
+			o.hostRules = make(map[string]string)
+		}
+		o.hostRules[host] = addr
+	}
+
+	if o.Geolocation != "" {
+		lat, lng, ok := strings.Cut(o.Geolocation, ",")
+		if !ok {
+			return fmt.Errorf("invalid geolocation %q: expected latitude,longitude", o.Geolocation)
+		}
+		latitude, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		if err != nil {
+			return fmt.Errorf("invalid geolocation latitude %q: %w", lat, err)
+		}
+		longitude, err := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+		if err != nil {
+			return fmt.Errorf("invalid geolocation longitude %q: %w", lng, err)
+		}
+		o.geolocation = &capture.LatLng{Latitude: latitude, Longitude: longitude}
+	}
+
+	compression, err := compress.Parse(o.Compress)
+	if err != nil {
+		return fmt.Errorf("invalid --compress: %w", err)
+	}
+	o.compression = compression
+
+	for _, a := range o.Actions {
+		action, err := parseAction(a)
+		if err != nil {
+			return err
+		}
+		o.actions = append(o.actions, action)
+	}
+
 	return nil
 }
 
+// parseAction parses a scripted action of the form KIND:ARGUMENT.
+func parseAction(s string) (capture.Action, error) {
+	kind, arg, ok := strings.Cut(s, ":")
+	if !ok {
+		return capture.Action{}, fmt.Errorf("invalid action %q: expected KIND:ARGUMENT", s)
+	}
+
+	switch kind {
+	case "click":
+		return capture.Action{Kind: capture.ActionClick, Selector: arg}, nil
+	case "type":
+		selector, text, ok := strings.Cut(arg, "=")
+		if !ok {
+			return capture.Action{}, fmt.Errorf("invalid type action %q: expected type:SELECTOR=TEXT", s)
+		}
+		return capture.Action{Kind: capture.ActionType, Selector: selector, Text: text}, nil
+	case "wait":
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return capture.Action{}, fmt.Errorf("invalid wait action %q: %w", s, err)
+		}
+		return capture.Action{Kind: capture.ActionWait, Duration: d}, nil
+	case "eval":
+		return capture.Action{Kind: capture.ActionEvaluate, Expression: arg}, nil
+	default:
+		return capture.Action{}, fmt.Errorf("invalid action %q: unknown kind %q", s, kind)
+	}
+}
+
 func (o *CaptureOptions) Validate() error {
 	if len(o.URL) == 0 {
 		return fmt.Errorf("URL is required")
@@ -107,38 +293,164 @@ func (o *CaptureOptions) Run() error {
 		defer o.outFile.Close()
 	}
 
+	var logger *slog.Logger
+	if o.Debug {
+		logger = slog.New(slog.NewTextHandler(o.ErrOut, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
 	fmt.Fprintf(o.Out, "Capturing HAR for %s...\n", o.URL)
 	result, err := capture.Capture(ctx, capture.Options{
-		URL:               o.URL,
-		NavigationTimeout: o.NavigationTimeout,
-		TotalTimeout:      o.TotalTimeout,
-		Screenshots:       true,
+		URL:                 o.URL,
+		NavigationTimeout:   o.NavigationTimeout,
+		TotalTimeout:        o.TotalTimeout,
+		IncludePending:      o.IncludePending,
+		IdleDuration:        o.IdleDuration,
+		MaxInflightRequests: o.MaxInflight,
+		Runs:                o.runMode(),
+		Screenshots:         true,
+		Cookies:             o.cookies,
+		UserAgent:           o.UserAgent,
+		AcceptLanguage:      o.AcceptLanguage,
+		DeviceScaleFactor:   o.DeviceScaleFactor,
+		Mobile:              o.Mobile,
+		Orientation:         capture.Orientation(o.Orientation),
+		DisableCache:        o.DisableCache,
+		BlockURLs:           o.BlockURLs,
+		Geolocation:         o.geolocation,
+		WaitForSelector:     o.WaitForSelector,
+		WaitForExpression:   o.WaitForExpression,
+		ScrollToBottom:      o.ScrollToBottom,
+		ScrollStep:          o.ScrollStep,
+		ScrollDelay:         o.ScrollDelay,
+
+		ScreenshotInterval: o.ScreenshotInterval,
+
+		CaptureBodies:     o.CaptureBodies,
+		BodyMIMEAllowlist: o.BodyMIMEAllowlist,
+		MaxBodyBytes:      o.MaxBodyBytes,
+		Actions:           o.actions,
+		FollowNavigations: o.FollowNavigations,
+		FailOnException:   o.FailOnException,
+		Trace:             o.Trace,
+		Coverage:          o.Coverage,
+		CapturePDF:        o.CapturePDF,
+		CaptureSnapshot:   o.CaptureSnapshot,
+		Screencast:        o.Screencast,
+
+		RemoteDebuggingURL: o.RemoteDebuggingURL,
+		ChromePath:         o.ChromePath,
+		ChromeFlags:        o.ChromeFlags,
+		UserDataDir:        o.UserDataDir,
+		Extensions:         o.Extensions,
+		HostRules:          o.hostRules,
+
+		FilterExtensionRequests: o.FilterExtensionRequests,
+		IncludeResourceTypes:    resourceTypes(o.IncludeResourceTypes),
+		ExcludeResourceTypes:    resourceTypes(o.ExcludeResourceTypes),
+		IncludeDomains:          o.IncludeDomains,
+		ExcludeDomains:          o.ExcludeDomains,
+
+		CreatorVersion: buildVersion(),
+		Logger:         logger,
 	})
+	if errors.Is(err, capture.ErrCancelled) {
+		return fmt.Errorf("capture cancelled before completion")
+	}
 	if err != nil {
 		return fmt.Errorf("capture failed: %w", err)
 	}
 
 	fmt.Fprintf(o.Out, "Capture complete: TTFB=%s, TimedOut=%t\n", result.TTFB, result.TimedOut)
+	if m := result.Metrics; m != nil {
+		fmt.Fprintf(o.Out, "Requests: %d, transferred %d bytes in %.0fms (DOMContentLoaded=%.0fms, load=%.0fms)\n",
+			m.Requests, m.TransferredBytes, m.TotalTime, m.DOMContentLoaded, m.OnLoad)
+		fmt.Fprintf(o.Out, "Paint: FP=%.0fms, FCP=%.0fms", m.FirstPaint, m.FirstContentfulPaint)
+		if m.SpeedIndex > 0 {
+			fmt.Fprintf(o.Out, ", Speed Index=%.0fms", m.SpeedIndex)
+		}
+		fmt.Fprintln(o.Out)
+	}
+	if v := result.WebVitals; v != nil {
+		fmt.Fprintf(o.Out, "Web vitals: LCP=%.0fms, CLS=%.3f, INP=%.0fms, FID=%.0fms, TBT=%.0fms\n", v.LCP, v.CLS, v.INP, v.FID, v.TBT)
+	}
 	if result.TimedOut {
 		fmt.Fprintln(o.ErrOut, "Capture timed out before networkIdle; HAR may be incomplete")
 	}
-
-	harJSON, err := json.MarshalIndent(result.HAR, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal HAR: %w", err)
+	for _, e := range result.Exceptions {
+		fmt.Fprintf(o.ErrOut, "Uncaught exception: %s\n", e.Message)
 	}
 
-	if _, err := o.outFile.Write(harJSON); err != nil {
+	if err := o.writeHAR(o.outFile, result.HAR); err != nil {
 		return fmt.Errorf("failed to write HAR file: %w", err)
 	}
 
-	path, err := os.Getwd()
+	uploader, err := newUploader(ctx, uploaderOptions{Destination: o.Storage})
 	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %w", err)
+		return err
 	}
-	uploader, err := storage.NewLocalUploader(path)
-	if err != nil {
-		return fmt.Errorf("failed to initialise local uploader: %w", err)
+
+	if warm := result.Warm; warm != nil {
+		fmt.Fprintf(o.Out, "Warm capture complete: TTFB=%s, TimedOut=%t\n", warm.TTFB, warm.TimedOut)
+		var warmHAR bytes.Buffer
+		if err := o.writeHAR(&warmHAR, warm.HAR); err != nil {
+			return fmt.Errorf("failed to write warm HAR: %w", err)
+		}
+		if _, err := uploader.Upload(ctx, &storage.UploadRequest{
+			ObjectName:      "capture_warm.har" + o.compression.Extension(),
+			Content:         &warmHAR,
+			ContentType:     "application/json",
+			ContentEncoding: o.compression.ContentEncoding(),
+		}); err != nil {
+			return fmt.Errorf("failed to upload warm HAR: %w", err)
+		}
+	}
+
+	if result.Trace != nil {
+		fmt.Fprintln(o.Out, "Uploading performance trace...")
+		if _, err := uploader.Upload(ctx, &storage.UploadRequest{
+			ObjectName:  "trace.json",
+			Content:     bytes.NewReader(result.Trace),
+			ContentType: "application/json",
+		}); err != nil {
+			return fmt.Errorf("failed to upload trace: %w", err)
+		}
+	}
+
+	if result.Coverage != nil {
+		coverageJSON, err := json.MarshalIndent(result.Coverage, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal coverage: %w", err)
+		}
+		fmt.Fprintln(o.Out, "Uploading coverage report...")
+		if _, err := uploader.Upload(ctx, &storage.UploadRequest{
+			ObjectName:  "coverage.json",
+			Content:     bytes.NewReader(coverageJSON),
+			ContentType: "application/json",
+		}); err != nil {
+			return fmt.Errorf("failed to upload coverage: %w", err)
+		}
+	}
+
+	if result.PDF != nil {
+		fmt.Fprintln(o.Out, "Uploading PDF...")
+		if _, err := uploader.Upload(ctx, &storage.UploadRequest{
+			ObjectName:  "page.pdf",
+			Content:     bytes.NewReader(result.PDF),
+			ContentType: "application/pdf",
+		}); err != nil {
+			return fmt.Errorf("failed to upload PDF: %w", err)
+		}
+	}
+
+	if result.Snapshot != nil {
+		fmt.Fprintln(o.Out, "Uploading snapshot...")
+		if _, err := uploader.Upload(ctx, &storage.UploadRequest{
+			ObjectName:  "page.mhtml",
+			Content:     bytes.NewReader(result.Snapshot),
+			ContentType: "multipart/related",
+		}); err != nil {
+			return fmt.Errorf("failed to upload snapshot: %w", err)
+		}
 	}
 
 	for _, s := range result.Screenshots {
@@ -150,5 +462,47 @@ func (o *CaptureOptions) Run() error {
 		})
 	}
 
+	if len(result.Filmstrip) > 0 {
+		fmt.Fprintf(o.Out, "Uploading %d filmstrip frames...\n", len(result.Filmstrip))
+	}
+	for i, f := range result.Filmstrip {
+		if _, err := uploader.Upload(ctx, &storage.UploadRequest{
+			ObjectName:  fmt.Sprintf("filmstrip/frame_%03d_%s.jpg", i+1, f.CapturedAt.Format("150405.000")),
+			Content:     bytes.NewReader(f.JPEG),
+			ContentType: "image/jpeg",
+		}); err != nil {
+			return fmt.Errorf("failed to upload filmstrip frame %d: %w", i+1, err)
+		}
+	}
+
 	return nil
 }
+
+// writeHAR writes h to w, compressed as selected by the flags.
+func (o *CaptureOptions) writeHAR(w io.Writer, h capture.HAR) error {
+	cw, err := o.compression.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := hario.WriteHARIndent(cw, h, "  "); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// resourceTypes converts resource type names given on the command line.
+func resourceTypes(names []string) []network.ResourceType {
+	types := make([]network.ResourceType, 0, len(names))
+	for _, name := range names {
+		types = append(types, network.ResourceType(name))
+	}
+	return types
+}
+
+// runMode returns the capture run mode selected by the flags.
+func (o *CaptureOptions) runMode() capture.RunMode {
+	if o.Warm {
+		return capture.RunColdWarm
+	}
+	return capture.RunCold
+}
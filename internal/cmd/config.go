@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+// envReference matches ${VAR} and ${VAR:-default} in the values of a
+// configuration file.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// applyConfigFile sets the flags of cmd from the YAML file at path, whose
+// keys are the names of the flags. Flags given on the command line take
+// precedence over the file. References to environment variables in its
+// values, as ${VAR} or ${VAR:-default}, are replaced by their values. The
+// keys named by sections are not flags, and are returned for the caller to
+// interpret.
+func applyConfigFile(cmd *cobra.Command, path string, sections ...string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]any
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	expanded, err := expandEnv(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	config, _ = expanded.(map[string]any)
+
+	flags := cmd.Flags()
+	extra := make(map[string]any)
+	for name, value := range config {
+		if slices.Contains(sections, name) {
+			extra[name] = value
+			continue
+		}
+		f := flags.Lookup(name)
+		if f == nil || name == "config" || name == "help" {
+			return nil, fmt.Errorf("invalid config file %s: unknown setting %q", path, name)
+		}
+		if f.Changed {
+			continue
+		}
+		if err := setFlag(f.Value, value); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %s: %w", path, name, err)
+		}
+	}
+	return extra, nil
+}
+
+// setFlag sets a flag to value, read from a configuration file: a scalar,
+// a list for a repeatable flag, or a mapping for a key=value flag.
+func setFlag(flag interface{ Set(string) error }, value any) error {
+	switch v := value.(type) {
+	case []any:
+		replacer, ok := flag.(interface{ Replace([]string) error })
+		if !ok {
+			return fmt.Errorf("must not be a list")
+		}
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := scalar(item)
+			if err != nil {
+				return err
+			}
+			values = append(values, s)
+		}
+		return replacer.Replace(values)
+	case map[string]any:
+		for key, item := range v {
+			s, err := scalar(item)
+			if err != nil {
+				return err
+			}
+			if err := flag.Set(key + "=" + s); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		s, err := scalar(v)
+		if err != nil {
+			return err
+		}
+		return flag.Set(s)
+	}
+}
+
+// scalar returns the string form of a scalar value of a configuration file.
+func scalar(value any) (string, error) {
+	switch value.(type) {
+	case []any, map[string]any:
+		return "", fmt.Errorf("must be a single value")
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// expandEnv replaces the references to environment variables in the strings
+// of value, failing if one names a variable that is unset and has no
+// default.
+func expandEnv(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		var err error
+		expanded := envReference.ReplaceAllStringFunc(v, func(ref string) string {
+			m := envReference.FindStringSubmatch(ref)
+			if value, ok := os.LookupEnv(m[1]); ok {
+				return value
+			}
+			if strings.Contains(ref, ":-") {
+				return m[2]
+			}
+			err = fmt.Errorf("environment variable %s is not set", m[1])
+			return ""
+		})
+		return expanded, err
+	case []any:
+		for i, item := range v {
+			expanded, err := expandEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+	case map[string]any:
+		for key, item := range v {
+			expanded, err := expandEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
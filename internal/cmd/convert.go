@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+)
+
+// ConvertOptions defines the options for the `convert` command.
+type ConvertOptions struct {
+	iooption.IOStreams
+
+	Path    string
+	To      string
+	OutPath string
+}
+
+var (
+	convertLong = templates.LongDesc(`
+		Convert a HAR file to another format. CSV and TSV produce one row per
+		entry with its URL, method, status, MIME type, sizes and timing phases,
+		ready for spreadsheets and BI tools. k6 and JMeter produce a load-test
+		script replaying the requests of each page with the think times
+		observed in the capture. trace produces Chrome trace event JSON with a
+		slice per request and timing phase, to be explored in Perfetto.`)
+
+	convertExample = templates.Examples(`
+		# Convert a capture to CSV
+		har convert capture.har --to csv -o capture.csv
+
+		# Turn a capture into a k6 load test
+		har convert capture.har --to k6 -o script.js
+
+		# Explore the timings of a capture in Perfetto
+		har convert capture.har --to trace -o trace.json`)
+)
+
+func NewConvertOptions(streams iooption.IOStreams) *ConvertOptions {
+	return &ConvertOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewConvertCommand(o *ConvertOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "convert [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Convert a HAR file to another format",
+		Long:                  convertLong,
+		Example:               convertExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.To, "to", "csv", "Output format: csv, tsv, k6, jmeter or trace")
+	cmd.Flags().StringVarP(&o.OutPath, "out", "o", "", "Output file (default: stdout)")
+
+	return cmd
+}
+
+func (o *ConvertOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+	return nil
+}
+
+func (o *ConvertOptions) Validate() error {
+	switch o.To {
+	case "csv", "tsv", "k6", "jmeter", "trace":
+		return nil
+	default:
+		return fmt.Errorf("unsupported --to %q: expected csv, tsv, k6, jmeter or trace", o.To)
+	}
+}
+
+func (o *ConvertOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = o.Out
+	if o.OutPath != "" {
+		f, err := os.Create(o.OutPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch o.To {
+	case "tsv":
+		return hario.WriteCSV(out, h, '\t')
+	case "k6":
+		return hario.WriteK6(out, h)
+	case "jmeter":
+		return hario.WriteJMeter(out, h)
+	case "trace":
+		return hario.WriteTrace(out, h)
+	default:
+		return hario.WriteCSV(out, h, ',')
+	}
+}
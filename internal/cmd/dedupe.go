@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+)
+
+// DedupeOptions defines the options for the `dedupe` command.
+type DedupeOptions struct {
+	iooption.IOStreams
+
+	Path    string
+	OutPath string
+}
+
+var (
+	dedupeLong = templates.LongDesc(`
+		Collapse identical repeated requests in a HAR file, those with the same
+		method, URL and request body that received the same response, into a
+		single entry. The entry kept records the number of requests it stands
+		for in a _repeatCount field, so polling does not drown analysis. Files
+		ending in .gz or .zst are decompressed, and the output compressed
+		likewise.`)
+
+	dedupeExample = templates.Examples(`
+		# Collapse repeated polls in a capture
+		har dedupe capture.har -o deduped.har`)
+)
+
+func NewDedupeOptions(streams iooption.IOStreams) *DedupeOptions {
+	return &DedupeOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewDedupeCommand(o *DedupeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "dedupe [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Collapse identical repeated requests in a HAR file",
+		Long:                  dedupeLong,
+		Example:               dedupeExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutPath, "out", "o", "", "Output file (default: stdout)")
+
+	return cmd
+}
+
+func (o *DedupeOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+	return nil
+}
+
+func (o *DedupeOptions) Validate() error {
+	return nil
+}
+
+func (o *DedupeOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+	return writeHARFile(o.Out, o.OutPath, hario.Dedupe(h))
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	cliflag "github.com/tomasbasham/cli-runtime/flag"
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+)
+
+// DiffOptions defines the options for the `diff` command.
+type DiffOptions struct {
+	iooption.IOStreams
+
+	PrintFlags *cliflag.PrinterFlags
+
+	BasePath      string
+	CurrentPath   string
+	TimeThreshold time.Duration
+}
+
+var (
+	diffLong = templates.LongDesc(`
+		Compare a HAR file against a baseline. Entries are matched by method
+		and URL, and requests added or removed, status code changes, and size
+		and timing deltas are reported. Sizes are those transferred, and are
+		compared only where both captures recorded them. Times vary between
+		any two captures, so an entry is only reported as slower or faster
+		when its time changes by more than --time-threshold.`)
+
+	diffExample = templates.Examples(`
+		# Compare a capture against a baseline
+		har diff baseline.har current.har
+
+		# Report every change in timing, however small
+		har diff baseline.har current.har --time-threshold 0
+
+		# Output the differences as JSON for further processing
+		har diff baseline.har current.har --format json`)
+)
+
+func NewDiffOptions(streams iooption.IOStreams) *DiffOptions {
+	accepted := cliflag.FormatTextFlag | cliflag.FormatJSONFlag | cliflag.FormatPrettyJSONFlag
+	return &DiffOptions{
+		IOStreams:  streams,
+		PrintFlags: cliflag.NewPrinterFlags(accepted, cliflag.FormatText),
+	}
+}
+
+func NewDiffCommand(o *DiffOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "diff [BASELINE] [CURRENT]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Compare a HAR file against a baseline",
+		Long:                  diffLong,
+		Example:               diffExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd.Flags())
+	cmd.Flags().DurationVar(&o.TimeThreshold, "time-threshold", 100*time.Millisecond, "Change in time below which an entry is not reported")
+
+	return cmd
+}
+
+func (o *DiffOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("BASELINE and CURRENT are required")
+	}
+	o.BasePath, o.CurrentPath = args[0], args[1]
+	return nil
+}
+
+func (o *DiffOptions) Validate() error {
+	if o.TimeThreshold < 0 {
+		return fmt.Errorf("--time-threshold must not be negative")
+	}
+	return nil
+}
+
+func (o *DiffOptions) Run() error {
+	base, err := hario.ReadFile(o.BasePath)
+	if err != nil {
+		return err
+	}
+	current, err := hario.ReadFile(o.CurrentPath)
+	if err != nil {
+		return err
+	}
+
+	p, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	return p.Print(o.Out, hario.DiffHAR(base, current, hario.DiffOptions{
+		TimeThreshold: float64(o.TimeThreshold) / float64(time.Millisecond),
+	}))
+}
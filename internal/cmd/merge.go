@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/compress"
+	"github.com/tomasbasham/har-capture/internal/hario"
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// MergeOptions defines the options for the `merge` command.
+type MergeOptions struct {
+	iooption.IOStreams
+
+	Paths   []string
+	OutPath string
+}
+
+var (
+	mergeLong = templates.LongDesc(`
+		Combine several HAR files into one, so that a journey captured in
+		several parts can be analysed as a single session. Pages are renumbered
+		and entries ordered by start time. Files ending in .gz or .zst are
+		decompressed, and the output compressed likewise.`)
+
+	mergeExample = templates.Examples(`
+		# Merge two captures into one file
+		har merge login.har checkout.har -o journey.har`)
+)
+
+func NewMergeOptions(streams iooption.IOStreams) *MergeOptions {
+	return &MergeOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewMergeCommand(o *MergeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "merge [FILE...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Combine several HAR files into one",
+		Long:                  mergeLong,
+		Example:               mergeExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutPath, "out", "o", "", "Output file (default: stdout)")
+
+	return cmd
+}
+
+func (o *MergeOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.Paths = args
+	return nil
+}
+
+func (o *MergeOptions) Validate() error {
+	if len(o.Paths) < 2 {
+		return fmt.Errorf("at least two files are required")
+	}
+	return nil
+}
+
+func (o *MergeOptions) Run() error {
+	hars := make([]capture.HAR, 0, len(o.Paths))
+	for _, path := range o.Paths {
+		h, err := hario.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hars = append(hars, h)
+	}
+
+	return writeHARFile(o.Out, o.OutPath, hario.Merge(hars...))
+}
+
+// writeHARFile writes h to the file at path, compressed according to its
+// extension, or to out if path is empty.
+func writeHARFile(out io.Writer, path string, h capture.HAR) error {
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w, err := compress.FromFilename(path).NewWriter(out)
+	if err != nil {
+		return err
+	}
+	if err := hario.WriteHARIndent(w, h, "  "); err != nil {
+		return fmt.Errorf("failed to write HAR: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write HAR: %w", err)
+	}
+	return nil
+}
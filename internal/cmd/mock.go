@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+	"github.com/tomasbasham/har-capture/internal/mock"
+)
+
+// MockOptions defines the options for the `mock` command.
+type MockOptions struct {
+	iooption.IOStreams
+
+	Path        string
+	Port        int
+	MatchHeader []string
+}
+
+var (
+	mockLong = templates.LongDesc(`
+		Serve the responses recorded in a HAR file from a local stub server.
+		Requests are matched to recorded ones by method, path and query, and
+		optionally by headers, so that a frontend can be developed against
+		archived backend behaviour without the backend.`)
+
+	mockExample = templates.Examples(`
+		# Replay a capture on port 8081
+		har mock capture.har --port 8081
+
+		# Tell apart responses recorded for different users
+		har mock capture.har --match-header Authorization`)
+)
+
+func NewMockOptions(streams iooption.IOStreams) *MockOptions {
+	return &MockOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewMockCommand(o *MockOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "mock [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Serve the responses recorded in a HAR file",
+		Long:                  mockLong,
+		Example:               mockExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&o.Port, "port", "p", 8081, "Port to listen on")
+	cmd.Flags().StringArrayVar(&o.MatchHeader, "match-header", nil, "Request header that must also match the recorded request (repeatable)")
+
+	return cmd
+}
+
+func (o *MockOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+	return nil
+}
+
+func (o *MockOptions) Validate() error {
+	return nil
+}
+
+func (o *MockOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	handler := mock.New(h, mock.WithHeaderMatch(o.MatchHeader...))
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", o.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(o.Out, "Serving %d recorded responses on %s\n", handler.Len(), srv.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
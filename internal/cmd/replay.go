@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	cliflag "github.com/tomasbasham/cli-runtime/flag"
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+	"github.com/tomasbasham/har-capture/internal/replay"
+)
+
+// ReplayOptions defines the options for the `replay` command.
+type ReplayOptions struct {
+	iooption.IOStreams
+
+	PrintFlags *cliflag.PrinterFlags
+
+	baseURL *url.URL
+
+	Path            string
+	BaseURL         string
+	Hosts           []string
+	Concurrency     int
+	Rate            float64
+	SendCredentials bool
+}
+
+var (
+	replayLong = templates.LongDesc(`
+		Re-issue the requests recorded in a HAR file against another server,
+		such as a staging environment, and report how the status and latency
+		of each response differ from the capture. Only requests to the host of
+		the first entry are replayed unless --host says otherwise.
+
+		Recorded Authorization, Cookie and API key headers are not sent unless
+		--send-credentials is given, so that credentials for the recorded
+		server are not disclosed to another.`)
+
+	replayExample = templates.Examples(`
+		# Replay a capture against staging
+		har replay capture.har --base-url https://staging.example.com
+
+		# Replay four requests at a time, at most ten a second
+		har replay capture.har --base-url https://staging.example.com --concurrency 4 --rate 10`)
+)
+
+func NewReplayOptions(streams iooption.IOStreams) *ReplayOptions {
+	accepted := cliflag.FormatTextFlag | cliflag.FormatJSONFlag | cliflag.FormatPrettyJSONFlag
+	return &ReplayOptions{
+		IOStreams:  streams,
+		PrintFlags: cliflag.NewPrinterFlags(accepted, cliflag.FormatText),
+	}
+}
+
+func NewReplayCommand(o *ReplayOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "replay [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Replay the requests of a HAR file against a server",
+		Long:                  replayLong,
+		Example:               replayExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.BaseURL, "base-url", "", "Server to send the requests to (required)")
+	cmd.Flags().StringArrayVar(&o.Hosts, "host", nil, "Recorded host whose requests are replayed (repeatable)")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 1, "Number of requests in flight at once")
+	cmd.Flags().Float64Var(&o.Rate, "rate", 0, "Maximum requests per second (0 for no limit)")
+	cmd.Flags().BoolVar(&o.SendCredentials, "send-credentials", false, "Replay recorded Authorization, Cookie and API key headers")
+
+	return cmd
+}
+
+func (o *ReplayOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+
+	if o.BaseURL != "" {
+		u, err := url.Parse(o.BaseURL)
+		if err != nil {
+			return fmt.Errorf("invalid --base-url: %w", err)
+		}
+		o.baseURL = u
+	}
+	return nil
+}
+
+func (o *ReplayOptions) Validate() error {
+	if o.baseURL == nil {
+		return fmt.Errorf("--base-url is required")
+	}
+	if o.baseURL.Scheme != "http" && o.baseURL.Scheme != "https" {
+		return fmt.Errorf("--base-url must be an http or https URL")
+	}
+	if o.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if o.Rate < 0 {
+		return fmt.Errorf("--rate must not be negative")
+	}
+	return nil
+}
+
+func (o *ReplayOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := replay.Run(ctx, h, replay.Options{
+		BaseURL:         o.baseURL,
+		Hosts:           o.Hosts,
+		Concurrency:     o.Concurrency,
+		Rate:            o.Rate,
+		SendCredentials: o.SendCredentials,
+	})
+	if err != nil {
+		return err
+	}
+
+	p, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	return p.Print(o.Out, report)
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+)
+
+// ReportOptions defines the options for the `report` command.
+type ReportOptions struct {
+	iooption.IOStreams
+
+	Path         string
+	OutPath      string
+	FilmstripDir string
+}
+
+var (
+	reportLong = templates.LongDesc(`
+		Render a HAR file as a standalone HTML report: a waterfall of its
+		entries, the timings of each page and, given a directory of filmstrip
+		frames, the filmstrip. Everything is embedded in the one file so it can
+		be opened in any browser without a HAR viewer.`)
+
+	reportExample = templates.Examples(`
+		# Render a report of a capture
+		har report capture.har -o report.html
+
+		# Include the frames of a filmstrip captured alongside it
+		har report capture.har --filmstrip ./filmstrip -o report.html`)
+)
+
+func NewReportOptions(streams iooption.IOStreams) *ReportOptions {
+	return &ReportOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewReportCommand(o *ReportOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "report [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Render a HAR file as an HTML waterfall report",
+		Long:                  reportLong,
+		Example:               reportExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutPath, "out", "o", "", "Output file (default: stdout)")
+	cmd.Flags().StringVar(&o.FilmstripDir, "filmstrip", "", "Directory of filmstrip frames to include")
+
+	return cmd
+}
+
+func (o *ReportOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+	return nil
+}
+
+func (o *ReportOptions) Validate() error {
+	return nil
+}
+
+func (o *ReportOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	var frames []hario.Frame
+	if o.FilmstripDir != "" {
+		if frames, err = readFrames(o.FilmstripDir); err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = o.Out
+	if o.OutPath != "" {
+		f, err := os.Create(o.OutPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return hario.WriteReport(out, h, frames)
+}
+
+// readFrames reads the images in dir in name order, which for the frames
+// written by `capture --filmstrip` is the order they were recorded.
+func readFrames(dir string) ([]hario.Frame, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filmstrip: %w", err)
+	}
+
+	var frames []hario.Frame
+	for _, de := range des {
+		mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(de.Name())))
+		if de.IsDir() || !strings.HasPrefix(mimeType, "image/") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, de.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read filmstrip: %w", err)
+		}
+		frames = append(frames, hario.Frame{Name: de.Name(), MimeType: mimeType, Data: data})
+	}
+
+	slices.SortFunc(frames, func(a, b hario.Frame) int { return strings.Compare(a.Name, b.Name) })
+	return frames, nil
+}
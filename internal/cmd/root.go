@@ -11,7 +11,7 @@ import (
 	"github.com/tomasbasham/cli-runtime/printer"
 	"github.com/tomasbasham/cli-runtime/templates"
 
-	_ "github.com/tomasbasham/har-capture/internal/capture"
+	"github.com/tomasbasham/har-capture/pkg/capture"
 )
 
 var (
@@ -67,6 +67,18 @@ func NewRootCommandWithArgs(o *HAROptions) *cobra.Command {
 
 	cmd.AddCommand(NewCaptureCommand(NewCaptureOptions(o.IOStreams)))
 	cmd.AddCommand(NewServeCommand(NewServeOptions()))
+	cmd.AddCommand(NewWorkerCommand(NewWorkerOptions()))
+	cmd.AddCommand(NewValidateCommand(NewValidateOptions(o.IOStreams)))
+	cmd.AddCommand(NewMergeCommand(NewMergeOptions(o.IOStreams)))
+	cmd.AddCommand(NewDiffCommand(NewDiffOptions(o.IOStreams)))
+	cmd.AddCommand(NewScrubCommand(NewScrubOptions(o.IOStreams)))
+	cmd.AddCommand(NewConvertCommand(NewConvertOptions(o.IOStreams)))
+	cmd.AddCommand(NewReportCommand(NewReportOptions(o.IOStreams)))
+	cmd.AddCommand(NewAnalyzeCommand(NewAnalyzeOptions(o.IOStreams)))
+	cmd.AddCommand(NewMockCommand(NewMockOptions(o.IOStreams)))
+	cmd.AddCommand(NewReplayCommand(NewReplayOptions(o.IOStreams)))
+	cmd.AddCommand(NewAssertCommand(NewAssertOptions(o.IOStreams)))
+	cmd.AddCommand(NewDedupeCommand(NewDedupeOptions(o.IOStreams)))
 
 	// The globlal normalisation function ensures that all flags specified meet
 	// the desired format, changing users' input if necessary.
@@ -75,6 +87,15 @@ func NewRootCommandWithArgs(o *HAROptions) *cobra.Command {
 	return cmd
 }
 
+// buildVersion returns the version of this build, which HARs and telemetry
+// identify as their creator, falling back to that of the capture package.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	return capture.Version
+}
+
 func versionInfo() string {
 	if version == "" {
 		return ""
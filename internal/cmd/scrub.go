@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+)
+
+// ScrubOptions defines the options for the `scrub` command.
+type ScrubOptions struct {
+	iooption.IOStreams
+
+	query []*regexp.Regexp
+
+	Path       string
+	OutPath    string
+	Headers    []string
+	Query      []string
+	Hash       bool
+	KeepBodies bool
+}
+
+var (
+	scrubLong = templates.LongDesc(`
+		Remove credentials and other secrets from a HAR file so that it can be
+		shared: cookie values, credential headers such as Authorization,
+		secret query parameters and request and response bodies.`)
+
+	scrubExample = templates.Examples(`
+		# Scrub a capture before sharing it
+		har scrub capture.har -o shared.har
+
+		# Hash secrets rather than removing them, and scrub a custom header
+		har scrub capture.har --hash --header X-Tenant-Key -o shared.har`)
+)
+
+func NewScrubOptions(streams iooption.IOStreams) *ScrubOptions {
+	return &ScrubOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewScrubCommand(o *ScrubOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "scrub [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Remove credentials and secrets from a HAR file",
+		Long:                  scrubLong,
+		Example:               scrubExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.OutPath, "out", "o", "", "Output file (default: stdout)")
+	cmd.Flags().StringArrayVar(&o.Headers, "header", nil, "Additional header to scrub (repeatable)")
+	cmd.Flags().StringArrayVar(&o.Query, "query-param", nil, "Regular expression matching additional query parameter names to scrub (repeatable)")
+	cmd.Flags().BoolVar(&o.Hash, "hash", false, "Replace secrets with a digest rather than removing them")
+	cmd.Flags().BoolVar(&o.KeepBodies, "keep-bodies", false, "Retain request and response bodies")
+
+	return cmd
+}
+
+func (o *ScrubOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+
+	for _, q := range o.Query {
+		re, err := regexp.Compile(q)
+		if err != nil {
+			return fmt.Errorf("invalid query parameter pattern %q: %w", q, err)
+		}
+		o.query = append(o.query, re)
+	}
+	return nil
+}
+
+func (o *ScrubOptions) Validate() error {
+	return nil
+}
+
+func (o *ScrubOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	hario.Scrub(h, hario.ScrubOptions{
+		Headers:    o.Headers,
+		Query:      o.query,
+		Hash:       o.Hash,
+		KeepBodies: o.KeepBodies,
+	})
+
+	return writeHARFile(o.Out, o.OutPath, h)
+}
@@ -1,10 +1,19 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -12,30 +21,107 @@ import (
 
 	"github.com/tomasbasham/cli-runtime/templates"
 
-	"github.com/tomasbasham/har-capture/internal/capture"
+	"github.com/tomasbasham/har-capture/internal/auth"
+	"github.com/tomasbasham/har-capture/internal/chat"
+	"github.com/tomasbasham/har-capture/internal/compress"
+	"github.com/tomasbasham/har-capture/internal/jobs"
 	"github.com/tomasbasham/har-capture/internal/operation"
 	"github.com/tomasbasham/har-capture/internal/server"
 	"github.com/tomasbasham/har-capture/internal/storage"
+	"github.com/tomasbasham/har-capture/internal/tracing"
+	"github.com/tomasbasham/har-capture/internal/webhook"
+	"github.com/tomasbasham/har-capture/pkg/capture"
 )
 
 type ServeOptions struct {
-	uploader storage.Uploader
+	uploader    storage.Uploader
+	compression compress.Format
+	logger      *slog.Logger
+	profiles    map[string]server.Profile
+
+	ConfigFile string
 
 	Port              int
+	GRPCPort          int
+	TLSCert           string
+	TLSKey            string
+	TLSSelfSigned     bool
+	Storage           string
 	GCSBucket         string
+	ArtefactRetention time.Duration
+	GzipUploads       bool
 	NavigationTimeout time.Duration
 	TotalTimeout      time.Duration
+	PoolSize          int
+	MaxConcurrent     int
+	InlineHARLimit    int64
+	MaxAttempts       int
+	RetryBackoff      time.Duration
+	UploadAttempts    int
+	UploadBackoff     time.Duration
+	UploadParallelism int
+	Compress          string
+	WebhookSecret     string
+	ChatWebhookURL    string
+	ChatFormat        string
+	ChatChannel       string
+	ChatTemplate      string
+	Tracing           bool
+	LogFormat         string
+	ShutdownTimeout   time.Duration
+	OperationTTL      time.Duration
+
+	CORSOrigins []string
+	CORSMethods []string
+	CORSHeaders []string
+	CORSMaxAge  time.Duration
+
+	JWTIssuer      string
+	JWTAudience    string
+	JWTJWKSURL     string
+	JWTTenantClaim string
+
+	APIKeysFile      string
+	KeyMaxConcurrent int
+	KeyDaily         int
+	TenantBuckets    map[string]string
+	ProfilesFile     string
+
+	PubSubProject       string
+	JobsTopic           string
+	UpdatesSubscription string
+
+	RemoteDebuggingURL string
+	ChromePath         string
+	ChromeFlags        []string
 }
 
 var (
-	serveLong = templates.LongDesc(`Start the HAR capture HTTP server.`)
+	serveLong = templates.LongDesc(`
+		Start the HAR capture HTTP server.
+
+		Settings may be given in a YAML file with --config, whose keys are the
+		names of the flags below, and whose values may refer to environment
+		variables as ${VAR} or ${VAR:-default}. Its "profiles" key may define
+		capture profiles as --profiles-file does. Flags given on the command
+		line take precedence over the file.`)
 
 	serveExample = templates.Examples(`
 		# Start on the default port
 		har serve
 
-		# Start on a custom port with a specific GCS bucket
-		har serve --port 9090 --bucket my-har-bucket`)
+		# Start on a custom port, storing artefacts in a GCS bucket
+		har serve --port 9090 --storage gs://my-har-bucket
+
+		# Also serve the gRPC API
+		har serve --grpc-port 9000
+
+		# Start with the settings of a config file
+		har serve --config config.yaml
+
+		# Run captures on separate workers, started with har worker
+		har serve --storage gs://my-har-bucket --pubsub-project my-project \
+		  --jobs-topic har-jobs --updates-subscription har-updates-api`)
 )
 
 func NewServeOptions() *ServeOptions {
@@ -62,19 +148,170 @@ func NewServeCommand(o *ServeOptions) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().StringVar(&o.ConfigFile, "config", "", "YAML file of settings, keyed by flag name")
 	cmd.Flags().IntVarP(&o.Port, "port", "p", 8080, "Port to listen on")
-	cmd.Flags().StringVarP(&o.GCSBucket, "bucket", "b", "", "GCS bucket name for artefact storage (required)")
+	cmd.Flags().IntVar(&o.GRPCPort, "grpc-port", 0, "Port on which to also serve the gRPC API (default: not served)")
+	cmd.Flags().StringVar(&o.TLSCert, "tls-cert", "", "PEM certificate with which to serve HTTPS and gRPC over TLS, followed by any intermediates")
+	cmd.Flags().StringVar(&o.TLSKey, "tls-key", "", "PEM private key of --tls-cert")
+	cmd.Flags().BoolVar(&o.TLSSelfSigned, "tls-self-signed", false, "Serve HTTPS with a self-signed certificate generated at startup, for development")
+	cmd.Flags().StringVar(&o.Storage, "storage", "", "URL of the storage for artefacts, one of "+storageSchemes+" (default: file:// of the current directory)")
+	cmd.Flags().StringVarP(&o.GCSBucket, "bucket", "b", "", "GCS bucket name for artefact storage")
+	_ = cmd.Flags().MarkDeprecated("bucket", "use --storage gs://<bucket> instead")
+	cmd.Flags().DurationVar(&o.ArtefactRetention, "artefact-retention", 0, "Record on artefacts that they may be deleted this long after upload, for the lifecycle rules of the bucket: as the custom time of GCS objects, or the retain_until index tag of Azure blobs (default: the --operation-ttl)")
+	cmd.Flags().BoolVar(&o.GzipUploads, "gzip-uploads", false, "Compress JSON, text and MHTML artefacts with gzip as they are uploaded, stored under the same name with a Content-Encoding of gzip that HTTP clients decode")
 	cmd.Flags().DurationVarP(&o.NavigationTimeout, "navigation-timeout", "n", 10*time.Second, "Default navigation timeout for captures")
 	cmd.Flags().DurationVarP(&o.TotalTimeout, "total-timeout", "t", 30*time.Second, "Default total timeout for captures")
+	cmd.Flags().IntVar(&o.PoolSize, "pool-size", 2, "Number of browsers kept running to serve captures")
+	cmd.Flags().IntVar(&o.MaxAttempts, "max-attempts", 1, "Times to attempt a capture that fails for a transient reason, such as a browser crash")
+	cmd.Flags().DurationVar(&o.RetryBackoff, "retry-backoff", 5*time.Second, "Wait before retrying a capture, doubled for each further attempt")
+	cmd.Flags().IntVar(&o.UploadAttempts, "upload-max-attempts", 3, "Times to attempt the upload of an artefact, rewriting the same object, before the capture fails")
+	cmd.Flags().DurationVar(&o.UploadBackoff, "upload-retry-backoff", time.Second, "Wait before retrying an upload, doubled for each further attempt")
+	cmd.Flags().IntVar(&o.UploadParallelism, "upload-parallelism", server.DefaultUploadParallelism, "Maximum artefacts of a capture uploaded at once")
+	cmd.Flags().IntVar(&o.MaxConcurrent, "max-concurrent-captures", server.DefaultMaxConcurrentCaptures, "Maximum captures run at once; further captures wait, pending")
+	cmd.Flags().Int64Var(&o.InlineHARLimit, "inline-har-limit", server.DefaultInlineHARLimit, "Largest HAR in bytes returned inline by GET /captures/{id}?include=har; 0 disables")
+	cmd.Flags().StringVar(&o.Compress, "compress", "", "Compress HAR artefacts: gzip or zstd")
+	cmd.Flags().StringArrayVar(&o.CORSOrigins, "cors-origin", nil, "Origin allowed to call the API from a browser, e.g. https://*.example.com, or * for any (repeatable)")
+	cmd.Flags().StringArrayVar(&o.CORSMethods, "cors-method", nil, "Method allowed in cross-origin requests (repeatable; default GET, POST and DELETE)")
+	cmd.Flags().StringArrayVar(&o.CORSHeaders, "cors-header", nil, "Header allowed in cross-origin requests (repeatable; default Authorization, Content-Type, Idempotency-Key and X-API-Key)")
+	cmd.Flags().DurationVar(&o.CORSMaxAge, "cors-max-age", 10*time.Minute, "How long browsers may cache the response to a preflight request")
+	cmd.Flags().StringVar(&o.JWTIssuer, "jwt-issuer", "", "Require requests to bear a JWT from this OpenID Connect issuer")
+	cmd.Flags().StringVar(&o.JWTAudience, "jwt-audience", "", "Audience JWTs must be issued for")
+	cmd.Flags().StringVar(&o.JWTJWKSURL, "jwt-jwks-url", "", "URL of the keys signing JWTs (default: discovered from the issuer)")
+	cmd.Flags().StringVar(&o.JWTTenantClaim, "jwt-tenant-claim", "", "JWT claim naming the tenant of the caller, for multi-tenant deployments")
+	cmd.Flags().StringVar(&o.APIKeysFile, "api-keys-file", "", "Require requests to bear an API key from this file, of lines \"<tenant> <key> [max_concurrent=N] [daily=N]\"; each tenant sees only its own captures")
+	cmd.Flags().IntVar(&o.KeyMaxConcurrent, "key-max-concurrent-captures", 0, "Maximum unfinished captures of each API key, or JWT subject, unless its key sets max_concurrent (default: unlimited)")
+	cmd.Flags().IntVar(&o.KeyDaily, "key-daily-captures", 0, "Maximum captures of each API key, or JWT subject, each day from midnight UTC, unless its key sets daily (default: unlimited)")
+	cmd.Flags().StringToStringVar(&o.TenantBuckets, "tenant-bucket", nil, "Storage for the artefacts of a tenant, as tenant=url, or tenant=bucket for GCS (repeatable; default: the --storage, under tenants/<tenant>/)")
+	cmd.Flags().StringVar(&o.PubSubProject, "pubsub-project", "", "Google Cloud project of the Pub/Sub topic and subscription below")
+	cmd.Flags().StringVar(&o.JobsTopic, "jobs-topic", "", "Pub/Sub topic to which to publish captures for workers to run, rather than running them in this process")
+	cmd.Flags().StringVar(&o.UpdatesSubscription, "updates-subscription", "", "Pub/Sub subscription from which to receive the progress of captures run by workers, not to be shared with other servers")
+	cmd.Flags().StringVar(&o.ProfilesFile, "profiles-file", "", "JSON file of named sets of capture options that requests may refer to by their profile field")
+	cmd.Flags().DurationVar(&o.OperationTTL, "operation-ttl", 0, "Delete operations and their artefacts this long after they finish (default: kept while the server runs)")
+	cmd.Flags().DurationVar(&o.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "Time allowed on SIGINT or SIGTERM for running captures to finish before they are interrupted")
+	cmd.Flags().StringVar(&o.LogFormat, "log-format", "text", "Format of the access and capture logs written to stderr: text or json")
+	cmd.Flags().BoolVar(&o.Tracing, "tracing", false, "Export OpenTelemetry traces over OTLP/HTTP, configured by the OTEL_EXPORTER_OTLP_* environment variables")
+	cmd.Flags().StringVar(&o.WebhookSecret, "webhook-secret", "", "Secret with which to sign capture callbacks (HMAC-SHA256)")
+	cmd.Flags().StringVar(&o.ChatWebhookURL, "chat-webhook-url", "", "Slack or Teams incoming webhook to which to post a message about each capture that completes or fails")
+	cmd.Flags().StringVar(&o.ChatFormat, "chat-format", string(chat.FormatSlack), "Kind of --chat-webhook-url: slack or teams")
+	cmd.Flags().StringVar(&o.ChatChannel, "chat-channel", "", "Slack channel to post to, overriding that of the webhook")
+	cmd.Flags().StringVar(&o.ChatTemplate, "chat-template", "", "Go template of the message posted for each capture, given the operation (default: its URL, status, TTFB and artefact links)")
+	cmd.Flags().StringVar(&o.RemoteDebuggingURL, "remote-debugging-url", "", "Run captures against a running browser at this CDP endpoint")
+	cmd.Flags().StringVar(&o.ChromePath, "chrome-path", "", "Path to the Chrome or Chromium executable to launch")
+	cmd.Flags().StringArrayVar(&o.ChromeFlags, "chrome-flag", nil, "Extra flag to pass to Chrome, e.g. --no-sandbox (repeatable)")
 
 	return cmd
 }
 
 func (o *ServeOptions) Complete(cmd *cobra.Command, args []string) error {
+	if o.ConfigFile != "" {
+		sections, err := applyConfigFile(cmd, o.ConfigFile, "profiles")
+		if err != nil {
+			return err
+		}
+		if profiles, ok := sections["profiles"]; ok {
+			if o.ProfilesFile != "" {
+				return fmt.Errorf("--profiles-file cannot be used with profiles in the config file")
+			}
+			data, err := json.Marshal(profiles)
+			if err != nil {
+				return fmt.Errorf("invalid config file %s: %w", o.ConfigFile, err)
+			}
+			if o.profiles, err = server.ReadProfiles(bytes.NewReader(data)); err != nil {
+				return fmt.Errorf("invalid config file %s: %w", o.ConfigFile, err)
+			}
+		}
+	}
+	if o.ProfilesFile != "" {
+		profiles, err := loadProfiles(o.ProfilesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load profiles: %w", err)
+		}
+		o.profiles = profiles
+	}
+
+	compression, err := compress.Parse(o.Compress)
+	if err != nil {
+		return fmt.Errorf("invalid --compress: %w", err)
+	}
+	o.compression = compression
+
+	switch o.LogFormat {
+	case "text":
+		o.logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	case "json":
+		o.logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	default:
+		return fmt.Errorf("invalid --log-format %q: must be text or json", o.LogFormat)
+	}
 	return nil
 }
 
 func (o *ServeOptions) Validate() error {
+	if o.PoolSize < 1 {
+		return fmt.Errorf("--pool-size must be at least 1")
+	}
+	if o.MaxAttempts < 1 {
+		return fmt.Errorf("--max-attempts must be at least 1")
+	}
+	if o.UploadAttempts < 1 {
+		return fmt.Errorf("--upload-max-attempts must be at least 1")
+	}
+	if o.UploadParallelism < 1 {
+		return fmt.Errorf("--upload-parallelism must be at least 1")
+	}
+	if o.GRPCPort != 0 && o.GRPCPort == o.Port {
+		return fmt.Errorf("--grpc-port must differ from --port")
+	}
+	if (o.TLSCert == "") != (o.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	if o.TLSSelfSigned && o.TLSCert != "" {
+		return fmt.Errorf("--tls-self-signed cannot be used with --tls-cert")
+	}
+	if o.KeyMaxConcurrent < 0 || o.KeyDaily < 0 {
+		return fmt.Errorf("--key-max-concurrent-captures and --key-daily-captures must not be negative")
+	}
+	if o.OperationTTL < 0 {
+		return fmt.Errorf("--operation-ttl must not be negative")
+	}
+	if o.MaxConcurrent < 1 {
+		return fmt.Errorf("--max-concurrent-captures must be at least 1")
+	}
+	if len(o.CORSOrigins) == 0 && (len(o.CORSMethods) > 0 || len(o.CORSHeaders) > 0) {
+		return fmt.Errorf("--cors-method and --cors-header require --cors-origin")
+	}
+	if o.JWTIssuer == "" && (o.JWTAudience != "" || o.JWTJWKSURL != "" || o.JWTTenantClaim != "") {
+		return fmt.Errorf("--jwt-audience, --jwt-jwks-url and --jwt-tenant-claim require --jwt-issuer")
+	}
+	if o.JWTIssuer != "" && o.APIKeysFile != "" {
+		return fmt.Errorf("--jwt-issuer and --api-keys-file cannot be used together")
+	}
+	if o.ChatWebhookURL == "" && (o.ChatChannel != "" || o.ChatTemplate != "") {
+		return fmt.Errorf("--chat-channel and --chat-template require --chat-webhook-url")
+	}
+	if (o.JobsTopic == "") != (o.UpdatesSubscription == "") {
+		return fmt.Errorf("--jobs-topic and --updates-subscription must be given together")
+	}
+	if o.JobsTopic != "" && o.PubSubProject == "" {
+		return fmt.Errorf("--jobs-topic requires --pubsub-project")
+	}
+	if o.ArtefactRetention < 0 {
+		return fmt.Errorf("--artefact-retention must not be negative")
+	}
+	if o.ArtefactRetention > 0 && o.ArtefactRetention < o.OperationTTL {
+		return fmt.Errorf("--artefact-retention must not be less than --operation-ttl")
+	}
+	if o.Storage != "" && o.GCSBucket != "" {
+		return fmt.Errorf("--storage and --bucket cannot be used together")
+	}
+	if o.JobsTopic != "" && strings.HasPrefix(o.Storage, "mem:") {
+		return fmt.Errorf("--storage mem:// cannot be used with --jobs-topic, as workers cannot share it")
+	}
+	for tenant := range o.TenantBuckets {
+		if !auth.ValidTenant(tenant) {
+			return fmt.Errorf("invalid --tenant-bucket tenant %q: must be lowercase letters, digits and hyphens", tenant)
+		}
+	}
 	return nil
 }
 
@@ -82,20 +319,31 @@ func (o *ServeOptions) Run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	var uploader storage.Uploader
-	var err error
-
-	if o.GCSBucket == "" {
-		uploader, err = storage.NewGCSUploader(ctx, o.GCSBucket)
+	if o.Tracing {
+		shutdown, err := tracing.Setup(ctx, buildVersion())
 		if err != nil {
-			return fmt.Errorf("failed to initialise GCS uploader: %w", err)
+			return err
 		}
-	} else {
-		path, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current working directory: %w", err)
-		}
-		uploader, err = storage.NewLocalUploader(path)
+		defer func() {
+			_ = shutdown(context.WithoutCancel(ctx))
+		}()
+	}
+
+	storageOpts := uploaderOptions{
+		Destination: o.Storage,
+		Retention:   o.ArtefactRetention,
+		Gzip:        o.GzipUploads,
+	}
+	if o.GCSBucket != "" {
+		storageOpts.Destination = "gs://" + o.GCSBucket
+	}
+	// Artefacts outlive their operations no longer than necessary.
+	if storageOpts.Retention == 0 {
+		storageOpts.Retention = o.OperationTTL
+	}
+	uploader, err := newUploader(ctx, storageOpts)
+	if err != nil {
+		return err
 	}
 
 	store := operation.NewMemoryStore()
@@ -103,11 +351,265 @@ func (o *ServeOptions) Run() error {
 	defaults := capture.Options{
 		NavigationTimeout: o.NavigationTimeout,
 		TotalTimeout:      o.TotalTimeout,
+		CreatorVersion:    buildVersion(),
+
+		RemoteDebuggingURL: o.RemoteDebuggingURL,
+		ChromePath:         o.ChromePath,
+		ChromeFlags:        o.ChromeFlags,
 	}
 
-	srv := server.New(store, uploader, defaults)
+	serverOpts := []server.Option{
+		server.WithCompression(o.compression),
+		server.WithMaxConcurrentCaptures(o.MaxConcurrent),
+		server.WithInlineHARLimit(o.InlineHARLimit),
+		server.WithLogger(o.logger),
+		server.WithOperationTTL(o.OperationTTL),
+		server.WithQuota(auth.Quota{
+			MaxConcurrent: o.KeyMaxConcurrent,
+			Daily:         o.KeyDaily,
+		}),
+		server.WithRetryPolicy(operation.RetryPolicy{
+			MaxAttempts: o.MaxAttempts,
+			Backoff:     o.RetryBackoff,
+		}),
+		server.WithUploadRetryPolicy(operation.RetryPolicy{
+			MaxAttempts: o.UploadAttempts,
+			Backoff:     o.UploadBackoff,
+		}),
+		server.WithUploadParallelism(o.UploadParallelism),
+		server.WithNotifier(webhook.New(webhook.WithSecret(o.WebhookSecret))),
+	}
+	if len(o.CORSOrigins) > 0 {
+		serverOpts = append(serverOpts, server.WithCORS(server.CORSConfig{
+			AllowedOrigins: o.CORSOrigins,
+			AllowedMethods: o.CORSMethods,
+			AllowedHeaders: o.CORSHeaders,
+			MaxAge:         o.CORSMaxAge,
+		}))
+	}
+	if o.TLSCert != "" || o.TLSSelfSigned {
+		cert, err := o.certificate()
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, server.WithTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}))
+	}
+	if o.ChatWebhookURL != "" {
+		notifier, err := chat.New(chat.Config{
+			URL:      o.ChatWebhookURL,
+			Format:   chat.Format(o.ChatFormat),
+			Channel:  o.ChatChannel,
+			Template: o.ChatTemplate,
+		}, webhook.New())
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, server.WithChatNotifier(notifier))
+	}
+	if o.JWTIssuer != "" {
+		authenticator, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+			Issuer:      o.JWTIssuer,
+			Audience:    o.JWTAudience,
+			JWKSURL:     o.JWTJWKSURL,
+			TenantClaim: o.JWTTenantClaim,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to configure JWT authentication: %w", err)
+		}
+		serverOpts = append(serverOpts, server.WithAuthenticator(authenticator))
+	}
+	if o.APIKeysFile != "" {
+		authenticator, err := loadAPIKeys(o.APIKeysFile)
+		if err != nil {
+			return fmt.Errorf("failed to configure API key authentication: %w", err)
+		}
+		serverOpts = append(serverOpts, server.WithAuthenticator(authenticator))
+	}
+	if o.profiles != nil {
+		serverOpts = append(serverOpts, server.WithProfiles(o.profiles))
+	}
+	// Local storage given a base_url hands out URLs of this server.
+	if h, ok := storage.Unwrap(uploader).(http.Handler); ok {
+		serverOpts = append(serverOpts, server.WithArtefactFiles(h))
+	}
+	if len(o.TenantBuckets) > 0 {
+		uploaders, err := newTenantUploaders(ctx, o.TenantBuckets, storageOpts)
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, server.WithTenantUploaders(uploaders))
+	}
+
+	// Captures run either by workers, or in browsers of this process.
+	var pool *capture.Pool
+	if o.JobsTopic != "" {
+		queue, err := jobs.NewClient(ctx, jobs.Config{
+			ProjectID:           o.PubSubProject,
+			JobsTopic:           o.JobsTopic,
+			UpdatesSubscription: o.UpdatesSubscription,
+		})
+		if err != nil {
+			return err
+		}
+		defer queue.Close()
+		serverOpts = append(serverOpts, server.WithJobQueue(queue))
+	} else {
+		// The browsers must outlive the signal so that running captures can
+		// finish while the server shuts down.
+		pool, err = capture.NewPool(context.WithoutCancel(ctx), o.PoolSize, defaults)
+		if err != nil {
+			return fmt.Errorf("failed to start browser pool: %w", err)
+		}
+		defer pool.Close()
+	}
+
+	srv := server.New(store, uploader, pool, defaults, serverOpts...)
 
 	addr := fmt.Sprintf(":%d", o.Port)
-	fmt.Printf("Starting HAR capture server on %s\n", addr)
-	return srv.ListenAndServe(addr)
+	o.logger.Info("starting HAR capture server", "addr", addr, "tls", o.TLSCert != "" || o.TLSSelfSigned)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe(addr)
+	}()
+
+	grpcErrc := make(chan error, 1)
+	if o.GRPCPort != 0 {
+		grpcAddr := fmt.Sprintf(":%d", o.GRPCPort)
+		o.logger.Info("starting gRPC server", "addr", grpcAddr)
+		go func() {
+			grpcErrc <- srv.ListenAndServeGRPC(grpcAddr)
+		}()
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case err := <-grpcErrc:
+		return fmt.Errorf("gRPC server: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Restore the default behaviour of the signals, so that a second one
+	// stops the server at once.
+	stop()
+	o.logger.Info("shutting down; waiting for running captures", "timeout", o.ShutdownTimeout)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), o.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		o.logger.Warn("captures were interrupted by shutdown", "error", err)
+	}
+
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// certificate returns the certificate with which to serve TLS.
+func (o *ServeOptions) certificate() (tls.Certificate, error) {
+	if o.TLSSelfSigned {
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if hostname, err := os.Hostname(); err == nil {
+			hosts = append(hosts, hostname)
+		}
+		o.logger.Warn("serving TLS with a self-signed certificate, which clients will not trust")
+		return server.SelfSignedCertificate(hosts...)
+	}
+
+	cert, err := tls.LoadX509KeyPair(o.TLSCert, o.TLSKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// storageSchemes lists the forms of storage URL, for help text.
+const storageSchemes = "gs://<bucket>/<prefix>?kms_key=<key>&encryption_key_file=<path>, azblob://<account>/<container>/<prefix> with AZURE_STORAGE_KEY, or file:///<path>?base_url=<URL of this server>"
+
+// uploaderOptions configures where artefacts are stored, and how.
+type uploaderOptions struct {
+	// Destination is the URL of the storage of artefacts, or empty for the
+	// current working directory.
+	Destination string
+
+	// Retention, if positive, is how long artefacts are retained.
+	Retention time.Duration
+
+	// Gzip compresses compressible artefacts as they are uploaded.
+	Gzip bool
+}
+
+// newUploader returns the uploader of artefacts to the destination of o.
+func newUploader(ctx context.Context, o uploaderOptions) (storage.Uploader, error) {
+	dest := o.Destination
+	if dest == "" {
+		path, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current working directory: %w", err)
+		}
+		dest = (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+	uploader, err := storage.New(ctx, dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise uploader: %w", err)
+	}
+	return o.wrap(uploader), nil
+}
+
+// newTenantUploaders returns the uploaders of artefacts to the storage of
+// each tenant, a URL or the name of a GCS bucket, configured otherwise as o.
+func newTenantUploaders(ctx context.Context, destinations map[string]string, o uploaderOptions) (map[string]storage.Uploader, error) {
+	uploaders := make(map[string]storage.Uploader, len(destinations))
+	for tenant, dest := range destinations {
+		if !strings.Contains(dest, "://") {
+			dest = "gs://" + dest
+		}
+		u, err := storage.New(ctx, dest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialise uploader for tenant %q: %w", tenant, err)
+		}
+		uploaders[tenant] = o.wrap(u)
+	}
+	return uploaders, nil
+}
+
+// wrap returns u with the retention and compression of o.
+func (o uploaderOptions) wrap(u storage.Uploader) storage.Uploader {
+	u = storage.WithRetention(u, o.Retention)
+	if o.Gzip {
+		u = storage.WithGzip(u)
+	}
+	return u
+}
+
+// loadAPIKeys returns an authenticator accepting the API keys in the file at
+// path.
+func loadAPIKeys(path string) (*auth.APIKeyAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys, err := auth.ReadAPIKeys(f)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewAPIKeyAuthenticator(keys)
+}
+
+// loadProfiles returns the capture profiles in the file at path.
+func loadProfiles(path string) (map[string]server.Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return server.ReadProfiles(f)
 }
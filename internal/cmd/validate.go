@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/iooption"
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/hario"
+)
+
+// ValidateOptions defines the options for the `validate` command.
+type ValidateOptions struct {
+	iooption.IOStreams
+
+	Path string
+}
+
+var (
+	validateLong = templates.LongDesc(`
+		Check a HAR file against the HAR 1.2 specification, reporting missing
+		required fields, malformed dates and inconsistent timings. Files ending
+		in .gz or .zst are decompressed first.`)
+
+	validateExample = templates.Examples(`
+		# Validate a HAR file
+		har validate capture.har`)
+)
+
+func NewValidateOptions(streams iooption.IOStreams) *ValidateOptions {
+	return &ValidateOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewValidateCommand(o *ValidateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "validate [FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Check a HAR file against the HAR 1.2 specification",
+		Long:                  validateLong,
+		Example:               validateExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func (o *ValidateOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("FILE is required")
+	}
+	o.Path = args[0]
+	return nil
+}
+
+func (o *ValidateOptions) Validate() error {
+	return nil
+}
+
+func (o *ValidateOptions) Run() error {
+	h, err := hario.ReadFile(o.Path)
+	if err != nil {
+		return err
+	}
+
+	problems := hario.Validate(h)
+	for _, p := range problems {
+		fmt.Fprintln(o.Out, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %d problems found", o.Path, len(problems))
+	}
+
+	fmt.Fprintf(o.Out, "%s: valid HAR 1.2\n", o.Path)
+	return nil
+}
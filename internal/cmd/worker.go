@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomasbasham/cli-runtime/templates"
+
+	"github.com/tomasbasham/har-capture/internal/auth"
+	"github.com/tomasbasham/har-capture/internal/compress"
+	"github.com/tomasbasham/har-capture/internal/jobs"
+	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/internal/server"
+	"github.com/tomasbasham/har-capture/internal/tracing"
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+type WorkerOptions struct {
+	compression compress.Format
+	logger      *slog.Logger
+
+	Storage           string
+	GCSBucket         string
+	ArtefactRetention time.Duration
+	GzipUploads       bool
+	TenantBuckets     map[string]string
+	ProfilesFile      string
+	NavigationTimeout time.Duration
+	TotalTimeout      time.Duration
+	PoolSize          int
+	MaxConcurrent     int
+	InlineHARLimit    int64
+	MaxAttempts       int
+	RetryBackoff      time.Duration
+	UploadAttempts    int
+	UploadBackoff     time.Duration
+	UploadParallelism int
+	Compress          string
+	Tracing           bool
+	LogFormat         string
+	ShutdownTimeout   time.Duration
+
+	PubSubProject    string
+	JobsSubscription string
+	UpdatesTopic     string
+
+	RemoteDebuggingURL string
+	ChromePath         string
+	ChromeFlags        []string
+}
+
+var (
+	workerLong = templates.LongDesc(`
+		Run the captures published by har serve --jobs-topic.
+
+		Workers receive captures from a Pub/Sub subscription to the jobs topic,
+		run them, upload their artefacts, and publish their progress to the
+		updates topic, from which the API records it. Captures still queued
+		when a worker stops are run by another.`)
+
+	workerExample = templates.Examples(`
+		# Run captures from the har-jobs-worker subscription
+		har worker --storage gs://my-har-bucket --pubsub-project my-project \
+		  --jobs-subscription har-jobs-worker --updates-topic har-updates`)
+)
+
+func NewWorkerOptions() *WorkerOptions {
+	return &WorkerOptions{}
+}
+
+func NewWorkerCommand(o *WorkerOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "worker",
+		Short:   "Run captures enqueued by the HAR capture server",
+		Long:    workerLong,
+		Example: workerExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.PubSubProject, "pubsub-project", "", "Google Cloud project of the Pub/Sub topic and subscription below (required)")
+	cmd.Flags().StringVar(&o.JobsSubscription, "jobs-subscription", "", "Pub/Sub subscription to the jobs topic of the server, from which to receive captures (required)")
+	cmd.Flags().StringVar(&o.UpdatesTopic, "updates-topic", "", "Pub/Sub topic to which to publish the progress of captures (required)")
+	cmd.Flags().StringVar(&o.Storage, "storage", "", "URL of the storage for artefacts, one of "+storageSchemes+" (default: file:// of the current directory)")
+	cmd.Flags().StringVarP(&o.GCSBucket, "bucket", "b", "", "GCS bucket name for artefact storage")
+	_ = cmd.Flags().MarkDeprecated("bucket", "use --storage gs://<bucket> instead")
+	cmd.Flags().DurationVar(&o.ArtefactRetention, "artefact-retention", 0, "Record on artefacts that they may be deleted this long after upload, for the lifecycle rules of the bucket: as the custom time of GCS objects, or the retain_until index tag of Azure blobs; should match that of the server")
+	cmd.Flags().BoolVar(&o.GzipUploads, "gzip-uploads", false, "Compress JSON, text and MHTML artefacts with gzip as they are uploaded, stored under the same name with a Content-Encoding of gzip that HTTP clients decode")
+	cmd.Flags().StringToStringVar(&o.TenantBuckets, "tenant-bucket", nil, "Storage for the artefacts of a tenant, as tenant=url, or tenant=bucket for GCS (repeatable; default: the --storage, under tenants/<tenant>/)")
+	cmd.Flags().StringVar(&o.ProfilesFile, "profiles-file", "", "JSON file of named sets of capture options that captures may refer to; must match that of the server")
+	cmd.Flags().DurationVarP(&o.NavigationTimeout, "navigation-timeout", "n", 10*time.Second, "Default navigation timeout for captures")
+	cmd.Flags().DurationVarP(&o.TotalTimeout, "total-timeout", "t", 30*time.Second, "Default total timeout for captures")
+	cmd.Flags().IntVar(&o.PoolSize, "pool-size", 2, "Number of browsers kept running to serve captures")
+	cmd.Flags().IntVar(&o.MaxAttempts, "max-attempts", 1, "Times to attempt a capture that fails for a transient reason, such as a browser crash")
+	cmd.Flags().DurationVar(&o.RetryBackoff, "retry-backoff", 5*time.Second, "Wait before retrying a capture, doubled for each further attempt")
+	cmd.Flags().IntVar(&o.UploadAttempts, "upload-max-attempts", 3, "Times to attempt the upload of an artefact, rewriting the same object, before the capture fails")
+	cmd.Flags().DurationVar(&o.UploadBackoff, "upload-retry-backoff", time.Second, "Wait before retrying an upload, doubled for each further attempt")
+	cmd.Flags().IntVar(&o.UploadParallelism, "upload-parallelism", server.DefaultUploadParallelism, "Maximum artefacts of a capture uploaded at once")
+	cmd.Flags().IntVar(&o.MaxConcurrent, "max-concurrent-captures", server.DefaultMaxConcurrentCaptures, "Maximum captures run at once; further captures wait in the subscription")
+	cmd.Flags().Int64Var(&o.InlineHARLimit, "inline-har-limit", server.DefaultInlineHARLimit, "Largest HAR in bytes kept on its operation to be returned inline; 0 disables")
+	cmd.Flags().StringVar(&o.Compress, "compress", "", "Compress HAR artefacts: gzip or zstd")
+	cmd.Flags().DurationVar(&o.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "Time allowed on SIGINT or SIGTERM for running captures to finish before they are returned to the queue")
+	cmd.Flags().StringVar(&o.LogFormat, "log-format", "text", "Format of the capture logs written to stderr: text or json")
+	cmd.Flags().BoolVar(&o.Tracing, "tracing", false, "Export OpenTelemetry traces over OTLP/HTTP, configured by the OTEL_EXPORTER_OTLP_* environment variables")
+	cmd.Flags().StringVar(&o.RemoteDebuggingURL, "remote-debugging-url", "", "Run captures against a running browser at this CDP endpoint")
+	cmd.Flags().StringVar(&o.ChromePath, "chrome-path", "", "Path to the Chrome or Chromium executable to launch")
+	cmd.Flags().StringArrayVar(&o.ChromeFlags, "chrome-flag", nil, "Extra flag to pass to Chrome, e.g. --no-sandbox (repeatable)")
+
+	return cmd
+}
+
+func (o *WorkerOptions) Complete(cmd *cobra.Command, args []string) error {
+	compression, err := compress.Parse(o.Compress)
+	if err != nil {
+		return fmt.Errorf("invalid --compress: %w", err)
+	}
+	o.compression = compression
+
+	switch o.LogFormat {
+	case "text":
+		o.logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	case "json":
+		o.logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	default:
+		return fmt.Errorf("invalid --log-format %q: must be text or json", o.LogFormat)
+	}
+	return nil
+}
+
+func (o *WorkerOptions) Validate() error {
+	if o.PubSubProject == "" || o.JobsSubscription == "" || o.UpdatesTopic == "" {
+		return fmt.Errorf("--pubsub-project, --jobs-subscription and --updates-topic are required")
+	}
+	if o.PoolSize < 1 {
+		return fmt.Errorf("--pool-size must be at least 1")
+	}
+	if o.MaxAttempts < 1 {
+		return fmt.Errorf("--max-attempts must be at least 1")
+	}
+	if o.UploadAttempts < 1 {
+		return fmt.Errorf("--upload-max-attempts must be at least 1")
+	}
+	if o.UploadParallelism < 1 {
+		return fmt.Errorf("--upload-parallelism must be at least 1")
+	}
+	if o.MaxConcurrent < 1 {
+		return fmt.Errorf("--max-concurrent-captures must be at least 1")
+	}
+	if o.ArtefactRetention < 0 {
+		return fmt.Errorf("--artefact-retention must not be negative")
+	}
+	if o.Storage != "" && o.GCSBucket != "" {
+		return fmt.Errorf("--storage and --bucket cannot be used together")
+	}
+	if strings.HasPrefix(o.Storage, "mem:") {
+		return fmt.Errorf("--storage mem:// cannot be shared with the server")
+	}
+	for tenant := range o.TenantBuckets {
+		if !auth.ValidTenant(tenant) {
+			return fmt.Errorf("invalid --tenant-bucket tenant %q: must be lowercase letters, digits and hyphens", tenant)
+		}
+	}
+	return nil
+}
+
+func (o *WorkerOptions) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if o.Tracing {
+		shutdown, err := tracing.Setup(ctx, buildVersion())
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = shutdown(context.WithoutCancel(ctx))
+		}()
+	}
+
+	storageOpts := uploaderOptions{
+		Destination: o.Storage,
+		Retention:   o.ArtefactRetention,
+		Gzip:        o.GzipUploads,
+	}
+	if o.GCSBucket != "" {
+		storageOpts.Destination = "gs://" + o.GCSBucket
+	}
+	uploader, err := newUploader(ctx, storageOpts)
+	if err != nil {
+		return err
+	}
+
+	defaults := capture.Options{
+		NavigationTimeout: o.NavigationTimeout,
+		TotalTimeout:      o.TotalTimeout,
+		CreatorVersion:    buildVersion(),
+
+		RemoteDebuggingURL: o.RemoteDebuggingURL,
+		ChromePath:         o.ChromePath,
+		ChromeFlags:        o.ChromeFlags,
+	}
+
+	// The browsers must outlive the signal so that running captures can
+	// finish while the worker shuts down.
+	pool, err := capture.NewPool(context.WithoutCancel(ctx), o.PoolSize, defaults)
+	if err != nil {
+		return fmt.Errorf("failed to start browser pool: %w", err)
+	}
+	defer pool.Close()
+
+	queue, err := jobs.NewClient(ctx, jobs.Config{
+		ProjectID:        o.PubSubProject,
+		JobsSubscription: o.JobsSubscription,
+		UpdatesTopic:     o.UpdatesTopic,
+	})
+	if err != nil {
+		return err
+	}
+	defer queue.Close()
+
+	serverOpts := []server.Option{
+		server.WithCompression(o.compression),
+		server.WithMaxConcurrentCaptures(o.MaxConcurrent),
+		server.WithInlineHARLimit(o.InlineHARLimit),
+		server.WithLogger(o.logger),
+		server.WithRetryPolicy(operation.RetryPolicy{
+			MaxAttempts: o.MaxAttempts,
+			Backoff:     o.RetryBackoff,
+		}),
+		server.WithUploadRetryPolicy(operation.RetryPolicy{
+			MaxAttempts: o.UploadAttempts,
+			Backoff:     o.UploadBackoff,
+		}),
+		server.WithUploadParallelism(o.UploadParallelism),
+	}
+	if o.ProfilesFile != "" {
+		profiles, err := loadProfiles(o.ProfilesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load profiles: %w", err)
+		}
+		serverOpts = append(serverOpts, server.WithProfiles(profiles))
+	}
+	if len(o.TenantBuckets) > 0 {
+		uploaders, err := newTenantUploaders(ctx, o.TenantBuckets, storageOpts)
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, server.WithTenantUploaders(uploaders))
+	}
+
+	// The worker holds no operations of its own; their progress is recorded
+	// by the API.
+	srv := server.New(operation.NewMemoryStore(), uploader, pool, defaults, serverOpts...)
+
+	o.logger.Info("starting HAR capture worker", "subscription", o.JobsSubscription)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Work(ctx, queue)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			return fmt.Errorf("failed to receive jobs: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Restore the default behaviour of the signals, so that a second one
+	// stops the worker at once.
+	stop()
+	o.logger.Info("shutting down; waiting for running captures", "timeout", o.ShutdownTimeout)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), o.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		o.logger.Warn("captures were returned to the queue by shutdown", "error", err)
+	}
+	return <-errc
+}
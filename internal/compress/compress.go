@@ -0,0 +1,107 @@
+// Package compress compresses artefacts for storage, in the formats that
+// HTTP clients can decode transparently via Content-Encoding.
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is a compression format. The zero value leaves content
+// uncompressed.
+type Format string
+
+const (
+	None Format = ""
+	Gzip Format = "gzip"
+	Zstd Format = "zstd"
+)
+
+// Parse returns the Format named s, which may be empty or "none" for None.
+func Parse(s string) (Format, error) {
+	switch Format(s) {
+	case None, "none":
+		return None, nil
+	case Gzip, Zstd:
+		return Format(s), nil
+	default:
+		return None, fmt.Errorf("compress: unknown format %q, expected gzip or zstd", s)
+	}
+}
+
+// Extension returns the file extension conventionally appended to the name
+// of content compressed in f, e.g. ".gz". Empty for None.
+func (f Format) Extension() string {
+	switch f {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// ContentEncoding returns the HTTP Content-Encoding of content compressed in
+// f. Empty for None.
+func (f Format) ContentEncoding() string {
+	return string(f)
+}
+
+// NewWriter returns a writer that compresses what is written to it in f
+// before writing it to w. It must be closed to flush the compressed stream;
+// closing it does not close w.
+func (f Format) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	switch f {
+	case None:
+		return nopCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("compress: unknown format %q", f)
+	}
+}
+
+// FromFilename returns the Format indicated by the extension of name, or
+// None if it has no compression extension.
+func FromFilename(name string) Format {
+	switch {
+	case strings.HasSuffix(name, Gzip.Extension()):
+		return Gzip
+	case strings.HasSuffix(name, Zstd.Extension()):
+		return Zstd
+	default:
+		return None
+	}
+}
+
+// NewReader returns a reader that decompresses content compressed in f
+// read from r.
+func (f Format) NewReader(r io.Reader) (io.ReadCloser, error) {
+	switch f {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("compress: unknown format %q", f)
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,200 @@
+package hario
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Analysis summarises the requests of a HAR. Sizes are in bytes and times in
+// milliseconds. Sizes are those transferred, and count only the requests
+// whose size is known.
+type Analysis struct {
+	Requests int   `json:"requests"`
+	Size     int64 `json:"size"`
+
+	// UnknownSizes is the number of requests whose size is unknown, so is
+	// not counted in Size.
+	UnknownSizes int `json:"unknown_sizes"`
+
+	// ByType breaks the requests down by the type of their response, largest
+	// first.
+	ByType []Breakdown `json:"by_type"`
+
+	// Domains are the hosts contacted, busiest first.
+	Domains []Breakdown `json:"domains"`
+
+	// Protocols are the HTTP versions of the responses, most common first.
+	Protocols []Breakdown `json:"protocols"`
+
+	Slowest []EntrySummary `json:"slowest"`
+
+	// Largest lists the largest responses of known size.
+	Largest []EntrySummary `json:"largest"`
+}
+
+// Breakdown counts the requests and bytes falling under one name.
+type Breakdown struct {
+	Name         string `json:"name"`
+	Requests     int    `json:"requests"`
+	Size         int64  `json:"size"`
+	UnknownSizes int    `json:"unknown_sizes"`
+}
+
+// Analyze summarises h, listing the top slowest and largest entries.
+func Analyze(h capture.HAR, top int) Analysis {
+	a := Analysis{
+		ByType:    []Breakdown{},
+		Domains:   []Breakdown{},
+		Protocols: []Breakdown{},
+		Slowest:   []EntrySummary{},
+		Largest:   []EntrySummary{},
+	}
+
+	byType := make(map[string]*Breakdown)
+	domains := make(map[string]*Breakdown)
+	protocols := make(map[string]*Breakdown)
+	add := func(m map[string]*Breakdown, name string, size int64) {
+		b, ok := m[name]
+		if !ok {
+			b = &Breakdown{Name: name}
+			m[name] = b
+		}
+		b.Requests++
+		if size < 0 {
+			b.UnknownSizes++
+			return
+		}
+		b.Size += size
+	}
+
+	es := entries(h)
+	for _, e := range es {
+		size := entrySize(e)
+		a.Requests++
+		if size < 0 {
+			a.UnknownSizes++
+		} else {
+			a.Size += size
+		}
+
+		add(byType, resourceType(e), size)
+		if u, err := url.Parse(e.Request.URL); err == nil && u.Host != "" {
+			add(domains, u.Hostname(), size)
+		}
+		protocol := "unknown"
+		if e.Response != nil && e.Response.HTTPVersion != "" {
+			protocol = e.Response.HTTPVersion
+		}
+		add(protocols, protocol, size)
+	}
+
+	a.ByType = breakdowns(byType, func(b Breakdown) int64 { return b.Size })
+	a.Domains = breakdowns(domains, func(b Breakdown) int64 { return int64(b.Requests) })
+	a.Protocols = breakdowns(protocols, func(b Breakdown) int64 { return int64(b.Requests) })
+
+	slowest := slices.Clone(es)
+	slices.SortStableFunc(slowest, func(x, y *capture.Entry) int { return cmp.Compare(y.Time, x.Time) })
+	for _, e := range slowest[:min(top, len(slowest))] {
+		a.Slowest = append(a.Slowest, summarise(e))
+	}
+
+	// Responses of unknown size cannot be ranked, so are left out.
+	largest := slices.DeleteFunc(slices.Clone(es), func(e *capture.Entry) bool { return entrySize(e) < 0 })
+	slices.SortStableFunc(largest, func(x, y *capture.Entry) int { return cmp.Compare(entrySize(y), entrySize(x)) })
+	for _, e := range largest[:min(top, len(largest))] {
+		a.Largest = append(a.Largest, summarise(e))
+	}
+
+	return a
+}
+
+// breakdowns returns the values of m ordered by key, largest first, and then
+// by name.
+func breakdowns(m map[string]*Breakdown, key func(Breakdown) int64) []Breakdown {
+	bs := make([]Breakdown, 0, len(m))
+	for _, b := range m {
+		bs = append(bs, *b)
+	}
+	slices.SortFunc(bs, func(x, y Breakdown) int {
+		if c := cmp.Compare(key(y), key(x)); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Name, y.Name)
+	})
+	return bs
+}
+
+// resourceType classifies an entry by the MIME type of its response.
+func resourceType(e *capture.Entry) string {
+	if e.Response == nil || e.Response.Content == nil {
+		return "other"
+	}
+	mimeType, _, _ := strings.Cut(strings.ToLower(e.Response.Content.MimeType), ";")
+	mimeType = strings.TrimSpace(mimeType)
+
+	switch {
+	case mimeType == "text/html" || mimeType == "application/xhtml+xml":
+		return "document"
+	case strings.Contains(mimeType, "javascript") || mimeType == "application/ecmascript":
+		return "script"
+	case mimeType == "text/css":
+		return "stylesheet"
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.HasPrefix(mimeType, "font/") || strings.Contains(mimeType, "font"):
+		return "font"
+	case strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "audio/"):
+		return "media"
+	case strings.Contains(mimeType, "json") || strings.Contains(mimeType, "xml"):
+		return "data"
+	default:
+		return "other"
+	}
+}
+
+// FormatText renders the analysis for humans.
+func (a Analysis) FormatText() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d requests, %d bytes%s\n", a.Requests, a.Size, unknownSizes(a.UnknownSizes))
+
+	fmt.Fprintln(&b, "\nBy type:")
+	for _, t := range a.ByType {
+		fmt.Fprintf(&b, "  %-12s %5d requests %12d bytes%s\n", t.Name, t.Requests, t.Size, unknownSizes(t.UnknownSizes))
+	}
+
+	fmt.Fprintln(&b, "\nSlowest requests:")
+	for _, e := range a.Slowest {
+		fmt.Fprintf(&b, "  %9.1fms  %s %s\n", e.Time, e.Method, e.URL)
+	}
+
+	fmt.Fprintln(&b, "\nLargest responses:")
+	for _, e := range a.Largest {
+		fmt.Fprintf(&b, "  %10d bytes  %s %s\n", e.Size, e.Method, e.URL)
+	}
+
+	fmt.Fprintln(&b, "\nDomains:")
+	for _, d := range a.Domains {
+		fmt.Fprintf(&b, "  %-40s %5d requests %12d bytes%s\n", d.Name, d.Requests, d.Size, unknownSizes(d.UnknownSizes))
+	}
+
+	fmt.Fprintln(&b, "\nProtocols:")
+	for _, p := range a.Protocols {
+		fmt.Fprintf(&b, "  %-12s %5d requests\n", p.Name, p.Requests)
+	}
+	return b.Bytes(), nil
+}
+
+// unknownSizes notes the number of requests left out of a byte count because
+// their sizes are unknown, if any were.
+func unknownSizes(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (excluding %d of unknown size)", n)
+}
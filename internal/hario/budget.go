@@ -0,0 +1,169 @@
+package hario
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Budget sets performance limits for a capture. Sizes are in bytes and times
+// in milliseconds. Zero limits are not checked.
+type Budget struct {
+	// Requests limits the number of requests made.
+	Requests int `yaml:"requests" json:"requests,omitempty"`
+
+	// TotalBytes limits the bytes transferred across all responses.
+	TotalBytes int64 `yaml:"total_bytes" json:"total_bytes,omitempty"`
+
+	// TTFB limits the time to first byte of the main document.
+	TTFB float64 `yaml:"ttfb_ms" json:"ttfb_ms,omitempty"`
+
+	// LCP limits the Largest Contentful Paint, which is not recorded in the
+	// HAR itself and must be supplied alongside it.
+	LCP float64 `yaml:"lcp_ms" json:"lcp_ms,omitempty"`
+
+	// BytesByType limits the bytes transferred per resource type, as
+	// classified by `har analyze`: document, script, stylesheet, image, font,
+	// media, data or other.
+	BytesByType map[string]int64 `yaml:"bytes_by_type" json:"bytes_by_type,omitempty"`
+}
+
+// ReadBudget parses a YAML budget from r. Unknown fields are rejected, so
+// that a misspelt limit does not go unchecked.
+func ReadBudget(r io.Reader) (Budget, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Budget{}, fmt.Errorf("hario: %w", err)
+	}
+	var b Budget
+	if err := yaml.UnmarshalWithOptions(data, &b, yaml.DisallowUnknownField()); err != nil {
+		return Budget{}, fmt.Errorf("hario: invalid budget: %w", err)
+	}
+	return b, nil
+}
+
+// Violation is a limit of a budget that a capture exceeded.
+type Violation struct {
+	Metric string  `json:"metric"`
+	Limit  float64 `json:"limit"`
+	Actual float64 `json:"actual"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %g exceeds budget of %g", v.Metric, v.Actual, v.Limit)
+}
+
+// Unchecked is a limit of a budget that could not be checked, because the
+// capture does not record what it limits.
+type Unchecked struct {
+	Metric string `json:"metric"`
+	Reason string `json:"reason"`
+}
+
+func (u Unchecked) String() string {
+	return fmt.Sprintf("%s: not checked, %s", u.Metric, u.Reason)
+}
+
+// BudgetReport lists the limits of a budget a capture exceeded, and those
+// that could not be checked. A capture is within budget only if both are
+// empty.
+type BudgetReport struct {
+	Violations []Violation `json:"violations"`
+	Unchecked  []Unchecked `json:"unchecked"`
+}
+
+// WithinBudget reports whether every limit was checked and none exceeded.
+func (r BudgetReport) WithinBudget() bool {
+	return len(r.Violations) == 0 && len(r.Unchecked) == 0
+}
+
+// FormatText renders the report for humans.
+func (r BudgetReport) FormatText() ([]byte, error) {
+	var b bytes.Buffer
+	for _, v := range r.Violations {
+		fmt.Fprintln(&b, v)
+	}
+	for _, u := range r.Unchecked {
+		fmt.Fprintln(&b, u)
+	}
+	if r.WithinBudget() {
+		fmt.Fprintln(&b, "within budget")
+	}
+	return b.Bytes(), nil
+}
+
+// CheckBudget checks h against budget. vitals supplies the LCP and may be
+// nil when the budget does not limit it. Byte limits are left unchecked
+// when the transfer size of a response they cover is unknown, rather than
+// passing on a partial count.
+func CheckBudget(h capture.HAR, budget Budget, vitals *capture.WebVitals) BudgetReport {
+	r := BudgetReport{Violations: []Violation{}, Unchecked: []Unchecked{}}
+	check := func(metric string, limit, actual float64) {
+		if limit > 0 && actual > limit {
+			r.Violations = append(r.Violations, Violation{Metric: metric, Limit: limit, Actual: actual})
+		}
+	}
+	checkSize := func(metric string, limit int64, b Breakdown) {
+		if limit > 0 && b.UnknownSizes > 0 {
+			r.Unchecked = append(r.Unchecked, Unchecked{
+				Metric: metric,
+				Reason: fmt.Sprintf("the transfer sizes of %d of %d responses are unknown", b.UnknownSizes, b.Requests),
+			})
+			return
+		}
+		check(metric, float64(limit), float64(b.Size))
+	}
+
+	a := Analyze(h, 0)
+	check("requests", float64(budget.Requests), float64(a.Requests))
+	checkSize("total_bytes", budget.TotalBytes, Breakdown{Requests: a.Requests, Size: a.Size, UnknownSizes: a.UnknownSizes})
+	check("ttfb_ms", budget.TTFB, ttfb(h))
+	if vitals != nil {
+		check("lcp_ms", budget.LCP, vitals.LCP)
+	}
+
+	types := make(map[string]Breakdown)
+	for _, t := range a.ByType {
+		types[t.Name] = t
+	}
+	for _, name := range slices.Sorted(maps.Keys(budget.BytesByType)) {
+		checkSize("bytes_by_type."+name, budget.BytesByType[name], types[name])
+	}
+
+	return r
+}
+
+// ttfb returns the time to first byte of the main document: the first
+// document requested, or the first request if there is none.
+func ttfb(h capture.HAR) float64 {
+	es := entries(h)
+	if len(es) == 0 {
+		return 0
+	}
+	doc := es[0]
+	for _, e := range es {
+		if resourceType(e) == "document" {
+			doc = e
+			break
+		}
+	}
+
+	t := doc.Timings
+	if t == nil {
+		return 0
+	}
+	// Connect includes the time spent on TLS, so SSL is not added again.
+	var total float64
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait} {
+		if phase > 0 {
+			total += phase
+		}
+	}
+	return total
+}
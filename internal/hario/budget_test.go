@@ -0,0 +1,165 @@
+package hario
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+func TestCheckBudget(t *testing.T) {
+	h := testHAR(
+		testEntry("GET", "https://example.com/", 200),
+		testEntry("GET", "https://example.com/app.js", 200),
+	)
+
+	tests := []struct {
+		name    string
+		budget  Budget
+		vitals  *capture.WebVitals
+		metrics []string
+	}{
+		{
+			name:   "empty",
+			budget: Budget{},
+		},
+		{
+			name:   "within budget",
+			budget: Budget{Requests: 2, TTFB: 30, LCP: 2500},
+			vitals: &capture.WebVitals{LCP: 2500},
+		},
+		{
+			name:    "too many requests",
+			budget:  Budget{Requests: 1},
+			metrics: []string{"requests"},
+		},
+		{
+			name:    "slow first byte",
+			budget:  Budget{TTFB: 25},
+			metrics: []string{"ttfb_ms"},
+		},
+		{
+			name:    "slow LCP",
+			budget:  Budget{LCP: 2500},
+			vitals:  &capture.WebVitals{LCP: 3100.5},
+			metrics: []string{"lcp_ms"},
+		},
+		{
+			name:   "LCP not recorded",
+			budget: Budget{LCP: 2500},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := CheckBudget(h, tt.budget, tt.vitals)
+
+			var metrics []string
+			for _, v := range r.Violations {
+				metrics = append(metrics, v.Metric)
+			}
+			if !slices.Equal(metrics, tt.metrics) {
+				t.Errorf("CheckBudget() violations %v, want %v", metrics, tt.metrics)
+			}
+		})
+	}
+}
+
+func TestCheckBudgetSizes(t *testing.T) {
+	sized := func(url, mimeType string, size int64) *capture.Entry {
+		e := testEntry("GET", url, 200)
+		e.Response.Content.MimeType = mimeType
+		e.TransferSize = &size
+		return e
+	}
+	unsized := testEntry("GET", "https://example.com/logo.png", 200)
+	unsized.Response.Content.MimeType = "image/png"
+
+	tests := []struct {
+		name      string
+		entries   []*capture.Entry
+		budget    Budget
+		metrics   []string
+		unchecked []string
+	}{
+		{
+			name:    "within budget",
+			entries: []*capture.Entry{sized("https://example.com/", "text/html", 1000), sized("https://example.com/app.js", "text/javascript", 4000)},
+			budget:  Budget{TotalBytes: 5000, BytesByType: map[string]int64{"script": 4000}},
+		},
+		{
+			name:    "over budget",
+			entries: []*capture.Entry{sized("https://example.com/", "text/html", 1000), sized("https://example.com/app.js", "text/javascript", 4000)},
+			budget:  Budget{TotalBytes: 4999, BytesByType: map[string]int64{"document": 999, "script": 4000}},
+			metrics: []string{"total_bytes", "bytes_by_type.document"},
+		},
+		{
+			name:      "unknown sizes",
+			entries:   []*capture.Entry{sized("https://example.com/app.js", "text/javascript", 4000), unsized},
+			budget:    Budget{TotalBytes: 5000, BytesByType: map[string]int64{"image": 1000, "script": 3000}},
+			metrics:   []string{"bytes_by_type.script"},
+			unchecked: []string{"total_bytes", "bytes_by_type.image"},
+		},
+		{
+			name:    "unknown sizes not limited",
+			entries: []*capture.Entry{unsized},
+			budget:  Budget{Requests: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := CheckBudget(testHAR(tt.entries...), tt.budget, nil)
+
+			var metrics, unchecked []string
+			for _, v := range r.Violations {
+				metrics = append(metrics, v.Metric)
+			}
+			for _, u := range r.Unchecked {
+				unchecked = append(unchecked, u.Metric)
+			}
+			if !slices.Equal(metrics, tt.metrics) {
+				t.Errorf("CheckBudget() violations %v, want %v", metrics, tt.metrics)
+			}
+			if !slices.Equal(unchecked, tt.unchecked) {
+				t.Errorf("CheckBudget() unchecked %v, want %v", unchecked, tt.unchecked)
+			}
+			if want := len(tt.metrics) == 0 && len(tt.unchecked) == 0; r.WithinBudget() != want {
+				t.Errorf("CheckBudget().WithinBudget() = %t, want %t", r.WithinBudget(), want)
+			}
+		})
+	}
+}
+
+func TestReadBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    Budget
+		wantErr bool
+	}{
+		{
+			name: "limits",
+			yaml: "requests: 50\nttfb_ms: 200\nlcp_ms: 2500\n",
+			want: Budget{Requests: 50, TTFB: 200, LCP: 2500},
+		},
+		{
+			name:    "misspelt limit",
+			yaml:    "request: 50\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadBudget(strings.NewReader(tt.yaml))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadBudget() error = %v, want error %t", err, tt.wantErr)
+			}
+			if got.Requests != tt.want.Requests || got.TTFB != tt.want.TTFB || got.LCP != tt.want.LCP {
+				t.Errorf("ReadBudget() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
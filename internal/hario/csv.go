@@ -0,0 +1,85 @@
+package hario
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// csvHeader names the columns written by WriteCSV. Sizes are in bytes and
+// times in milliseconds, with -1 where unknown, as in the HAR itself.
+var csvHeader = []string{
+	"started", "pageref", "method", "url", "status", "mime_type",
+	"request_headers_size", "request_body_size",
+	"response_headers_size", "response_body_size", "content_size",
+	"time", "blocked", "dns", "connect", "ssl", "send", "wait", "receive",
+}
+
+// WriteCSV writes a header row and then one row per entry of h to w, with
+// fields separated by comma, e.g. ',' for CSV or '\t' for TSV.
+func WriteCSV(w io.Writer, h capture.HAR, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	for _, e := range entries(h) {
+		if err := cw.Write(csvRow(e)); err != nil {
+			return fmt.Errorf("hario: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	return nil
+}
+
+func csvRow(e *capture.Entry) []string {
+	req := e.Request
+	resp := e.Response
+	if resp == nil {
+		resp = &har.Response{HeadersSize: -1, BodySize: -1}
+	}
+	content := resp.Content
+	if content == nil {
+		content = &har.Content{}
+	}
+	t := e.Timings
+	if t == nil {
+		t = &har.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: -1, Wait: -1, Receive: -1}
+	}
+
+	return []string{
+		e.StartedDateTime,
+		e.Pageref,
+		req.Method,
+		req.URL,
+		strconv.FormatInt(resp.Status, 10),
+		content.MimeType,
+		strconv.FormatInt(req.HeadersSize, 10),
+		strconv.FormatInt(req.BodySize, 10),
+		strconv.FormatInt(resp.HeadersSize, 10),
+		strconv.FormatInt(resp.BodySize, 10),
+		strconv.FormatInt(content.Size, 10),
+		formatMillis(e.Time),
+		formatMillis(t.Blocked),
+		formatMillis(t.DNS),
+		formatMillis(t.Connect),
+		formatMillis(t.Ssl),
+		formatMillis(t.Send),
+		formatMillis(t.Wait),
+		formatMillis(t.Receive),
+	}
+}
+
+func formatMillis(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', -1, 64)
+}
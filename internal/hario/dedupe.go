@@ -0,0 +1,68 @@
+package hario
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Dedupe collapses identical repeated requests in h into the first of them:
+// those with the same method, URL and request body that received the same
+// response status and body. Each entry standing for repeats is annotated with
+// the number of requests it represents, so that polls issued hundreds of
+// times are counted once without being lost. Where response bodies were not
+// recorded, responses are compared by status alone. h is left unmodified.
+func Dedupe(h capture.HAR) capture.HAR {
+	var out capture.HAR
+	if h.Log == nil {
+		return out
+	}
+	log := *h.Log
+	log.Entries = nil
+	out.Log = &log
+
+	type key struct{ method, url, request, response string }
+	first := make(map[key]*capture.Entry)
+	for _, e := range h.Log.Entries {
+		if e == nil || e.Request == nil {
+			continue
+		}
+		k := key{
+			method:   e.Request.Method,
+			url:      e.Request.URL,
+			request:  requestBody(e),
+			response: responseHash(e),
+		}
+		if f, ok := first[k]; ok {
+			f.RepeatCount = repeats(f) + repeats(e)
+			continue
+		}
+		entry := *e
+		first[k] = &entry
+		out.Log.Entries = append(out.Log.Entries, &entry)
+	}
+	return out
+}
+
+// repeats returns the number of requests e stands for, which is more than
+// one if it was itself collapsed from repeats.
+func repeats(e *capture.Entry) int {
+	return max(e.RepeatCount, 1)
+}
+
+// responseHash identifies the response of e by its status and body.
+func responseHash(e *capture.Entry) string {
+	resp := e.Response
+	if resp == nil {
+		return ""
+	}
+	sum := sha256.New()
+	sum.Write([]byte{byte(resp.Status >> 8), byte(resp.Status)})
+	if resp.Content != nil {
+		sum.Write([]byte(resp.Content.Encoding))
+		sum.Write([]byte{0})
+		sum.Write([]byte(resp.Content.Text))
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
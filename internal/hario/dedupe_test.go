@@ -0,0 +1,62 @@
+package hario
+
+import (
+	"testing"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+func TestDedupe(t *testing.T) {
+	collapsed := testEntry("GET", "https://example.com/poll", 200)
+	collapsed.RepeatCount = 3
+	mocked := testEntry("GET", "https://example.com/poll", 200)
+	mocked.Mocked = true
+
+	tests := []struct {
+		name    string
+		entries []*capture.Entry
+		want    []int
+	}{
+		{
+			name:    "distinct",
+			entries: []*capture.Entry{testEntry("GET", "https://example.com/a", 200), testEntry("GET", "https://example.com/b", 200)},
+			want:    []int{0, 0},
+		},
+		{
+			name:    "repeats",
+			entries: []*capture.Entry{mocked, testEntry("GET", "https://example.com/poll", 200), testEntry("GET", "https://example.com/poll", 200)},
+			want:    []int{3},
+		},
+		{
+			name:    "different status",
+			entries: []*capture.Entry{testEntry("GET", "https://example.com/poll", 200), testEntry("GET", "https://example.com/poll", 500)},
+			want:    []int{0, 0},
+		},
+		{
+			name:    "already collapsed",
+			entries: []*capture.Entry{collapsed, testEntry("GET", "https://example.com/poll", 200)},
+			want:    []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Dedupe(testHAR(tt.entries...))
+
+			if len(out.Log.Entries) != len(tt.want) {
+				t.Fatalf("Dedupe() kept %d entries, want %d", len(out.Log.Entries), len(tt.want))
+			}
+			for i, e := range out.Log.Entries {
+				if e.RepeatCount != tt.want[i] {
+					t.Errorf("Dedupe() entry %d has repeat count %d, want %d", i, e.RepeatCount, tt.want[i])
+				}
+				if e.Mocked != tt.entries[i].Mocked {
+					t.Errorf("Dedupe() entry %d has mocked %t, want %t", i, e.Mocked, tt.entries[i].Mocked)
+				}
+			}
+			if collapsed.RepeatCount != 3 || mocked.RepeatCount != 0 {
+				t.Errorf("Dedupe() modified its input")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package hario
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Diff is the difference between a baseline HAR and a current one. Entries
+// are matched by method and URL; where several share both, they are matched
+// in order of appearance.
+type Diff struct {
+	Added   []EntrySummary `json:"added"`
+	Removed []EntrySummary `json:"removed"`
+	Changed []EntryChange  `json:"changed"`
+
+	// Totals compares the archives as a whole.
+	Totals TotalsChange `json:"totals"`
+}
+
+// EntrySummary identifies an entry present in only one of the archives. Its
+// size is the number of bytes transferred, or -1 if unknown.
+type EntrySummary struct {
+	Method string  `json:"method"`
+	URL    string  `json:"url"`
+	Status int64   `json:"status"`
+	Size   int64   `json:"size"`
+	Time   float64 `json:"time"`
+}
+
+// EntryChange describes an entry present in both archives whose status,
+// size or time differs. Sizes are the bytes transferred, or -1 if unknown,
+// and times are in milliseconds. SizeDelta is 0 unless both sizes are known.
+type EntryChange struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+
+	BaseStatus int64 `json:"base_status"`
+	Status     int64 `json:"status"`
+
+	BaseSize  int64 `json:"base_size"`
+	Size      int64 `json:"size"`
+	SizeDelta int64 `json:"size_delta"`
+
+	BaseTime  float64 `json:"base_time"`
+	Time      float64 `json:"time"`
+	TimeDelta float64 `json:"time_delta"`
+}
+
+// StatusChanged reports whether the response status differs.
+func (c EntryChange) StatusChanged() bool {
+	return c.BaseStatus != c.Status
+}
+
+// TotalsChange compares the number of requests, bytes and time of two
+// archives. Sizes count only the requests whose size is known; the others
+// are counted by the UnknownSizes fields.
+type TotalsChange struct {
+	BaseRequests     int     `json:"base_requests"`
+	Requests         int     `json:"requests"`
+	BaseSize         int64   `json:"base_size"`
+	Size             int64   `json:"size"`
+	BaseUnknownSizes int     `json:"base_unknown_sizes"`
+	UnknownSizes     int     `json:"unknown_sizes"`
+	BaseTime         float64 `json:"base_time"`
+	Time             float64 `json:"time"`
+}
+
+// DiffOptions controls what DiffHAR reports as changed.
+type DiffOptions struct {
+	// TimeThreshold is how many milliseconds the time of an entry must
+	// change by to be reported, so that the jitter of every request between
+	// captures is not. Zero reports any change.
+	TimeThreshold float64
+}
+
+// DiffHAR compares current against base.
+func DiffHAR(base, current capture.HAR, opts DiffOptions) Diff {
+	d := Diff{
+		Added:   []EntrySummary{},
+		Removed: []EntrySummary{},
+		Changed: []EntryChange{},
+	}
+
+	type key struct{ method, url string }
+	unmatched := make(map[key][]*capture.Entry)
+	var order []key
+	for _, e := range entries(base) {
+		k := key{e.Request.Method, e.Request.URL}
+		if _, ok := unmatched[k]; !ok {
+			order = append(order, k)
+		}
+		unmatched[k] = append(unmatched[k], e)
+		d.Totals.BaseRequests++
+		if size := entrySize(e); size >= 0 {
+			d.Totals.BaseSize += size
+		} else {
+			d.Totals.BaseUnknownSizes++
+		}
+		d.Totals.BaseTime += e.Time
+	}
+
+	for _, e := range entries(current) {
+		d.Totals.Requests++
+		if size := entrySize(e); size >= 0 {
+			d.Totals.Size += size
+		} else {
+			d.Totals.UnknownSizes++
+		}
+		d.Totals.Time += e.Time
+
+		k := key{e.Request.Method, e.Request.URL}
+		candidates := unmatched[k]
+		if len(candidates) == 0 {
+			d.Added = append(d.Added, summarise(e))
+			continue
+		}
+		b := candidates[0]
+		unmatched[k] = candidates[1:]
+
+		c := EntryChange{
+			Method:     e.Request.Method,
+			URL:        e.Request.URL,
+			BaseStatus: entryStatus(b),
+			Status:     entryStatus(e),
+			BaseSize:   entrySize(b),
+			Size:       entrySize(e),
+			BaseTime:   b.Time,
+			Time:       e.Time,
+		}
+		if c.Size >= 0 && c.BaseSize >= 0 {
+			c.SizeDelta = c.Size - c.BaseSize
+		}
+		c.TimeDelta = c.Time - c.BaseTime
+		if c.StatusChanged() || c.SizeDelta != 0 || math.Abs(c.TimeDelta) > opts.TimeThreshold {
+			d.Changed = append(d.Changed, c)
+		}
+	}
+
+	for _, k := range order {
+		for _, e := range unmatched[k] {
+			d.Removed = append(d.Removed, summarise(e))
+		}
+	}
+
+	return d
+}
+
+// FormatText renders the diff for humans.
+func (d Diff) FormatText() ([]byte, error) {
+	var b bytes.Buffer
+	for _, e := range d.Removed {
+		fmt.Fprintf(&b, "- %s %s (%d, %s)\n", e.Method, e.URL, e.Status, formatSize(e.Size))
+	}
+	for _, e := range d.Added {
+		fmt.Fprintf(&b, "+ %s %s (%d, %s)\n", e.Method, e.URL, e.Status, formatSize(e.Size))
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "~ %s %s", c.Method, c.URL)
+		if c.StatusChanged() {
+			fmt.Fprintf(&b, " status %d → %d", c.BaseStatus, c.Status)
+		}
+		if c.SizeDelta != 0 {
+			fmt.Fprintf(&b, " size %+d bytes", c.SizeDelta)
+		}
+		if c.TimeDelta != 0 {
+			fmt.Fprintf(&b, " time %+.1fms", c.TimeDelta)
+		}
+		b.WriteString("\n")
+	}
+
+	t := d.Totals
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed; requests %d → %d, size %d → %d bytes (%+d), time %.1f → %.1fms (%+.1f)\n",
+		len(d.Added), len(d.Removed), len(d.Changed),
+		t.BaseRequests, t.Requests,
+		t.BaseSize, t.Size, t.Size-t.BaseSize,
+		t.BaseTime, t.Time, t.Time-t.BaseTime)
+	if t.BaseUnknownSizes > 0 || t.UnknownSizes > 0 {
+		fmt.Fprintf(&b, "sizes exclude %d → %d requests of unknown size\n", t.BaseUnknownSizes, t.UnknownSizes)
+	}
+	return b.Bytes(), nil
+}
+
+// formatSize renders a size that is -1 when unknown.
+func formatSize(size int64) string {
+	if size < 0 {
+		return "unknown size"
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// entries returns the entries of h that have a request, which every entry of
+// a valid archive does.
+func entries(h capture.HAR) []*capture.Entry {
+	if h.Log == nil {
+		return nil
+	}
+	var es []*capture.Entry
+	for _, e := range h.Log.Entries {
+		if e != nil && e.Request != nil {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+func summarise(e *capture.Entry) EntrySummary {
+	return EntrySummary{
+		Method: e.Request.Method,
+		URL:    e.Request.URL,
+		Status: entryStatus(e),
+		Size:   entrySize(e),
+		Time:   e.Time,
+	}
+}
+
+func entryStatus(e *capture.Entry) int64 {
+	if e.Response == nil {
+		return 0
+	}
+	return e.Response.Status
+}
+
+// entrySize returns the number of bytes received for the response of e,
+// including its headers where they were recorded, or -1 if unknown.
+func entrySize(e *capture.Entry) int64 {
+	if e.TransferSize != nil {
+		return *e.TransferSize
+	}
+	r := e.Response
+	if r == nil || r.BodySize < 0 {
+		return -1
+	}
+	if r.HeadersSize >= 0 {
+		return r.HeadersSize + r.BodySize
+	}
+	return r.BodySize
+}
@@ -0,0 +1,121 @@
+package hario
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+func TestDiffHAR(t *testing.T) {
+	entry := func(url string, status int64, time float64) *capture.Entry {
+		e := testEntry("GET", url, status)
+		e.Time = time
+		return e
+	}
+	sized := func(url string, size int64) *capture.Entry {
+		e := entry(url, 200, 30)
+		e.TransferSize = &size
+		return e
+	}
+
+	tests := []struct {
+		name    string
+		opts    DiffOptions
+		base    []*capture.Entry
+		current []*capture.Entry
+		added   []string
+		removed []string
+		changed []string
+	}{
+		{
+			name:    "identical",
+			base:    []*capture.Entry{entry("https://example.com/", 200, 30)},
+			current: []*capture.Entry{entry("https://example.com/", 200, 30)},
+		},
+		{
+			name:    "added and removed",
+			base:    []*capture.Entry{entry("https://example.com/old", 200, 30)},
+			current: []*capture.Entry{entry("https://example.com/new", 200, 30)},
+			added:   []string{"https://example.com/new"},
+			removed: []string{"https://example.com/old"},
+		},
+		{
+			name:    "status changed",
+			base:    []*capture.Entry{entry("https://example.com/", 200, 30)},
+			current: []*capture.Entry{entry("https://example.com/", 404, 30)},
+			changed: []string{"https://example.com/"},
+		},
+		{
+			name:    "time changed",
+			base:    []*capture.Entry{entry("https://example.com/", 200, 30)},
+			current: []*capture.Entry{entry("https://example.com/", 200, 45)},
+			changed: []string{"https://example.com/"},
+		},
+		{
+			name:    "time changed within threshold",
+			opts:    DiffOptions{TimeThreshold: 20},
+			base:    []*capture.Entry{entry("https://example.com/", 200, 30)},
+			current: []*capture.Entry{entry("https://example.com/", 200, 45)},
+		},
+		{
+			name:    "time changed beyond threshold",
+			opts:    DiffOptions{TimeThreshold: 20},
+			base:    []*capture.Entry{entry("https://example.com/", 200, 30)},
+			current: []*capture.Entry{entry("https://example.com/", 200, 5)},
+			changed: []string{"https://example.com/"},
+		},
+		{
+			name:    "size changed",
+			opts:    DiffOptions{TimeThreshold: 20},
+			base:    []*capture.Entry{sized("https://example.com/", 1000)},
+			current: []*capture.Entry{sized("https://example.com/", 1200)},
+			changed: []string{"https://example.com/"},
+		},
+		{
+			name:    "size unknown",
+			opts:    DiffOptions{TimeThreshold: 20},
+			base:    []*capture.Entry{entry("https://example.com/", 200, 30)},
+			current: []*capture.Entry{sized("https://example.com/", 1200)},
+		},
+		{
+			name: "repeats matched in order",
+			base: []*capture.Entry{
+				entry("https://example.com/poll", 200, 30),
+				entry("https://example.com/poll", 500, 30),
+			},
+			current: []*capture.Entry{entry("https://example.com/poll", 200, 30)},
+			removed: []string{"https://example.com/poll"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiffHAR(testHAR(tt.base...), testHAR(tt.current...), tt.opts)
+
+			var added, removed, changed []string
+			for _, e := range d.Added {
+				added = append(added, e.URL)
+			}
+			for _, e := range d.Removed {
+				removed = append(removed, e.URL)
+			}
+			for _, c := range d.Changed {
+				changed = append(changed, c.URL)
+			}
+			if !slices.Equal(added, tt.added) {
+				t.Errorf("DiffHAR() added %v, want %v", added, tt.added)
+			}
+			if !slices.Equal(removed, tt.removed) {
+				t.Errorf("DiffHAR() removed %v, want %v", removed, tt.removed)
+			}
+			if !slices.Equal(changed, tt.changed) {
+				t.Errorf("DiffHAR() changed %v, want %v", changed, tt.changed)
+			}
+			if d.Totals.BaseRequests != len(tt.base) || d.Totals.Requests != len(tt.current) {
+				t.Errorf("DiffHAR() totals %d → %d requests, want %d → %d",
+					d.Totals.BaseRequests, d.Totals.Requests, len(tt.base), len(tt.current))
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package hario
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// jmxNode is an element of a JMeter test plan. The format is generic enough,
+// with every component described by typed properties, that building the tree
+// node by node is simpler than modelling each component.
+type jmxNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",attr"`
+	Text     string     `xml:",chardata"`
+	Children []jmxNode
+}
+
+func jmxElement(name string, attrs map[string]string, children ...jmxNode) jmxNode {
+	n := jmxNode{XMLName: xml.Name{Local: name}, Children: children}
+	// Attributes are written in a fixed order so the output is stable.
+	for _, k := range []string{"name", "elementType", "guiclass", "testclass", "testname", "enabled"} {
+		if v, ok := attrs[k]; ok {
+			n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Local: k}, Value: v})
+		}
+	}
+	return n
+}
+
+func jmxComponent(class, gui, name string, children ...jmxNode) jmxNode {
+	return jmxElement(class, map[string]string{
+		"guiclass":  gui,
+		"testclass": class,
+		"testname":  name,
+		"enabled":   "true",
+	}, children...)
+}
+
+func jmxProp(kind, name, value string) jmxNode {
+	n := jmxElement(kind, map[string]string{"name": name})
+	n.Text = value
+	return n
+}
+
+func jmxString(name, value string) jmxNode {
+	return jmxProp("stringProp", name, value)
+}
+
+func jmxBool(name string, value bool) jmxNode {
+	return jmxProp("boolProp", name, strconv.FormatBool(value))
+}
+
+// jmxTree wraps the children of a component. JMeter pairs every component
+// with a following hashTree holding those nested beneath it.
+func jmxTree(children ...jmxNode) jmxNode {
+	return jmxElement("hashTree", nil, children...)
+}
+
+// WriteJMeter writes h to w as a JMeter test plan. Each page becomes a
+// transaction controller whose samplers replay its requests in the order
+// they were captured, each preceded by a timer for the think time observed
+// in the capture.
+func WriteJMeter(w io.Writer, h capture.HAR) error {
+	var pages []jmxNode
+	for _, p := range loadTestPages(h) {
+		var samplers []jmxNode
+		for _, s := range p.Steps {
+			samplers = append(samplers, jmxSampler(s.Entry), jmxSamplerTree(s))
+		}
+		pages = append(pages,
+			jmxComponent("TransactionController", "TransactionControllerGui", p.Name,
+				jmxBool("TransactionController.includeTimers", false),
+			),
+			jmxTree(samplers...),
+		)
+	}
+
+	threads := jmxComponent("ThreadGroup", "ThreadGroupGui", "Thread Group",
+		jmxString("ThreadGroup.on_sample_error", "continue"),
+		jmxString("ThreadGroup.num_threads", "1"),
+		jmxString("ThreadGroup.ramp_time", "1"),
+		jmxElement("elementProp", map[string]string{
+			"name":        "ThreadGroup.main_controller",
+			"elementType": "LoopController",
+			"guiclass":    "LoopControlPanel",
+			"testclass":   "LoopController",
+		},
+			jmxBool("LoopController.continue_forever", false),
+			jmxString("LoopController.loops", "1"),
+		),
+	)
+
+	plan := jmxElement("jmeterTestPlan", nil,
+		jmxTree(
+			jmxComponent("TestPlan", "TestPlanGui", "HAR replay"),
+			jmxTree(threads, jmxTree(pages...)),
+		),
+	)
+	plan.Attrs = []xml.Attr{
+		{Name: xml.Name{Local: "version"}, Value: "1.2"},
+		{Name: xml.Name{Local: "properties"}, Value: "5.0"},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(plan); err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	return nil
+}
+
+func jmxSampler(e *capture.Entry) jmxNode {
+	var protocol, domain, port, path string
+	if u, err := url.Parse(e.Request.URL); err == nil {
+		protocol, domain, port, path = u.Scheme, u.Hostname(), u.Port(), u.RequestURI()
+	} else {
+		path = e.Request.URL
+	}
+
+	var args []jmxNode
+	body := requestBody(e)
+	if body != "" {
+		args = append(args, jmxElement("elementProp", map[string]string{"name": "", "elementType": "HTTPArgument"},
+			jmxBool("HTTPArgument.always_encode", false),
+			jmxString("Argument.value", body),
+			jmxString("Argument.metadata", "="),
+		))
+	}
+
+	return jmxComponent("HTTPSamplerProxy", "HttpTestSampleGui", requestLabel(e),
+		jmxString("HTTPSampler.protocol", protocol),
+		jmxString("HTTPSampler.domain", domain),
+		jmxString("HTTPSampler.port", port),
+		jmxString("HTTPSampler.path", path),
+		jmxString("HTTPSampler.method", e.Request.Method),
+		jmxBool("HTTPSampler.follow_redirects", false),
+		jmxBool("HTTPSampler.use_keepalive", true),
+		jmxBool("HTTPSampler.postBodyRaw", body != ""),
+		jmxElement("elementProp", map[string]string{"name": "HTTPsampler.Arguments", "elementType": "Arguments"},
+			jmxElement("collectionProp", map[string]string{"name": "Arguments.arguments"}, args...),
+		),
+	)
+}
+
+// jmxSamplerTree holds the headers of a sampler and the timer that delays it
+// by the think time.
+func jmxSamplerTree(s loadTestStep) jmxNode {
+	var headers []jmxNode
+	for _, h := range replayHeaders(s.Entry) {
+		headers = append(headers, jmxElement("elementProp", map[string]string{"name": "", "elementType": "Header"},
+			jmxString("Header.name", h.Name),
+			jmxString("Header.value", h.Value),
+		))
+	}
+
+	children := []jmxNode{
+		jmxComponent("HeaderManager", "HeaderPanel", "HTTP Header Manager",
+			jmxElement("collectionProp", map[string]string{"name": "HeaderManager.headers"}, headers...),
+		),
+		jmxTree(),
+	}
+	if s.Think > 0 {
+		children = append(children,
+			jmxComponent("ConstantTimer", "ConstantTimerGui", "Think time",
+				jmxString("ConstantTimer.delay", strconv.FormatInt(int64(s.Think), 10)),
+			),
+			jmxTree(),
+		)
+	}
+	return jmxTree(children...)
+}
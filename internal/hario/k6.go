@@ -0,0 +1,61 @@
+package hario
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// WriteK6 writes h to w as a k6 script. Each page becomes a group of
+// requests issued in the order they were captured, separated by the think
+// times observed in the capture.
+func WriteK6(w io.Writer, h capture.HAR) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, `import http from "k6/http";`)
+	fmt.Fprintln(bw, `import { group, sleep } from "k6";`)
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "export default function () {")
+	for _, p := range loadTestPages(h) {
+		fmt.Fprintf(bw, "  group(%s, function () {\n", jsString(p.Name))
+		for _, s := range p.Steps {
+			if s.Think > 0 {
+				fmt.Fprintf(bw, "    sleep(%.3f);\n", s.Think/1000)
+			}
+			writeK6Request(bw, s.Entry)
+		}
+		fmt.Fprintln(bw, "  });")
+	}
+	fmt.Fprintln(bw, "}")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	return nil
+}
+
+func writeK6Request(w io.Writer, e *capture.Entry) {
+	body := "null"
+	if b := requestBody(e); b != "" {
+		body = jsString(b)
+	}
+
+	fmt.Fprintf(w, "    http.request(%s, %s, %s, {\n", jsString(e.Request.Method), jsString(e.Request.URL), body)
+	fmt.Fprintln(w, "      headers: {")
+	for _, h := range replayHeaders(e) {
+		fmt.Fprintf(w, "        %s: %s,\n", jsString(h.Name), jsString(h.Value))
+	}
+	fmt.Fprintln(w, "      },")
+	fmt.Fprintf(w, "      tags: { name: %s },\n", jsString(requestLabel(e)))
+	fmt.Fprintln(w, "    });")
+}
+
+// jsString quotes s as a JavaScript string literal. JSON strings are valid
+// JavaScript, including the escaping of U+2028 and U+2029.
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
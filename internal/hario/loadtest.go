@@ -0,0 +1,117 @@
+package hario
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// loadTestPage is a page of a HAR as replayed by a load-test script: the
+// requests made while it loaded, each preceded by a think time.
+type loadTestPage struct {
+	Name  string
+	Steps []loadTestStep
+}
+
+type loadTestStep struct {
+	Entry *capture.Entry
+
+	// Think is the idle time, in milliseconds, between the previous request
+	// of the page completing and this one starting. Load-test tools issue
+	// requests one after another, so replaying this gap rather than the
+	// difference in start times keeps the pacing of the original page load.
+	Think float64
+}
+
+// loadTestPages groups the entries of h by page, in the order of the pages,
+// with any entries belonging to no page last.
+func loadTestPages(h capture.HAR) []loadTestPage {
+	var pages []loadTestPage
+	index := make(map[string]int)
+	if h.Log != nil {
+		for _, p := range h.Log.Pages {
+			if p == nil {
+				continue
+			}
+			name := p.Title
+			if name == "" {
+				name = p.ID
+			}
+			index[p.ID] = len(pages)
+			pages = append(pages, loadTestPage{Name: name})
+		}
+	}
+
+	ends := make(map[int]time.Time)
+	for _, e := range entries(h) {
+		i, ok := index[e.Pageref]
+		if !ok {
+			i, ok = index[""]
+			if !ok {
+				i = len(pages)
+				index[""] = i
+				pages = append(pages, loadTestPage{Name: "Requests"})
+			}
+		}
+
+		step := loadTestStep{Entry: e}
+		start, err := time.Parse(time.RFC3339Nano, e.StartedDateTime)
+		if err == nil {
+			if end, ok := ends[i]; ok && start.After(end) {
+				step.Think = float64(start.Sub(end)) / float64(time.Millisecond)
+			}
+			end := start.Add(time.Duration(e.Time * float64(time.Millisecond)))
+			if end.After(ends[i]) {
+				ends[i] = end
+			}
+		}
+		pages[i].Steps = append(pages[i].Steps, step)
+	}
+
+	// Pages during which no requests were made have nothing to replay.
+	var ps []loadTestPage
+	for _, p := range pages {
+		if len(p.Steps) > 0 {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// replayHeaders returns the request headers of e worth replaying. HTTP/2
+// pseudo-headers and those the load-test tool computes itself are dropped.
+func replayHeaders(e *capture.Entry) []*har.NameValuePair {
+	var hs []*har.NameValuePair
+	for _, h := range e.Request.Headers {
+		if h == nil || strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		switch strings.ToLower(h.Name) {
+		case "host", "content-length", "connection":
+			continue
+		}
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+// requestBody returns the body of the request of e, if any.
+func requestBody(e *capture.Entry) string {
+	if e.Request.PostData == nil {
+		return ""
+	}
+	return e.Request.PostData.Text
+}
+
+// requestLabel names the request of e by its method and path.
+func requestLabel(e *capture.Entry) string {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil || u.Path == "" {
+		return e.Request.Method + " " + e.Request.URL
+	}
+	return e.Request.Method + " " + u.Path
+}
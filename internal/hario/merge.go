@@ -0,0 +1,105 @@
+package hario
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Merge combines hars into a single log, so that a journey captured in
+// several parts can be analysed as one session. Pages are renumbered so that
+// their IDs remain unique, and pages and entries are ordered chronologically.
+// The inputs are not modified.
+func Merge(hars ...capture.HAR) capture.HAR {
+	log := &capture.Log{
+		Version: "1.2",
+		Creator: &har.Creator{
+			Name:    "har-capture",
+			Version: capture.Version,
+			Comment: fmt.Sprintf("merged from %d archives", len(hars)),
+		},
+		Pages:   []*har.Page{},
+		Entries: []*capture.Entry{},
+	}
+
+	// Page IDs are only unique within their own archive, so are keyed by
+	// the archive they came from until renumbered.
+	type pageKey struct {
+		archive int
+		id      string
+	}
+	var keys []pageKey
+	pages := make(map[pageKey]*har.Page)
+	entryArchive := make(map[*capture.Entry]int)
+
+	for i, h := range hars {
+		if h.Log == nil {
+			continue
+		}
+		if log.Browser == nil {
+			log.Browser = h.Log.Browser
+		}
+		// A null page or entry describes nothing, so is dropped.
+		for _, p := range h.Log.Pages {
+			if p == nil {
+				continue
+			}
+			page := *p
+			k := pageKey{i, p.ID}
+			keys = append(keys, k)
+			pages[k] = &page
+		}
+		for _, e := range h.Log.Entries {
+			if e == nil {
+				continue
+			}
+			entry := *e
+			log.Entries = append(log.Entries, &entry)
+			entryArchive[&entry] = i
+		}
+	}
+
+	slices.SortStableFunc(keys, func(a, b pageKey) int {
+		return compareDates(pages[a].StartedDateTime, pages[b].StartedDateTime)
+	})
+	ids := make(map[pageKey]string, len(keys))
+	for n, k := range keys {
+		p := pages[k]
+		p.ID = fmt.Sprintf("page_%d", n+1)
+		ids[k] = p.ID
+		log.Pages = append(log.Pages, p)
+	}
+
+	for _, e := range log.Entries {
+		if e.Pageref != "" {
+			e.Pageref = ids[pageKey{entryArchive[e], e.Pageref}]
+		}
+	}
+	slices.SortStableFunc(log.Entries, func(a, b *capture.Entry) int {
+		return compareDates(a.StartedDateTime, b.StartedDateTime)
+	})
+
+	return capture.HAR{Log: log}
+}
+
+// compareDates orders two ISO 8601 dates. Dates that cannot be parsed are
+// compared as strings, which orders them correctly in the common case of a
+// shared format and time zone.
+func compareDates(a, b string) int {
+	ta, errA := time.Parse(time.RFC3339Nano, a)
+	tb, errB := time.Parse(time.RFC3339Nano, b)
+	if errA != nil || errB != nil {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+	return ta.Compare(tb)
+}
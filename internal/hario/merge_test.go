@@ -0,0 +1,119 @@
+package hario
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+func TestMerge(t *testing.T) {
+	entry := func(url, started, pageref string) *capture.Entry {
+		e := testEntry("GET", url, 200)
+		e.StartedDateTime = started
+		e.Pageref = pageref
+		return e
+	}
+	page := func(id, started string) *har.Page {
+		return &har.Page{ID: id, StartedDateTime: started, PageTimings: &har.PageTimings{}}
+	}
+
+	type want struct {
+		url, pageref string
+	}
+	tests := []struct {
+		name    string
+		hars    []capture.HAR
+		pages   []string
+		entries []want
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "pages renumbered chronologically",
+			hars: []capture.HAR{
+				{Log: &capture.Log{
+					Pages:   []*har.Page{page("page_1", "2024-01-01T00:01:00Z")},
+					Entries: []*capture.Entry{entry("https://example.com/b", "2024-01-01T00:01:00Z", "page_1")},
+				}},
+				{Log: &capture.Log{
+					Pages:   []*har.Page{page("page_1", "2024-01-01T00:00:00Z")},
+					Entries: []*capture.Entry{entry("https://example.com/a", "2024-01-01T00:00:00Z", "page_1")},
+				}},
+			},
+			pages: []string{"page_1", "page_2"},
+			entries: []want{
+				{"https://example.com/a", "page_1"},
+				{"https://example.com/b", "page_2"},
+			},
+		},
+		{
+			name: "entries interleaved",
+			hars: []capture.HAR{
+				testHAR(
+					entry("https://example.com/1", "2024-01-01T00:00:01Z", ""),
+					entry("https://example.com/3", "2024-01-01T00:00:03Z", ""),
+				),
+				testHAR(entry("https://example.com/2", "2024-01-01T00:00:02+00:00", "")),
+			},
+			entries: []want{
+				{"https://example.com/1", ""},
+				{"https://example.com/2", ""},
+				{"https://example.com/3", ""},
+			},
+		},
+		{
+			name: "null pages and entries dropped",
+			hars: []capture.HAR{
+				{Log: &capture.Log{
+					Pages:   []*har.Page{nil},
+					Entries: []*capture.Entry{nil, entry("https://example.com/", "2024-01-01T00:00:00Z", "")},
+				}},
+				{},
+			},
+			entries: []want{{"https://example.com/", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Merge(tt.hars...)
+
+			var pages []string
+			for _, p := range h.Log.Pages {
+				pages = append(pages, p.ID)
+			}
+			if !slices.Equal(pages, tt.pages) {
+				t.Errorf("Merge() pages = %v, want %v", pages, tt.pages)
+			}
+
+			var entries []want
+			for _, e := range h.Log.Entries {
+				entries = append(entries, want{e.Request.URL, e.Pageref})
+			}
+			if !slices.Equal(entries, tt.entries) {
+				t.Errorf("Merge() entries = %v, want %v", entries, tt.entries)
+			}
+		})
+	}
+}
+
+func TestMergeKeepsInputsAndCustomFields(t *testing.T) {
+	e := testEntry("GET", "https://example.com/", 200)
+	e.Pageref = "page_7"
+	e.Mocked = true
+	in := testHAR(e)
+	in.Log.Pages = []*har.Page{{ID: "page_7", StartedDateTime: e.StartedDateTime}}
+
+	out := Merge(in)
+
+	if got := out.Log.Entries[0]; !got.Mocked || got.Pageref != "page_1" {
+		t.Errorf("Merge() entry has mocked %t and pageref %q, want true and page_1", got.Mocked, got.Pageref)
+	}
+	if e.Pageref != "page_7" || in.Log.Pages[0].ID != "page_7" {
+		t.Errorf("Merge() modified its input")
+	}
+}
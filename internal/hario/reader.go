@@ -0,0 +1,41 @@
+package hario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tomasbasham/har-capture/internal/compress"
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Read decodes a HAR from r.
+func Read(r io.Reader) (capture.HAR, error) {
+	var h capture.HAR
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return capture.HAR{}, fmt.Errorf("hario: failed to decode HAR: %w", err)
+	}
+	if h.Log == nil {
+		return capture.HAR{}, fmt.Errorf("hario: HAR has no log")
+	}
+	return h, nil
+}
+
+// ReadFile decodes the HAR file at path, decompressing it first if its name
+// ends in .gz or .zst.
+func ReadFile(path string) (capture.HAR, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return capture.HAR{}, fmt.Errorf("hario: %w", err)
+	}
+	defer f.Close()
+
+	r, err := compress.FromFilename(path).NewReader(f)
+	if err != nil {
+		return capture.HAR{}, fmt.Errorf("hario: failed to decompress %s: %w", path, err)
+	}
+	defer r.Close()
+
+	return Read(r)
+}
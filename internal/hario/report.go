@@ -0,0 +1,186 @@
+package hario
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+var reportTmpl = template.Must(template.New("report").Parse(reportTemplate))
+
+// Frame is an image shown in the filmstrip of a report, such as a frame
+// recorded during a capture with --filmstrip.
+type Frame struct {
+	Name     string
+	MimeType string
+	Data     []byte
+}
+
+// reportData is what the report template renders.
+type reportData struct {
+	Title        string
+	Creator      string
+	Total        float64
+	Requests     int
+	Size         int64
+	UnknownSizes int
+	Pages        []reportPage
+	Entries      []reportEntry
+	Frames       []reportFrame
+}
+
+type reportPage struct {
+	ID            string
+	Title         string
+	Started       string
+	OnContentLoad float64
+	OnLoad        float64
+}
+
+type reportEntry struct {
+	Method   string
+	URL      string
+	Status   int64
+	MimeType string
+	Size     int64
+	Start    float64
+	Time     float64
+	Bar      template.CSS
+	Phases   []reportPhase
+}
+
+type reportPhase struct {
+	Name  string
+	Time  float64
+	Style template.CSS
+}
+
+type reportFrame struct {
+	Name string
+	Src  template.URL
+}
+
+// WriteReport renders h as a standalone HTML page to w: a waterfall of its
+// entries, the timings of its pages and a filmstrip of frames, if any. The
+// page needs nothing beyond itself to be viewed.
+func WriteReport(w io.Writer, h capture.HAR, frames []Frame) error {
+	data := reportData{Title: "HAR report"}
+	if h.Log != nil && h.Log.Creator != nil {
+		data.Creator = h.Log.Creator.Name + " " + h.Log.Creator.Version
+	}
+
+	es := entries(h)
+	var origin time.Time
+	for _, e := range es {
+		t, err := time.Parse(time.RFC3339Nano, e.StartedDateTime)
+		if err == nil && (origin.IsZero() || t.Before(origin)) {
+			origin = t
+		}
+	}
+
+	for _, e := range es {
+		start := 0.0
+		if t, err := time.Parse(time.RFC3339Nano, e.StartedDateTime); err == nil && !origin.IsZero() {
+			start = float64(t.Sub(origin)) / float64(time.Millisecond)
+		}
+		data.Total = max(data.Total, start+e.Time)
+		if size := entrySize(e); size >= 0 {
+			data.Size += size
+		} else {
+			data.UnknownSizes++
+		}
+
+		re := reportEntry{
+			Method: e.Request.Method,
+			URL:    e.Request.URL,
+			Status: entryStatus(e),
+			Size:   entrySize(e),
+			Start:  start,
+			Time:   e.Time,
+		}
+		if e.Response != nil && e.Response.Content != nil {
+			re.MimeType = e.Response.Content.MimeType
+		}
+		if e.Timings != nil {
+			re.Phases = phases(e.Timings)
+		}
+		data.Entries = append(data.Entries, re)
+	}
+	data.Requests = len(data.Entries)
+
+	// Bars are positioned as percentages of the whole capture so the page
+	// scales with the window.
+	for i := range data.Entries {
+		re := &data.Entries[i]
+		re.Bar = template.CSS(fmt.Sprintf("left:%.3f%%;width:%.3f%%",
+			percent(re.Start, data.Total), percent(re.Time, data.Total)))
+		for j := range re.Phases {
+			p := &re.Phases[j]
+			p.Style = template.CSS(fmt.Sprintf("width:%.3f%%", percent(p.Time, re.Time)))
+		}
+	}
+
+	if h.Log != nil {
+		for _, p := range h.Log.Pages {
+			if p == nil {
+				continue
+			}
+			rp := reportPage{ID: p.ID, Title: p.Title, Started: p.StartedDateTime}
+			if p.PageTimings != nil {
+				rp.OnContentLoad = p.PageTimings.OnContentLoad
+				rp.OnLoad = p.PageTimings.OnLoad
+			}
+			data.Pages = append(data.Pages, rp)
+		}
+		if len(data.Pages) > 0 && data.Pages[0].Title != "" {
+			data.Title = data.Pages[0].Title
+		}
+	}
+
+	for _, f := range frames {
+		src := fmt.Sprintf("data:%s;base64,%s", f.MimeType, base64.StdEncoding.EncodeToString(f.Data))
+		data.Frames = append(data.Frames, reportFrame{Name: f.Name, Src: template.URL(src)})
+	}
+
+	if err := reportTmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	return nil
+}
+
+// phases returns the timing phases of an entry that took any time, in the
+// order they occur.
+func phases(t *har.Timings) []reportPhase {
+	all := []reportPhase{
+		{Name: "blocked", Time: t.Blocked},
+		{Name: "dns", Time: t.DNS},
+		{Name: "connect", Time: t.Connect},
+		{Name: "send", Time: t.Send},
+		{Name: "wait", Time: t.Wait},
+		{Name: "receive", Time: t.Receive},
+	}
+	var ps []reportPhase
+	for _, p := range all {
+		if p.Time > 0 {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+func percent(v, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return v / total * 100
+}
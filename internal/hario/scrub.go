@@ -0,0 +1,209 @@
+package hario
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// redacted replaces secrets that are removed rather than hashed.
+const redacted = "REDACTED"
+
+// DefaultScrubHeaders are the headers whose values always hold credentials.
+var DefaultScrubHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-CSRF-Token",
+}
+
+// DefaultScrubQuery matches the names of query parameters that commonly hold
+// secrets.
+var DefaultScrubQuery = regexp.MustCompile(`(?i)token|key|secret|passw|auth|session|sig|credential`)
+
+// ScrubOptions controls what Scrub removes from an archive.
+type ScrubOptions struct {
+	// Headers are scrubbed in addition to DefaultScrubHeaders, matched
+	// case-insensitively.
+	Headers []string
+
+	// Query parameters whose names match any of these patterns are
+	// scrubbed, in addition to those matching DefaultScrubQuery.
+	Query []*regexp.Regexp
+
+	// Hash replaces each secret with a digest of it rather than removing it,
+	// so that requests carrying the same secret can still be correlated.
+	Hash bool
+
+	// KeepBodies retains request and response bodies, which are otherwise
+	// removed since they may hold anything.
+	KeepBodies bool
+}
+
+// Scrub removes credentials and other secrets from h in place, so that it can
+// be shared: the values of cookies and credential headers, secret query and
+// fragment parameters in every URL, userinfo passwords, and bodies.
+func Scrub(h capture.HAR, opts ScrubOptions) {
+	if h.Log == nil {
+		return
+	}
+	s := &scrubber{opts: opts, headers: make(map[string]bool)}
+	for _, name := range slices.Concat(DefaultScrubHeaders, opts.Headers) {
+		s.headers[strings.ToLower(name)] = true
+	}
+	s.query = append([]*regexp.Regexp{DefaultScrubQuery}, opts.Query...)
+
+	for _, p := range h.Log.Pages {
+		if p != nil {
+			p.Title = s.url(p.Title)
+		}
+	}
+	for _, e := range h.Log.Entries {
+		if e != nil {
+			s.entry(e)
+		}
+	}
+}
+
+type scrubber struct {
+	opts    ScrubOptions
+	headers map[string]bool
+	query   []*regexp.Regexp
+}
+
+func (s *scrubber) entry(e *capture.Entry) {
+	if r := e.Request; r != nil {
+		r.URL = s.url(r.URL)
+		s.cookies(r.Cookies)
+		s.nameValues(r.Headers)
+		for _, q := range r.QueryString {
+			if q != nil && s.secretParam(q.Name) {
+				q.Value = s.replace(q.Value)
+			}
+		}
+		if r.PostData != nil && !s.opts.KeepBodies {
+			r.PostData.Text = ""
+			r.PostData.Params = nil
+		}
+	}
+
+	if r := e.Response; r != nil {
+		r.RedirectURL = s.url(r.RedirectURL)
+		s.cookies(r.Cookies)
+		s.nameValues(r.Headers)
+		if r.Content != nil && !s.opts.KeepBodies {
+			r.Content.Text = ""
+			r.Content.Encoding = ""
+		}
+	}
+}
+
+// nameValues scrubs credential headers, and secrets in URLs held by any
+// other header, such as Referer and Location.
+func (s *scrubber) nameValues(pairs []*har.NameValuePair) {
+	for _, p := range pairs {
+		if p == nil {
+			continue
+		}
+		if s.headers[strings.ToLower(p.Name)] {
+			p.Value = s.replace(p.Value)
+			continue
+		}
+		p.Value = s.url(p.Value)
+	}
+}
+
+func (s *scrubber) cookies(cookies []*har.Cookie) {
+	for _, c := range cookies {
+		if c != nil {
+			c.Value = s.replace(c.Value)
+		}
+	}
+}
+
+// url scrubs the password and secret parameters of raw if it is an absolute
+// URL, preserving the order and encoding of the other parameters. Parameters
+// are scrubbed from the fragment as well as the query, since implicit OAuth
+// flows return tokens such as access_token in the fragment. Anything else is
+// returned unchanged.
+func (s *scrubber) url(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() {
+		return raw
+	}
+
+	changed := false
+	if password, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), s.replace(password))
+		changed = true
+	}
+
+	if query, ok := s.params(u.RawQuery); ok {
+		u.RawQuery = query
+		changed = true
+	}
+
+	if fragment, ok := s.params(u.EscapedFragment()); ok {
+		if unescaped, err := url.PathUnescape(fragment); err == nil {
+			u.Fragment, u.RawFragment = unescaped, fragment
+			changed = true
+		}
+	}
+
+	if !changed {
+		return raw
+	}
+	return u.String()
+}
+
+// params scrubs the secret parameters of raw, an encoded list of name=value
+// pairs separated by &, and reports whether any were found.
+func (s *scrubber) params(raw string) (string, bool) {
+	if raw == "" {
+		return raw, false
+	}
+
+	changed := false
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		rawName, value, ok := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(rawName)
+		if !ok || err != nil || !s.secretParam(name) {
+			continue
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		params[i] = rawName + "=" + url.QueryEscape(s.replace(value))
+		changed = true
+	}
+	return strings.Join(params, "&"), changed
+}
+
+func (s *scrubber) secretParam(name string) bool {
+	for _, re := range s.query {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// replace returns what a secret is replaced with.
+func (s *scrubber) replace(secret string) string {
+	if !s.opts.Hash || secret == "" {
+		return redacted
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
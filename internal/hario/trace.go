@@ -0,0 +1,143 @@
+package hario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// traceEvent is an event of the Chrome trace event format, as understood by
+// Perfetto and chrome://tracing. Timestamps are in microseconds.
+type traceEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	TS   float64        `json:"ts"`
+	PID  int            `json:"pid"`
+	TID  int            `json:"tid"`
+	ID   string         `json:"id,omitempty"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// WriteTrace writes the timings of h to w as Chrome trace event JSON. Each
+// request is an async slice, with a nested slice for each phase of its
+// timings, so that a capture can be explored in Perfetto. Requests are
+// grouped by page, each page appearing as a process.
+func WriteTrace(w io.Writer, h capture.HAR) error {
+	es := entries(h)
+
+	var origin time.Time
+	for _, e := range es {
+		t, err := time.Parse(time.RFC3339Nano, e.StartedDateTime)
+		if err == nil && (origin.IsZero() || t.Before(origin)) {
+			origin = t
+		}
+	}
+
+	titles := make(map[string]string)
+	if h.Log != nil {
+		for _, p := range h.Log.Pages {
+			if p != nil && p.Title != "" {
+				titles[p.ID] = p.Title
+			}
+		}
+	}
+
+	pids := make(map[string]int)
+	events := []traceEvent{}
+	pid := func(pageref string) int {
+		if id, ok := pids[pageref]; ok {
+			return id
+		}
+		id := len(pids) + 1
+		pids[pageref] = id
+		name := pageref
+		if title, ok := titles[pageref]; ok {
+			name = title
+		} else if name == "" {
+			name = "requests"
+		}
+		events = append(events, traceEvent{
+			Name: "process_name",
+			Ph:   "M",
+			PID:  id,
+			Args: map[string]any{"name": name},
+		})
+		return id
+	}
+
+	for i, e := range es {
+		start, err := time.Parse(time.RFC3339Nano, e.StartedDateTime)
+		if err != nil {
+			continue
+		}
+		ts := float64(start.Sub(origin)) / float64(time.Microsecond)
+		p := pid(e.Pageref)
+		id := strconv.Itoa(i + 1)
+
+		args := map[string]any{
+			"url":    e.Request.URL,
+			"method": e.Request.Method,
+			"status": entryStatus(e),
+			"size":   entrySize(e),
+		}
+		name := requestLabel(e)
+		events = append(events, traceEvent{Name: name, Cat: "request", Ph: "b", TS: ts, PID: p, TID: 1, ID: id, Args: args})
+
+		if t := e.Timings; t != nil {
+			at := ts
+			for _, phase := range tracePhases(t) {
+				end := at + phase.Time*1000
+				events = append(events,
+					traceEvent{Name: phase.Name, Cat: "request", Ph: "b", TS: at, PID: p, TID: 1, ID: id},
+					traceEvent{Name: phase.Name, Cat: "request", Ph: "e", TS: end, PID: p, TID: 1, ID: id},
+				)
+				at = end
+			}
+		}
+
+		events = append(events, traceEvent{Name: name, Cat: "request", Ph: "e", TS: ts + e.Time*1000, PID: p, TID: 1, ID: id})
+	}
+
+	enc := json.NewEncoder(w)
+	err := enc.Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+	if err != nil {
+		return fmt.Errorf("hario: %w", err)
+	}
+	return nil
+}
+
+// tracePhases returns the timing phases of an entry that took any time, in
+// the order they occur. Unlike in the report, SSL is shown in its own right
+// and taken out of connect, which includes it.
+func tracePhases(t *har.Timings) []reportPhase {
+	connect := t.Connect
+	if t.Ssl > 0 {
+		connect -= t.Ssl
+	}
+	all := []reportPhase{
+		{Name: "blocked", Time: t.Blocked},
+		{Name: "dns", Time: t.DNS},
+		{Name: "connect", Time: connect},
+		{Name: "ssl", Time: t.Ssl},
+		{Name: "send", Time: t.Send},
+		{Name: "wait", Time: t.Wait},
+		{Name: "receive", Time: t.Receive},
+	}
+	var ps []reportPhase
+	for _, p := range all {
+		if p.Time > 0 {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
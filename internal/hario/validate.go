@@ -0,0 +1,240 @@
+package hario
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Problem is a departure from the HAR 1.2 specification.
+type Problem struct {
+	// Path locates the offending field, e.g. "log.entries[3].request.url".
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// timeTolerance is how far, in milliseconds, the total time of an entry may
+// differ from the sum of its timings, allowing for rounding.
+const timeTolerance = 1
+
+// Validate checks h against the HAR 1.2 specification: that required fields
+// are present, that dates are ISO 8601 and that timings are consistent. It
+// returns the problems found, in document order; none if h conforms.
+func Validate(h capture.HAR) []Problem {
+	v := &validator{}
+	if h.Log == nil {
+		v.report("log", "is required")
+		return v.problems
+	}
+	v.log(h.Log)
+	return v.problems
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) report(path, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) log(l *capture.Log) {
+	if l.Version == "" {
+		v.report("log.version", "is required")
+	}
+	v.creator("log.creator", l.Creator, true)
+	v.creator("log.browser", l.Browser, false)
+
+	pages := make(map[string]bool, len(l.Pages))
+	for i, p := range l.Pages {
+		path := fmt.Sprintf("log.pages[%d]", i)
+		if p == nil {
+			v.report(path, "must not be null")
+			continue
+		}
+		v.page(path, p)
+		if pages[p.ID] {
+			v.report(path+".id", "duplicates %q", p.ID)
+		}
+		pages[p.ID] = true
+	}
+
+	if l.Entries == nil {
+		v.report("log.entries", "is required")
+	}
+	for i, e := range l.Entries {
+		path := fmt.Sprintf("log.entries[%d]", i)
+		if e == nil {
+			v.report(path, "must not be null")
+			continue
+		}
+		v.entry(path, e, pages)
+	}
+}
+
+func (v *validator) creator(path string, c *har.Creator, required bool) {
+	if c == nil {
+		if required {
+			v.report(path, "is required")
+		}
+		return
+	}
+	if c.Name == "" {
+		v.report(path+".name", "is required")
+	}
+	if c.Version == "" {
+		v.report(path+".version", "is required")
+	}
+}
+
+func (v *validator) page(path string, p *har.Page) {
+	v.date(path+".startedDateTime", p.StartedDateTime)
+	if p.ID == "" {
+		v.report(path+".id", "is required")
+	}
+	if p.PageTimings == nil {
+		v.report(path+".pageTimings", "is required")
+		return
+	}
+	v.optionalTiming(path+".pageTimings.onContentLoad", p.PageTimings.OnContentLoad)
+	v.optionalTiming(path+".pageTimings.onLoad", p.PageTimings.OnLoad)
+}
+
+func (v *validator) entry(path string, e *capture.Entry, pages map[string]bool) {
+	if e.Pageref != "" && !pages[e.Pageref] {
+		v.report(path+".pageref", "refers to unknown page %q", e.Pageref)
+	}
+	v.date(path+".startedDateTime", e.StartedDateTime)
+
+	if e.Request == nil {
+		v.report(path+".request", "is required")
+	} else {
+		v.request(path+".request", e.Request)
+	}
+	if e.Response == nil {
+		v.report(path+".response", "is required")
+	} else {
+		v.response(path+".response", e.Response)
+	}
+	if e.Cache == nil {
+		v.report(path+".cache", "is required")
+	}
+	if e.Timings == nil {
+		v.report(path+".timings", "is required")
+		return
+	}
+	v.timings(path+".timings", e.Timings)
+
+	// The total time excludes ssl, which is already counted in connect.
+	t := e.Timings
+	var sum float64
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if phase > 0 {
+			sum += phase
+		}
+	}
+	if math.Abs(e.Time-sum) > timeTolerance {
+		v.report(path+".time", "is %g but the timings sum to %g", e.Time, sum)
+	}
+}
+
+func (v *validator) request(path string, r *har.Request) {
+	if r.Method == "" {
+		v.report(path+".method", "is required")
+	}
+	if r.URL == "" {
+		v.report(path+".url", "is required")
+	}
+	if r.HTTPVersion == "" {
+		v.report(path+".httpVersion", "is required")
+	}
+	if r.Cookies == nil {
+		v.report(path+".cookies", "is required")
+	}
+	if r.Headers == nil {
+		v.report(path+".headers", "is required")
+	}
+	if r.QueryString == nil {
+		v.report(path+".queryString", "is required")
+	}
+	v.size(path+".headersSize", r.HeadersSize)
+	v.size(path+".bodySize", r.BodySize)
+}
+
+func (v *validator) response(path string, r *har.Response) {
+	if r.HTTPVersion == "" {
+		v.report(path+".httpVersion", "is required")
+	}
+	if r.Cookies == nil {
+		v.report(path+".cookies", "is required")
+	}
+	if r.Headers == nil {
+		v.report(path+".headers", "is required")
+	}
+	if r.Content == nil {
+		v.report(path+".content", "is required")
+	} else {
+		if r.Content.MimeType == "" {
+			v.report(path+".content.mimeType", "is required")
+		}
+		if r.Content.Size < 0 {
+			v.report(path+".content.size", "must not be negative, got %d", r.Content.Size)
+		}
+		if r.Content.Encoding != "" && r.Content.Encoding != "base64" {
+			v.report(path+".content.encoding", "is %q, expected base64", r.Content.Encoding)
+		}
+	}
+	v.size(path+".headersSize", r.HeadersSize)
+	v.size(path+".bodySize", r.BodySize)
+}
+
+func (v *validator) timings(path string, t *har.Timings) {
+	v.optionalTiming(path+".blocked", t.Blocked)
+	v.optionalTiming(path+".dns", t.DNS)
+	v.optionalTiming(path+".connect", t.Connect)
+	v.optionalTiming(path+".ssl", t.Ssl)
+	v.requiredTiming(path+".send", t.Send)
+	v.requiredTiming(path+".wait", t.Wait)
+	v.requiredTiming(path+".receive", t.Receive)
+	if t.Ssl > 0 && t.Connect >= 0 && t.Ssl > t.Connect {
+		v.report(path+".ssl", "is %g but must be included in connect, which is %g", t.Ssl, t.Connect)
+	}
+}
+
+// size checks a size that is -1 when unknown.
+func (v *validator) size(path string, size int64) {
+	if size < -1 {
+		v.report(path, "must be -1 or more, got %d", size)
+	}
+}
+
+// optionalTiming checks a timing that is -1 when it does not apply.
+func (v *validator) optionalTiming(path string, ms float64) {
+	if ms < 0 && ms != -1 {
+		v.report(path, "must be -1 or non-negative, got %g", ms)
+	}
+}
+
+func (v *validator) requiredTiming(path string, ms float64) {
+	if ms < 0 {
+		v.report(path, "must not be negative, got %g", ms)
+	}
+}
+
+func (v *validator) date(path, s string) {
+	if s == "" {
+		v.report(path, "is required")
+		return
+	}
+	if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+		v.report(path, "is %q, expected an ISO 8601 date", s)
+	}
+}
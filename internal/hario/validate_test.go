@@ -0,0 +1,142 @@
+package hario
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// testEntry returns an entry that conforms to the HAR 1.2 specification.
+func testEntry(method, url string, status int64) *capture.Entry {
+	return &capture.Entry{Entry: har.Entry{
+		StartedDateTime: "2024-01-01T00:00:00Z",
+		Time:            30,
+		Request: &har.Request{
+			Method:      method,
+			URL:         url,
+			HTTPVersion: "h2",
+			Cookies:     []*har.Cookie{},
+			Headers:     []*har.NameValuePair{},
+			QueryString: []*har.NameValuePair{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: &har.Response{
+			Status:      status,
+			HTTPVersion: "h2",
+			Cookies:     []*har.Cookie{},
+			Headers:     []*har.NameValuePair{},
+			Content:     &har.Content{MimeType: "text/html"},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Cache:   &har.Cache{},
+		Timings: &har.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1, Send: 10, Wait: 20},
+	}}
+}
+
+// testHAR returns an archive of entries that conforms to the HAR 1.2
+// specification.
+func testHAR(entries ...*capture.Entry) capture.HAR {
+	return capture.HAR{Log: &capture.Log{
+		Version: "1.2",
+		Creator: &har.Creator{Name: "test", Version: "1"},
+		Entries: append([]*capture.Entry{}, entries...),
+	}}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(h capture.HAR)
+		paths  []string
+	}{
+		{
+			name:   "valid",
+			modify: func(h capture.HAR) {},
+		},
+		{
+			name: "pending entry",
+			modify: func(h capture.HAR) {
+				e := h.Log.Entries[0]
+				e.Pending = true
+				e.Time = 0
+				e.Response.Status = 0
+				e.Timings = &har.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}
+			},
+		},
+		{
+			name:   "no log",
+			modify: func(h capture.HAR) { *h.Log = capture.Log{} },
+			paths:  []string{"log.version", "log.creator", "log.entries"},
+		},
+		{
+			name:   "no cache",
+			modify: func(h capture.HAR) { h.Log.Entries[0].Cache = nil },
+			paths:  []string{"log.entries[0].cache"},
+		},
+		{
+			name:   "null entry",
+			modify: func(h capture.HAR) { h.Log.Entries[0] = nil },
+			paths:  []string{"log.entries[0]"},
+		},
+		{
+			name: "negative send",
+			modify: func(h capture.HAR) {
+				h.Log.Entries[0].Timings.Send = -1
+				h.Log.Entries[0].Time = 20
+			},
+			paths: []string{"log.entries[0].timings.send"},
+		},
+		{
+			name:   "time differs from timings",
+			modify: func(h capture.HAR) { h.Log.Entries[0].Time = 100 },
+			paths:  []string{"log.entries[0].time"},
+		},
+		{
+			name: "ssl exceeds connect",
+			modify: func(h capture.HAR) {
+				h.Log.Entries[0].Timings.Connect = 2
+				h.Log.Entries[0].Timings.Ssl = 5
+				h.Log.Entries[0].Time = 32
+			},
+			paths: []string{"log.entries[0].timings.ssl"},
+		},
+		{
+			name:   "unknown page",
+			modify: func(h capture.HAR) { h.Log.Entries[0].Pageref = "page_9" },
+			paths:  []string{"log.entries[0].pageref"},
+		},
+		{
+			name:   "invalid date",
+			modify: func(h capture.HAR) { h.Log.Entries[0].StartedDateTime = "yesterday" },
+			paths:  []string{"log.entries[0].startedDateTime"},
+		},
+		{
+			name: "missing required fields",
+			modify: func(h capture.HAR) {
+				h.Log.Entries[0].Request.HTTPVersion = ""
+				h.Log.Entries[0].Response.Content.MimeType = ""
+			},
+			paths: []string{"log.entries[0].request.httpVersion", "log.entries[0].response.content.mimeType"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHAR(testEntry("GET", "https://example.com/", 200))
+			tt.modify(h)
+
+			var paths []string
+			for _, p := range Validate(h) {
+				paths = append(paths, p.Path)
+			}
+			if !slices.Equal(paths, tt.paths) {
+				t.Errorf("Validate() problems at %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package hario reads and writes HAR files.
+package hario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// logHeader holds the fields of a log that precede its entries, in the
+// order in which capture.Log declares them.
+type logHeader struct {
+	Version string       `json:"version"`
+	Creator *har.Creator `json:"creator"`
+	Browser *har.Creator `json:"browser,omitempty"`
+	Pages   []*har.Page  `json:"pages,omitempty"`
+}
+
+// WriteHAR writes h to w as compact JSON, as json.Marshal would encode it,
+// but encoding one entry at a time, so that the encoded archive is never
+// held in memory as a whole.
+func WriteHAR(w io.Writer, h capture.HAR) error {
+	return WriteHARIndent(w, h, "")
+}
+
+// WriteHARIndent is like WriteHAR but indents the output, as
+// json.MarshalIndent would with an empty prefix.
+func WriteHARIndent(w io.Writer, h capture.HAR, indent string) error {
+	if h.Log == nil {
+		return errors.New("hario: HAR has no log")
+	}
+	l := h.Log
+	hw := &harWriter{w: w, indent: indent}
+
+	// The header is encoded as an object of its own, then left open for the
+	// entries to follow by dropping its closing brace.
+	header, err := hw.encode(logHeader{Version: l.Version, Creator: l.Creator, Browser: l.Browser, Pages: l.Pages}, 1)
+	if err != nil {
+		return fmt.Errorf("hario: failed to encode log: %w", err)
+	}
+	header = bytes.TrimRight(header[:len(header)-1], " \t\n")
+
+	hw.write([]byte("{"))
+	hw.newline(1)
+	hw.key("log")
+	hw.write(header)
+	hw.write([]byte(","))
+	hw.newline(2)
+	hw.key("entries")
+	hw.write([]byte("["))
+	for i, e := range l.Entries {
+		b, err := hw.encode(e, 3)
+		if err != nil {
+			return fmt.Errorf("hario: failed to encode entry: %w", err)
+		}
+		if i > 0 {
+			hw.write([]byte(","))
+		}
+		hw.newline(3)
+		hw.write(b)
+	}
+	if len(l.Entries) > 0 {
+		hw.newline(2)
+	}
+	hw.write([]byte("]"))
+	if l.Comment != "" {
+		b, err := hw.encode(l.Comment, 2)
+		if err != nil {
+			return fmt.Errorf("hario: failed to encode log: %w", err)
+		}
+		hw.write([]byte(","))
+		hw.newline(2)
+		hw.key("comment")
+		hw.write(b)
+	}
+	hw.newline(1)
+	hw.write([]byte("}"))
+	hw.newline(0)
+	hw.write([]byte("}"))
+	return hw.err
+}
+
+// harWriter writes the parts of an archive, keeping the first error.
+type harWriter struct {
+	w      io.Writer
+	indent string
+	err    error
+}
+
+// encode encodes v, indented as it would be at the given depth.
+func (w *harWriter) encode(v any, depth int) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || w.indent == "" {
+		return b, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, strings.Repeat(w.indent, depth), w.indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// key writes the key of an object member.
+func (w *harWriter) key(name string) {
+	if w.indent == "" {
+		w.write([]byte(`"` + name + `":`))
+		return
+	}
+	w.write([]byte(`"` + name + `": `))
+}
+
+// newline starts a new line at the given depth, if indenting.
+func (w *harWriter) newline(depth int) {
+	if w.indent != "" {
+		w.write([]byte("\n" + strings.Repeat(w.indent, depth)))
+	}
+}
+
+// write writes b unless an earlier write failed.
+func (w *harWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	if _, err := w.w.Write(b); err != nil {
+		w.err = fmt.Errorf("hario: %w", err)
+	}
+}
+
+// Writer writes an archive one entry at a time, as entries are passed to the
+// OnEntry func of capture.Stream, so that the entries of a capture are never
+// held in memory. The browser, pages and comment of the log, known only once
+// the capture has finished, are written by Close, after the entries.
+//
+// Entries streamed from a capture have neither bodies nor transfer sizes.
+type Writer struct {
+	hw      *harWriter
+	entries int
+}
+
+// NewWriter writes the opening of an archive created by creator to w,
+// indenting it with indent as WriteHARIndent does.
+func NewWriter(w io.Writer, creator *har.Creator, indent string) (*Writer, error) {
+	hw := &harWriter{w: w, indent: indent}
+	b, err := hw.encode(creator, 2)
+	if err != nil {
+		return nil, fmt.Errorf("hario: failed to encode log: %w", err)
+	}
+
+	hw.write([]byte("{"))
+	hw.newline(1)
+	hw.key("log")
+	hw.write([]byte("{"))
+	hw.newline(2)
+	hw.key("version")
+	hw.write([]byte(`"1.2",`))
+	hw.newline(2)
+	hw.key("creator")
+	hw.write(b)
+	hw.write([]byte(","))
+	hw.newline(2)
+	hw.key("entries")
+	hw.write([]byte("["))
+	if hw.err != nil {
+		return nil, hw.err
+	}
+	return &Writer{hw: hw}, nil
+}
+
+// WriteEntry writes e to the archive.
+func (w *Writer) WriteEntry(e *capture.Entry) error {
+	b, err := w.hw.encode(e, 3)
+	if err != nil {
+		return fmt.Errorf("hario: failed to encode entry: %w", err)
+	}
+	if w.entries > 0 {
+		w.hw.write([]byte(","))
+	}
+	w.hw.newline(3)
+	w.hw.write(b)
+	w.entries++
+	return w.hw.err
+}
+
+// Close completes the archive with the browser, pages and comment of l,
+// whose entries are ignored. l may be nil.
+func (w *Writer) Close(l *capture.Log) error {
+	hw := w.hw
+	if w.entries > 0 {
+		hw.newline(2)
+	}
+	hw.write([]byte("]"))
+	if l != nil {
+		members := []struct {
+			name  string
+			value any
+			set   bool
+		}{
+			{"browser", l.Browser, l.Browser != nil},
+			{"pages", l.Pages, len(l.Pages) > 0},
+			{"comment", l.Comment, l.Comment != ""},
+		}
+		for _, m := range members {
+			if !m.set {
+				continue
+			}
+			b, err := hw.encode(m.value, 2)
+			if err != nil {
+				return fmt.Errorf("hario: failed to encode log: %w", err)
+			}
+			hw.write([]byte(","))
+			hw.newline(2)
+			hw.key(m.name)
+			hw.write(b)
+		}
+	}
+	hw.newline(1)
+	hw.write([]byte("}"))
+	hw.newline(0)
+	hw.write([]byte("}"))
+	return hw.err
+}
@@ -0,0 +1,82 @@
+package hario
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+func TestWriteHARIndent(t *testing.T) {
+	mocked := testEntry("GET", "https://example.com/", 200)
+	mocked.Mocked = true
+	full := testHAR(mocked, testEntry("GET", "https://example.com/missing", 404))
+	full.Log.Browser = &har.Creator{Name: "Google Chrome", Version: "120"}
+	full.Log.Pages = []*har.Page{{ID: "page_1", Title: "Example", PageTimings: &har.PageTimings{}}}
+	full.Log.Comment = `captured "as is"`
+
+	hars := map[string]capture.HAR{
+		"no entries": testHAR(),
+		"one entry":  testHAR(mocked),
+		"full":       full,
+	}
+	for name, h := range hars {
+		for _, indent := range []string{"", "  ", "\t"} {
+			t.Run(name+"/indent "+strconv.Quote(indent), func(t *testing.T) {
+				want, err := json.MarshalIndent(h, "", indent)
+				if indent == "" {
+					want, err = json.Marshal(h)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var got bytes.Buffer
+				if err := WriteHARIndent(&got, h, indent); err != nil {
+					t.Fatalf("WriteHARIndent() error = %v", err)
+				}
+				if !bytes.Equal(got.Bytes(), want) {
+					t.Errorf("WriteHARIndent(%q) =\n%s\nwant\n%s", indent, got.Bytes(), want)
+				}
+			})
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	h := testHAR(testEntry("GET", "https://example.com/", 200), testEntry("GET", "https://example.com/app.js", 200))
+	h.Log.Browser = &har.Creator{Name: "Google Chrome", Version: "120"}
+	h.Log.Pages = []*har.Page{{ID: "page_1", Title: "Example", PageTimings: &har.PageTimings{}}}
+
+	for _, indent := range []string{"", "  "} {
+		t.Run("indent "+strconv.Quote(indent), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, h.Log.Creator, indent)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			for _, e := range h.Log.Entries {
+				if err := w.WriteEntry(e); err != nil {
+					t.Fatalf("WriteEntry() error = %v", err)
+				}
+			}
+			if err := w.Close(h.Log); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			got, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("Read() error = %v\n%s", err, buf.Bytes())
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(h)
+			if !bytes.Equal(gotJSON, wantJSON) {
+				t.Errorf("Writer wrote\n%s\nwant\n%s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package jobs distributes captures from the API to workers in other
+// processes through Cloud Pub/Sub, so that capture capacity can be scaled
+// independently of the API, and enqueued captures survive restarts of it.
+//
+// The API publishes a Job for each capture to the jobs topic. Workers
+// consume them from a subscription to it, and publish an Update to the
+// updates topic for each change to the operation, which the API applies to
+// its store.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"google.golang.org/api/option"
+
+	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Job is a capture enqueued by the API for a worker to run.
+type Job struct {
+	OperationID string `json:"operation_id"`
+
+	// Tenant names the tenant the operation belongs to, whose storage the
+	// artefacts are uploaded to.
+	Tenant string `json:"tenant,omitempty"`
+
+	// Request is the body of the request that created the operation, from
+	// which the worker derives the options of the capture.
+	Request json.RawMessage `json:"request"`
+
+	// CallbackURL, if set, is sent the operation by the API once it has
+	// finished. The worker returns it with the final Update, so that the
+	// API need not hold it meanwhile.
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// UpdateType is the kind of change an Update reports.
+type UpdateType string
+
+const (
+	UpdateRunning   UpdateType = "running"
+	UpdateAttempt   UpdateType = "attempt"
+	UpdateComplete  UpdateType = "complete"
+	UpdateFailed    UpdateType = "failed"
+	UpdateCancelled UpdateType = "cancelled"
+)
+
+// Update reports a change to an operation run by a worker.
+type Update struct {
+	OperationID string     `json:"operation_id"`
+	Type        UpdateType `json:"type"`
+	Time        time.Time  `json:"time"`
+
+	// Attempt is set for UpdateAttempt.
+	Attempt *operation.Attempt `json:"attempt,omitempty"`
+
+	// Outcome is set for UpdateComplete and UpdateCancelled.
+	Outcome *Outcome `json:"outcome,omitempty"`
+
+	// Error is set for UpdateFailed.
+	Error string `json:"error,omitempty"`
+
+	// Duration is how long the operation ran, set once it has finished.
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// CallbackURL is that of the job, set once the operation has finished.
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// MaxInlineHAR is the size in bytes of the largest HAR a worker sends inline
+// with an Update, which leaves room for the rest of the update within the
+// 10 MB Pub/Sub allows a message.
+const MaxInlineHAR = 8 << 20
+
+// Outcome is an operation.Outcome as it is sent to the API.
+type Outcome struct {
+	TTFB      time.Duration      `json:"ttfb"`
+	TimedOut  bool               `json:"timed_out,omitempty"`
+	WebVitals *capture.WebVitals `json:"web_vitals,omitempty"`
+	Metrics   *capture.Metrics   `json:"metrics,omitempty"`
+	Artefacts []Artefact         `json:"artefacts,omitempty"`
+	HAR       json.RawMessage    `json:"har,omitempty"`
+}
+
+// Artefact is an operation.Artefact as it is sent to the API.
+type Artefact struct {
+	Name       string    `json:"name"`
+	SignedURL  string    `json:"signed_url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ObjectName string    `json:"object_name"`
+}
+
+func outcomeOf(o operation.Outcome) *Outcome {
+	out := &Outcome{
+		TTFB:      o.TTFB,
+		TimedOut:  o.TimedOut,
+		WebVitals: o.WebVitals,
+		Metrics:   o.Metrics,
+		HAR:       o.HAR,
+	}
+	for _, a := range o.Artefacts {
+		out.Artefacts = append(out.Artefacts, Artefact(a))
+	}
+	return out
+}
+
+// Operation returns o as an operation.Outcome.
+func (o *Outcome) Operation() operation.Outcome {
+	if o == nil {
+		return operation.Outcome{}
+	}
+	out := operation.Outcome{
+		TTFB:      o.TTFB,
+		TimedOut:  o.TimedOut,
+		WebVitals: o.WebVitals,
+		Metrics:   o.Metrics,
+		HAR:       o.HAR,
+	}
+	for _, a := range o.Artefacts {
+		out.Artefacts = append(out.Artefacts, operation.Artefact(a))
+	}
+	return out
+}
+
+// Config names the Pub/Sub resources through which jobs and updates pass.
+// The API uses JobsTopic and UpdatesSubscription; workers use
+// JobsSubscription and UpdatesTopic.
+type Config struct {
+	ProjectID string
+
+	JobsTopic        string
+	JobsSubscription string
+
+	UpdatesTopic        string
+	UpdatesSubscription string
+}
+
+// Client publishes and consumes jobs and updates.
+type Client struct {
+	client *pubsub.Client
+	config Config
+
+	jobs    *pubsub.Publisher
+	updates *pubsub.Publisher
+}
+
+// NewClient creates a Client for the resources of config. opts are passed
+// through to the underlying Pub/Sub client.
+func NewClient(ctx context.Context, config Config, opts ...option.ClientOption) (*Client, error) {
+	if config.ProjectID == "" {
+		return nil, errors.New("jobs: project ID is required")
+	}
+	client, err := pubsub.NewClient(ctx, config.ProjectID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: failed to create Pub/Sub client: %w", err)
+	}
+
+	c := &Client{client: client, config: config}
+	if config.JobsTopic != "" {
+		c.jobs = client.Publisher(config.JobsTopic)
+	}
+	if config.UpdatesTopic != "" {
+		// The updates of an operation must be applied in order, so the
+		// updates subscription should have message ordering enabled.
+		c.updates = client.Publisher(config.UpdatesTopic)
+		c.updates.EnableMessageOrdering = true
+	}
+	return c, nil
+}
+
+// Close flushes messages still to be published and closes the client.
+func (c *Client) Close() error {
+	if c.jobs != nil {
+		c.jobs.Stop()
+	}
+	if c.updates != nil {
+		c.updates.Stop()
+	}
+	return c.client.Close()
+}
+
+// Enqueue publishes job for a worker to run. It returns once Pub/Sub has
+// accepted it.
+func (c *Client) Enqueue(ctx context.Context, job Job) error {
+	if c.jobs == nil {
+		return errors.New("jobs: no jobs topic configured")
+	}
+	return publish(ctx, c.jobs, job, "")
+}
+
+// Report publishes u for the API to apply.
+func (c *Client) Report(ctx context.Context, u Update) error {
+	if c.updates == nil {
+		return errors.New("jobs: no updates topic configured")
+	}
+	return publish(ctx, c.updates, u, u.OperationID)
+}
+
+// ReceiveJobs calls handle for each job published, running at most
+// concurrency at once, until ctx is done. A job is acknowledged once handle
+// returns nil; otherwise it is redelivered, perhaps to another worker.
+// Malformed jobs are discarded.
+func (c *Client) ReceiveJobs(ctx context.Context, concurrency int, handle func(context.Context, Job) error) error {
+	if c.config.JobsSubscription == "" {
+		return errors.New("jobs: no jobs subscription configured")
+	}
+	sub := c.client.Subscriber(c.config.JobsSubscription)
+	sub.ReceiveSettings.MaxOutstandingMessages = concurrency
+	// Captures may take minutes, during which the job must not be
+	// redelivered.
+	sub.ReceiveSettings.MaxExtension = time.Hour
+
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		var job Job
+		if err := json.Unmarshal(msg.Data, &job); err != nil || job.OperationID == "" {
+			msg.Ack()
+			return
+		}
+		if err := handle(ctx, job); err != nil {
+			msg.Nack()
+			return
+		}
+		msg.Ack()
+	})
+}
+
+// ReceiveUpdates calls apply for each update published, until ctx is done.
+// The updates of each operation are applied in the order they were published
+// if the updates subscription has message ordering enabled.
+func (c *Client) ReceiveUpdates(ctx context.Context, apply func(Update)) error {
+	if c.config.UpdatesSubscription == "" {
+		return errors.New("jobs: no updates subscription configured")
+	}
+	sub := c.client.Subscriber(c.config.UpdatesSubscription)
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		var u Update
+		if err := json.Unmarshal(msg.Data, &u); err == nil && u.OperationID != "" {
+			apply(u)
+		}
+		msg.Ack()
+	})
+}
+
+// publish publishes v as JSON, ordered after earlier messages with the same
+// key if key is set, and waits for it to be accepted.
+func publish(ctx context.Context, p *pubsub.Publisher, v any, key string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("jobs: failed to encode message: %w", err)
+	}
+	if _, err := p.Publish(ctx, &pubsub.Message{Data: data, OrderingKey: key}).Get(ctx); err != nil {
+		if key != "" {
+			// Publication of the key is paused by a failure until resumed.
+			p.ResumePublish(key)
+		}
+		return fmt.Errorf("jobs: failed to publish to %s: %w", p, err)
+	}
+	return nil
+}
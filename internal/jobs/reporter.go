@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tomasbasham/har-capture/internal/operation"
+)
+
+// errReporterUnsupported is returned by the methods of a Reporter that a
+// worker has no use for.
+var errReporterUnsupported = errors.New("jobs: not supported by a worker")
+
+// Reporter is the operation.Store of a worker running a job. It holds no
+// operations, but publishes each change the worker makes to the operation
+// of the job as an Update for the API to apply to its own store.
+type Reporter struct {
+	client *Client
+	ctx    context.Context
+	job    Job
+
+	started     time.Time
+	interrupted bool
+	unreported  bool
+}
+
+// Reporter returns a Reporter publishing the updates of job through c. ctx
+// bounds each publication.
+func (c *Client) Reporter(ctx context.Context, job Job) *Reporter {
+	return &Reporter{client: c, ctx: ctx, job: job}
+}
+
+// Interrupted reports whether the worker abandoned the operation because it
+// was shutting down, in which case the job should be run again by another.
+func (r *Reporter) Interrupted() bool {
+	return r.interrupted
+}
+
+// Unreported reports whether the update finishing the operation could not
+// be published, in which case the job should be run again, as the API would
+// otherwise never learn that it finished.
+func (r *Reporter) Unreported() bool {
+	return r.unreported
+}
+
+func (r *Reporter) report(u Update) error {
+	u.Time = time.Now()
+	if u.Type == UpdateRunning || u.Type == UpdateAttempt {
+		return r.client.Report(r.ctx, u)
+	}
+
+	if !r.started.IsZero() {
+		u.Duration = u.Time.Sub(r.started)
+	}
+	u.CallbackURL = r.job.CallbackURL
+	err := r.client.Report(r.ctx, u)
+	if err != nil && u.Outcome != nil && u.Outcome.HAR != nil {
+		// The HAR is also among the artefacts, so the update is sent again
+		// without it, should it be what made the update too large.
+		outcome := *u.Outcome
+		outcome.HAR = nil
+		u.Outcome = &outcome
+		err = r.client.Report(r.ctx, u)
+	}
+	r.unreported = err != nil
+	return err
+}
+
+func (r *Reporter) MarkRunning(id string) error {
+	r.started = time.Now()
+	return r.report(Update{OperationID: id, Type: UpdateRunning})
+}
+
+func (r *Reporter) RecordAttempt(id string, attempt operation.Attempt) error {
+	return r.report(Update{OperationID: id, Type: UpdateAttempt, Attempt: &attempt})
+}
+
+func (r *Reporter) MarkComplete(id string, outcome operation.Outcome) error {
+	return r.report(Update{OperationID: id, Type: UpdateComplete, Outcome: outcomeOf(outcome)})
+}
+
+func (r *Reporter) MarkFailed(id string, err error) error {
+	return r.report(Update{OperationID: id, Type: UpdateFailed, Error: err.Error()})
+}
+
+func (r *Reporter) MarkCancelled(id string, outcome operation.Outcome) error {
+	return r.report(Update{OperationID: id, Type: UpdateCancelled, Outcome: outcomeOf(outcome)})
+}
+
+// MarkInterrupted publishes nothing: the operation remains running at the
+// API until the job is redelivered to another worker.
+func (r *Reporter) MarkInterrupted(id string) error {
+	r.interrupted = true
+	return nil
+}
+
+func (r *Reporter) Create(string, operation.Owner) (*operation.Operation, error) {
+	return nil, errReporterUnsupported
+}
+
+func (r *Reporter) CreateOnce(string, string, operation.Owner) (*operation.Operation, bool, error) {
+	return nil, false, errReporterUnsupported
+}
+
+func (r *Reporter) Get(string) (*operation.Operation, error) {
+	return nil, errReporterUnsupported
+}
+
+func (r *Reporter) List(operation.ListOptions) ([]*operation.Operation, string, error) {
+	return nil, "", errReporterUnsupported
+}
+
+func (r *Reporter) MarkCancelling(string) error {
+	return errReporterUnsupported
+}
+
+func (r *Reporter) Expire(time.Time) ([]*operation.Operation, error) {
+	return nil, errReporterUnsupported
+}
+
+var _ operation.Store = (*Reporter)(nil)
@@ -0,0 +1,140 @@
+// Package metrics exposes the Prometheus metrics of the capture server, so
+// that operators can monitor and alert on it.
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/tomasbasham/har-capture/internal/storage"
+)
+
+const namespace = "har_capture"
+
+// Metrics holds the collectors of the server, registered on a registry of
+// their own rather than the global default.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	capturesStarted  prometheus.Counter
+	capturesFinished *prometheus.CounterVec
+	captureDuration  *prometheus.HistogramVec
+	ttfb             prometheus.Histogram
+	uploadDuration   prometheus.Histogram
+}
+
+// New returns Metrics with the Go runtime and process collectors
+// registered alongside the server's own.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		capturesStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "captures_started_total",
+			Help:      "Captures taken from the queue and started.",
+		}),
+		capturesFinished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "captures_finished_total",
+			Help:      "Captures finished, by final status: complete, failed or cancelled.",
+		}, []string{"status"}),
+		captureDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "capture_duration_seconds",
+			Help:      "Time from starting a capture to finishing it, including retries and uploads.",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+		}, []string{"status"}),
+		ttfb: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ttfb_seconds",
+			Help:      "Time to first byte of the pages of completed captures.",
+			Buckets:   prometheus.ExponentialBuckets(0.025, 2, 10),
+		}),
+		uploadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upload_duration_seconds",
+			Help:      "Time taken to upload each artefact to storage.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.capturesStarted,
+		m.capturesFinished,
+		m.captureDuration,
+		m.ttfb,
+		m.uploadDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Gauge registers a gauge named name whose value is read from fn at each
+// scrape, e.g. the depth of a queue.
+func (m *Metrics) Gauge(name, help string, fn func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// CaptureStarted records that a capture has started.
+func (m *Metrics) CaptureStarted() {
+	m.capturesStarted.Inc()
+}
+
+// CaptureFinished records a capture that finished in status after
+// duration. ttfb is recorded only for complete captures.
+func (m *Metrics) CaptureFinished(status string, duration, ttfb time.Duration) {
+	m.capturesFinished.WithLabelValues(status).Inc()
+	m.captureDuration.WithLabelValues(status).Observe(duration.Seconds())
+	if status == "complete" && ttfb > 0 {
+		m.ttfb.Observe(ttfb.Seconds())
+	}
+}
+
+// InstrumentUploader returns an Uploader that records the duration of each
+// upload made through u.
+func (m *Metrics) InstrumentUploader(u storage.Uploader) storage.Uploader {
+	return &instrumentedUploader{Uploader: u, duration: m.uploadDuration}
+}
+
+type instrumentedUploader struct {
+	storage.Uploader
+	duration prometheus.Histogram
+}
+
+func (u *instrumentedUploader) Upload(ctx context.Context, req *storage.UploadRequest) (*storage.UploadResult, error) {
+	start := time.Now()
+	defer func() {
+		u.duration.Observe(time.Since(start).Seconds())
+	}()
+	return u.Uploader.Upload(ctx, req)
+}
+
+// Unwrap returns the underlying uploader.
+func (u *instrumentedUploader) Unwrap() storage.Uploader {
+	return u.Uploader
+}
+
+// Delete deletes objectName with the underlying uploader, if it can.
+func (u *instrumentedUploader) Delete(ctx context.Context, objectName string) error {
+	return storage.Delete(ctx, u.Uploader, objectName)
+}
+
+// Open opens objectName with the underlying uploader, if it can.
+func (u *instrumentedUploader) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	return storage.Open(ctx, u.Uploader, objectName)
+}
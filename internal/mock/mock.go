@@ -0,0 +1,182 @@
+// Package mock serves the responses recorded in a HAR file, standing in for
+// the servers that produced them.
+package mock
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/har"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Handler replays recorded responses. A request is answered with the
+// response to the recorded request of the same method, path and query, the
+// order of query parameters aside. Where several recorded requests match,
+// they are replayed in turn, repeating the last once exhausted.
+type Handler struct {
+	headers []string
+
+	mu      sync.Mutex
+	routes  map[string][]*capture.Entry
+	replays map[string]int
+}
+
+// Option configures optional behaviour of a Handler.
+type Option func(*Handler)
+
+// WithHeaderMatch additionally requires the named request headers to match
+// those recorded, e.g. to tell apart responses that vary by Accept or by
+// Authorization.
+func WithHeaderMatch(names ...string) Option {
+	return func(h *Handler) {
+		for _, name := range names {
+			h.headers = append(h.headers, textproto.CanonicalMIMEHeaderKey(name))
+		}
+	}
+}
+
+// New creates a Handler replaying the responses recorded in archive.
+// Entries without a response, such as those still pending when the capture
+// ended, are skipped.
+func New(archive capture.HAR, opts ...Option) *Handler {
+	h := &Handler{
+		routes:  make(map[string][]*capture.Entry),
+		replays: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if archive.Log == nil {
+		return h
+	}
+	for _, e := range archive.Log.Entries {
+		if e == nil || e.Request == nil || e.Response == nil || e.Response.Status == 0 {
+			continue
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			continue
+		}
+		k := routeKey(e.Request.Method, u)
+		h.routes[k] = append(h.routes[k], e)
+	}
+	return h
+}
+
+// Len returns the number of responses the Handler can replay.
+func (h *Handler) Len() int {
+	n := 0
+	for _, es := range h.routes {
+		n += len(es)
+	}
+	return n
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e := h.match(r)
+	if e == nil {
+		http.Error(w, fmt.Sprintf("no recorded response for %s %s", r.Method, r.URL.RequestURI()), http.StatusNotFound)
+		return
+	}
+
+	resp := e.Response
+	body, err := responseBody(resp.Content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, hdr := range resp.Headers {
+		if hdr == nil || strings.HasPrefix(hdr.Name, ":") {
+			continue
+		}
+		// The body is served as recorded, decoded, so the framing and
+		// encoding of the original response no longer apply.
+		switch strings.ToLower(hdr.Name) {
+		case "content-length", "content-encoding", "transfer-encoding", "connection":
+			continue
+		}
+		w.Header().Add(hdr.Name, hdr.Value)
+	}
+	w.WriteHeader(int(resp.Status))
+	w.Write(body)
+}
+
+// match returns the recorded entry to answer r with, or nil if there is none.
+func (h *Handler) match(r *http.Request) *capture.Entry {
+	k := routeKey(r.Method, r.URL)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var candidates []*capture.Entry
+	for _, e := range h.routes[k] {
+		if h.headersMatch(r, e.Request) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Replays are counted per route and set of matched headers, so that
+	// requests differing only in those headers are replayed independently.
+	rk := k
+	for _, name := range h.headers {
+		rk += "\n" + name + ": " + r.Header.Get(name)
+	}
+	i := h.replays[rk]
+	h.replays[rk]++
+	return candidates[min(i, len(candidates)-1)]
+}
+
+func (h *Handler) headersMatch(r *http.Request, req *har.Request) bool {
+	for _, name := range h.headers {
+		if r.Header.Get(name) != recordedHeader(req, name) {
+			return false
+		}
+	}
+	return true
+}
+
+func recordedHeader(req *har.Request, name string) string {
+	for _, hdr := range req.Headers {
+		if hdr != nil && strings.EqualFold(hdr.Name, name) {
+			return hdr.Value
+		}
+	}
+	return ""
+}
+
+// routeKey identifies a request by method, path and query. Encoding the
+// query sorts its parameters.
+func routeKey(method string, u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return method + " " + path + "?" + u.Query().Encode()
+}
+
+func responseBody(c *har.Content) ([]byte, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.Encoding == "base64" {
+		b, err := base64.StdEncoding.DecodeString(c.Text)
+		if err != nil {
+			return nil, fmt.Errorf("mock: invalid recorded body: %w", err)
+		}
+		return b, nil
+	}
+	return []byte(c.Text), nil
+}
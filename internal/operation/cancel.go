@@ -0,0 +1,62 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrInterrupted is the cause with which CancelAll cancels workers when the
+// server shuts down.
+var ErrInterrupted = errors.New("interrupted by server shutdown")
+
+// Canceller holds the means to cancel the workers of running operations.
+type Canceller struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+func NewCanceller() *Canceller {
+	return &Canceller{cancels: make(map[string]context.CancelCauseFunc)}
+}
+
+// Register returns a context for the worker of operation id, cancelled by a
+// call to Cancel, and a func to release it once the worker has returned.
+func (c *Canceller) Register(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	c.mu.Lock()
+	c.cancels[id] = cancel
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.cancels, id)
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel cancels the worker of operation id. It reports whether a worker was
+// registered for it.
+func (c *Canceller) Cancel(id string) bool {
+	c.mu.Lock()
+	cancel, ok := c.cancels[id]
+	c.mu.Unlock()
+
+	if ok {
+		cancel(nil)
+	}
+	return ok
+}
+
+// CancelAll cancels the worker of every operation with ErrInterrupted, so
+// that they record the operations as interrupted rather than cancelled.
+func (c *Canceller) CancelAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cancel := range c.cancels {
+		cancel(ErrInterrupted)
+	}
+}
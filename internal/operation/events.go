@@ -0,0 +1,169 @@
+package operation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	// EventStatus reports that an operation moved to a new Status.
+	EventStatus EventType = "status"
+
+	// EventNavigation reports that the page under capture started loading
+	// a URL.
+	EventNavigation EventType = "navigation"
+
+	// EventEntries reports the number of HAR entries collected so far.
+	EventEntries EventType = "entries"
+
+	// EventEntry carries a HAR entry as it is collected. Response bodies are
+	// attached only once the HAR is assembled, so are absent.
+	EventEntry EventType = "entry"
+
+	// EventUploading reports that an artefact is being uploaded.
+	EventUploading EventType = "uploading"
+
+	// EventDropped reports that the subscriber fell behind and missed the
+	// number of events in Dropped, published since the last it received.
+	EventDropped EventType = "dropped"
+)
+
+// Event is a change in the status or progress of an operation. Only the
+// fields relevant to its Type are set.
+type Event struct {
+	Type     EventType      `json:"type"`
+	Time     time.Time      `json:"time"`
+	Status   Status         `json:"status,omitempty"`
+	URL      string         `json:"url,omitempty"`
+	Entries  int            `json:"entries,omitempty"`
+	Artefact string         `json:"artefact,omitempty"`
+	Entry    *capture.Entry `json:"entry,omitempty"`
+	Dropped  int            `json:"dropped,omitempty"`
+}
+
+// subscriberBuffer is the number of events held for a subscriber that is
+// slow to receive them before further events are dropped.
+const subscriberBuffer = 256
+
+// Bus distributes the events of operations to their subscribers. Events are
+// not stored: a subscriber receives only those published after it
+// subscribed, and a subscriber that falls behind misses events rather than
+// holding up the operation. It is told how many it missed by an
+// EventDropped, received before any later event.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]*subscriber
+}
+
+// subscriber is the state of a channel returned by Subscribe.
+type subscriber struct {
+	// dropped is the number of events not sent since the last EventDropped.
+	dropped int
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string]map[chan Event]*subscriber)}
+}
+
+// Publish sends e to the subscribers of operation id.
+func (b *Bus) Publish(id string, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, sub := range b.subs[id] {
+		if sub.dropped > 0 {
+			select {
+			case ch <- Event{Type: EventDropped, Time: e.Time, Dropped: sub.dropped}:
+				sub.dropped = 0
+			default:
+				sub.dropped++
+				continue
+			}
+		}
+		select {
+		case ch <- e:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the events of operation id, and a
+// func to be called to unsubscribe once they are no longer wanted.
+func (b *Bus) Subscribe(id string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[id] == nil {
+		b.subs[id] = make(map[chan Event]*subscriber)
+	}
+	b.subs[id][ch] = &subscriber{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[id], ch)
+		if len(b.subs[id]) == 0 {
+			delete(b.subs, id)
+		}
+	}
+}
+
+// publishingStore publishes an EventStatus whenever an operation of the
+// Store it wraps changes state.
+type publishingStore struct {
+	Store
+	bus *Bus
+}
+
+// WithEvents returns store, publishing status changes of its operations on
+// bus.
+func WithEvents(store Store, bus *Bus) Store {
+	return &publishingStore{Store: store, bus: bus}
+}
+
+func (s *publishingStore) MarkRunning(id string) error {
+	return s.published(id, s.Store.MarkRunning(id))
+}
+
+func (s *publishingStore) MarkComplete(id string, outcome Outcome) error {
+	return s.published(id, s.Store.MarkComplete(id, outcome))
+}
+
+func (s *publishingStore) MarkFailed(id string, err error) error {
+	return s.published(id, s.Store.MarkFailed(id, err))
+}
+
+func (s *publishingStore) MarkCancelling(id string) error {
+	return s.published(id, s.Store.MarkCancelling(id))
+}
+
+func (s *publishingStore) MarkCancelled(id string, outcome Outcome) error {
+	return s.published(id, s.Store.MarkCancelled(id, outcome))
+}
+
+func (s *publishingStore) MarkInterrupted(id string) error {
+	return s.published(id, s.Store.MarkInterrupted(id))
+}
+
+// published publishes the status of operation id, unless err shows that it
+// did not change.
+func (s *publishingStore) published(id string, err error) error {
+	if err != nil {
+		return err
+	}
+	op, getErr := s.Store.Get(id)
+	if getErr == nil {
+		s.bus.Publish(id, Event{Type: EventStatus, Status: op.Status})
+	}
+	return nil
+}
@@ -1,36 +1,65 @@
 // Package operation provides the domain model for async capture operations.  An
 // Operation moves through a linear lifecycle:
 //
-//	pending → running → complete | failed.
+//	pending → running → complete | failed | cancelled.
+//
+// A request to cancel an operation moves it to cancelling until its worker
+// has stopped and uploaded whatever it had captured. An operation still
+// unfinished when the server shuts down is interrupted, and may be created
+// again once the server has restarted.
 //
 // The store is the authoritative source of truth for operation state; HTTP
 // handlers read and write exclusively through it.
 package operation
 
 import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
 )
 
 // Status represents the lifecycle state of an operation.
 type Status string
 
 const (
-	StatusPending  Status = "pending"
-	StatusRunning  Status = "running"
-	StatusComplete Status = "complete"
-	StatusFailed   Status = "failed"
+	StatusPending    Status = "pending"
+	StatusRunning    Status = "running"
+	StatusComplete   Status = "complete"
+	StatusFailed     Status = "failed"
+	StatusCancelling Status = "cancelling"
+	StatusCancelled  Status = "cancelled"
+
+	// StatusInterrupted marks an operation abandoned because the server
+	// shut down before it could finish.
+	StatusInterrupted Status = "interrupted"
 )
 
+// Terminal reports whether an operation in status s has finished.
+func (s Status) Terminal() bool {
+	return s == StatusComplete || s == StatusFailed || s == StatusCancelled || s == StatusInterrupted
+}
+
 // Artefact is a named output produced by a completed operation, referenced by
 // a signed URL valid for a bounded period.
 type Artefact struct {
 	Name      string    `json:"name"`
 	SignedURL string    `json:"signed_url"`
 	ExpiresAt time.Time `json:"expires_at"`
+
+	// ObjectName locates the artefact in storage, so that it can be deleted
+	// with its operation.
+	ObjectName string `json:"-"`
 }
 
 // Operation represents a single async capture job.
@@ -41,50 +70,163 @@ type Operation struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
+	// Subject identifies who requested the operation, when requests are
+	// authenticated.
+	Subject string `json:"subject,omitempty"`
+
+	// Tenant names the tenant the operation belongs to, if any. Operations
+	// are visible only to their own tenant.
+	Tenant string `json:"tenant,omitempty"`
+
 	// TTFB is populated once the operation reaches StatusComplete.
 	TTFB time.Duration `json:"ttfb_ms"`
 
 	// TimedOut is true if the capture was cut off before networkIdle.
 	TimedOut bool `json:"timed_out"`
 
+	// WebVitals is populated once the operation reaches StatusComplete, if
+	// the vitals could be read from the page.
+	WebVitals *capture.WebVitals `json:"web_vitals,omitempty"`
+
+	// Metrics is populated once the operation reaches StatusComplete.
+	Metrics *capture.Metrics `json:"metrics,omitempty"`
+
+	// Attempts records each attempt at the capture, when captures that fail
+	// for transient reasons are retried.
+	Attempts []Attempt `json:"attempts,omitempty"`
+
 	// Artefacts lists the GCS objects produced by a completed operation.
 	// Empty until the operation reaches StatusComplete.
 	Artefacts []Artefact `json:"artefacts,omitempty"`
 
+	// HAR is the HAR of a completed operation, kept when it is small enough
+	// to be returned inline. It is omitted from the operation's JSON, and
+	// included only on request.
+	HAR json.RawMessage `json:"-"`
+
 	// Error is non-empty if the operation reached StatusFailed.
 	Error string `json:"error,omitempty"`
 }
 
+// Outcome holds the results recorded against an operation when it reaches
+// StatusComplete, or StatusCancelled with whatever was captured beforehand.
+type Outcome struct {
+	TTFB      time.Duration
+	TimedOut  bool
+	WebVitals *capture.WebVitals
+	Metrics   *capture.Metrics
+	Artefacts []Artefact
+
+	// HAR, if set, is kept on the operation to be returned inline.
+	HAR json.RawMessage
+}
+
+// Owner identifies who an operation belongs to.
+type Owner struct {
+	Tenant  string
+	Subject string
+}
+
+// ListOptions filters and pages the operations returned by Store.List.
+type ListOptions struct {
+	// Tenant restricts the list to the operations of that tenant. Operations
+	// of other tenants are never listed.
+	Tenant string
+
+	// Status, if set, restricts the list to operations in that state.
+	Status Status
+
+	// URL, if set, restricts the list to operations whose URL contains it.
+	URL string
+
+	// CreatedAfter and CreatedBefore, if set, restrict the list to
+	// operations created at or after, and before, those times.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// PageToken continues a previous listing from where it left off.
+	PageToken string
+
+	// Limit caps the number of operations returned. Zero means no limit.
+	Limit int
+}
+
+// ErrInvalidPageToken is returned by Store.List when the page token was not
+// produced by a previous listing.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
 // Store is the interface for persisting and retrieving operations. The
 // in-memory implementation below is suitable for a single instance; a Firestore
 // or Cloud SQL-backed implementation would satisfy the same interface for
 // multi-instance deployments.
 type Store interface {
-	Create(url string) (*Operation, error)
+	Create(url string, owner Owner) (*Operation, error)
+
+	// CreateOnce creates an operation as Create does, unless owner has
+	// already created one with the same idempotency key, in which case that
+	// operation is returned and created is false. Reusing a key for a
+	// different URL fails with ErrIdempotencyKeyReused.
+	CreateOnce(key, url string, owner Owner) (op *Operation, created bool, err error)
 	Get(id string) (*Operation, error)
+
+	// List returns operations matching opts, most recently created first,
+	// and a token for the next page, or "" if there are no more.
+	List(opts ListOptions) ([]*Operation, string, error)
+
 	MarkRunning(id string) error
-	MarkComplete(id string, ttfb time.Duration, timedOut bool, artefacts []Artefact) error
+	MarkComplete(id string, outcome Outcome) error
 	MarkFailed(id string, err error) error
+
+	// RecordAttempt appends attempt to the history of an operation.
+	RecordAttempt(id string, attempt Attempt) error
+
+	// MarkCancelling records a request to cancel an operation that has not
+	// yet finished. It fails with ErrFinished if the operation has.
+	MarkCancelling(id string) error
+	MarkCancelled(id string, outcome Outcome) error
+
+	// MarkInterrupted records that an operation was abandoned when the
+	// server shut down.
+	MarkInterrupted(id string) error
+
+	// Expire deletes the operations that finished before the given time,
+	// with their idempotency keys, and returns them so that their artefacts
+	// can be deleted too. Unfinished operations are never expired.
+	Expire(before time.Time) ([]*Operation, error)
 }
 
+// ErrIdempotencyKeyReused is returned by Store.CreateOnce when an
+// idempotency key is reused for a different request.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+
+// ErrFinished is returned when an operation cannot change state because it
+// has already finished.
+var ErrFinished = errors.New("operation has finished")
+
 // MemoryStore is a concurrency-safe in-memory Store implementation.
 type MemoryStore struct {
 	mu  sync.RWMutex
 	ops map[string]*Operation
+
+	// idempotent maps the idempotency keys of owners to the IDs of the
+	// operations they created.
+	idempotent map[idempotencyKey]string
 }
 
-func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{ops: make(map[string]*Operation)}
+type idempotencyKey struct {
+	owner Owner
+	key   string
 }
 
-func (s *MemoryStore) Create(url string) (*Operation, error) {
-	op := &Operation{
-		ID:        uuid.New().String(),
-		Status:    StatusPending,
-		URL:       url,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		ops:        make(map[string]*Operation),
+		idempotent: make(map[idempotencyKey]string),
 	}
+}
+
+func (s *MemoryStore) Create(url string, owner Owner) (*Operation, error) {
+	op := newOperation(url, owner)
 
 	s.mu.Lock()
 	s.ops[op.ID] = op
@@ -93,6 +235,43 @@ func (s *MemoryStore) Create(url string) (*Operation, error) {
 	return op, nil
 }
 
+func (s *MemoryStore) CreateOnce(key, url string, owner Owner) (*Operation, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{owner: owner, key: key}
+	if id, ok := s.idempotent[k]; ok {
+		if existing, ok := s.ops[id]; ok {
+			if existing.URL != url {
+				return nil, false, ErrIdempotencyKeyReused
+			}
+			copy := *existing
+			copy.Attempts = slices.Clone(existing.Attempts)
+			return &copy, false, nil
+		}
+	}
+
+	op := newOperation(url, owner)
+	s.ops[op.ID] = op
+	s.idempotent[k] = op.ID
+
+	copy := *op
+	return &copy, true, nil
+}
+
+func newOperation(url string, owner Owner) *Operation {
+	now := time.Now()
+	return &Operation{
+		ID:        uuid.New().String(),
+		Status:    StatusPending,
+		URL:       url,
+		Subject:   owner.Subject,
+		Tenant:    owner.Tenant,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
 func (s *MemoryStore) Get(id string) (*Operation, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -103,21 +282,103 @@ func (s *MemoryStore) Get(id string) (*Operation, error) {
 	}
 	// Return a copy to prevent callers from mutating internal state.
 	copy := *op
+	copy.Attempts = slices.Clone(op.Attempts)
 	return &copy, nil
 }
 
+func (s *MemoryStore) Expire(before time.Time) ([]*Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*Operation
+	for id, op := range s.ops {
+		if op.Status.Terminal() && op.UpdatedAt.Before(before) {
+			delete(s.ops, id)
+			expired = append(expired, op)
+		}
+	}
+	if len(expired) > 0 {
+		for k, id := range s.idempotent {
+			if _, ok := s.ops[id]; !ok {
+				delete(s.idempotent, k)
+			}
+		}
+	}
+	return expired, nil
+}
+
+func (s *MemoryStore) List(opts ListOptions) ([]*Operation, string, error) {
+	var after *pageCursor
+	if opts.PageToken != "" {
+		c, err := decodePageToken(opts.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &c
+	}
+
+	s.mu.RLock()
+	var ops []*Operation
+	for _, op := range s.ops {
+		if op.Tenant != opts.Tenant {
+			continue
+		}
+		if opts.Status != "" && op.Status != opts.Status {
+			continue
+		}
+		if opts.URL != "" && !strings.Contains(op.URL, opts.URL) {
+			continue
+		}
+		if !opts.CreatedAfter.IsZero() && op.CreatedAt.Before(opts.CreatedAfter) {
+			continue
+		}
+		if !opts.CreatedBefore.IsZero() && !op.CreatedAt.Before(opts.CreatedBefore) {
+			continue
+		}
+		copy := *op
+		copy.Attempts = slices.Clone(op.Attempts)
+		ops = append(ops, &copy)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(ops, func(a, b *Operation) int {
+		return cursorOf(a).compare(cursorOf(b))
+	})
+	if after != nil {
+		i, _ := slices.BinarySearchFunc(ops, *after, func(op *Operation, c pageCursor) int {
+			return cursorOf(op).compare(c)
+		})
+		for i < len(ops) && cursorOf(ops[i]) == *after {
+			i++
+		}
+		ops = ops[i:]
+	}
+
+	if opts.Limit > 0 && len(ops) > opts.Limit {
+		ops = ops[:opts.Limit]
+		return ops, cursorOf(ops[len(ops)-1]).token(), nil
+	}
+	return ops, "", nil
+}
+
 func (s *MemoryStore) MarkRunning(id string) error {
 	return s.update(id, func(op *Operation) {
-		op.Status = StatusRunning
+		// An operation cancelled before its worker started stays cancelling.
+		if op.Status == StatusPending {
+			op.Status = StatusRunning
+		}
 	})
 }
 
-func (s *MemoryStore) MarkComplete(id string, ttfb time.Duration, timedOut bool, artefacts []Artefact) error {
+func (s *MemoryStore) MarkComplete(id string, outcome Outcome) error {
 	return s.update(id, func(op *Operation) {
 		op.Status = StatusComplete
-		op.TTFB = ttfb
-		op.TimedOut = timedOut
-		op.Artefacts = artefacts
+		op.TTFB = outcome.TTFB
+		op.TimedOut = outcome.TimedOut
+		op.WebVitals = outcome.WebVitals
+		op.Metrics = outcome.Metrics
+		op.Artefacts = outcome.Artefacts
+		op.HAR = outcome.HAR
 	})
 }
 
@@ -128,6 +389,45 @@ func (s *MemoryStore) MarkFailed(id string, err error) error {
 	})
 }
 
+func (s *MemoryStore) RecordAttempt(id string, attempt Attempt) error {
+	return s.update(id, func(op *Operation) {
+		op.Attempts = append(op.Attempts, attempt)
+	})
+}
+
+func (s *MemoryStore) MarkCancelling(id string) error {
+	var err error
+	updateErr := s.update(id, func(op *Operation) {
+		if op.Status.Terminal() {
+			err = ErrFinished
+			return
+		}
+		op.Status = StatusCancelling
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+func (s *MemoryStore) MarkCancelled(id string, outcome Outcome) error {
+	return s.update(id, func(op *Operation) {
+		op.Status = StatusCancelled
+		op.TTFB = outcome.TTFB
+		op.TimedOut = outcome.TimedOut
+		op.WebVitals = outcome.WebVitals
+		op.Metrics = outcome.Metrics
+		op.Artefacts = outcome.Artefacts
+	})
+}
+
+func (s *MemoryStore) MarkInterrupted(id string) error {
+	return s.update(id, func(op *Operation) {
+		op.Status = StatusInterrupted
+		op.Error = ErrInterrupted.Error()
+	})
+}
+
 func (s *MemoryStore) update(id string, fn func(*Operation)) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -140,3 +440,41 @@ func (s *MemoryStore) update(id string, fn func(*Operation)) error {
 	op.UpdatedAt = time.Now()
 	return nil
 }
+
+// pageCursor identifies the last operation of a page of a listing, ordered
+// most recently created first and then by ID.
+type pageCursor struct {
+	createdAt int64
+	id        string
+}
+
+func cursorOf(op *Operation) pageCursor {
+	return pageCursor{createdAt: op.CreatedAt.UnixNano(), id: op.ID}
+}
+
+func (c pageCursor) compare(o pageCursor) int {
+	if n := cmp.Compare(o.createdAt, c.createdAt); n != 0 {
+		return n
+	}
+	return strings.Compare(c.id, o.id)
+}
+
+func (c pageCursor) token() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.createdAt, 10) + ":" + c.id))
+}
+
+func decodePageToken(token string) (pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	createdAt, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	n, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	return pageCursor{createdAt: n, id: id}, nil
+}
@@ -0,0 +1,84 @@
+package operation
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQueueClosed is returned by Queue.Enqueue once the queue has been closed.
+var ErrQueueClosed = errors.New("queue is closed")
+
+// Queue runs jobs on a fixed number of workers, in the order they were
+// enqueued, so that a burst of operations is worked through rather than
+// started all at once. Jobs waiting for a worker are held without limit;
+// their operations remain pending meanwhile.
+type Queue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   []func()
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewQueue starts workers workers, at least one.
+func NewQueue(workers int) *Queue {
+	q := &Queue{}
+	q.cond = sync.NewCond(&q.mu)
+
+	for range max(workers, 1) {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue adds job to the queue, to be run once a worker is free.
+func (q *Queue) Enqueue(job func()) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.jobs = append(q.jobs, job)
+	q.cond.Signal()
+	return nil
+}
+
+// Len returns the number of jobs waiting for a worker.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Close stops the queue accepting jobs and waits for the workers to finish
+// those already enqueued.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		q.mu.Lock()
+		for len(q.jobs) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.jobs) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		job := q.jobs[0]
+		q.jobs[0] = nil
+		q.jobs = q.jobs[1:]
+		q.mu.Unlock()
+
+		job()
+	}
+}
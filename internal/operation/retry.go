@@ -0,0 +1,91 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// maxRetryBackoff caps the wait between attempts.
+const maxRetryBackoff = 5 * time.Minute
+
+// RetryPolicy controls how often a capture that fails for a reason likely
+// to be transient, or an upload that fails, is attempted again.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a capture is attempted, including
+	// the first. Zero or one disables retries.
+	MaxAttempts int
+
+	// Backoff is the wait before the second attempt, doubled before each
+	// further attempt.
+	Backoff time.Duration
+}
+
+// backoff returns the wait after the attempt-th attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// Attempt records one attempt at the capture of an operation.
+type Attempt struct {
+	Number     int       `json:"number"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// TimedOut is true if the capture was cut off before the page settled.
+	TimedOut bool `json:"timed_out,omitempty"`
+
+	// Error is non-empty if the attempt failed.
+	Error string `json:"error,omitempty"`
+}
+
+// transientNetErrors are the Chrome network errors with which navigation
+// fails that may not recur on another attempt.
+var transientNetErrors = []string{
+	"net::ERR_TIMED_OUT",
+	"net::ERR_CONNECTION_RESET",
+	"net::ERR_CONNECTION_CLOSED",
+	"net::ERR_CONNECTION_REFUSED",
+	"net::ERR_EMPTY_RESPONSE",
+	"net::ERR_NETWORK_CHANGED",
+	"net::ERR_HTTP2_PROTOCOL_ERROR",
+}
+
+// retryable reports whether a capture that ended with result and err, while
+// its worker ran under ctx, is worth attempting again: if it timed out, or
+// the browser crashed or could not be reached, or navigation failed with a
+// transient network error.
+func retryable(ctx context.Context, result *capture.Result, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err == nil {
+		return result != nil && result.TimedOut
+	}
+	if errors.Is(err, capture.ErrCancelled) || errors.Is(err, capture.ErrAborted) || errors.Is(err, capture.ErrPoolClosed) {
+		return false
+	}
+
+	// The browser's context ends early when the browser crashes or the
+	// connection to it is lost.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "failed to start browser") || strings.Contains(msg, "failed to connect to") {
+		return true
+	}
+	for _, netErr := range transientNetErrors {
+		if strings.Contains(msg, netErr) {
+			return true
+		}
+	}
+	return false
+}
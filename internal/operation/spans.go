@@ -0,0 +1,19 @@
+package operation
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the lifecycle of each operation as OpenTelemetry spans.
+// The spans of the capture itself are children of the attempt that made it.
+var tracer = otel.Tracer("github.com/tomasbasham/har-capture/internal/operation")
+
+// recordError marks span as failed with err, if err is non-nil.
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -4,11 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"time"
 
-	"github.com/tomasbasham/har-capture/internal/capture"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/tomasbasham/har-capture/internal/compress"
+	"github.com/tomasbasham/har-capture/internal/hario"
 	"github.com/tomasbasham/har-capture/internal/storage"
+	"github.com/tomasbasham/har-capture/pkg/capture"
 )
 
 // WorkerOptions configures a capture worker invocation.
@@ -17,86 +26,490 @@ type WorkerOptions struct {
 	OperationID    string
 	Store          Store
 	Uploader       storage.Uploader
+
+	// Tenant, when set, is the tenant owning the operation, recorded in the
+	// metadata of its artefacts.
+	Tenant string
+
+	// Pool, when set, supplies the browser for the capture. Otherwise a
+	// browser is launched for this capture alone.
+	Pool *capture.Pool
+
+	// Compression is applied to the HAR artefacts, whose filenames take the
+	// extension of the format.
+	Compression compress.Format
+
+	// Events, when set, receives the progress of the capture.
+	Events *Bus
+
+	// Retry controls whether a capture that fails for a transient reason,
+	// or times out, is attempted again.
+	Retry RetryPolicy
+
+	// UploadRetry controls whether the upload of an artefact that fails is
+	// attempted again, so that a transient storage error does not waste a
+	// successful capture.
+	UploadRetry RetryPolicy
+
+	// UploadParallelism is the most artefacts uploaded at once. Zero or
+	// less uploads them one at a time.
+	UploadParallelism int
+
+	// ArtefactURL, when set, returns the URL from which the named artefact
+	// is served, given in place of the signed URL for storage that has none,
+	// such as memory.
+	ArtefactURL func(name string) string
+
+	// InlineHARLimit, when positive, keeps the HAR of a completed capture on
+	// the operation if it serialises to no more than this many bytes, so
+	// that clients can read it without a trip to storage.
+	InlineHARLimit int64
+
+	// Logger, when set, receives the lifecycle of the operation. It is also
+	// passed to the capture, unless CaptureOptions sets a logger of its own.
+	Logger *slog.Logger
+}
+
+// logger returns the logger for the operation, annotated with its ID.
+func (opts WorkerOptions) logger() *slog.Logger {
+	if opts.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return opts.Logger.With("operation_id", opts.OperationID)
 }
 
 // Run executes a capture, uploads the resulting artefacts to GCS, and
-// transitions the operation through running → complete | failed.
+// transitions the operation through running → complete | failed | cancelled.
 //
 // Run is intended to be called in a separate goroutine; it owns the full
 // lifecycle of the operation from the moment it is called.
 func Run(ctx context.Context, opts WorkerOptions) {
+	ctx, span := tracer.Start(ctx, "operation.run", trace.WithAttributes(
+		attribute.String("operation.id", opts.OperationID),
+		attribute.String("url.full", opts.CaptureOptions.URL),
+	))
+	defer span.End()
+
+	logger := opts.logger()
 	if err := opts.Store.MarkRunning(opts.OperationID); err != nil {
 		// If we cannot even mark it running the store is broken; nothing to do.
+		recordError(span, err)
+		logger.Error("failed to mark operation running", "error", err)
 		return
 	}
 
-	result, err := capture.Capture(ctx, opts.CaptureOptions)
+	start := time.Now()
+	logger.Info("capture started", "url", opts.CaptureOptions.URL)
+
+	result, err := attempt(ctx, opts)
+	if err != nil && errors.Is(context.Cause(ctx), ErrInterrupted) {
+		// The server is shutting down and cannot wait for the upload.
+		span.SetAttributes(attribute.String("operation.status", string(StatusInterrupted)))
+		logger.Warn("capture interrupted", "duration", time.Since(start))
+		_ = opts.Store.MarkInterrupted(opts.OperationID)
+		return
+	}
+	if errors.Is(err, capture.ErrCancelled) || (err != nil && errors.Is(ctx.Err(), context.Canceled)) {
+		span.SetAttributes(attribute.String("operation.status", string(StatusCancelled)))
+		logger.Info("capture cancelled", "duration", time.Since(start))
+		cancelled(ctx, opts, result)
+		return
+	}
 	if err != nil {
+		recordError(span, err)
+		logger.Error("capture failed", "duration", time.Since(start), "error", err)
 		_ = opts.Store.MarkFailed(opts.OperationID, fmt.Errorf("capture: %w", err))
 		return
 	}
 
-	artefacts, err := uploadArtefacts(ctx, opts.OperationID, result, opts.Uploader)
+	// The upload must outlive a cancellation or shutdown that arrives while
+	// it is under way; the cause then decides how the operation ends.
+	artefacts, err := uploadArtefacts(context.WithoutCancel(ctx), opts, result)
 	if err != nil {
+		recordError(span, err)
+		logger.Error("upload failed", "duration", time.Since(start), "error", err)
 		_ = opts.Store.MarkFailed(opts.OperationID, fmt.Errorf("upload: %w", err))
 		return
 	}
 
-	_ = opts.Store.MarkComplete(opts.OperationID, result.TTFB, result.TimedOut, artefacts)
+	outcome := Outcome{
+		TTFB:      result.TTFB,
+		TimedOut:  result.TimedOut,
+		WebVitals: result.WebVitals,
+		Metrics:   result.Metrics,
+		Artefacts: artefacts,
+		HAR:       inlineHAR(result.HAR, opts.InlineHARLimit),
+	}
+	switch {
+	case errors.Is(context.Cause(ctx), ErrInterrupted):
+		span.SetAttributes(attribute.String("operation.status", string(StatusInterrupted)))
+		logger.Warn("capture interrupted", "duration", time.Since(start))
+		_ = opts.Store.MarkInterrupted(opts.OperationID)
+	case ctx.Err() != nil:
+		span.SetAttributes(attribute.String("operation.status", string(StatusCancelled)))
+		logger.Info("capture cancelled", "duration", time.Since(start))
+		_ = opts.Store.MarkCancelled(opts.OperationID, outcome)
+	default:
+		logger.Info("capture complete",
+			"duration", time.Since(start),
+			"ttfb", result.TTFB,
+			"timed_out", result.TimedOut,
+			"artefacts", len(artefacts),
+		)
+		_ = opts.Store.MarkComplete(opts.OperationID, outcome)
+	}
+}
+
+// inlineHAR serialises h, returning nil if limit is not positive or the
+// HAR is larger than limit bytes.
+func inlineHAR(h capture.HAR, limit int64) json.RawMessage {
+	if limit <= 0 {
+		return nil
+	}
+	buf := &limitedBuffer{limit: limit}
+	if err := hario.WriteHAR(buf, h); err != nil {
+		return nil
+	}
+	return buf.Bytes()
 }
 
-// uploadArtefacts serialises the HAR and any screenshots and uploads them to
-// GCS. Returns the artefact list ready to be stored on the operation.
-func uploadArtefacts(ctx context.Context, operationID string, result *capture.Result, uploader storage.Uploader) ([]Artefact, error) {
-	var artefacts []Artefact
+// errTooLarge is returned by a limitedBuffer once its limit is exceeded.
+var errTooLarge = errors.New("exceeds limit")
+
+// limitedBuffer is a bytes.Buffer that refuses to grow beyond limit bytes,
+// so that a large HAR is abandoned without being held in memory.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int64
+}
 
-	// Upload HAR.
-	harJSON, err := json.Marshal(result.HAR)
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if int64(b.Len()+len(p)) > b.limit {
+		return 0, errTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// attempt runs the capture, attempting it again as opts.Retry allows, and
+// records each attempt on the operation. It returns the outcome of the last.
+func attempt(ctx context.Context, opts WorkerOptions) (*capture.Result, error) {
+	for n := 1; ; n++ {
+		a := Attempt{Number: n, StartedAt: time.Now()}
+
+		captureOpts := opts.CaptureOptions
+		if captureOpts.Logger == nil && opts.Logger != nil {
+			captureOpts.Logger = opts.logger()
+		}
+		if opts.Events != nil {
+			captureOpts.Hooks = progressHooks(captureOpts.Hooks, opts.Events, opts.OperationID)
+		}
+
+		attemptCtx, span := tracer.Start(ctx, "operation.attempt", trace.WithAttributes(attribute.Int("operation.attempt", n)))
+		var result *capture.Result
+		var err error
+		if opts.Pool != nil {
+			result, err = opts.Pool.Capture(attemptCtx, captureOpts)
+		} else {
+			result, err = capture.Capture(attemptCtx, captureOpts)
+		}
+		recordError(span, err)
+		span.End()
+
+		a.FinishedAt = time.Now()
+		if err != nil {
+			a.Error = err.Error()
+		} else {
+			a.TimedOut = result.TimedOut
+		}
+		if opts.Retry.MaxAttempts > 1 {
+			_ = opts.Store.RecordAttempt(opts.OperationID, a)
+		}
+
+		if n >= opts.Retry.MaxAttempts || !retryable(ctx, result, err) {
+			return result, err
+		}
+		opts.logger().Warn("capture attempt failed; retrying",
+			"attempt", n,
+			"backoff", opts.Retry.backoff(n),
+			"timed_out", a.TimedOut,
+			"error", a.Error,
+		)
+
+		select {
+		case <-ctx.Done():
+			// Cancelled between attempts: nothing is left to upload.
+			return nil, fmt.Errorf("capture: %w", capture.ErrCancelled)
+		case <-time.After(opts.Retry.backoff(n)):
+		}
+	}
+}
+
+// cancelled records the cancellation of an operation, first uploading the
+// artefacts of result, the partial capture made before it was cancelled, if
+// there is one. The upload must outlive the cancelled context.
+func cancelled(ctx context.Context, opts WorkerOptions, result *capture.Result) {
+	if result == nil {
+		_ = opts.Store.MarkCancelled(opts.OperationID, Outcome{})
+		return
+	}
+
+	artefacts, err := uploadArtefacts(context.WithoutCancel(ctx), opts, result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal HAR: %w", err)
+		_ = opts.Store.MarkFailed(opts.OperationID, fmt.Errorf("upload: %w", err))
+		return
+	}
+
+	_ = opts.Store.MarkCancelled(opts.OperationID, Outcome{
+		TTFB:      result.TTFB,
+		TimedOut:  result.TimedOut,
+		WebVitals: result.WebVitals,
+		Metrics:   result.Metrics,
+		Artefacts: artefacts,
+		HAR:       inlineHAR(result.HAR, opts.InlineHARLimit),
+	})
+}
+
+// progressHooks returns hooks that publish the progress of the capture of
+// operation id on bus before calling those of next.
+func progressHooks(next capture.Hooks, bus *Bus, id string) capture.Hooks {
+	hooks := next
+	hooks.OnNavigationStart = func(url string) error {
+		bus.Publish(id, Event{Type: EventNavigation, URL: url})
+		if next.OnNavigationStart != nil {
+			return next.OnNavigationStart(url)
+		}
+		return nil
 	}
 
-	harRequest := &storage.UploadRequest{
-		ObjectName:  objectPath(operationID, "capture.har"),
-		Content:     bytes.NewReader(harJSON),
-		ContentType: "application/json",
+	// Hooks are never called concurrently, so the count needs no lock.
+	entries := 0
+	hooks.OnEntryCompleted = func(entry capture.Entry) error {
+		entries++
+		bus.Publish(id, Event{Type: EventEntry, Entry: &entry})
+		bus.Publish(id, Event{Type: EventEntries, Entries: entries})
+		if next.OnEntryCompleted != nil {
+			return next.OnEntryCompleted(entry)
+		}
+		return nil
+	}
+	return hooks
+}
+
+// pendingArtefact is a serialised artefact awaiting upload.
+type pendingArtefact struct {
+	// name identifies the artefact on the operation, e.g. "har".
+	name        string
+	filename    string
+	contentType string
+	content     []byte
+
+	// contentEncoding is the compression applied to content, if any.
+	contentEncoding string
+
+	// write, when set, writes the content in place of content, so that
+	// large artefacts are streamed to storage rather than held in memory.
+	write func(io.Writer) error
+}
+
+// reader returns the content of the artefact, and a func to be called once
+// it has been read, or abandoned.
+func (p pendingArtefact) reader() (io.Reader, func()) {
+	if p.write == nil {
+		return bytes.NewReader(p.content), func() {}
 	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(p.write(pw))
+	}()
+	// Closing the reader unblocks the writer should the upload give up.
+	return pr, func() { pr.Close() }
+}
 
-	uploaded, err := uploader.Upload(ctx, harRequest)
+// uploadArtefacts serialises the HAR, console messages and any optional
+// outputs (trace, coverage, PDF, MHTML snapshot, screenshots, filmstrip) and
+// uploads them to GCS. Returns the artefact list ready to be stored on the
+// operation.
+func uploadArtefacts(ctx context.Context, opts WorkerOptions, result *capture.Result) ([]Artefact, error) {
+	pending, err := collectArtefacts(result, opts.Compression)
 	if err != nil {
 		return nil, err
 	}
-	artefacts = append(artefacts, Artefact{
-		Name:      "har",
-		SignedURL: uploaded.SignedURL,
-		ExpiresAt: uploaded.ExpiresAt,
-	})
 
-	// Upload screenshots.
-	for i, s := range result.Screenshots {
-		name := fmt.Sprintf("screenshot_%02d_%s.png", i+1, s.Stage)
+	// Every artefact of the operation is stored under the same date, even
+	// should the uploads straddle midnight.
+	capturedAt := time.Now().UTC()
+	metadata := map[string]string{
+		"operation_id": opts.OperationID,
+		"url":          opts.CaptureOptions.URL,
+		"captured_at":  capturedAt.Format(time.RFC3339),
+	}
+	if opts.Tenant != "" {
+		metadata["tenant"] = opts.Tenant
+	}
+
+	// Artefacts are listed in the order they were collected, whichever
+	// finishes uploading first.
+	artefacts := make([]Artefact, len(pending))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(opts.UploadParallelism, 1))
+	for i, p := range pending {
+		g.Go(func() error {
+			if opts.Events != nil {
+				opts.Events.Publish(opts.OperationID, Event{Type: EventUploading, Artefact: p.name})
+			}
+			uploaded, err := uploadArtefact(gctx, opts, p, objectPath(opts.OperationID, capturedAt, p.filename), metadata)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p.name, err)
+			}
+			signedURL := uploaded.SignedURL
+			if signedURL == "" && opts.ArtefactURL != nil {
+				signedURL = opts.ArtefactURL(p.name)
+			}
+			artefacts[i] = Artefact{
+				Name:       p.name,
+				SignedURL:  signedURL,
+				ExpiresAt:  uploaded.ExpiresAt,
+				ObjectName: uploaded.ObjectName,
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
-		screenshotRequest := &storage.UploadRequest{
-			ObjectName:  objectPath(operationID, name),
-			Content:     bytes.NewReader(s.PNG),
-			ContentType: "image/png",
+	return artefacts, nil
+}
+
+// uploadArtefact uploads p to objectName, attempting it again as
+// opts.UploadRetry allows should it fail. Each attempt rewrites the same
+// object, so an attempt that failed part way through leaves nothing behind.
+func uploadArtefact(ctx context.Context, opts WorkerOptions, p pendingArtefact, objectName string, metadata map[string]string) (*storage.UploadResult, error) {
+	ctx, span := tracer.Start(ctx, "operation.upload", trace.WithAttributes(attribute.String("operation.artefact", p.name)))
+	defer span.End()
+
+	for attempt := 1; ; attempt++ {
+		content, done := p.reader()
+		uploaded, err := opts.Uploader.Upload(ctx, &storage.UploadRequest{
+			ObjectName:      objectName,
+			Content:         content,
+			ContentType:     p.contentType,
+			ContentEncoding: p.contentEncoding,
+			Metadata:        metadata,
+		})
+		done()
+		if err == nil || attempt >= opts.UploadRetry.MaxAttempts || ctx.Err() != nil {
+			span.SetAttributes(attribute.Int("operation.upload.attempts", attempt))
+			recordError(span, err)
+			return uploaded, err
 		}
 
-		uploaded, err := uploader.Upload(ctx, screenshotRequest)
+		backoff := opts.UploadRetry.backoff(attempt)
+		opts.logger().Warn("upload failed; retrying", "artefact", p.name, "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			recordError(span, ctx.Err())
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// collectArtefacts serialises every output of result that should be stored,
+// in the order they are to be listed on the operation. HARs are compressed in
+// compression.
+func collectArtefacts(result *capture.Result, compression compress.Format) ([]pendingArtefact, error) {
+	var pending []pendingArtefact
+
+	pending = append(pending, harArtefact("har", "capture.har", result.HAR, compression))
+	if result.Warm != nil {
+		pending = append(pending, harArtefact("har_warm", "capture_warm.har", result.Warm.HAR, compression))
+	}
+
+	consoleJSON, err := json.Marshal(result.ConsoleMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal console messages: %w", err)
+	}
+	pending = append(pending, pendingArtefact{name: "console", filename: "console.json", contentType: "application/json", content: consoleJSON})
+
+	if result.Trace != nil {
+		pending = append(pending, pendingArtefact{name: "trace", filename: "trace.json", contentType: "application/json", content: result.Trace})
+	}
+
+	if result.Coverage != nil {
+		coverageJSON, err := json.Marshal(result.Coverage)
 		if err != nil {
-			return nil, fmt.Errorf("screenshot %d: %w", i+1, err)
+			return nil, fmt.Errorf("failed to marshal coverage: %w", err)
 		}
-		artefacts = append(artefacts, Artefact{
-			Name:      fmt.Sprintf("screenshot_%s", s.Stage),
-			SignedURL: uploaded.SignedURL,
-			ExpiresAt: uploaded.ExpiresAt,
+		pending = append(pending, pendingArtefact{name: "coverage", filename: "coverage.json", contentType: "application/json", content: coverageJSON})
+	}
+
+	if result.PDF != nil {
+		pending = append(pending, pendingArtefact{name: "pdf", filename: "page.pdf", contentType: "application/pdf", content: result.PDF})
+	}
+
+	if result.Snapshot != nil {
+		pending = append(pending, pendingArtefact{name: "snapshot", filename: "page.mhtml", contentType: "multipart/related", content: result.Snapshot})
+	}
+
+	intervals := 0
+	for i, s := range result.Screenshots {
+		name := fmt.Sprintf("screenshot_%s", s.Stage)
+		if s.Stage == capture.StageInterval {
+			// Periodic screenshots share a stage, so are numbered apart.
+			intervals++
+			name = fmt.Sprintf("screenshot_interval_%03d", intervals)
+		}
+		pending = append(pending, pendingArtefact{
+			name:        name,
+			filename:    fmt.Sprintf("screenshot_%02d_%s.png", i+1, s.Stage),
+			contentType: "image/png",
+			content:     s.PNG,
 		})
 	}
 
-	return artefacts, nil
+	for i, f := range result.Filmstrip {
+		pending = append(pending, pendingArtefact{
+			name:        fmt.Sprintf("filmstrip_%03d", i+1),
+			filename:    filmstripFilename(i, f),
+			contentType: "image/jpeg",
+			content:     f.JPEG,
+		})
+	}
+
+	return pending, nil
+}
+
+// harArtefact returns h as the named artefact, compressed in compression. It
+// is written to storage one entry at a time.
+func harArtefact(name, filename string, h capture.HAR, compression compress.Format) pendingArtefact {
+	return pendingArtefact{
+		name:            name,
+		filename:        filename + compression.Extension(),
+		contentType:     "application/json",
+		contentEncoding: compression.ContentEncoding(),
+		write: func(w io.Writer) error {
+			cw, err := compression.NewWriter(w)
+			if err != nil {
+				return err
+			}
+			if err := hario.WriteHAR(cw, h); err != nil {
+				return err
+			}
+			return cw.Close()
+		},
+	}
+}
+
+// filmstripFilename names the i-th filmstrip frame so that frames sort in
+// rendering order and carry their capture time.
+func filmstripFilename(i int, f capture.Frame) string {
+	return fmt.Sprintf("filmstrip/frame_%03d_%s.jpg", i+1, f.CapturedAt.UTC().Format("150405.000"))
 }
 
-func objectPath(operationID, filename string) string {
-	date := time.Now().UTC().Format("2006/01/02")
+// objectPath names the object in which filename of operation operationID,
+// captured at capturedAt, is stored.
+func objectPath(operationID string, capturedAt time.Time, filename string) string {
+	date := capturedAt.UTC().Format("2006/01/02")
 	return fmt.Sprintf("operations/%s/%s/%s", date, operationID, filename)
 }
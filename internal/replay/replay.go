@@ -0,0 +1,245 @@
+// Package replay re-issues the requests recorded in a HAR file against
+// another server and compares the responses with those recorded.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Options configures a replay.
+type Options struct {
+	// BaseURL is the server requests are sent to in place of the one they
+	// were recorded against. Its path, if any, prefixes theirs.
+	BaseURL *url.URL
+
+	// Hosts are the recorded hosts whose requests are replayed. When empty,
+	// only requests to the host of the first entry are, as the rest are
+	// typically to third parties a staging server does not stand in for.
+	Hosts []string
+
+	// Concurrency is the number of requests in flight at once. Defaults to 1.
+	Concurrency int
+
+	// Rate limits requests per second. Zero means no limit.
+	Rate float64
+
+	// SendCredentials replays the recorded credentialHeaders, which are
+	// otherwise stripped so that credentials issued by the recorded server
+	// are not sent to BaseURL.
+	SendCredentials bool
+
+	// Client sends the requests. Defaults to a client that does not follow
+	// redirects, so that statuses compare with those recorded.
+	Client *http.Client
+}
+
+// credentialHeaders are the request headers that carry credentials, in
+// lower case.
+var credentialHeaders = map[string]bool{
+	"authorization":       true,
+	"cookie":              true,
+	"proxy-authorization": true,
+	"x-api-key":           true,
+}
+
+// Report is the outcome of a replay. Times are in milliseconds.
+type Report struct {
+	Results []Result `json:"results"`
+
+	Replayed       int     `json:"replayed"`
+	Skipped        int     `json:"skipped"`
+	StatusMismatch int     `json:"status_mismatch"`
+	Errors         int     `json:"errors"`
+	RecordedTime   float64 `json:"recorded_time"`
+	ReplayTime     float64 `json:"replay_time"`
+}
+
+// Result compares the replayed response to a request with the recorded one.
+type Result struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+
+	RecordedStatus int64   `json:"recorded_status"`
+	Status         int64   `json:"status"`
+	RecordedTime   float64 `json:"recorded_time"`
+	Time           float64 `json:"time"`
+
+	// Error is set when no response was received.
+	Error string `json:"error,omitempty"`
+}
+
+// StatusChanged reports whether the replayed status differs from the one
+// recorded.
+func (r Result) StatusChanged() bool {
+	return r.Error == "" && r.Status != r.RecordedStatus
+}
+
+// Run replays the requests of h as configured by opts. It returns early only
+// if ctx is done; failed requests are reported in their results.
+func Run(ctx context.Context, h capture.HAR, opts Options) (Report, error) {
+	if opts.BaseURL == nil {
+		return Report{}, fmt.Errorf("replay: base URL is required")
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if opts.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.Rate), 1)
+	}
+
+	var report Report
+	var es []*capture.Entry
+	hosts := make(map[string]bool)
+	for _, host := range opts.Hosts {
+		hosts[strings.ToLower(host)] = true
+	}
+	if h.Log != nil {
+		for _, e := range h.Log.Entries {
+			if e == nil || e.Request == nil {
+				continue
+			}
+			u, err := url.Parse(e.Request.URL)
+			if err != nil {
+				report.Skipped++
+				continue
+			}
+			if len(hosts) == 0 {
+				hosts[strings.ToLower(u.Hostname())] = true
+			}
+			if !hosts[strings.ToLower(u.Hostname())] {
+				report.Skipped++
+				continue
+			}
+			es = append(es, e)
+		}
+	}
+
+	results := make([]Result, len(es))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(opts.Concurrency, 1))
+	for i, e := range es {
+		if err := limiter.Wait(gctx); err != nil {
+			break
+		}
+		g.Go(func() error {
+			results[i] = replay(gctx, client, opts, e)
+			return nil
+		})
+	}
+	g.Wait()
+	if err := ctx.Err(); err != nil {
+		return Report{}, fmt.Errorf("replay: %w", err)
+	}
+
+	report.Results = results
+	for _, r := range results {
+		report.Replayed++
+		switch {
+		case r.Error != "":
+			report.Errors++
+		case r.StatusChanged():
+			report.StatusMismatch++
+		}
+		report.RecordedTime += r.RecordedTime
+		report.ReplayTime += r.Time
+	}
+	return report, nil
+}
+
+func replay(ctx context.Context, client *http.Client, opts Options, e *capture.Entry) Result {
+	r := Result{
+		Method:       e.Request.Method,
+		RecordedTime: e.Time,
+	}
+	if e.Response != nil {
+		r.RecordedStatus = e.Response.Status
+	}
+
+	// The encoded path is joined as well as the decoded one, so that escapes
+	// such as %2F are sent as recorded.
+	u, _ := url.Parse(e.Request.URL)
+	base := opts.BaseURL
+	target := *base
+	target.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+	target.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + u.EscapedPath()
+	target.RawQuery = u.RawQuery
+	r.URL = target.String()
+
+	var body io.Reader
+	if e.Request.PostData != nil && e.Request.PostData.Text != "" {
+		body = bytes.NewReader([]byte(e.Request.PostData.Text))
+	}
+	req, err := http.NewRequestWithContext(ctx, e.Request.Method, r.URL, body)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	for _, hdr := range e.Request.Headers {
+		if hdr == nil || strings.HasPrefix(hdr.Name, ":") {
+			continue
+		}
+		// The client sets these itself, and decompresses responses only when
+		// it asked for compression.
+		switch strings.ToLower(hdr.Name) {
+		case "host", "content-length", "connection", "accept-encoding":
+			continue
+		}
+		if credentialHeaders[strings.ToLower(hdr.Name)] && !opts.SendCredentials {
+			continue
+		}
+		req.Header.Add(hdr.Name, hdr.Value)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	r.Time = float64(time.Since(start)) / float64(time.Millisecond)
+	r.Status = int64(resp.StatusCode)
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// FormatText renders the report for humans.
+func (r Report) FormatText() ([]byte, error) {
+	var b bytes.Buffer
+	for _, res := range r.Results {
+		switch {
+		case res.Error != "":
+			fmt.Fprintf(&b, "! %s %s: %s\n", res.Method, res.URL, res.Error)
+		case res.StatusChanged():
+			fmt.Fprintf(&b, "~ %s %s status %d → %d, %.1f → %.1fms\n", res.Method, res.URL, res.RecordedStatus, res.Status, res.RecordedTime, res.Time)
+		default:
+			fmt.Fprintf(&b, "  %s %s %d, %.1f → %.1fms\n", res.Method, res.URL, res.Status, res.RecordedTime, res.Time)
+		}
+	}
+	fmt.Fprintf(&b, "%d replayed, %d skipped, %d status changes, %d errors; time %.1f → %.1fms (%+.1f)\n",
+		r.Replayed, r.Skipped, r.StatusMismatch, r.Errors,
+		r.RecordedTime, r.ReplayTime, r.ReplayTime-r.RecordedTime)
+	return b.Bytes(), nil
+}
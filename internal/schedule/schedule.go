@@ -0,0 +1,269 @@
+// Package schedule runs captures of a URL repeatedly on a cron schedule, so
+// that a page can be monitored over time. Each run creates an ordinary
+// capture operation; a Schedule records the operations it has created.
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+
+	"github.com/tomasbasham/har-capture/internal/operation"
+)
+
+// MinInterval is the shortest interval between the runs of a schedule.
+const MinInterval = time.Minute
+
+// maxRuns bounds the run history kept for each schedule. Older runs are
+// discarded.
+const maxRuns = 100
+
+// ErrNotFound is returned when a schedule does not exist.
+var ErrNotFound = errors.New("schedule not found")
+
+// Schedule captures a URL each time its cron expression fires.
+type Schedule struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Cron      string    `json:"cron"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Subject identifies who created the schedule, when requests are
+	// authenticated. Its captures are attributed to the same subject.
+	Subject string `json:"subject,omitempty"`
+
+	// Tenant names the tenant the schedule, and its captures, belong to.
+	Tenant string `json:"tenant,omitempty"`
+
+	// NextRunAt is when the schedule next fires.
+	NextRunAt time.Time `json:"next_run_at"`
+
+	// Runs records the most recent runs of the schedule, oldest first.
+	Runs []Run `json:"-"`
+
+	// Request is the body of the request that created the schedule, from
+	// which the options of every run are derived.
+	Request json.RawMessage `json:"-"`
+
+	schedule cron.Schedule
+}
+
+// Run is a single firing of a schedule.
+type Run struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+
+	// OperationID identifies the capture operation created by the run.
+	// Empty if it could not be created.
+	OperationID string `json:"operation_id,omitempty"`
+
+	// Error is non-empty if the capture could not be started.
+	Error string `json:"error,omitempty"`
+}
+
+// Trigger starts a capture for a run of s, returning the ID of the
+// operation it created.
+type Trigger func(s Schedule) (operationID string, err error)
+
+// Parse parses a standard five-field cron expression, or a descriptor such
+// as "@hourly", rejecting expressions that fire more often than MinInterval.
+func Parse(expr string) (cron.Schedule, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("schedule: invalid cron expression %q: %w", expr, err)
+	}
+
+	first := sched.Next(time.Now())
+	if sched.Next(first).Sub(first) < MinInterval {
+		return nil, fmt.Errorf("schedule: cron expression %q fires more often than every %s", expr, MinInterval)
+	}
+	return sched, nil
+}
+
+// Scheduler holds schedules in memory and fires them as they fall due.
+type Scheduler struct {
+	trigger Trigger
+
+	mu        sync.Mutex
+	schedules map[string]*Schedule
+
+	// wake interrupts the wait for the next schedule when one is added.
+	wake chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewScheduler returns a Scheduler that starts the captures of its
+// schedules with trigger. Close stops it.
+func NewScheduler(trigger Trigger) *Scheduler {
+	s := &Scheduler{
+		trigger:   trigger,
+		schedules: make(map[string]*Schedule),
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// Add creates a schedule for owner capturing url as request describes
+// whenever expr fires.
+func (s *Scheduler) Add(url, expr string, owner operation.Owner, request json.RawMessage) (*Schedule, error) {
+	sched, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sc := &Schedule{
+		ID:        uuid.New().String(),
+		URL:       url,
+		Cron:      expr,
+		CreatedAt: now,
+		Subject:   owner.Subject,
+		Tenant:    owner.Tenant,
+		NextRunAt: sched.Next(now),
+		Request:   request,
+		schedule:  sched,
+	}
+
+	s.mu.Lock()
+	s.schedules[sc.ID] = sc
+	copy := sc.clone()
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return copy, nil
+}
+
+// Get returns a copy of the schedule with the given ID.
+func (s *Scheduler) Get(id string) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return sc.clone(), nil
+}
+
+// List returns copies of every schedule, most recently created first.
+func (s *Scheduler) List() []*Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]*Schedule, 0, len(s.schedules))
+	for _, sc := range s.schedules {
+		list = append(list, sc.clone())
+	}
+	slices.SortFunc(list, func(a, b *Schedule) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return list
+}
+
+// Delete removes the schedule with the given ID. Captures it has already
+// started are unaffected.
+func (s *Scheduler) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.schedules, id)
+	return nil
+}
+
+// Close stops the scheduler. Schedules no longer fire once it returns.
+func (s *Scheduler) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// loop fires schedules as they fall due until the scheduler is closed.
+func (s *Scheduler) loop() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		next := s.fire(time.Now())
+		timer.Reset(time.Until(next))
+
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// fire triggers every schedule due at now and returns when the next one
+// falls due.
+func (s *Scheduler) fire(now time.Time) time.Time {
+	s.mu.Lock()
+	var due []*Schedule
+	for _, sc := range s.schedules {
+		if !sc.NextRunAt.After(now) {
+			due = append(due, sc.clone())
+			sc.NextRunAt = sc.schedule.Next(now)
+		}
+	}
+	s.mu.Unlock()
+
+	// The trigger is called without holding the lock, so that it may use the
+	// scheduler.
+	for _, sc := range due {
+		run := Run{ScheduledAt: sc.NextRunAt}
+		id, err := s.trigger(*sc)
+		run.OperationID = id
+		if err != nil {
+			run.Error = err.Error()
+		}
+		s.record(sc.ID, run)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := now.Add(time.Hour)
+	for _, sc := range s.schedules {
+		if sc.NextRunAt.Before(next) {
+			next = sc.NextRunAt
+		}
+	}
+	return next
+}
+
+// record appends run to the history of the schedule with the given ID, if
+// it still exists.
+func (s *Scheduler) record(id string, run Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return
+	}
+	sc.Runs = append(sc.Runs, run)
+	if len(sc.Runs) > maxRuns {
+		sc.Runs = slices.Delete(sc.Runs, 0, len(sc.Runs)-maxRuns)
+	}
+}
+
+func (sc *Schedule) clone() *Schedule {
+	copy := *sc
+	copy.Runs = slices.Clone(sc.Runs)
+	return &copy
+}
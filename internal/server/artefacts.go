@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tomasbasham/har-capture/internal/storage"
+)
+
+// artefactURL returns the func giving the path at which the server serves
+// each artefact of the operation id, relative to the root of the API.
+func artefactURL(id string) func(name string) string {
+	return func(name string) string {
+		return "/captures/" + url.PathEscape(id) + "/artefacts/" + url.PathEscape(name)
+	}
+}
+
+// handleGetArtefact handles GET /captures/{id}/artefacts/{name}, serving an
+// artefact from storage through the server, for storage without signed URLs
+// or clients that cannot reach it.
+func (s *Server) handleGetArtefact(w http.ResponseWriter, r *http.Request) {
+	id, name := r.PathValue("id"), r.PathValue("name")
+	logOperation(r.Context(), id)
+
+	op, err := s.getOperation(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("operation %q not found", id))
+		return
+	}
+	var objectName string
+	for _, a := range op.Artefacts {
+		if a.Name == name {
+			objectName = a.ObjectName
+			break
+		}
+	}
+	if objectName == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("artefact %q not found", name))
+		return
+	}
+
+	rc, err := storage.Open(r.Context(), s.storageFor(op.Tenant), objectName)
+	switch {
+	case errors.Is(err, storage.ErrOpenUnsupported):
+		writeError(w, http.StatusNotImplemented, "artefacts cannot be read back from this storage")
+		return
+	case errors.Is(err, storage.ErrObjectNotExist):
+		writeError(w, http.StatusNotFound, fmt.Sprintf("artefact %q no longer exists", name))
+		return
+	case err != nil:
+		s.logger.Error("failed to open artefact", "operation_id", id, "artefact", name, "error", err)
+		writeError(w, http.StatusBadGateway, "failed to read artefact from storage")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", artefactContentType(objectName))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": path.Base(objectName)}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// A large artefact may take longer to send than the server's write
+	// timeout allows.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if _, err := io.Copy(w, rc); err != nil {
+		s.logger.Warn("failed to serve artefact", "operation_id", id, "artefact", name, "error", err)
+	}
+}
+
+// withoutWriteDeadline lifts the server's write timeout for the responses of
+// h, which serve artefacts that may take longer to send than it allows.
+func withoutWriteDeadline(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		h.ServeHTTP(w, r)
+	})
+}
+
+// artefactContentType returns the MIME type of the artefact stored as
+// objectName, from its extension.
+func artefactContentType(objectName string) string {
+	ext := path.Ext(objectName)
+	switch strings.ToLower(ext) {
+	case ".har", ".json":
+		return "application/json"
+	case ".mhtml":
+		return "multipart/related"
+	case ".gz":
+		return "application/gzip"
+	case ".zst":
+		return "application/zstd"
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomasbasham/har-capture/internal/auth"
+)
+
+// CORSConfig allows browser-based clients, such as dashboards, served from
+// other origins to call the API.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to make requests, such as
+	// "https://dashboard.example.com". "*" allows any origin, and a leading
+	// wildcard in the host, as in "https://*.example.com", any subdomain.
+	AllowedOrigins []string
+
+	// AllowedMethods lists the methods allowed in requests. Defaults to
+	// GET, POST and DELETE.
+	AllowedMethods []string
+
+	// AllowedHeaders lists the request headers allowed beyond those that
+	// are always safe. Defaults to Authorization, Content-Type,
+	// Idempotency-Key and X-API-Key.
+	AllowedHeaders []string
+
+	// MaxAge is how long browsers may cache the response to a preflight
+	// request. Defaults to 10 minutes.
+	MaxAge time.Duration
+}
+
+// WithCORS answers cross-origin requests, including preflight requests, as
+// cfg allows. Without it, browsers refuse cross-origin calls to the API.
+func WithCORS(cfg CORSConfig) Option {
+	return func(s *Server) {
+		if len(cfg.AllowedMethods) == 0 {
+			cfg.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodDelete}
+		}
+		if len(cfg.AllowedHeaders) == 0 {
+			cfg.AllowedHeaders = []string{"Authorization", "Content-Type", idempotencyKeyHeader, auth.APIKeyHeader}
+		}
+		if cfg.MaxAge == 0 {
+			cfg.MaxAge = 10 * time.Minute
+		}
+		s.cors = &cfg
+	}
+}
+
+// allowsOrigin reports whether origin may make requests.
+func (cfg *CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsHandler wraps next to answer cross-origin requests as cfg allows.
+// Preflight requests are answered here, before they reach authentication,
+// since browsers send them without credentials.
+func corsHandler(cfg *CORSConfig, next http.Handler) http.Handler {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !cfg.allowsOrigin(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if slices.Contains(cfg.AllowedOrigins, "*") {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", methods)
+		h.Set("Access-Control-Allow-Headers", headers)
+		h.Set("Access-Control-Max-Age", maxAge)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,463 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tomasbasham/har-capture/internal/auth"
+	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/pkg/capturepb"
+)
+
+// ListenAndServeGRPC serves the gRPC API on the given address, alongside the
+// HTTP API and sharing its operations. It returns nil once Shutdown has
+// been called.
+func (s *Server) ListenAndServeGRPC(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(s.logRPC, s.authenticateRPC),
+		grpc.ChainStreamInterceptor(s.logStreamRPC, s.authenticateStreamRPC),
+	}
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	srv := grpc.NewServer(opts...)
+	capturepb.RegisterCaptureServiceServer(srv, &grpcService{s: s})
+
+	s.mu.Lock()
+	if s.shuttingDown.Load() {
+		s.mu.Unlock()
+		_ = lis.Close()
+		return nil
+	}
+	s.grpcServer = srv
+	s.mu.Unlock()
+
+	return srv.Serve(lis)
+}
+
+// stopGRPC stops srv once its streams have finished, or after a second.
+func stopGRPC(srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		srv.Stop()
+	}
+}
+
+// grpcService implements the gRPC API on the operations of a Server.
+type grpcService struct {
+	capturepb.UnimplementedCaptureServiceServer
+	s *Server
+}
+
+func (g *grpcService) CreateCapture(ctx context.Context, req *capturepb.CreateCaptureRequest) (*capturepb.Capture, error) {
+	op, _, err := g.s.createCapture(ctx, createCaptureRequestFromProto(req), req.GetIdempotencyKey())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return captureToProto(op), nil
+}
+
+func (g *grpcService) GetCapture(ctx context.Context, req *capturepb.GetCaptureRequest) (*capturepb.Capture, error) {
+	op, err := g.s.getOperation(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "operation %q not found", req.GetId())
+	}
+	return captureToProto(op), nil
+}
+
+func (g *grpcService) ListCaptures(ctx context.Context, req *capturepb.ListCapturesRequest) (*capturepb.ListCapturesResponse, error) {
+	opts := operation.ListOptions{
+		Tenant:    auth.Tenant(ctx),
+		Status:    statusFromProto[req.GetStatus()],
+		URL:       req.GetUrl(),
+		PageToken: req.GetPageToken(),
+		Limit:     defaultListLimit,
+	}
+	if t := req.GetCreatedAfter(); t != nil {
+		opts.CreatedAfter = t.AsTime()
+	}
+	if t := req.GetCreatedBefore(); t != nil {
+		opts.CreatedBefore = t.AsTime()
+	}
+	if n := req.GetPageSize(); n != 0 {
+		if n < 1 || n > maxListLimit {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page_size %d: must be between 1 and %d", n, maxListLimit)
+		}
+		opts.Limit = int(n)
+	}
+
+	ops, next, err := g.s.store.List(opts)
+	if errors.Is(err, operation.ErrInvalidPageToken) {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list operations: "+err.Error())
+	}
+
+	resp := &capturepb.ListCapturesResponse{NextPageToken: next}
+	for _, op := range ops {
+		resp.Captures = append(resp.Captures, captureToProto(op))
+	}
+	return resp, nil
+}
+
+func (g *grpcService) WatchCapture(req *capturepb.WatchCaptureRequest, stream grpc.ServerStreamingServer[capturepb.CaptureEvent]) error {
+	id := req.GetId()
+
+	// Subscribe before reading the operation so that no change is missed in
+	// between.
+	events, unsubscribe := g.s.events.Subscribe(id)
+	defer unsubscribe()
+
+	op, err := g.s.getOperation(stream.Context(), id)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "operation %q not found", id)
+	}
+
+	first := operation.Event{Type: operation.EventStatus, Time: op.UpdatedAt, Status: op.Status}
+	if err := stream.Send(eventToProto(first)); err != nil || op.Status.Terminal() {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case e := <-events:
+			// HAR entries are too large to send one by one; the HAR is
+			// fetched once the operation has finished. CaptureEvent has no
+			// type for dropped events, which mostly count entries.
+			if e.Type == operation.EventEntry || e.Type == operation.EventDropped {
+				continue
+			}
+			if err := stream.Send(eventToProto(e)); err != nil {
+				return err
+			}
+			// The stream ends with the operation.
+			if e.Type == operation.EventStatus && e.Status.Terminal() {
+				return nil
+			}
+		}
+	}
+}
+
+// grpcError converts err, returned from creating a capture, to a gRPC status
+// error.
+func grpcError(err error) error {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code := codes.Internal
+	switch apiErr.status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		code = codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusTooManyRequests, http.StatusForbidden:
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, apiErr.msg)
+}
+
+// authenticateRPC authenticates unary calls as auth.Middleware does HTTP
+// requests, from the bearer token in their "authorization" metadata or the
+// API key in their "x-api-key" metadata.
+func (s *Server) authenticateRPC(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticateContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticateStreamRPC authenticates streaming calls as authenticateRPC
+// does unary ones.
+func (s *Server) authenticateStreamRPC(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticateContext(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticateContext returns ctx carrying the identity of the call it
+// belongs to, if the server authenticates requests.
+func (s *Server) authenticateContext(ctx context.Context) (context.Context, error) {
+	if s.authenticator == nil {
+		return ctx, nil
+	}
+
+	// Authenticators read HTTP requests, so the credentials of the call are
+	// presented as one.
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header)
+	for _, key := range []string{"Authorization", auth.APIKeyHeader} {
+		for _, v := range md.Get(key) {
+			header.Add(key, v)
+		}
+	}
+	r := (&http.Request{Header: header}).WithContext(ctx)
+	id, err := s.authenticator.Authenticate(r)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return auth.WithIdentity(ctx, id), nil
+}
+
+// contextStream replaces the context of a ServerStream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// logRPC logs each unary call once it has been handled, as logRequests does
+// HTTP requests.
+func (s *Server) logRPC(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	var id string
+	if c, ok := resp.(*capturepb.Capture); ok {
+		id = c.GetId()
+	}
+	s.logCall(ctx, info.FullMethod, id, err, start)
+	return resp, err
+}
+
+// logStreamRPC logs each streaming call once it has ended.
+func (s *Server) logStreamRPC(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	s.logCall(ss.Context(), info.FullMethod, "", err, start)
+	return err
+}
+
+func (s *Server) logCall(ctx context.Context, method, operationID string, err error, start time.Time) {
+	code := status.Code(err)
+	attrs := []any{
+		"method", method,
+		"code", code.String(),
+		"latency", time.Since(start),
+	}
+	if operationID != "" {
+		attrs = append(attrs, "operation_id", operationID)
+	}
+
+	switch code {
+	case codes.Internal, codes.Unavailable, codes.Unknown, codes.DataLoss:
+		s.logger.ErrorContext(ctx, "rpc", attrs...)
+	default:
+		s.logger.InfoContext(ctx, "rpc", attrs...)
+	}
+}
+
+// createCaptureRequestFromProto converts req to the equivalent JSON request,
+// so that it is validated and run exactly as one.
+func createCaptureRequestFromProto(req *capturepb.CreateCaptureRequest) createCaptureRequest {
+	r := createCaptureRequest{
+		URL:               req.GetUrl(),
+		NavigationTimeout: durationString(req.GetNavigationTimeout()),
+		TotalTimeout:      durationString(req.GetTotalTimeout()),
+		Screenshots:       req.GetScreenshots(),
+		Device:            req.GetDevice(),
+		UserAgent:         req.GetUserAgent(),
+		AcceptLanguage:    req.GetAcceptLanguage(),
+		Headers:           req.GetHeaders(),
+		BlockURLs:         req.GetBlockUrls(),
+		CallbackURL:       req.GetCallbackUrl(),
+		Profile:           req.GetProfile(),
+	}
+
+	if v := req.GetViewport(); v != nil {
+		r.Viewport = &viewportRequest{
+			Width:             v.GetWidth(),
+			Height:            v.GetHeight(),
+			DeviceScaleFactor: v.GetDeviceScaleFactor(),
+			Mobile:            v.GetMobile(),
+		}
+	}
+	for _, c := range req.GetCookies() {
+		cookie := cookieRequest{
+			Name:     c.GetName(),
+			Value:    c.GetValue(),
+			Domain:   c.GetDomain(),
+			Path:     c.GetPath(),
+			URL:      c.GetUrl(),
+			Secure:   c.GetSecure(),
+			HTTPOnly: c.GetHttpOnly(),
+			SameSite: c.GetSameSite(),
+		}
+		if c.GetExpireTime() != nil {
+			cookie.Expires = c.GetExpireTime().AsTime()
+		}
+		r.Cookies = append(r.Cookies, cookie)
+	}
+	if t := req.GetThrottling(); t != nil {
+		r.Throttling = &throttlingRequest{
+			Preset:             t.GetPreset(),
+			Latency:            durationString(t.GetLatency()),
+			DownloadThroughput: t.GetDownloadThroughput(),
+			UploadThroughput:   t.GetUploadThroughput(),
+			Offline:            t.GetOffline(),
+		}
+	}
+	if b := req.GetBodies(); b != nil {
+		r.Bodies = &bodiesRequest{
+			MIMETypes: b.GetMimeTypes(),
+			MaxBytes:  b.GetMaxBytes(),
+		}
+	}
+	if w := req.GetWait(); w != nil {
+		r.Wait = &waitRequest{
+			Selector:       w.GetSelector(),
+			Expression:     w.GetExpression(),
+			ScrollToBottom: w.GetScrollToBottom(),
+			IdleDuration:   durationString(w.GetIdleDuration()),
+		}
+	}
+	return r
+}
+
+// durationString formats d as the JSON API expects, or returns "" if it is
+// unset.
+func durationString(d *durationpb.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.AsDuration().String()
+}
+
+var statusToProto = map[operation.Status]capturepb.Status{
+	operation.StatusPending:     capturepb.Status_STATUS_PENDING,
+	operation.StatusRunning:     capturepb.Status_STATUS_RUNNING,
+	operation.StatusComplete:    capturepb.Status_STATUS_COMPLETE,
+	operation.StatusFailed:      capturepb.Status_STATUS_FAILED,
+	operation.StatusCancelling:  capturepb.Status_STATUS_CANCELLING,
+	operation.StatusCancelled:   capturepb.Status_STATUS_CANCELLED,
+	operation.StatusInterrupted: capturepb.Status_STATUS_INTERRUPTED,
+}
+
+var statusFromProto = func() map[capturepb.Status]operation.Status {
+	m := make(map[capturepb.Status]operation.Status, len(statusToProto))
+	for s, p := range statusToProto {
+		m[p] = s
+	}
+	return m
+}()
+
+var eventTypeToProto = map[operation.EventType]capturepb.CaptureEvent_Type{
+	operation.EventStatus:     capturepb.CaptureEvent_TYPE_STATUS,
+	operation.EventNavigation: capturepb.CaptureEvent_TYPE_NAVIGATION,
+	operation.EventEntries:    capturepb.CaptureEvent_TYPE_ENTRIES,
+	operation.EventUploading:  capturepb.CaptureEvent_TYPE_UPLOADING,
+}
+
+// captureToProto converts op to its gRPC representation.
+func captureToProto(op *operation.Operation) *capturepb.Capture {
+	c := &capturepb.Capture{
+		Id:         op.ID,
+		Status:     statusToProto[op.Status],
+		Url:        op.URL,
+		CreateTime: timestamppb.New(op.CreatedAt),
+		UpdateTime: timestamppb.New(op.UpdatedAt),
+		Subject:    op.Subject,
+		Tenant:     op.Tenant,
+		Ttfb:       optionalDuration(op.TTFB),
+		TimedOut:   op.TimedOut,
+		Error:      op.Error,
+	}
+	if v := op.WebVitals; v != nil {
+		c.WebVitals = &capturepb.WebVitals{
+			Lcp: optionalMillis(v.LCP),
+			Cls: v.CLS,
+			Inp: optionalMillis(v.INP),
+			Fid: optionalMillis(v.FID),
+			Tbt: optionalMillis(v.TBT),
+		}
+	}
+	for _, a := range op.Attempts {
+		c.Attempts = append(c.Attempts, &capturepb.Attempt{
+			Number:     int32(a.Number),
+			StartTime:  timestamppb.New(a.StartedAt),
+			FinishTime: optionalTimestamp(a.FinishedAt),
+			TimedOut:   a.TimedOut,
+			Error:      a.Error,
+		})
+	}
+	for _, a := range op.Artefacts {
+		c.Artefacts = append(c.Artefacts, &capturepb.Artefact{
+			Name:       a.Name,
+			SignedUrl:  a.SignedURL,
+			ExpireTime: timestamppb.New(a.ExpiresAt),
+		})
+	}
+	return c
+}
+
+// eventToProto converts e to its gRPC representation.
+func eventToProto(e operation.Event) *capturepb.CaptureEvent {
+	return &capturepb.CaptureEvent{
+		Type:     eventTypeToProto[e.Type],
+		Time:     timestamppb.New(e.Time),
+		Status:   statusToProto[e.Status],
+		Url:      e.URL,
+		Entries:  int32(e.Entries),
+		Artefact: e.Artefact,
+	}
+}
+
+// optionalDuration returns d, or nil if it is zero.
+func optionalDuration(d time.Duration) *durationpb.Duration {
+	if d == 0 {
+		return nil
+	}
+	return durationpb.New(d)
+}
+
+// optionalMillis returns the duration of ms milliseconds, or nil if it is
+// zero.
+func optionalMillis(ms float64) *durationpb.Duration {
+	return optionalDuration(time.Duration(ms * float64(time.Millisecond)))
+}
+
+// optionalTimestamp returns t, or nil if it is zero.
+func optionalTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tomasbasham/har-capture/internal/jobs"
+	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// WithJobQueue sends captures through q to be run by workers in other
+// processes, started with Work, rather than running them itself. The
+// progress the workers report is applied to the server's store.
+//
+// Operations run by workers cannot be cancelled, and their HAR entries are
+// not streamed. Their updates are applied to the server's own store, so the
+// updates subscription must not be shared with other servers, and
+// operations still running are lost should the server restart.
+func WithJobQueue(q *jobs.Client) Option {
+	return func(s *Server) {
+		s.jobs = q
+	}
+}
+
+// errCancelUnsupported is returned when cancellation is requested of an
+// operation run by a worker in another process.
+var errCancelUnsupported = errors.New("operations run by workers cannot be cancelled")
+
+// dispatch runs the capture of op, as req describes, on a worker of the job
+// queue if the server has one, and on one of its own otherwise. The
+// operation is marked failed if it cannot be queued.
+func (s *Server) dispatch(ctx context.Context, op *operation.Operation, req createCaptureRequest) error {
+	if s.jobs == nil {
+		opts, err := s.captureOptions(req)
+		if err != nil {
+			_ = s.store.MarkFailed(op.ID, err)
+			return err
+		}
+		return s.startCapture(ctx, op, opts, req.CallbackURL)
+	}
+
+	// The callback is the server's to send once the operation has finished;
+	// the worker returns it with the operation's final update.
+	job := jobs.Job{OperationID: op.ID, Tenant: op.Tenant, CallbackURL: req.CallbackURL}
+	req.CallbackURL = ""
+	request, err := json.Marshal(req)
+	if err == nil {
+		job.Request = request
+		err = s.jobs.Enqueue(ctx, job)
+	}
+	if err != nil {
+		_ = s.store.MarkFailed(op.ID, err)
+	}
+	return err
+}
+
+// receiveUpdates applies the updates reported by workers until ctx is done.
+func (s *Server) receiveUpdates(ctx context.Context) {
+	for {
+		err := s.jobs.ReceiveUpdates(ctx, func(u jobs.Update) {
+			s.applyUpdate(ctx, u)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Error("failed to receive updates from workers", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// applyUpdate records u against its operation.
+func (s *Server) applyUpdate(ctx context.Context, u jobs.Update) {
+	id := u.OperationID
+	var err error
+	switch u.Type {
+	case jobs.UpdateRunning:
+		if err = s.store.MarkRunning(id); err == nil {
+			s.metrics.CaptureStarted()
+		}
+	case jobs.UpdateAttempt:
+		if u.Attempt != nil {
+			err = s.store.RecordAttempt(id, *u.Attempt)
+		}
+	case jobs.UpdateComplete:
+		err = s.store.MarkComplete(id, u.Outcome.Operation())
+	case jobs.UpdateFailed:
+		err = s.store.MarkFailed(id, errors.New(u.Error))
+	case jobs.UpdateCancelled:
+		err = s.store.MarkCancelled(id, u.Outcome.Operation())
+	default:
+		return
+	}
+	if err != nil {
+		s.logger.Warn("failed to apply update from worker", "operation_id", id, "update", u.Type, "error", err)
+		return
+	}
+
+	if u.Type == jobs.UpdateRunning || u.Type == jobs.UpdateAttempt {
+		return
+	}
+	if op, err := s.store.Get(id); err == nil {
+		s.metrics.CaptureFinished(string(op.Status), u.Duration, op.TTFB)
+	}
+	s.finished(ctx, id, u.CallbackURL)
+}
+
+// Work runs the captures sent through q by servers configured with
+// WithJobQueue, as many at once as the server's workers allow, reporting
+// their progress through q. It returns once ctx is done or Shutdown has been
+// called; Shutdown then waits for the captures still running to finish.
+func (s *Server) Work(ctx context.Context, q *jobs.Client) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.background, cancel)
+	defer stop()
+
+	err := q.ReceiveJobs(ctx, s.maxConcurrentCaptures, func(ctx context.Context, job jobs.Job) error {
+		return s.runJob(ctx, q, job)
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// runJob runs the capture of job and waits for it to finish. It fails if the
+// job should be run again, because the server shut down before it could.
+func (s *Server) runJob(ctx context.Context, q *jobs.Client, job jobs.Job) error {
+	// The capture outlives the receipt of further jobs, which stops at
+	// shutdown. Publications of its progress are likewise not cancelled.
+	ctx = context.WithoutCancel(ctx)
+	reporter := q.Reporter(ctx, job)
+	logger := s.logger.With("operation_id", job.OperationID)
+
+	opts, err := s.jobOptions(job)
+	if err != nil {
+		// The job will never succeed, so is not run again.
+		logger.Error("rejected job", "error", err)
+		_ = reporter.MarkFailed(job.OperationID, err)
+		return nil
+	}
+
+	ctx, release := s.cancels.Register(ctx, job.OperationID)
+	done := make(chan struct{})
+	err = s.queue.Enqueue(func() {
+		defer close(done)
+		defer release()
+		if ctx.Err() != nil {
+			// The server shut down while the capture was waiting for a
+			// worker.
+			_ = reporter.MarkInterrupted(job.OperationID)
+			return
+		}
+		operation.Run(ctx, operation.WorkerOptions{
+			OperationID:       job.OperationID,
+			Store:             reporter,
+			Tenant:            job.Tenant,
+			Uploader:          s.uploaderFor(job.Tenant),
+			Pool:              s.pool,
+			Compression:       s.compression,
+			Retry:             s.retry,
+			UploadRetry:       s.uploadRetry,
+			UploadParallelism: s.uploadParallelism,
+			ArtefactURL:       artefactURL(job.OperationID),
+			InlineHARLimit:    min(s.inlineHARLimit, jobs.MaxInlineHAR),
+			Logger:            s.logger,
+			CaptureOptions:    opts,
+		})
+	})
+	if err != nil {
+		release()
+		return err
+	}
+
+	<-done
+	if reporter.Interrupted() {
+		return operation.ErrInterrupted
+	}
+	if reporter.Unreported() {
+		logger.Error("failed to report the outcome of the capture; it will be run again")
+		return errors.New("outcome of the capture was not reported")
+	}
+	return nil
+}
+
+// jobOptions returns the options of the capture of job.
+func (s *Server) jobOptions(job jobs.Job) (capture.Options, error) {
+	var req createCaptureRequest
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		return capture.Options{}, fmt.Errorf("invalid job: %w", err)
+	}
+	return s.captureOptions(req)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// openAPIDocument describes the API. Request bodies are validated against it
+// before they are decoded, so that every malformed request is rejected in the
+// same way, and clients can be generated from it.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// openAPI is openAPIDocument, loaded once. The document is embedded, so an
+// invalid one is a programming error.
+var openAPI = func() *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPIDocument)
+	if err == nil {
+		err = doc.Validate(context.Background())
+	}
+	if err != nil {
+		panic("server: invalid OpenAPI document: " + err.Error())
+	}
+	return doc
+}()
+
+// maxRequestBodyBytes bounds the size of request bodies.
+const maxRequestBodyBytes = 1 << 20
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
+
+// decodeBody validates the JSON body of r against the schema the OpenAPI
+// document gives for the route r matched, then decodes it into v.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	var body any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if schema := requestSchema(r.Pattern); schema != nil {
+		if err := schema.VisitJSON(body); err != nil {
+			return fmt.Errorf("invalid request body: %s", schemaErrorMessage(err))
+		}
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// requestSchema returns the schema of the JSON request body of the route
+// with the given pattern, such as "POST /captures", or nil if it has none.
+func requestSchema(pattern string) *openapi3.Schema {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return nil
+	}
+	item := openAPI.Paths.Find(path)
+	if item == nil {
+		return nil
+	}
+	op := item.GetOperation(method)
+	if op == nil || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	media := op.RequestBody.Value.Content.Get("application/json")
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+	return media.Schema.Value
+}
+
+// schemaErrorMessage describes err, returned from validating a value against
+// a schema, by the field at fault rather than by the schema.
+func schemaErrorMessage(err error) string {
+	var schemaErr *openapi3.SchemaError
+	if !errors.As(err, &schemaErr) {
+		return err.Error()
+	}
+	field := strings.Join(schemaErr.JSONPointer(), ".")
+	if field == "" {
+		return schemaErr.Reason
+	}
+	return field + ": " + schemaErr.Reason
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Bounds on the capture options accepted by POST /captures, so that a single
+// request cannot tie up a browser indefinitely or exhaust its memory.
+const (
+	maxTimeout          = 10 * time.Minute
+	maxIdleDuration     = time.Minute
+	maxLatency          = 10 * time.Second
+	maxViewportSize     = 8192
+	maxScaleFactor      = 4
+	maxHeaders          = 50
+	maxCookies          = 50
+	maxBlockedURLs      = 100
+	maxStringLength     = 4096
+	maxBodyBytes        = 10 << 20
+	defaultMaxBodyBytes = 1 << 20
+)
+
+// viewportRequest sizes the browser viewport.
+type viewportRequest struct {
+	Width             int64   `json:"width"`
+	Height            int64   `json:"height"`
+	DeviceScaleFactor float64 `json:"device_scale_factor,omitempty"`
+	Mobile            bool    `json:"mobile,omitempty"`
+}
+
+// cookieRequest is a cookie to install before navigation.
+type cookieRequest struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HTTPOnly bool      `json:"http_only,omitempty"`
+	SameSite string    `json:"same_site,omitempty"`
+	Expires  time.Time `json:"expires"`
+}
+
+// throttlingRequest emulates a slower network, either from a named preset or
+// from explicit conditions.
+type throttlingRequest struct {
+	Preset             string `json:"preset,omitempty"`
+	Latency            string `json:"latency,omitempty"`
+	DownloadThroughput int64  `json:"download_throughput,omitempty"`
+	UploadThroughput   int64  `json:"upload_throughput,omitempty"`
+	Offline            bool   `json:"offline,omitempty"`
+}
+
+// bodiesRequest enables response body capture.
+type bodiesRequest struct {
+	MIMETypes []string `json:"mime_types,omitempty"`
+	MaxBytes  int64    `json:"max_bytes,omitempty"`
+}
+
+// waitRequest delays completion until the page reaches some state.
+type waitRequest struct {
+	Selector       string `json:"selector,omitempty"`
+	Expression     string `json:"expression,omitempty"`
+	ScrollToBottom bool   `json:"scroll_to_bottom,omitempty"`
+	IdleDuration   string `json:"idle_duration,omitempty"`
+}
+
+// captureOptions validates req and applies it to base, the server's default
+// capture options with those of any profile applied. The error describes the
+// first invalid field.
+func (req createCaptureRequest) captureOptions(base capture.Options) (capture.Options, error) {
+	opts := base
+	opts.URL = req.URL
+	if req.Screenshots {
+		opts.Screenshots = true
+	}
+
+	var err error
+	if opts.NavigationTimeout, err = parseBoundedDuration("navigation_timeout", req.NavigationTimeout, opts.NavigationTimeout, maxTimeout); err != nil {
+		return opts, err
+	}
+	if opts.TotalTimeout, err = parseBoundedDuration("total_timeout", req.TotalTimeout, opts.TotalTimeout, maxTimeout); err != nil {
+		return opts, err
+	}
+
+	// A device preset is applied first so that an explicit viewport or user
+	// agent in the same request overrides it.
+	if req.Device != "" {
+		d, ok := capture.Devices[req.Device]
+		if !ok {
+			return opts, fmt.Errorf("unknown device %q: must be one of %s", req.Device, presetNames(capture.Devices))
+		}
+		capture.WithDevice(d)(&opts)
+	}
+
+	if v := req.Viewport; v != nil {
+		if v.Width <= 0 || v.Height <= 0 || v.Width > maxViewportSize || v.Height > maxViewportSize {
+			return opts, fmt.Errorf("viewport must be between 1x1 and %dx%d, got %dx%d", maxViewportSize, maxViewportSize, v.Width, v.Height)
+		}
+		if v.DeviceScaleFactor < 0 || v.DeviceScaleFactor > maxScaleFactor {
+			return opts, fmt.Errorf("viewport.device_scale_factor must be between 0 and %d, got %g", maxScaleFactor, v.DeviceScaleFactor)
+		}
+		opts.ViewportWidth = v.Width
+		opts.ViewportHeight = v.Height
+		opts.DeviceScaleFactor = v.DeviceScaleFactor
+		opts.Mobile = v.Mobile
+	}
+
+	if req.UserAgent != "" {
+		if len(req.UserAgent) > maxStringLength {
+			return opts, fmt.Errorf("user_agent must not exceed %d bytes", maxStringLength)
+		}
+		opts.UserAgent = req.UserAgent
+	}
+	if req.AcceptLanguage != "" {
+		if len(req.AcceptLanguage) > maxStringLength {
+			return opts, fmt.Errorf("accept_language must not exceed %d bytes", maxStringLength)
+		}
+		opts.AcceptLanguage = req.AcceptLanguage
+	}
+
+	if len(req.Headers) > 0 {
+		if len(req.Headers) > maxHeaders {
+			return opts, fmt.Errorf("headers must not exceed %d entries", maxHeaders)
+		}
+		for name, value := range req.Headers {
+			if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+				return opts, fmt.Errorf("invalid header name %q", name)
+			}
+			if len(value) > maxStringLength || strings.ContainsAny(value, "\r\n") {
+				return opts, fmt.Errorf("invalid value for header %q", name)
+			}
+		}
+		// Copy so as not to modify the defaults' map.
+		opts.Headers = nil
+		capture.WithHeaders(base.Headers)(&opts)
+		capture.WithHeaders(req.Headers)(&opts)
+	}
+
+	if len(req.Cookies) > 0 {
+		if len(req.Cookies) > maxCookies {
+			return opts, fmt.Errorf("cookies must not exceed %d entries", maxCookies)
+		}
+		cookies := make([]capture.CookieSeed, 0, len(base.Cookies)+len(req.Cookies))
+		cookies = append(cookies, base.Cookies...)
+		for _, c := range req.Cookies {
+			if c.Name == "" {
+				return opts, fmt.Errorf("cookie name is required")
+			}
+			if len(c.Value) > maxStringLength {
+				return opts, fmt.Errorf("value of cookie %q must not exceed %d bytes", c.Name, maxStringLength)
+			}
+			switch c.SameSite {
+			case "", "Strict", "Lax", "None":
+			default:
+				return opts, fmt.Errorf("same_site of cookie %q must be one of Strict, Lax or None", c.Name)
+			}
+			cookies = append(cookies, capture.CookieSeed{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				URL:      c.URL,
+				Secure:   c.Secure,
+				HTTPOnly: c.HTTPOnly,
+				SameSite: c.SameSite,
+				Expires:  c.Expires,
+			})
+		}
+		opts.Cookies = cookies
+	}
+
+	if t := req.Throttling; t != nil {
+		nc, err := t.networkConditions()
+		if err != nil {
+			return opts, err
+		}
+		opts.Throttling = &nc
+	}
+
+	if len(req.BlockURLs) > 0 {
+		if len(req.BlockURLs) > maxBlockedURLs {
+			return opts, fmt.Errorf("block_urls must not exceed %d entries", maxBlockedURLs)
+		}
+		blocked := make([]string, 0, len(base.BlockURLs)+len(req.BlockURLs))
+		blocked = append(blocked, base.BlockURLs...)
+		for _, pattern := range req.BlockURLs {
+			if pattern == "" || len(pattern) > maxStringLength {
+				return opts, fmt.Errorf("block_urls patterns must be between 1 and %d bytes", maxStringLength)
+			}
+			blocked = append(blocked, pattern)
+		}
+		opts.BlockURLs = blocked
+	}
+
+	if b := req.Bodies; b != nil {
+		if b.MaxBytes < 0 || b.MaxBytes > maxBodyBytes {
+			return opts, fmt.Errorf("bodies.max_bytes must be between 0 and %d, got %d", maxBodyBytes, b.MaxBytes)
+		}
+		maxBytes := b.MaxBytes
+		if maxBytes == 0 {
+			maxBytes = defaultMaxBodyBytes
+		}
+		capture.WithBodies(b.MIMETypes, maxBytes)(&opts)
+	}
+
+	if w := req.Wait; w != nil {
+		if len(w.Selector) > maxStringLength || len(w.Expression) > maxStringLength {
+			return opts, fmt.Errorf("wait.selector and wait.expression must not exceed %d bytes", maxStringLength)
+		}
+		if w.Selector != "" {
+			opts.WaitForSelector = w.Selector
+		}
+		if w.Expression != "" {
+			opts.WaitForExpression = w.Expression
+		}
+		if w.ScrollToBottom {
+			opts.ScrollToBottom = true
+		}
+		if opts.IdleDuration, err = parseBoundedDuration("wait.idle_duration", w.IdleDuration, opts.IdleDuration, maxIdleDuration); err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+// networkConditions resolves t to the conditions to emulate.
+func (t throttlingRequest) networkConditions() (capture.NetworkConditions, error) {
+	if t.Preset != "" {
+		nc, ok := capture.NetworkPresets[t.Preset]
+		if !ok {
+			return nc, fmt.Errorf("unknown throttling.preset %q: must be one of %s", t.Preset, presetNames(capture.NetworkPresets))
+		}
+		return nc, nil
+	}
+
+	latency, err := parseBoundedDuration("throttling.latency", t.Latency, 0, maxLatency)
+	if err != nil {
+		return capture.NetworkConditions{}, err
+	}
+	if t.DownloadThroughput < 0 || t.UploadThroughput < 0 {
+		return capture.NetworkConditions{}, fmt.Errorf("throttling throughput must not be negative")
+	}
+	return capture.NetworkConditions{
+		Latency:            latency,
+		DownloadThroughput: t.DownloadThroughput,
+		UploadThroughput:   t.UploadThroughput,
+		Offline:            t.Offline,
+	}, nil
+}
+
+// parseBoundedDuration parses the duration s of the named field, returning
+// def when s is empty.
+func parseBoundedDuration(field, s string, def, max time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", field, s, err)
+	}
+	if d < 0 || d > max {
+		return 0, fmt.Errorf("%s must be between 0s and %s, got %s", field, max, d)
+	}
+	return d, nil
+}
+
+// presetNames lists the keys of presets in order, for error messages.
+func presetNames[T any](presets map[string]T) string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
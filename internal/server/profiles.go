@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tomasbasham/har-capture/pkg/capture"
+)
+
+// Profile is a named set of capture options, so that requests may refer to
+// it rather than repeat each option.
+type Profile struct {
+	request createCaptureRequest
+}
+
+// ReadProfiles reads profiles from r: a JSON object mapping the name of each
+// profile to its options, which take the fields of the body of
+// POST /captures other than url, callback_url and profile. For example:
+//
+//	{
+//	  "mobile-3g": {"device": "pixel-7", "throttling": {"preset": "slow-3g"}},
+//	  "desktop-cold-cache": {"device": "desktop", "headers": {"Cache-Control": "no-cache"}}
+//	}
+func ReadProfiles(r io.Reader) (map[string]Profile, error) {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("server: invalid profiles: %w", err)
+	}
+
+	profiles := make(map[string]Profile, len(raw))
+	for name, data := range raw {
+		p, err := parseProfile(data)
+		if err != nil {
+			return nil, fmt.Errorf("server: invalid profile %q: %w", name, err)
+		}
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// parseProfile parses and validates the options of a profile.
+func parseProfile(data []byte) (Profile, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var req createCaptureRequest
+	if err := dec.Decode(&req); err != nil {
+		return Profile{}, err
+	}
+	switch {
+	case req.URL != "":
+		return Profile{}, fmt.Errorf("url is given by each request")
+	case req.CallbackURL != "":
+		return Profile{}, fmt.Errorf("callback_url is given by each request")
+	case req.Profile != "":
+		return Profile{}, fmt.Errorf("profiles cannot refer to other profiles")
+	}
+	if _, err := req.captureOptions(capture.Options{}); err != nil {
+		return Profile{}, err
+	}
+	return Profile{request: req}, nil
+}
+
+// WithProfiles lets requests refer to profiles by name, instead of repeating
+// their options.
+func WithProfiles(profiles map[string]Profile) Option {
+	return func(s *Server) {
+		s.profiles = profiles
+	}
+}
+
+// captureOptions validates req and returns the options of its capture: the
+// server's defaults, with those of the profile req names, if any, and then
+// those of req applied.
+func (s *Server) captureOptions(req createCaptureRequest) (capture.Options, error) {
+	base := s.defaultCaptureOptions
+	if req.Profile != "" {
+		p, ok := s.profiles[req.Profile]
+		if !ok && len(s.profiles) == 0 {
+			return capture.Options{}, fmt.Errorf("unknown profile %q: the server defines no profiles", req.Profile)
+		}
+		if !ok {
+			return capture.Options{}, fmt.Errorf("unknown profile %q: must be one of %s", req.Profile, presetNames(s.profiles))
+		}
+		var err error
+		if base, err = p.request.captureOptions(base); err != nil {
+			return capture.Options{}, fmt.Errorf("profile %q: %w", req.Profile, err)
+		}
+	}
+	return req.captureOptions(base)
+}
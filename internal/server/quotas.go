@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tomasbasham/har-capture/internal/auth"
+)
+
+// WithQuota bounds the captures of each API key, or of each subject of
+// callers authenticated otherwise, by quota, unless its key has a quota of
+// its own. Requests that are not authenticated share a single quota. By
+// default captures are bounded only by the server's workers.
+//
+// Scheduled captures are not counted against quotas.
+func WithQuota(quota auth.Quota) Option {
+	return func(s *Server) {
+		s.quota = quota
+	}
+}
+
+// quotaStatus describes the quota a request exceeded.
+type quotaStatus struct {
+	Limit int `json:"limit"`
+	Used  int `json:"used"`
+
+	// ResetsAt is when the quota is next replenished, for daily quotas.
+	ResetsAt *time.Time `json:"resets_at,omitempty"`
+}
+
+// quotas counts the captures of each client against its quota.
+type quotas struct {
+	mu sync.Mutex
+
+	// unfinished counts the unfinished captures of each client, and owners
+	// records the client of each.
+	unfinished map[string]int
+	owners     map[string]string
+
+	// daily counts the captures of each client on day, a date in UTC.
+	daily map[string]int
+	day   time.Time
+}
+
+func newQuotas() *quotas {
+	return &quotas{
+		unfinished: make(map[string]int),
+		owners:     make(map[string]string),
+		daily:      make(map[string]int),
+	}
+}
+
+// quotaFor returns the client making the request of ctx and its quota, and
+// whether the quota limits anything.
+func (s *Server) quotaFor(ctx context.Context) (string, auth.Quota, bool) {
+	id := auth.FromContext(ctx)
+	quota := s.quota
+	if id.Quota != nil {
+		quota = *id.Quota
+	}
+
+	client := "subject:" + id.Subject
+	if id.Key != "" {
+		client = "key:" + id.Key
+	}
+	return client, quota, quota.MaxConcurrent > 0 || quota.Daily > 0
+}
+
+// reserve counts a capture by client against quota, failing with an
+// *apiError if quota does not allow it. The capture is counted until it is
+// assigned an operation and that finishes, or it is refunded.
+func (q *quotas) reserve(client string, quota auth.Quota, now time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	if !today.Equal(q.day) {
+		q.day = today
+		clear(q.daily)
+	}
+
+	if quota.MaxConcurrent > 0 && q.unfinished[client] >= quota.MaxConcurrent {
+		return &apiError{
+			status: http.StatusTooManyRequests,
+			msg:    fmt.Sprintf("too many captures: at most %d may be unfinished at once", quota.MaxConcurrent),
+			quota:  &quotaStatus{Limit: quota.MaxConcurrent, Used: q.unfinished[client]},
+		}
+	}
+	if quota.Daily > 0 && q.daily[client] >= quota.Daily {
+		resetsAt := today.Add(24 * time.Hour)
+		return &apiError{
+			status: http.StatusForbidden,
+			msg:    fmt.Sprintf("daily quota of %d captures exhausted", quota.Daily),
+			quota:  &quotaStatus{Limit: quota.Daily, Used: q.daily[client], ResetsAt: &resetsAt},
+		}
+	}
+
+	q.unfinished[client]++
+	q.daily[client]++
+	return nil
+}
+
+// refund uncounts a capture reserved by client that was not made.
+func (q *quotas) refund(client string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.decrement(client)
+	if q.daily[client] > 0 {
+		q.daily[client]--
+	}
+}
+
+// assign records that the capture reserved by client is operation id.
+func (q *quotas) assign(id, client string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.owners[id] = client
+}
+
+// release uncounts operation id, now finished, from the unfinished captures
+// of its client.
+func (q *quotas) release(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	client, ok := q.owners[id]
+	if !ok {
+		return
+	}
+	delete(q.owners, id)
+	q.decrement(client)
+}
+
+func (q *quotas) decrement(client string) {
+	if q.unfinished[client] <= 1 {
+		delete(q.unfinished, client)
+		return
+	}
+	q.unfinished[client]--
+}
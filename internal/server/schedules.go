@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/tomasbasham/har-capture/internal/auth"
+	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/internal/schedule"
+)
+
+// createScheduleRequest is the JSON body for POST /schedules: the options of
+// a capture, as for POST /captures, and when to run it.
+type createScheduleRequest struct {
+	createCaptureRequest
+
+	// Cron is a standard five-field cron expression, or a descriptor such as
+	// "@hourly", evaluated in the server's time zone.
+	Cron string `json:"cron"`
+}
+
+// listSchedulesResponse is returned from GET /schedules.
+type listSchedulesResponse struct {
+	Schedules []*schedule.Schedule `json:"schedules"`
+}
+
+// scheduleRun is a run of a schedule with the status of the operation it
+// created.
+type scheduleRun struct {
+	schedule.Run
+	Status operation.Status `json:"status,omitempty"`
+}
+
+// listScheduleRunsResponse is returned from GET /schedules/{id}/runs.
+type listScheduleRunsResponse struct {
+	Runs []scheduleRun `json:"runs"`
+}
+
+func (s *Server) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.shuttingDown.Load() {
+		writeError(w, http.StatusServiceUnavailable, errShuttingDown.Error())
+		return
+	}
+
+	// The schema rejects callback_url, which schedules do not support.
+	var req createScheduleRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := s.captureOptions(req.createCaptureRequest); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Each run derives its options from the request afresh, so that runs
+	// can be sent to workers as requests.
+	request, err := json.Marshal(req.createCaptureRequest)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode schedule: "+err.Error())
+		return
+	}
+
+	owner := operation.Owner{Tenant: auth.Tenant(r.Context()), Subject: auth.Subject(r.Context())}
+	sc, err := s.schedules.Add(req.URL, req.Cron, owner, request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sc)
+}
+
+func (s *Server) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	tenant := auth.Tenant(r.Context())
+	schedules := slices.DeleteFunc(s.schedules.List(), func(sc *schedule.Schedule) bool {
+		return sc.Tenant != tenant
+	})
+	writeJSON(w, http.StatusOK, listSchedulesResponse{
+		Schedules: schedules,
+	})
+}
+
+func (s *Server) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sc, err := s.getSchedule(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("schedule %q not found", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sc)
+}
+
+func (s *Server) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := s.getSchedule(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("schedule %q not found", id))
+		return
+	}
+	if err := s.schedules.Delete(id); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("schedule %q not found", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sc, err := s.getSchedule(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("schedule %q not found", id))
+		return
+	}
+
+	// Most recent first, as for GET /captures.
+	runs := make([]scheduleRun, 0, len(sc.Runs))
+	for _, run := range slices.Backward(sc.Runs) {
+		sr := scheduleRun{Run: run}
+		if run.OperationID != "" {
+			if op, err := s.store.Get(run.OperationID); err == nil {
+				sr.Status = op.Status
+			}
+		}
+		runs = append(runs, sr)
+	}
+
+	writeJSON(w, http.StatusOK, listScheduleRunsResponse{Runs: runs})
+}
+
+// runSchedule starts the capture for a run of sc.
+func (s *Server) runSchedule(sc schedule.Schedule) (string, error) {
+	logger := s.logger.With("schedule_id", sc.ID)
+	var req createCaptureRequest
+	if err := json.Unmarshal(sc.Request, &req); err != nil {
+		logger.Error("failed to decode scheduled capture", "error", err)
+		return "", err
+	}
+	op, err := s.store.Create(sc.URL, operation.Owner{Tenant: sc.Tenant, Subject: sc.Subject})
+	if err != nil {
+		logger.Error("failed to create scheduled capture", "error", err)
+		return "", err
+	}
+	if err := s.dispatch(context.Background(), op, req); err != nil {
+		logger.Error("failed to start scheduled capture", "operation_id", op.ID, "error", err)
+		return op.ID, err
+	}
+	logger.Info("scheduled capture started", "operation_id", op.ID)
+	return op.ID, nil
+}
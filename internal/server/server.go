@@ -3,66 +3,506 @@
 // Endpoints:
 //
 //	POST /captures        — enqueue a new capture; returns operation ID immediately
-//	GET  /captures/{id}   — poll operation status and retrieve artefact URLs
+//	GET  /captures        — list operations, filtered by status, URL and creation time, a page at a time
+//	GET  /captures/{id}   — poll operation status and retrieve artefact URLs, optionally waiting for it to finish
+//	POST /captures/{id}/cancel — cancel an operation, keeping what it captured
+//	GET  /captures/{id}/events — stream the progress of an operation as server-sent events
+//	GET  /captures/{id}/stream — stream HAR entries over a WebSocket as they are collected
+//	POST /schedules       — capture a URL repeatedly on a cron schedule
+//	GET  /schedules       — list schedules
+//	GET  /schedules/{id}  — retrieve a schedule
+//	DELETE /schedules/{id} — stop a schedule
+//	GET  /schedules/{id}/runs — list the recent runs of a schedule and their status
+//	GET  /metrics         — Prometheus metrics
+//	GET  /openapi.json    — the OpenAPI document describing this API
+//
+// Deployments serving several teams authenticate them by API key, or by JWT
+// with a tenant claim. Each tenant sees only its own operations and
+// schedules, and its artefacts are stored apart from those of others.
+//
+// The same operations are served over gRPC by ListenAndServeGRPC, as the
+// CaptureService of package capturepb.
+//
+// Captures run on the server's own workers, or, with WithJobQueue, are sent
+// through Cloud Pub/Sub to servers in other processes running Work, so that
+// capture capacity scales independently of the API.
+//
+// Requests continue any trace context they carry, and the handling of each
+// capture is recorded as OpenTelemetry spans.
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/tomasbasham/har-capture/internal/capture"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+
+	"github.com/tomasbasham/har-capture/internal/auth"
+	"github.com/tomasbasham/har-capture/internal/chat"
+	"github.com/tomasbasham/har-capture/internal/compress"
+	"github.com/tomasbasham/har-capture/internal/jobs"
+	"github.com/tomasbasham/har-capture/internal/metrics"
 	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/internal/schedule"
 	"github.com/tomasbasham/har-capture/internal/storage"
+	"github.com/tomasbasham/har-capture/internal/webhook"
+	"github.com/tomasbasham/har-capture/pkg/capture"
 )
 
 // Server holds the dependencies shared across HTTP handlers.
 type Server struct {
 	store    operation.Store
 	uploader storage.Uploader
+	pool     *capture.Pool
 	mux      *http.ServeMux
 
+	// authenticator, when set, authenticates every request.
+	authenticator auth.Authenticator
+
+	// queue runs captures on a bounded number of workers.
+	queue *operation.Queue
+
+	// maxConcurrentCaptures is the number of workers of the queue.
+	maxConcurrentCaptures int
+
+	// retry is applied to every capture.
+	retry operation.RetryPolicy
+
+	// uploadRetry is applied to the upload of every artefact.
+	uploadRetry operation.RetryPolicy
+
+	// uploadParallelism is the most artefacts of a capture uploaded at once.
+	uploadParallelism int
+
+	// artefactFiles, when set, serves the files of local storage at the
+	// signed URLs it hands out.
+	artefactFiles http.Handler
+
+	// inlineHARLimit is the size of the largest HAR returned inline by
+	// GET /captures/{id}?include=har.
+	inlineHARLimit int64
+
+	// cancels holds the cancel funcs of the workers of running operations.
+	cancels *operation.Canceller
+
+	// schedules fires the recurring captures created with POST /schedules.
+	schedules *schedule.Scheduler
+
+	// httpServer is the server started by ListenAndServe, to be stopped by
+	// Shutdown.
+	mu         sync.Mutex
+	httpServer *http.Server
+
+	// grpcServer is the server started by ListenAndServeGRPC, if any.
+	grpcServer *grpc.Server
+
+	// shuttingDown is set once Shutdown has been called, after which no
+	// further captures are accepted.
+	shuttingDown atomic.Bool
+
+	// tlsConfig, when set, secures connections to the server with TLS.
+	tlsConfig *tls.Config
+
+	// cors, when set, allows cross-origin requests from browsers.
+	cors *CORSConfig
+
+	// logger receives an access log of every request, and the lifecycle of
+	// every capture.
+	logger *slog.Logger
+
+	// metrics are served on GET /metrics.
+	metrics *metrics.Metrics
+
+	// events carries the status changes and progress of operations.
+	events *operation.Bus
+
 	// defaultCaptureOptions are used as a base for every capture; request
 	// fields may override individual values.
 	defaultCaptureOptions capture.Options
+
+	// quota bounds the captures of each client, unless its API key has a
+	// quota of its own. quotas counts their captures.
+	quota  auth.Quota
+	quotas *quotas
+
+	// profiles are the named sets of capture options requests may refer to.
+	profiles map[string]Profile
+
+	// compression is applied to the HAR artefacts of every capture.
+	compression compress.Format
+
+	// notifier delivers the callbacks requested with captures.
+	notifier *webhook.Notifier
+
+	// chat, when set, is posted a message about each capture that completes
+	// or fails.
+	chat *chat.Notifier
+
+	// notifications tracks the callbacks and chat messages being delivered,
+	// for Shutdown to wait for.
+	notifications sync.WaitGroup
+
+	// tenantUploaders store the artefacts of the tenants that have their own
+	// storage.
+	tenantUploaders map[string]storage.Uploader
+
+	// operationTTL, when positive, is how long finished operations are kept.
+	operationTTL time.Duration
+
+	// jobs, when set, carries captures to workers in other processes, and
+	// their progress back.
+	jobs *jobs.Client
+
+	// background is the context of the work the server does other than
+	// handling requests, such as expiring operations. stopBackground
+	// cancels it.
+	background     context.Context
+	stopBackground context.CancelFunc
 }
 
-// New creates a Server wired to the given store and uploader.
-func New(store operation.Store, uploader storage.Uploader, defaults capture.Options) *Server {
+// Option configures optional behaviour of a Server.
+type Option func(*Server)
+
+// WithCompression compresses the HAR artefacts of every capture in format.
+func WithCompression(format compress.Format) Option {
+	return func(s *Server) {
+		s.compression = format
+	}
+}
+
+// WithNotifier delivers the callbacks requested with captures through n, e.g.
+// to sign them. By default they are delivered unsigned.
+func WithNotifier(n *webhook.Notifier) Option {
+	return func(s *Server) {
+		s.notifier = n
+	}
+}
+
+// WithChatNotifier posts a message about each capture that completes or
+// fails through n, e.g. to a Slack channel.
+func WithChatNotifier(n *chat.Notifier) Option {
+	return func(s *Server) {
+		s.chat = n
+	}
+}
+
+// WithMaxConcurrentCaptures runs at most n captures at once. Further
+// captures remain pending until one finishes. Defaults to
+// DefaultMaxConcurrentCaptures.
+func WithMaxConcurrentCaptures(n int) Option {
+	return func(s *Server) {
+		s.maxConcurrentCaptures = n
+	}
+}
+
+// WithRetryPolicy attempts captures that fail for transient reasons again
+// as policy allows. By default they are not retried.
+func WithRetryPolicy(policy operation.RetryPolicy) Option {
+	return func(s *Server) {
+		s.retry = policy
+	}
+}
+
+// WithUploadRetryPolicy attempts the upload of an artefact again, as policy
+// allows, should it fail. Uploads are not retried by default.
+func WithUploadRetryPolicy(policy operation.RetryPolicy) Option {
+	return func(s *Server) {
+		s.uploadRetry = policy
+	}
+}
+
+// WithUploadParallelism uploads at most n artefacts of each capture at once.
+// Defaults to DefaultUploadParallelism.
+func WithUploadParallelism(n int) Option {
+	return func(s *Server) {
+		s.uploadParallelism = n
+	}
+}
+
+// WithArtefactFiles serves artefacts with h at GET /artefacts/{object...},
+// for local storage that hands out URLs of the server. Requests are not
+// authenticated, since the URLs are signed.
+func WithArtefactFiles(h http.Handler) Option {
+	return func(s *Server) {
+		s.artefactFiles = h
+	}
+}
+
+// WithInlineHARLimit keeps HARs of up to n bytes on their operations, to be
+// returned by GET /captures/{id}?include=har. Zero or less disables inline
+// HARs. Defaults to DefaultInlineHARLimit.
+func WithInlineHARLimit(n int64) Option {
+	return func(s *Server) {
+		s.inlineHARLimit = n
+	}
+}
+
+// WithLogger logs every request, and the progress of every capture, to
+// logger. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithAuthenticator requires every request to be authenticated by a, and
+// records the subject of each on the operations it creates.
+func WithAuthenticator(a auth.Authenticator) Option {
+	return func(s *Server) {
+		s.authenticator = a
+	}
+}
+
+// DefaultMaxConcurrentCaptures is the number of captures a Server runs at
+// once unless configured otherwise.
+const DefaultMaxConcurrentCaptures = 2
+
+// DefaultUploadParallelism is the number of artefacts of a capture a Server
+// uploads at once unless configured otherwise.
+const DefaultUploadParallelism = 4
+
+// DefaultInlineHARLimit is the size in bytes of the largest HAR a Server
+// returns inline unless configured otherwise.
+const DefaultInlineHARLimit = 1 << 20
+
+// New creates a Server wired to the given store and uploader. Captures run in
+// browsers from pool, which may be nil to launch a browser per capture.
+func New(store operation.Store, uploader storage.Uploader, pool *capture.Pool, defaults capture.Options, opts ...Option) *Server {
+	events := operation.NewBus()
+	m := metrics.New()
 	s := &Server{
-		store:                 store,
-		uploader:              uploader,
+		store:                 operation.WithEvents(store, events),
+		uploader:              m.InstrumentUploader(uploader),
+		pool:                  pool,
+		cancels:               operation.NewCanceller(),
+		quotas:                newQuotas(),
+		events:                events,
+		metrics:               m,
+		logger:                slog.New(slog.DiscardHandler),
+		notifier:              webhook.New(),
+		maxConcurrentCaptures: DefaultMaxConcurrentCaptures,
+		inlineHARLimit:        DefaultInlineHARLimit,
+		uploadParallelism:     DefaultUploadParallelism,
 		defaultCaptureOptions: defaults,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	tenantUploaders := make(map[string]storage.Uploader, len(s.tenantUploaders))
+	for tenant, u := range s.tenantUploaders {
+		tenantUploaders[tenant] = m.InstrumentUploader(u)
+	}
+	s.tenantUploaders = tenantUploaders
+	s.queue = operation.NewQueue(s.maxConcurrentCaptures)
+	s.schedules = schedule.NewScheduler(s.runSchedule)
+
+	s.background, s.stopBackground = context.WithCancel(context.Background())
+	if s.operationTTL > 0 {
+		go s.sweep(s.background)
+	}
+	if s.jobs != nil {
+		go s.receiveUpdates(s.background)
+	}
+
+	s.metrics.Gauge("queue_depth", "Captures waiting for a worker.", func() float64 {
+		return float64(s.queue.Len())
+	})
+	s.metrics.Gauge("chrome_processes", "Chrome processes launched by the server and still running.", func() float64 {
+		return float64(capture.RunningBrowsers())
+	})
 
 	s.mux = http.NewServeMux()
 	s.mux.HandleFunc("POST /captures", s.handleCreateCapture)
+	s.mux.HandleFunc("GET /captures", s.handleListCaptures)
 	s.mux.HandleFunc("GET /captures/{id}", s.handleGetCapture)
+	s.mux.HandleFunc("POST /captures/{id}/cancel", s.handleCancelCapture)
+	s.mux.HandleFunc("GET /captures/{id}/events", s.handleCaptureEvents)
+	s.mux.HandleFunc("GET /captures/{id}/stream", s.handleCaptureStream)
+	s.mux.HandleFunc("GET /captures/{id}/artefacts/{name}", s.handleGetArtefact)
+	s.mux.HandleFunc("POST /schedules", s.handleCreateSchedule)
+	s.mux.HandleFunc("GET /schedules", s.handleListSchedules)
+	s.mux.HandleFunc("GET /schedules/{id}", s.handleGetSchedule)
+	s.mux.HandleFunc("DELETE /schedules/{id}", s.handleDeleteSchedule)
+	s.mux.HandleFunc("GET /schedules/{id}/runs", s.handleListScheduleRuns)
+	s.mux.Handle("GET /metrics", s.metrics.Handler())
+	s.mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+	if s.artefactFiles != nil {
+		s.mux.Handle("GET /artefacts/{object...}", withoutWriteDeadline(s.artefactFiles))
+	}
 
 	return s
 }
 
-// ListenAndServe starts the HTTP server on the given address.
+// ListenAndServe starts the HTTP server on the given address, serving HTTPS
+// if the server was configured WithTLS. It returns http.ErrServerClosed once
+// Shutdown has been called.
 func (s *Server) ListenAndServe(addr string) error {
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      s.mux,
+		Handler:      s.handler(),
+		TLSConfig:    s.tlsConfig,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+
+	s.mu.Lock()
+	if s.shuttingDown.Load() {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.httpServer = srv
+	s.mu.Unlock()
+
+	if s.tlsConfig != nil {
+		// The certificate is taken from the TLS config.
+		return srv.ListenAndServeTLS("", "")
+	}
 	return srv.ListenAndServe()
 }
 
+// Shutdown stops the server gracefully. New captures and scheduled runs are
+// refused at once, but requests for existing operations continue to be
+// served while the captures already running or queued finish and upload
+// their artefacts, and their callbacks are delivered. Once ctx is done, any
+// captures still unfinished are cancelled and marked interrupted, and ctx's
+// error is returned. The HTTP server is then shut down.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shuttingDown.Store(true)
+	srv := s.httpServer
+	grpcSrv := s.grpcServer
+	s.mu.Unlock()
+
+	s.schedules.Close()
+	s.stopBackground()
+
+	drained := make(chan struct{})
+	go func() {
+		s.queue.Close()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		s.logger.Warn("interrupting captures that did not finish before shutdown")
+		s.cancels.CancelAll()
+		<-drained
+	}
+
+	// Callbacks still being delivered once ctx is done are abandoned.
+	delivered := make(chan struct{})
+	go func() {
+		s.notifications.Wait()
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if srv != nil {
+		// Streams of events end with their operations, so the only
+		// connections left open are idle ones.
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+			_ = srv.Close()
+		}
+	}
+	if grpcSrv != nil {
+		stopGRPC(grpcSrv)
+	}
+	return err
+}
+
+// handler returns the root handler of the server.
+func (s *Server) handler() http.Handler {
+	// Requests continue the traces of their callers. Spans are named after
+	// the route once the request has been routed.
+	traced := otelhttp.NewHandler(s.mux, "har-capture",
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			if r.Pattern != "" {
+				return r.Pattern
+			}
+			return r.Method
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/metrics"
+		}),
+	)
+
+	var h http.Handler = traced
+	if s.authenticator != nil {
+		// Metrics are scraped by monitoring infrastructure rather than by
+		// clients, and the OpenAPI document describes nothing secret, so both
+		// are served without authentication. Artefact URLs carry their own
+		// signatures.
+		root := http.NewServeMux()
+		root.Handle("GET /metrics", s.metrics.Handler())
+		root.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+		if s.artefactFiles != nil {
+			root.Handle("GET /artefacts/{object...}", traced)
+		}
+		root.Handle("/", auth.Middleware(s.authenticator, traced))
+		h = root
+	}
+	if s.cors != nil {
+		h = corsHandler(s.cors, h)
+	}
+	return logRequests(s.logger, h)
+}
+
 // createCaptureRequest is the JSON body for POST /captures.
 type createCaptureRequest struct {
 	URL               string `json:"url"`
 	NavigationTimeout string `json:"navigation_timeout,omitempty"`
 	TotalTimeout      string `json:"total_timeout,omitempty"`
 	Screenshots       bool   `json:"screenshots"`
+
+	// Device names a preset from capture.Devices. Viewport and UserAgent,
+	// when also given, take precedence over it.
+	Device         string            `json:"device,omitempty"`
+	Viewport       *viewportRequest  `json:"viewport,omitempty"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	AcceptLanguage string            `json:"accept_language,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Cookies        []cookieRequest   `json:"cookies,omitempty"`
+
+	Throttling *throttlingRequest `json:"throttling,omitempty"`
+	BlockURLs  []string           `json:"block_urls,omitempty"`
+	Bodies     *bodiesRequest     `json:"bodies,omitempty"`
+	Wait       *waitRequest       `json:"wait,omitempty"`
+
+	// Profile names a Profile of the server, applied before the other
+	// fields, which take precedence over it.
+	Profile string `json:"profile,omitempty"`
+
+	// CallbackURL, if set, is sent the operation once it has finished, so
+	// that the client need not poll for it.
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
+// idempotencyKeyHeader names a POST /captures request, so that repeating it
+// returns the operation it created rather than starting another.
+const idempotencyKeyHeader = "Idempotency-Key"
+
 // createCaptureResponse is returned immediately from POST /captures.
 type createCaptureResponse struct {
 	OperationID string `json:"operation_id"`
@@ -70,73 +510,527 @@ type createCaptureResponse struct {
 }
 
 func (s *Server) handleCreateCapture(w http.ResponseWriter, r *http.Request) {
+	if s.shuttingDown.Load() {
+		writeError(w, http.StatusServiceUnavailable, errShuttingDown.Error())
+		return
+	}
+
 	var req createCaptureRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	op, created, err := s.createCapture(r.Context(), req, r.Header.Get(idempotencyKeyHeader))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	logOperation(r.Context(), op.ID)
+
+	status := http.StatusAccepted
+	if !created {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, createCaptureResponse{
+		OperationID: op.ID,
+		Status:      string(op.Status),
+	})
+}
+
+// errShuttingDown is returned when a capture is requested once Shutdown has
+// been called.
+var errShuttingDown = &apiError{status: http.StatusServiceUnavailable, msg: "server is shutting down"}
+
+// apiError is an error in handling a request, with the HTTP status that
+// describes it.
+type apiError struct {
+	status int
+	msg    string
+
+	// quota, when set, describes the quota the request exceeded.
+	quota *quotaStatus
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+// writeAPIError writes err, reported with its status if it is an *apiError
+// and as an internal error otherwise.
+func writeAPIError(w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if apiErr.quota == nil {
+		writeError(w, apiErr.status, apiErr.msg)
 		return
 	}
+
+	if resetsAt := apiErr.quota.ResetsAt; resetsAt != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(*resetsAt).Seconds())+1))
+	}
+	writeJSON(w, apiErr.status, quotaErrorResponse{Error: apiErr.msg, Quota: apiErr.quota})
+}
+
+// quotaErrorResponse is returned when a request exceeds a quota.
+type quotaErrorResponse struct {
+	Error string       `json:"error"`
+	Quota *quotaStatus `json:"quota"`
+}
+
+// createCapture creates an operation for req and starts its capture,
+// returning the operation. If idempotencyKey names an earlier request, the
+// operation it created is returned instead, and created is false. Errors
+// are *apiErrors.
+func (s *Server) createCapture(ctx context.Context, req createCaptureRequest, idempotencyKey string) (op *operation.Operation, created bool, err error) {
+	if s.shuttingDown.Load() {
+		return nil, false, errShuttingDown
+	}
 	if req.URL == "" {
-		writeError(w, http.StatusBadRequest, "url is required")
+		return nil, false, &apiError{status: http.StatusBadRequest, msg: "url is required"}
+	}
+
+	if req.CallbackURL != "" {
+		u, err := url.Parse(req.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, false, &apiError{status: http.StatusBadRequest, msg: fmt.Sprintf("invalid callback_url %q: must be an absolute http or https URL", req.CallbackURL)}
+		}
+	}
+
+	if _, err := s.captureOptions(req); err != nil {
+		return nil, false, &apiError{status: http.StatusBadRequest, msg: err.Error()}
+	}
+
+	client, quota, limited := s.quotaFor(ctx)
+	if limited {
+		if err := s.quotas.reserve(client, quota, time.Now()); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Clients retrying a request, or driven by webhooks delivered more than
+	// once, name it with an idempotency key so as to start a single capture.
+	owner := operation.Owner{Tenant: auth.Tenant(ctx), Subject: auth.Subject(ctx)}
+	if idempotencyKey != "" {
+		op, created, err = s.store.CreateOnce(idempotencyKey, req.URL, owner)
+	} else {
+		op, err = s.store.Create(req.URL, owner)
+		created = err == nil
+	}
+	if limited {
+		if created {
+			s.quotas.assign(op.ID, client)
+		} else {
+			s.quotas.refund(client)
+		}
+	}
+	if errors.Is(err, operation.ErrIdempotencyKeyReused) {
+		return nil, false, &apiError{status: http.StatusUnprocessableEntity, msg: "idempotency key was already used for a different url"}
+	}
+	if err != nil {
+		return nil, false, &apiError{status: http.StatusInternalServerError, msg: "failed to create operation: " + err.Error()}
+	}
+	if !created {
+		return op, false, nil
+	}
+
+	// The request context is intentionally not used to run the capture — we
+	// do not want the capture to be cancelled when the connection closes. It
+	// is cancelled only by POST /captures/{id}/cancel.
+	if err := s.dispatch(context.WithoutCancel(ctx), op, req); err != nil {
+		s.quotas.release(op.ID)
+		return nil, false, &apiError{status: http.StatusServiceUnavailable, msg: "failed to enqueue capture: " + err.Error()}
+	}
+	return op, true, nil
+}
+
+// startCapture runs the capture of op in the background once a worker is
+// free, notifying callbackURL, if set, once it has finished. The operation is
+// marked failed if it cannot be queued.
+func (s *Server) startCapture(ctx context.Context, op *operation.Operation, opts capture.Options, callbackURL string) error {
+	id := op.ID
+	uploader := s.uploaderFor(op.Tenant)
+	ctx, release := s.cancels.Register(ctx, id)
+	err := s.queue.Enqueue(func() {
+		defer release()
+		if errors.Is(context.Cause(ctx), operation.ErrInterrupted) {
+			// The server shut down while the capture was waiting for a
+			// worker.
+			_ = s.store.MarkInterrupted(id)
+		} else if ctx.Err() != nil {
+			// Cancelled while waiting for a worker.
+			_ = s.store.MarkCancelled(id, operation.Outcome{})
+		} else {
+			s.metrics.CaptureStarted()
+			start := time.Now()
+			operation.Run(ctx, operation.WorkerOptions{
+				OperationID:       id,
+				Store:             s.store,
+				Tenant:            op.Tenant,
+				Uploader:          uploader,
+				Pool:              s.pool,
+				Compression:       s.compression,
+				Events:            s.events,
+				Retry:             s.retry,
+				UploadRetry:       s.uploadRetry,
+				UploadParallelism: s.uploadParallelism,
+				ArtefactURL:       artefactURL(id),
+				InlineHARLimit:    s.inlineHARLimit,
+				Logger:            s.logger,
+				CaptureOptions:    opts,
+			})
+			if op, err := s.store.Get(id); err == nil {
+				s.metrics.CaptureFinished(string(op.Status), time.Since(start), op.TTFB)
+			}
+		}
+		s.finished(ctx, id, callbackURL)
+	})
+	if err != nil {
+		release()
+		_ = s.store.MarkFailed(id, err)
+	}
+	return err
+}
+
+// finished announces that operation id has finished: it is sent to
+// callbackURL, if set, and a message about it posted to chat. Delivery is
+// best effort: a callback or message that cannot be delivered is dropped,
+// and the operation can still be polled. It happens in the background, so
+// that a slow receiver holds up neither a worker nor the updates from
+// workers.
+func (s *Server) finished(ctx context.Context, id, callbackURL string) {
+	s.quotas.release(id)
+	if callbackURL == "" && s.chat == nil {
 		return
 	}
+	op, err := s.store.Get(id)
+	if err != nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
 
-	opts := s.defaultCaptureOptions
-	opts.URL = req.URL
-	opts.Screenshots = req.Screenshots
+	s.notifications.Add(1)
+	go func() {
+		defer s.notifications.Done()
+		// The callback is sent even if the operation was cancelled.
+		if callbackURL != "" {
+			_ = s.notifier.Notify(ctx, callbackURL, op)
+		}
+		if s.chat != nil {
+			if err := s.chat.Notify(ctx, op); err != nil {
+				s.logger.Warn("failed to post to chat", "operation_id", id, "error", err)
+			}
+		}
+	}()
+}
 
-	if req.NavigationTimeout != "" {
-		d, err := time.ParseDuration(req.NavigationTimeout)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid navigation_timeout %q: %s", req.NavigationTimeout, err))
-			return
+func (s *Server) handleGetCapture(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "operation id is required")
+		return
+	}
+	logOperation(r.Context(), id)
+
+	includeHAR := false
+	if include := r.URL.Query().Get("include"); include != "" {
+		for _, field := range strings.Split(include, ",") {
+			if field != "har" {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid include %q: must be har", field))
+				return
+			}
+			includeHAR = true
 		}
-		opts.NavigationTimeout = d
 	}
-	if req.TotalTimeout != "" {
-		d, err := time.ParseDuration(req.TotalTimeout)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid total_timeout %q: %s", req.TotalTimeout, err))
+
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 || d > maxWait {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid wait %q: must be a duration of at most %s", v, maxWait))
 			return
 		}
-		opts.TotalTimeout = d
+		wait = d
 	}
 
-	op, err := s.store.Create(req.URL)
+	var events <-chan operation.Event
+	if wait > 0 {
+		// Subscribe before reading the operation so that no change is
+		// missed in between.
+		var unsubscribe func()
+		events, unsubscribe = s.events.Subscribe(id)
+		defer unsubscribe()
+	}
+
+	op, err := s.getOperation(r.Context(), id)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create operation: "+err.Error())
+		writeError(w, http.StatusNotFound, fmt.Sprintf("operation %q not found", id))
 		return
 	}
+	if wait > 0 && !op.Status.Terminal() {
+		// The wait may outlast the server's write timeout.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 10*time.Second))
+		if finished := s.waitFinished(r.Context(), id, events, wait); finished != nil {
+			op = finished
+		}
+	}
 
-	// Run the capture in the background. The request context is intentionally
-	// not used here — we do not want the capture to be cancelled when the HTTP
-	// connection closes.
-	go operation.Run(r.Context(), operation.WorkerOptions{
-		OperationID:    op.ID,
-		Store:          s.store,
-		Uploader:       s.uploader,
-		CaptureOptions: opts,
-	})
+	resp := getCaptureResponse{Operation: op}
+	if includeHAR {
+		resp.HAR = op.HAR
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
 
-	writeJSON(w, http.StatusAccepted, createCaptureResponse{
-		OperationID: op.ID,
-		Status:      string(operation.StatusPending),
-	})
+// maxWait bounds the wait requested with GET /captures/{id}?wait=, so that
+// requests are not held open indefinitely.
+const maxWait = time.Minute
+
+// waitRecheckInterval is how often an operation being waited for is read
+// again, in case the event reporting that it finished was dropped.
+const waitRecheckInterval = time.Second
+
+// waitFinished waits for at most wait for operation id to finish, learning
+// of its changes from events, and returns it once it has. It returns nil if
+// the wait elapses first.
+func (s *Server) waitFinished(ctx context.Context, id string, events <-chan operation.Event, wait time.Duration) *operation.Operation {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	recheck := time.NewTicker(waitRecheckInterval)
+	defer recheck.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			return nil
+		case <-recheck.C:
+		case e := <-events:
+			if e.Type != operation.EventStatus || !e.Status.Terminal() {
+				continue
+			}
+		}
+		if op, err := s.store.Get(id); err == nil && op.Status.Terminal() {
+			return op
+		}
+	}
 }
 
-func (s *Server) handleGetCapture(w http.ResponseWriter, r *http.Request) {
+// getCaptureResponse is returned from GET /captures/{id}. HAR is included
+// only with ?include=har, and only if the HAR was small enough to be kept;
+// otherwise it must be fetched from the "har" artefact.
+type getCaptureResponse struct {
+	*operation.Operation
+	HAR json.RawMessage `json:"har,omitempty"`
+}
+
+func (s *Server) handleCancelCapture(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
 		writeError(w, http.StatusBadRequest, "operation id is required")
 		return
 	}
+	logOperation(r.Context(), id)
+
+	if _, err := s.getOperation(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("operation %q not found", id))
+		return
+	}
+	if s.jobs != nil {
+		writeError(w, http.StatusNotImplemented, errCancelUnsupported.Error())
+		return
+	}
+	err := s.store.MarkCancelling(id)
+	if errors.Is(err, operation.ErrFinished) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("operation %q has already finished", id))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to cancel operation: "+err.Error())
+		return
+	}
+
+	// The worker records the cancelled state once it has uploaded whatever
+	// it captured; until then the operation reports cancelling.
+	s.cancels.Cancel(id)
 
 	op, err := s.store.Get(id)
 	if err != nil {
 		writeError(w, http.StatusNotFound, fmt.Sprintf("operation %q not found", id))
 		return
 	}
+	writeJSON(w, http.StatusAccepted, op)
+}
+
+// heartbeatInterval is how often a comment is sent on an otherwise idle event
+// stream, so that proxies do not close it.
+const heartbeatInterval = 15 * time.Second
+
+func (s *Server) handleCaptureEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "operation id is required")
+		return
+	}
+	logOperation(r.Context(), id)
+
+	// Subscribe before reading the operation so that no change is missed in
+	// between.
+	events, unsubscribe := s.events.Subscribe(id)
+	defer unsubscribe()
+
+	op, err := s.getOperation(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("operation %q not found", id))
+		return
+	}
+
+	// The stream lasts as long as the operation, well beyond the server's
+	// write timeout.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(e operation.Event) bool {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !send(operation.Event{Type: operation.EventStatus, Time: op.UpdatedAt, Status: op.Status}) || op.Status.Terminal() {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	recheck := time.NewTicker(waitRecheckInterval)
+	defer recheck.Stop()
+	for {
+		var e operation.Event
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+			continue
+		case <-recheck.C:
+			// The event reporting that the operation finished may have
+			// been dropped.
+			op, err := s.store.Get(id)
+			if err != nil || !op.Status.Terminal() {
+				continue
+			}
+			e = operation.Event{Type: operation.EventStatus, Time: op.UpdatedAt, Status: op.Status}
+		case e = <-events:
+			// Entries are streamed over GET /captures/{id}/stream instead.
+			if e.Type == operation.EventEntry {
+				continue
+			}
+		}
+		if !send(e) {
+			return
+		}
+		// The stream ends with the operation.
+		if e.Type == operation.EventStatus && e.Status.Terminal() {
+			return
+		}
+	}
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// listCapturesResponse is returned from GET /captures.
+type listCapturesResponse struct {
+	Captures      []*operation.Operation `json:"captures"`
+	NextPageToken string                 `json:"next_page_token,omitempty"`
+}
+
+func (s *Server) handleListCaptures(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := operation.ListOptions{
+		Tenant:    auth.Tenant(r.Context()),
+		Status:    operation.Status(q.Get("status")),
+		URL:       q.Get("url_contains"),
+		PageToken: q.Get("page_token"),
+		Limit:     defaultListLimit,
+	}
+
+	// url is the original name of url_contains.
+	if url := q.Get("url"); url != "" {
+		if opts.URL != "" {
+			writeError(w, http.StatusBadRequest, "url and url_contains cannot be used together")
+			return
+		}
+		opts.URL = url
+	}
+	for _, param := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"created_after", &opts.CreatedAfter},
+		{"created_before", &opts.CreatedBefore},
+	} {
+		v := q.Get(param.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s %q: must be an RFC 3339 time", param.name, v))
+			return
+		}
+		*param.t = t
+	}
+
+	switch opts.Status {
+	case "", operation.StatusPending, operation.StatusRunning, operation.StatusComplete, operation.StatusFailed, operation.StatusCancelling, operation.StatusCancelled, operation.StatusInterrupted:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid status %q", opts.Status))
+		return
+	}
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxListLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be between 1 and %d", l, maxListLimit))
+			return
+		}
+		opts.Limit = n
+	}
 
-	writeJSON(w, http.StatusOK, op)
+	ops, next, err := s.store.List(opts)
+	if errors.Is(err, operation.ErrInvalidPageToken) {
+		writeError(w, http.StatusBadRequest, "invalid page_token")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list operations: "+err.Error())
+		return
+	}
+	if ops == nil {
+		ops = []*operation.Operation{}
+	}
+
+	writeJSON(w, http.StatusOK, listCapturesResponse{
+		Captures:      ops,
+		NextPageToken: next,
+	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gobwas/ws"
+
+	"github.com/tomasbasham/har-capture/internal/operation"
+)
+
+// maxClientFrame bounds the payload of frames read from stream clients,
+// which have nothing to send beyond control frames.
+const maxClientFrame = 4096
+
+// streamWriteTimeout bounds the write of each frame to a stream client, so
+// that a client that stops reading is disconnected rather than holding the
+// stream open.
+const streamWriteTimeout = 10 * time.Second
+
+// handleCaptureStream upgrades to a WebSocket and sends a text message for
+// each HAR entry the capture collects from then on, and for each change of
+// status. A client too slow to keep up is sent a dropped event counting the
+// messages it missed, and one that stops reading is disconnected. The socket
+// is closed once the operation has finished.
+func (s *Server) handleCaptureStream(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "operation id is required")
+		return
+	}
+	logOperation(r.Context(), id)
+
+	// Subscribe before reading the operation so that no entry is missed in
+	// between.
+	events, unsubscribe := s.events.Subscribe(id)
+	defer unsubscribe()
+
+	op, err := s.getOperation(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("operation %q not found", id))
+		return
+	}
+
+	conn, _, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// The stream lasts as long as the operation, well beyond the server's
+	// timeouts. Each write is given a deadline of its own instead.
+	_ = conn.SetDeadline(time.Time{})
+
+	pings, closed := readControlFrames(conn)
+
+	send := func(e operation.Event) bool {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return false
+		}
+		return writeFrame(conn, ws.NewTextFrame(data)) == nil
+	}
+	finish := func() {
+		body := ws.NewCloseFrameBody(ws.StatusNormalClosure, "operation finished")
+		_ = writeFrame(conn, ws.NewCloseFrame(body))
+	}
+
+	if !send(operation.Event{Type: operation.EventStatus, Time: op.UpdatedAt, Status: op.Status}) {
+		return
+	}
+	if op.Status.Terminal() {
+		finish()
+		return
+	}
+
+	recheck := time.NewTicker(waitRecheckInterval)
+	defer recheck.Stop()
+	for {
+		var e operation.Event
+		select {
+		case <-closed:
+			return
+		case p := <-pings:
+			if writeFrame(conn, ws.NewPongFrame(p)) != nil {
+				return
+			}
+			continue
+		case <-recheck.C:
+			// The event reporting that the operation finished may have
+			// been dropped.
+			op, err := s.store.Get(id)
+			if err != nil || !op.Status.Terminal() {
+				continue
+			}
+			e = operation.Event{Type: operation.EventStatus, Time: op.UpdatedAt, Status: op.Status}
+		case e = <-events:
+			switch e.Type {
+			case operation.EventEntry, operation.EventStatus, operation.EventDropped:
+			default:
+				continue
+			}
+		}
+		if !send(e) {
+			return
+		}
+		if e.Type == operation.EventStatus && e.Status.Terminal() {
+			finish()
+			return
+		}
+	}
+}
+
+// writeFrame writes f to conn, failing should the client not accept it
+// within streamWriteTimeout.
+func writeFrame(conn net.Conn, f ws.Frame) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		return err
+	}
+	return ws.WriteFrame(conn, f)
+}
+
+// readControlFrames reads frames from a client until it closes the
+// connection or sends something unexpected, which closes the returned
+// channel. The payloads of pings are sent on the other channel so that only
+// the handler writes to the connection.
+func readControlFrames(conn net.Conn) (<-chan []byte, <-chan struct{}) {
+	pings := make(chan []byte, 1)
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+		for {
+			h, err := ws.ReadHeader(conn)
+			if err != nil || h.Length > maxClientFrame {
+				return
+			}
+			payload := make([]byte, h.Length)
+			if _, err := io.ReadFull(conn, payload); err != nil {
+				return
+			}
+			if h.Masked {
+				ws.Cipher(payload, h.Mask, 0)
+			}
+
+			switch h.OpCode {
+			case ws.OpClose:
+				return
+			case ws.OpPing:
+				select {
+				case pings <- payload:
+				default:
+				}
+			}
+		}
+	}()
+
+	return pings, closed
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tomasbasham/har-capture/internal/storage"
+)
+
+// maxSweepInterval bounds how long an operation outlives its TTL.
+const maxSweepInterval = time.Minute
+
+// sweepTimeout bounds the deletion of the artefacts of expired operations
+// in each sweep.
+const sweepTimeout = 5 * time.Minute
+
+// WithOperationTTL deletes operations, and their artefacts, once ttl has
+// passed since they finished. Zero or less keeps them for as long as the
+// server runs, which is the default.
+func WithOperationTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.operationTTL = ttl
+	}
+}
+
+// sweep expires operations until ctx is done.
+func (s *Server) sweep(ctx context.Context) {
+	interval := min(s.operationTTL, maxSweepInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.expire(ctx, now)
+		}
+	}
+}
+
+// expire deletes the operations that finished more than the TTL before now,
+// and their artefacts.
+func (s *Server) expire(ctx context.Context, now time.Time) {
+	expired, err := s.store.Expire(now.Add(-s.operationTTL))
+	if err != nil {
+		s.logger.Error("failed to expire operations", "error", err)
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sweepTimeout)
+	defer cancel()
+
+	for _, op := range expired {
+		u := s.storageFor(op.Tenant)
+		for _, a := range op.Artefacts {
+			if a.ObjectName == "" {
+				continue
+			}
+			err := storage.Delete(ctx, u, a.ObjectName)
+			// Artefacts in storage that cannot delete them are left to its
+			// own retention policy.
+			if err != nil && !errors.Is(err, storage.ErrDeleteUnsupported) {
+				s.logger.Warn("failed to delete artefact of expired operation", "operation_id", op.ID, "artefact", a.Name, "error", err)
+			}
+		}
+	}
+	s.logger.Info("expired operations", "count", len(expired))
+}
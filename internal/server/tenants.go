@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tomasbasham/har-capture/internal/auth"
+	"github.com/tomasbasham/har-capture/internal/operation"
+	"github.com/tomasbasham/har-capture/internal/schedule"
+	"github.com/tomasbasham/har-capture/internal/storage"
+)
+
+// tenantPrefix is the prefix under which the artefacts of a tenant without
+// an uploader of its own are stored.
+const tenantPrefix = "tenants/%s/"
+
+// WithTenantUploaders stores the artefacts of each tenant of uploaders with
+// its own uploader, e.g. in a bucket of its own. The artefacts of other
+// tenants are stored by the server's uploader under the prefix
+// "tenants/{tenant}/".
+func WithTenantUploaders(uploaders map[string]storage.Uploader) Option {
+	return func(s *Server) {
+		s.tenantUploaders = uploaders
+	}
+}
+
+// uploaderFor returns the uploader of the artefacts of tenant.
+func (s *Server) uploaderFor(tenant string) storage.Uploader {
+	if _, ok := s.tenantUploaders[tenant]; ok || tenant == "" {
+		return s.storageFor(tenant)
+	}
+	return storage.WithPrefix(s.uploader, fmt.Sprintf(tenantPrefix, tenant))
+}
+
+// storageFor returns the uploader storing the artefacts of tenant, without
+// the prefix under which they are named, which is part of the names
+// recorded on their operations.
+func (s *Server) storageFor(tenant string) storage.Uploader {
+	if u, ok := s.tenantUploaders[tenant]; ok {
+		return u
+	}
+	return s.uploader
+}
+
+// getOperation returns the operation with the given ID if it belongs to the
+// tenant of ctx. The operations of other tenants are reported not to exist,
+// so that their IDs reveal nothing.
+func (s *Server) getOperation(ctx context.Context, id string) (*operation.Operation, error) {
+	op, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if op.Tenant != auth.Tenant(ctx) {
+		return nil, fmt.Errorf("operation %q not found", id)
+	}
+	return op, nil
+}
+
+// getSchedule returns the schedule with the given ID if it belongs to the
+// tenant of ctx.
+func (s *Server) getSchedule(ctx context.Context, id string) (*schedule.Schedule, error) {
+	sc, err := s.schedules.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Tenant != auth.Tenant(ctx) {
+		return nil, schedule.ErrNotFound
+	}
+	return sc, nil
+}
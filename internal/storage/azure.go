@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+// delegationKeyTTL is how long a user delegation key is requested for. The
+// key is reused to sign URLs until it would expire before they do.
+const delegationKeyTTL = 24 * time.Hour
+
+// retainUntilTag is the blob index tag recording UploadRequest.RetainUntil,
+// formatted as retainUntilFormat so that it sorts by time.
+const (
+	retainUntilTag    = "retain_until"
+	retainUntilFormat = "2006-01-02T15:04:05Z"
+)
+
+func init() {
+	Register("azblob", newAzureFromURL)
+}
+
+// newAzureFromURL creates the uploader of azblob://account/container/prefix,
+// authenticated by the shared key in AZURE_STORAGE_KEY, if set. The query
+// may set service_url to the blob endpoint of the account.
+func newAzureFromURL(ctx context.Context, dest *url.URL) (Uploader, error) {
+	container, prefix, _ := strings.Cut(strings.TrimPrefix(dest.Path, "/"), "/")
+	u, err := NewAzureUploader(ctx, AzureConfig{
+		Account:    dest.Host,
+		Container:  container,
+		Key:        os.Getenv("AZURE_STORAGE_KEY"),
+		ServiceURL: dest.Query().Get("service_url"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		return WithPrefix(u, prefix), nil
+	}
+	return u, nil
+}
+
+// AzureConfig configures an AzureUploader.
+type AzureConfig struct {
+	// Account is the name of the storage account.
+	Account string
+
+	// Container is the name of the blob container within Account.
+	Container string
+
+	// Key is the shared key of Account, with which URLs are signed. If
+	// empty, the default Azure credential is used instead and URLs are
+	// signed with a user delegation key, which requires a role granting
+	// generateUserDelegationKey, such as Storage Blob Delegator.
+	Key string
+
+	// ServiceURL is the blob endpoint of Account. Defaults to
+	// https://<account>.blob.core.windows.net/.
+	ServiceURL string
+}
+
+// AzureUploader uploads objects to an Azure Blob Storage container and
+// returns SAS URLs for them.
+type AzureUploader struct {
+	service   *service.Client
+	container *container.Client
+	name      string
+	sharedKey *service.SharedKeyCredential
+
+	mu            sync.Mutex
+	delegation    *service.UserDelegationCredential
+	delegationExp time.Time
+}
+
+// NewAzureUploader creates an AzureUploader for the container of config.
+func NewAzureUploader(_ context.Context, config AzureConfig) (*AzureUploader, error) {
+	if config.Account == "" || config.Container == "" {
+		return nil, fmt.Errorf("storage: Azure account and container are required")
+	}
+	serviceURL := config.ServiceURL
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", config.Account)
+	}
+
+	u := &AzureUploader{name: config.Container}
+	var err error
+	if config.Key != "" {
+		u.sharedKey, err = service.NewSharedKeyCredential(config.Account, config.Key)
+		if err != nil {
+			return nil, fmt.Errorf("storage: invalid Azure shared key: %w", err)
+		}
+		u.service, err = service.NewClientWithSharedKeyCredential(serviceURL, u.sharedKey, nil)
+	} else {
+		cred, credErr := azidentity.NewDefaultAzureCredential(nil)
+		if credErr != nil {
+			return nil, fmt.Errorf("storage: failed to obtain Azure credential: %w", credErr)
+		}
+		u.service, err = service.NewClient(serviceURL, cred, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to create Azure client: %w", err)
+	}
+	u.container = u.service.NewContainerClient(config.Container)
+	return u, nil
+}
+
+// Upload writes content to the container at objectName and returns a SAS URL
+// granting read access to it.
+func (u *AzureUploader) Upload(ctx context.Context, req *UploadRequest) (*UploadResult, error) {
+	b := u.container.NewBlockBlobClient(req.ObjectName)
+	headers := &blob.HTTPHeaders{}
+	if req.ContentType != "" {
+		headers.BlobContentType = to.Ptr(req.ContentType)
+	}
+	if req.ContentEncoding != "" {
+		headers.BlobContentEncoding = to.Ptr(req.ContentEncoding)
+	}
+	// Unlike metadata, blob index tags can be queried, so that the blobs no
+	// longer retained can be found with Find Blobs by Tags.
+	var tags map[string]string
+	if !req.RetainUntil.IsZero() {
+		tags = map[string]string{retainUntilTag: req.RetainUntil.UTC().Format(retainUntilFormat)}
+	}
+	var metadata map[string]*string
+	if len(req.Metadata) > 0 {
+		metadata = make(map[string]*string, len(req.Metadata))
+		for k, v := range req.Metadata {
+			metadata[k] = to.Ptr(v)
+		}
+	}
+	opts := &blockblob.UploadStreamOptions{HTTPHeaders: headers, Metadata: metadata, Tags: tags}
+	if _, err := b.UploadStream(ctx, req.Content, opts); err != nil {
+		return nil, fmt.Errorf("storage: upload failed for %q: %w", req.ObjectName, err)
+	}
+
+	expiresAt := time.Now().Add(signedURLTTL)
+	signedURL, err := u.signURL(ctx, b.URL(), req.ObjectName, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to sign URL for %q: %w", req.ObjectName, err)
+	}
+
+	return &UploadResult{
+		ObjectName: req.ObjectName,
+		SignedURL:  signedURL,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// signURL returns blobURL with a SAS granting read access to objectName
+// until expiresAt.
+func (u *AzureUploader) signURL(ctx context.Context, blobURL, objectName string, expiresAt time.Time) (string, error) {
+	values := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPS,
+		ExpiryTime:    expiresAt.UTC(),
+		Permissions:   to.Ptr(sas.BlobPermissions{Read: true}).String(),
+		ContainerName: u.name,
+		BlobName:      objectName,
+	}
+
+	var params sas.QueryParameters
+	var err error
+	if u.sharedKey != nil {
+		params, err = values.SignWithSharedKey(u.sharedKey)
+	} else {
+		var cred *service.UserDelegationCredential
+		if cred, err = u.delegationCredential(ctx, expiresAt); err != nil {
+			return "", err
+		}
+		params, err = values.SignWithUserDelegation(cred)
+	}
+	if err != nil {
+		return "", err
+	}
+	return blobURL + "?" + params.Encode(), nil
+}
+
+// delegationCredential returns a user delegation key valid until at least
+// expiresAt, requesting a new one if the last has expired.
+func (u *AzureUploader) delegationCredential(ctx context.Context, expiresAt time.Time) (*service.UserDelegationCredential, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.delegation != nil && u.delegationExp.After(expiresAt) {
+		return u.delegation, nil
+	}
+
+	now := time.Now().UTC()
+	exp := now.Add(delegationKeyTTL)
+	info := service.KeyInfo{
+		// Start slightly in the past to allow for clock skew.
+		Start:  to.Ptr(now.Add(-5 * time.Minute).Format(sas.TimeFormat)),
+		Expiry: to.Ptr(exp.Format(sas.TimeFormat)),
+	}
+	cred, err := u.service.GetUserDelegationCredential(ctx, info, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user delegation key: %w", err)
+	}
+	u.delegation, u.delegationExp = cred, exp
+	return cred, nil
+}
+
+// Open reads the blob objectName from the container.
+func (u *AzureUploader) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	resp, err := u.container.NewBlockBlobClient(objectName).DownloadStream(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, fmt.Errorf("%w: %q", ErrObjectNotExist, objectName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to open %q: %w", objectName, err)
+	}
+	return resp.Body, nil
+}
+
+// Delete deletes the blob objectName from the container.
+func (u *AzureUploader) Delete(ctx context.Context, objectName string) error {
+	_, err := u.container.NewBlockBlobClient(objectName).Delete(ctx, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("storage: failed to delete %q: %w", objectName, err)
+	}
+	return nil
+}
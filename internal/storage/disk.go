@@ -1,20 +1,64 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 )
 
+func init() {
+	Register("file", newLocalFromURL)
+}
+
+// newLocalFromURL creates the uploader of file:///path/to/directory, or of a
+// relative directory given as file:path. The query may set base_url to the
+// URL of the server that is to serve the files.
+func newLocalFromURL(_ context.Context, dest *url.URL) (Uploader, error) {
+	if dest.Host != "" && dest.Host != "localhost" {
+		return nil, fmt.Errorf("storage: %q names a remote host", dest.Redacted())
+	}
+	dir := dest.Path
+	if dir == "" {
+		dir = dest.Opaque
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("storage: %q does not name a directory", dest.Redacted())
+	}
+	u, err := NewLocalUploader(filepath.FromSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+	if base := dest.Query().Get("base_url"); base != "" {
+		if err := u.ServeFrom(base); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
 // LocalUploader writes artefacts to a directory on the local filesystem. The
 // signed URL returned is a file:// URL - there is no expiry concept for local
-// files, so ExpiresAt is set to the zero value.
+// files, so ExpiresAt is set to the zero value - unless the uploader serves
+// the files itself over HTTP; see ServeFrom.
 type LocalUploader struct {
 	baseDir string
+
+	// baseURL and key are set by ServeFrom.
+	baseURL *url.URL
+	key     []byte
 }
 
 // NewLocalUploader creates a LocalUploader that writes artefacts under
@@ -50,6 +94,15 @@ func (u *LocalUploader) Upload(_ context.Context, req *UploadRequest) (*UploadRe
 		return nil, fmt.Errorf("storage: failed to write file %q: %w", dest, err)
 	}
 
+	if u.baseURL != nil {
+		expiresAt := time.Now().Add(signedURLTTL)
+		return &UploadResult{
+			ObjectName: req.ObjectName,
+			SignedURL:  u.signURL(req.ObjectName, expiresAt),
+			ExpiresAt:  expiresAt,
+		}, nil
+	}
+
 	fileURL := &url.URL{Scheme: "file", Path: filepath.ToSlash(dest)}
 
 	return &UploadResult{
@@ -58,3 +111,108 @@ func (u *LocalUploader) Upload(_ context.Context, req *UploadRequest) (*UploadRe
 		ExpiresAt:  time.Time{},
 	}, nil
 }
+
+// ServeFrom makes Upload return signed HTTP URLs under baseURL, the URL of
+// the server, such as https://har.example.com, in place of file:// URLs, so
+// that remote clients can download artefacts. The server must mount the
+// uploader, as an http.Handler, on GET /artefacts/{object...}. URLs expire
+// as those of other backends do, and are signed with a key generated for
+// this uploader alone, so do not outlive it.
+func (u *LocalUploader) ServeFrom(baseURL string) error {
+	base, err := url.Parse(baseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return fmt.Errorf("storage: base URL %q must be an absolute http or https URL", baseURL)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("storage: failed to generate URL signing key: %w", err)
+	}
+	u.baseURL, u.key = base, key
+	return nil
+}
+
+// signURL returns the URL of objectName, valid until expiresAt.
+func (u *LocalUploader) signURL(objectName string, expiresAt time.Time) string {
+	ref := u.baseURL.JoinPath("artefacts", objectName)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	ref.RawQuery = url.Values{
+		"expires":   {expires},
+		"signature": {u.signature(objectName, expires)},
+	}.Encode()
+	return ref.String()
+}
+
+// signature returns the signature of objectName until expires, a Unix time.
+func (u *LocalUploader) signature(objectName, expires string) string {
+	mac := hmac.New(sha256.New, u.key)
+	mac.Write([]byte(objectName + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves the file named by the object path value of r, if the
+// query of r bears an unexpired signature for it from Upload.
+func (u *LocalUploader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if u.key == nil {
+		http.NotFound(w, r)
+		return
+	}
+	objectName := r.PathValue("object")
+	expires := r.URL.Query().Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(u.signature(objectName, expires))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > unix {
+		http.Error(w, "URL has expired", http.StatusForbidden)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(u.baseDir, filepath.FromSlash(objectName)))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	if filepath.Ext(objectName) == ".har" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	// Files compressed as they were uploaded, by WithGzip, are decoded by
+	// clients given their encoding.
+	magic := make([]byte, 2)
+	if _, err := f.ReadAt(magic, 0); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) && filepath.Ext(objectName) != ".gz" {
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// Delete removes the file baseDir/objectName, and its directory if that is
+// left empty.
+func (u *LocalUploader) Delete(_ context.Context, objectName string) error {
+	dest := filepath.Join(u.baseDir, filepath.FromSlash(objectName))
+	if err := os.Remove(dest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("storage: failed to delete file %q: %w", dest, err)
+	}
+	// Fails, harmlessly, unless the directory is empty.
+	_ = os.Remove(filepath.Dir(dest))
+	return nil
+}
+
+// Open opens the file baseDir/objectName.
+func (u *LocalUploader) Open(_ context.Context, objectName string) (io.ReadCloser, error) {
+	dest := filepath.Join(u.baseDir, filepath.FromSlash(objectName))
+	f, err := os.Open(dest)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrObjectNotExist, objectName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to open file %q: %w", dest, err)
+	}
+	return f, nil
+}
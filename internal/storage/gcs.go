@@ -1,13 +1,19 @@
 // Package storage provides an abstraction for uploading capture artefacts and
-// generating time-limited signed URLs for retrieval. The GCS implementation is
-// the production backend; the interface allows alternative implementations for
-// testing.
+// generating time-limited signed URLs for retrieval. The GCS and Azure Blob
+// Storage implementations are the production backends; the interface allows
+// alternative implementations for testing. New creates the Uploader of a
+// destination URL with the backend registered for its scheme.
 package storage
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -16,10 +22,68 @@ import (
 
 const signedURLTTL = 1 * time.Hour
 
+func init() {
+	Register("gs", newGCSFromURL)
+}
+
+// newGCSFromURL creates the uploader of gs://bucket/prefix. The query may set
+// kms_key to the Cloud KMS key with which to encrypt objects, or
+// encryption_key_file to a file of a base64 customer-supplied key.
+func newGCSFromURL(ctx context.Context, dest *url.URL) (Uploader, error) {
+	if dest.Host == "" {
+		return nil, fmt.Errorf("storage: %q does not name a bucket", dest.Redacted())
+	}
+	u, err := NewGCSUploader(ctx, dest.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	query := dest.Query()
+	u.KMSKeyName = query.Get("kms_key")
+	if path := query.Get("encryption_key_file"); path != "" {
+		if u.KMSKeyName != "" {
+			return nil, fmt.Errorf("storage: kms_key and encryption_key_file cannot be used together")
+		}
+		if u.EncryptionKey, err = readEncryptionKey(path); err != nil {
+			return nil, err
+		}
+	}
+	return withURLPrefix(u, dest), nil
+}
+
+// readEncryptionKey returns the AES-256 key encoded in base64 in the file at
+// path.
+func readEncryptionKey(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to read encryption key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("storage: encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
 // GCSUploader uploads objects to a Google Cloud Storage bucket.
 type GCSUploader struct {
 	client *storage.Client
 	bucket string
+
+	// KMSKeyName, if set, is the resource name of the Cloud KMS key with
+	// which objects are encrypted, instead of the default key of the bucket.
+	// The GCS service agent must be allowed to use it, and decrypts objects
+	// transparently when they are read.
+	KMSKeyName string
+
+	// EncryptionKey, if set, is the 32 byte AES-256 key with which objects
+	// are encrypted. GCS does not keep it, so whoever reads an object must
+	// present it in the x-goog-encryption-* headers, even with a signed URL.
+	// It cannot be used with KMSKeyName.
+	EncryptionKey []byte
 }
 
 // NewGCSUploader creates a GCSUploader for the given bucket. opts are passed
@@ -35,8 +99,17 @@ func NewGCSUploader(ctx context.Context, bucket string, opts ...option.ClientOpt
 // Upload writes content to GCS at objectName and returns a signed URL.
 func (u *GCSUploader) Upload(ctx context.Context, req *UploadRequest) (*UploadResult, error) {
 	obj := u.client.Bucket(u.bucket).Object(req.ObjectName)
+	if u.EncryptionKey != nil {
+		obj = obj.Key(u.EncryptionKey)
+	}
 	w := obj.NewWriter(ctx)
+	w.KMSKeyName = u.KMSKeyName
+	w.Metadata = req.Metadata
 	w.ContentType = req.ContentType
+	w.ContentEncoding = req.ContentEncoding
+	// A lifecycle rule with a daysSinceCustomTime condition deletes the
+	// object once it is no longer retained.
+	w.CustomTime = req.RetainUntil
 
 	if _, err := io.Copy(w, req.Content); err != nil {
 		_ = w.Close()
@@ -61,3 +134,29 @@ func (u *GCSUploader) Upload(ctx context.Context, req *UploadRequest) (*UploadRe
 		ExpiresAt:  expiresAt,
 	}, nil
 }
+
+// Open reads the object objectName from the bucket. Content stored with a
+// Content-Encoding is returned still encoded.
+func (u *GCSUploader) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	obj := u.client.Bucket(u.bucket).Object(objectName)
+	if u.EncryptionKey != nil {
+		obj = obj.Key(u.EncryptionKey)
+	}
+	r, err := obj.ReadCompressed(true).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrObjectNotExist, objectName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to open %q: %w", objectName, err)
+	}
+	return r, nil
+}
+
+// Delete deletes the object objectName from the bucket.
+func (u *GCSUploader) Delete(ctx context.Context, objectName string) error {
+	err := u.client.Bucket(u.bucket).Object(objectName).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("storage: failed to delete %q: %w", objectName, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// WithGzip returns an Uploader that compresses the content of compressible
+// types, such as JSON and text, with gzip as it is uploaded with u. The
+// objects are stored with a Content-Encoding of gzip, under the same name,
+// so that HTTP clients decode them transparently. Content that is already
+// encoded is uploaded as it is.
+func WithGzip(u Uploader) Uploader {
+	return &gzipUploader{Uploader: u}
+}
+
+type gzipUploader struct {
+	Uploader
+}
+
+func (u *gzipUploader) Upload(ctx context.Context, req *UploadRequest) (*UploadResult, error) {
+	if req.ContentEncoding != "" || !Compressible(req.ContentType) {
+		return u.Uploader.Upload(ctx, req)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, req.Content)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	// Stops the compression if the upload ends before all is read.
+	defer pr.Close()
+
+	compressed := *req
+	compressed.Content = pr
+	compressed.ContentEncoding = "gzip"
+	return u.Uploader.Upload(ctx, &compressed)
+}
+
+// Unwrap returns the underlying uploader.
+func (u *gzipUploader) Unwrap() Uploader {
+	return u.Uploader
+}
+
+// Delete deletes objectName with the underlying uploader, if it can.
+func (u *gzipUploader) Delete(ctx context.Context, objectName string) error {
+	return Delete(ctx, u.Uploader, objectName)
+}
+
+// Open opens objectName with the underlying uploader, if it can, decoding
+// the content compressed as it was uploaded. Objects named as compressed,
+// such as capture.har.gz, were compressed before upload, so are returned as
+// they are.
+func (u *gzipUploader) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	rc, err := Open(ctx, u.Uploader, objectName)
+	if err != nil || strings.HasSuffix(objectName, ".gz") || strings.HasSuffix(objectName, ".zst") {
+		return rc, err
+	}
+
+	// Nothing compressible, such as JSON or text, starts with the magic
+	// number of gzip.
+	br := bufio.NewReader(rc)
+	if magic, _ := br.Peek(2); !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return readCloser{br, rc}, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("storage: failed to decompress %q: %w", objectName, err)
+	}
+	return readCloser{zr, rc}, nil
+}
+
+// readCloser reads from a Reader wrapping that of a Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Compressible reports whether content of contentType, a MIME type, is
+// worth compressing: text, JSON, XML, JavaScript and MHTML snapshots.
+// Images, PDFs and the like are already compressed.
+func Compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "multipart/related":
+		return true
+	}
+	return false
+}
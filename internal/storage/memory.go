@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("mem", func(context.Context, *url.URL) (Uploader, error) {
+		return NewMemoryUploader(), nil
+	})
+}
+
+// MemoryUploader keeps artefacts in memory, for tests and for servers that
+// need no storage beyond their own lifetime. It has no URLs of its own, so
+// SignedURL is empty and the artefacts are read back with Open, as the
+// artefact endpoint of the server does.
+type MemoryUploader struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+// NewMemoryUploader creates an empty MemoryUploader.
+func NewMemoryUploader() *MemoryUploader {
+	return &MemoryUploader{objects: make(map[string][]byte)}
+}
+
+// Upload reads content into memory as objectName, replacing any object of
+// the same name.
+func (u *MemoryUploader) Upload(_ context.Context, req *UploadRequest) (*UploadResult, error) {
+	content, err := io.ReadAll(req.Content)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to read %q: %w", req.ObjectName, err)
+	}
+
+	u.mu.Lock()
+	u.objects[req.ObjectName] = content
+	u.mu.Unlock()
+
+	return &UploadResult{
+		ObjectName: req.ObjectName,
+		ExpiresAt:  time.Time{},
+	}, nil
+}
+
+// Open returns the content of objectName.
+func (u *MemoryUploader) Open(_ context.Context, objectName string) (io.ReadCloser, error) {
+	u.mu.RLock()
+	content, ok := u.objects[objectName]
+	u.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrObjectNotExist, objectName)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Delete removes objectName from memory.
+func (u *MemoryUploader) Delete(_ context.Context, objectName string) error {
+	u.mu.Lock()
+	delete(u.objects, objectName)
+	u.mu.Unlock()
+	return nil
+}
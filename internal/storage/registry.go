@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates the Uploader of the destination dest, a URL whose scheme
+// the factory was registered for.
+type Factory func(ctx context.Context, dest *url.URL) (Uploader, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a backend available to New for destinations with the given
+// URL scheme. It panics if the scheme is already registered.
+func Register(scheme string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, dup := factories[scheme]; dup {
+		panic("storage: Register called twice for scheme " + scheme)
+	}
+	factories[scheme] = factory
+}
+
+// Schemes returns the URL schemes of the registered backends, in order.
+func Schemes() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// New returns the Uploader of the destination URL dest, created by the
+// backend registered for its scheme:
+//
+//	gs://bucket/prefix?kms_key=...&encryption_key_file=...
+//	azblob://account/container/prefix?service_url=...
+//	file:///path/to/directory?base_url=...
+//
+// Objects are stored under the path of the URL, if any.
+func New(ctx context.Context, dest string) (Uploader, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid destination %q: %w", dest, err)
+	}
+
+	factoriesMu.RLock()
+	factory, ok := factories[u.Scheme]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("storage: unsupported destination %q: scheme must be one of %s", dest, strings.Join(Schemes(), ", "))
+	}
+	return factory(ctx, u)
+}
+
+// withURLPrefix returns u storing objects under the path of dest, if any.
+func withURLPrefix(u Uploader, dest *url.URL) Uploader {
+	prefix := strings.Trim(dest.Path, "/")
+	if prefix == "" {
+		return u
+	}
+	return WithPrefix(u, prefix)
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// WithRetention returns an Uploader that uploads objects with u to be
+// retained for retention, so that the lifecycle policies of the bucket can
+// delete them afterwards. A retention of zero or less returns u unchanged.
+func WithRetention(u Uploader, retention time.Duration) Uploader {
+	if retention <= 0 {
+		return u
+	}
+	return &retainedUploader{Uploader: u, retention: retention}
+}
+
+type retainedUploader struct {
+	Uploader
+	retention time.Duration
+}
+
+func (u *retainedUploader) Upload(ctx context.Context, req *UploadRequest) (*UploadResult, error) {
+	if !req.RetainUntil.IsZero() {
+		return u.Uploader.Upload(ctx, req)
+	}
+	retained := *req
+	retained.RetainUntil = time.Now().Add(u.retention)
+	return u.Uploader.Upload(ctx, &retained)
+}
+
+// Unwrap returns the underlying uploader.
+func (u *retainedUploader) Unwrap() Uploader {
+	return u.Uploader
+}
+
+// Delete deletes objectName with the underlying uploader, if it can.
+func (u *retainedUploader) Delete(ctx context.Context, objectName string) error {
+	return Delete(ctx, u.Uploader, objectName)
+}
+
+// Open opens objectName with the underlying uploader, if it can.
+func (u *retainedUploader) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	return Open(ctx, u.Uploader, objectName)
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
+	"path"
 	"time"
 )
 
@@ -20,6 +22,23 @@ type UploadRequest struct {
 
 	// ContentType is the MIME type of the content, e.g. "application/json".
 	ContentType string
+
+	// ContentEncoding is the compression applied to the content, e.g.
+	// "gzip", so that it can be decoded when served. Empty if uncompressed.
+	ContentEncoding string
+
+	// RetainUntil is when the object may be deleted, or zero to keep it
+	// indefinitely. Backends record it on the object so that the lifecycle
+	// policies of the bucket can delete it, even if the server that
+	// uploaded it never does.
+	RetainUntil time.Time
+
+	// Metadata is stored with the object as custom metadata, such as the
+	// ID of the operation that captured it, so that objects can be searched
+	// and matched by lifecycle rules. Keys should be lowercase letters,
+	// digits and underscores, which every backend accepts. Backends without
+	// object metadata, such as the local filesystem, ignore it.
+	Metadata map[string]string
 }
 
 // UploadResult is the outcome of a successful upload.
@@ -33,3 +52,99 @@ type UploadResult struct {
 	// ExpiresAt is when the signed URL becomes invalid.
 	ExpiresAt time.Time
 }
+
+// Deleter is implemented by Uploaders that can delete the objects they
+// have uploaded.
+type Deleter interface {
+	// Delete deletes the object objectName. Deleting an object that does
+	// not exist is not an error.
+	Delete(ctx context.Context, objectName string) error
+}
+
+// ErrDeleteUnsupported is returned by Delete when an Uploader cannot delete
+// objects.
+var ErrDeleteUnsupported = errors.New("storage: uploader does not support deletion")
+
+// Delete deletes objectName with u, if u is a Deleter.
+func Delete(ctx context.Context, u Uploader, objectName string) error {
+	d, ok := u.(Deleter)
+	if !ok {
+		return ErrDeleteUnsupported
+	}
+	return d.Delete(ctx, objectName)
+}
+
+// Downloader is implemented by Uploaders that can read back the objects
+// they have uploaded.
+type Downloader interface {
+	// Open returns the content of the object objectName, as it was
+	// uploaded. The caller must close it. Opening an object that does not
+	// exist returns an error wrapping ErrObjectNotExist.
+	Open(ctx context.Context, objectName string) (io.ReadCloser, error)
+}
+
+var (
+	// ErrOpenUnsupported is returned by Open when an Uploader cannot read
+	// objects back.
+	ErrOpenUnsupported = errors.New("storage: uploader does not support downloads")
+
+	// ErrObjectNotExist is wrapped by the error opening an object that does
+	// not exist.
+	ErrObjectNotExist = errors.New("storage: object does not exist")
+)
+
+// Open opens objectName with u, if u is a Downloader.
+func Open(ctx context.Context, u Uploader, objectName string) (io.ReadCloser, error) {
+	d, ok := u.(Downloader)
+	if !ok {
+		return nil, ErrOpenUnsupported
+	}
+	return d.Open(ctx, objectName)
+}
+
+// Unwrap returns the innermost Uploader wrapped by u, such as by WithPrefix,
+// or u itself if it wraps none.
+func Unwrap(u Uploader) Uploader {
+	for {
+		w, ok := u.(interface{ Unwrap() Uploader })
+		if !ok {
+			return u
+		}
+		u = w.Unwrap()
+	}
+}
+
+// WithPrefix returns an Uploader that stores objects with u under prefix,
+// such as "tenants/acme/", so that the objects of different owners are kept
+// apart in one bucket.
+func WithPrefix(u Uploader, prefix string) Uploader {
+	return &prefixedUploader{Uploader: u, prefix: prefix}
+}
+
+type prefixedUploader struct {
+	Uploader
+	prefix string
+}
+
+func (u *prefixedUploader) Upload(ctx context.Context, req *UploadRequest) (*UploadResult, error) {
+	prefixed := *req
+	prefixed.ObjectName = path.Join(u.prefix, req.ObjectName)
+	return u.Uploader.Upload(ctx, &prefixed)
+}
+
+// Unwrap returns the underlying uploader.
+func (u *prefixedUploader) Unwrap() Uploader {
+	return u.Uploader
+}
+
+// Delete deletes objectName with the underlying uploader, if it can.
+// objectName is that returned by Upload, so is already prefixed.
+func (u *prefixedUploader) Delete(ctx context.Context, objectName string) error {
+	return Delete(ctx, u.Uploader, objectName)
+}
+
+// Open opens objectName with the underlying uploader, if it can.
+// objectName is that returned by Upload, so is already prefixed.
+func (u *prefixedUploader) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	return Open(ctx, u.Uploader, objectName)
+}
@@ -0,0 +1,59 @@
+// Package tracing configures the export of OpenTelemetry traces, so that
+// captures can be followed from the request that started them through to
+// the upload of their artefacts.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// ServiceName identifies the server in the traces it exports.
+const ServiceName = "har-capture"
+
+// Setup installs a global tracer provider that exports spans over OTLP/HTTP,
+// and the W3C trace context propagator, so that incoming requests continue
+// the traces of their callers. The exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables, and the sampler by
+// OTEL_TRACES_SAMPLER.
+//
+// The returned func flushes any spans not yet exported and must be called
+// before the process exits.
+func Setup(ctx context.Context, version string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: failed to create exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(
+			semconv.ServiceName(ServiceName),
+			semconv.ServiceVersion(version),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: failed to describe resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,138 @@
+// Package webhook delivers notifications to URLs supplied by API clients.
+//
+// Each notification is POSTed as JSON. When a secret is configured, the body
+// is signed with HMAC-SHA256 and the signature sent in the SignatureHeader as
+// "sha256=" followed by its hex encoding, so that receivers can verify the
+// notification came from this server.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries the HMAC signature of a notification body.
+const SignatureHeader = "X-Har-Capture-Signature"
+
+// Notifier POSTs notifications, retrying failed deliveries with exponential
+// backoff.
+type Notifier struct {
+	client   *http.Client
+	secret   []byte
+	attempts int
+	backoff  time.Duration
+}
+
+// Option configures optional behaviour of a Notifier.
+type Option func(*Notifier)
+
+// WithSecret signs notifications with secret.
+func WithSecret(secret string) Option {
+	return func(n *Notifier) {
+		n.secret = []byte(secret)
+	}
+}
+
+// WithRetry makes up to attempts deliveries of each notification, waiting
+// backoff after the first failure and doubling the wait after each further
+// failure.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(n *Notifier) {
+		n.attempts = attempts
+		n.backoff = backoff
+	}
+}
+
+// WithClient sends notifications with client.
+func WithClient(client *http.Client) Option {
+	return func(n *Notifier) {
+		n.client = client
+	}
+}
+
+// New creates a Notifier. By default notifications are unsigned and each is
+// attempted up to five times, starting with a one second backoff.
+func New(opts ...Option) *Notifier {
+	n := &Notifier{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		attempts: 5,
+		backoff:  time.Second,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Notify POSTs v as JSON to url. A delivery fails on a network error or a
+// response status other than 2xx; those failing with a 4xx status, other
+// than 408 and 429, are not retried as they would fail again.
+func (n *Notifier) Notify(ctx context.Context, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.deliver(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.attempts {
+			return fmt.Errorf("webhook: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// deliver makes a single delivery, reporting whether a failure is worth
+// retrying.
+func (n *Notifier) deliver(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("%s responded %s", url, resp.Status)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return false, fmt.Errorf("%s responded %s", url, resp.Status)
+	default:
+		return true, fmt.Errorf("%s responded %s", url, resp.Status)
+	}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body under secret, as sent in
+// the SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
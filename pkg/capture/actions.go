@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ActionKind identifies the type of a scripted interaction.
+type ActionKind string
+
+const (
+	ActionClick    ActionKind = "click"
+	ActionType     ActionKind = "type"
+	ActionWait     ActionKind = "wait"
+	ActionEvaluate ActionKind = "evaluate"
+)
+
+// Action is a single step of a scripted user flow, executed after document
+// load so that the network traffic it causes is recorded in the HAR.
+type Action struct {
+	Kind ActionKind
+
+	// Selector is the CSS selector of the element to click or type into.
+	// Required for ActionClick and ActionType.
+	Selector string
+
+	// Text is typed into the element matched by Selector. Used by ActionType.
+	Text string
+
+	// Duration is how long to pause. Used by ActionWait, and typically
+	// appended after the final interaction to let its requests complete.
+	Duration time.Duration
+
+	// Expression is the JavaScript to evaluate. Used by ActionEvaluate.
+	Expression string
+}
+
+// validate reports whether the fields required by the action's kind are set.
+func (a Action) validate() error {
+	switch a.Kind {
+	case ActionClick, ActionType:
+		if a.Selector == "" {
+			return fmt.Errorf("capture: %s action requires a selector", a.Kind)
+		}
+	case ActionWait:
+		if a.Duration <= 0 {
+			return fmt.Errorf("capture: wait action requires a positive duration")
+		}
+	case ActionEvaluate:
+		if a.Expression == "" {
+			return fmt.Errorf("capture: evaluate action requires an expression")
+		}
+	default:
+		return fmt.Errorf("capture: unknown action kind %q", a.Kind)
+	}
+	return nil
+}
+
+// chromedpAction translates the action into its chromedp equivalent.
+func (a Action) chromedpAction() chromedp.Action {
+	switch a.Kind {
+	case ActionClick:
+		return chromedp.Click(a.Selector, chromedp.ByQuery)
+	case ActionType:
+		return chromedp.SendKeys(a.Selector, a.Text, chromedp.ByQuery)
+	case ActionWait:
+		return chromedp.Sleep(a.Duration)
+	default:
+		return chromedp.Evaluate(a.Expression, nil)
+	}
+}
+
+// runActions executes actions in order, stopping at the first failure.
+func runActions(ctx context.Context, actions []Action) error {
+	for i, a := range actions {
+		if err := chromedp.Run(ctx, a.chromedpAction()); err != nil {
+			return fmt.Errorf("action %d (%s): %w", i+1, a.Kind, err)
+		}
+	}
+	return nil
+}
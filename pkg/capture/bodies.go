@@ -0,0 +1,127 @@
+package capture
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"mime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// responseBody is a response body ready to be recorded as HAR content.
+type responseBody struct {
+	// text is the body itself, or its base64 encoding when the body is not
+	// valid UTF-8.
+	text     string
+	encoding string
+	size     int64
+}
+
+// bodyRecorder fetches response bodies as requests finish loading, keeping
+// only those that pass the MIME type and size filters of Options.
+type bodyRecorder struct {
+	allowlist []string
+	maxBytes  int64
+	logger    *slog.Logger
+
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	wanted map[network.RequestID]bool
+	bodies map[network.RequestID]*responseBody
+}
+
+func newBodyRecorder(allowlist []string, maxBytes int64, logger *slog.Logger) *bodyRecorder {
+	return &bodyRecorder{
+		allowlist: allowlist,
+		maxBytes:  maxBytes,
+		logger:    logger,
+		wanted:    make(map[network.RequestID]bool),
+		bodies:    make(map[network.RequestID]*responseBody),
+	}
+}
+
+// responseReceived notes whether the body of the response is to be recorded
+// once it has finished loading.
+func (r *bodyRecorder) responseReceived(ev *network.EventResponseReceived) {
+	if !r.allowed(ev.Response.MimeType) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wanted[ev.RequestID] = true
+}
+
+// loadingFinished fetches the body of a wanted response. The fetch happens
+// in its own goroutine, since it issues a CDP command and so must not block
+// the listener goroutine.
+func (r *bodyRecorder) loadingFinished(ctx context.Context, ev *network.EventLoadingFinished) {
+	r.mu.Lock()
+	wanted := r.wanted[ev.RequestID]
+	delete(r.wanted, ev.RequestID)
+	r.mu.Unlock()
+	if !wanted {
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		var body []byte
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			body, err = network.GetResponseBody(ev.RequestID).Do(ctx)
+			return err
+		}))
+		if err != nil {
+			r.logger.Debug("failed to fetch response body", "request_id", ev.RequestID, "error", err)
+			return
+		}
+		if r.maxBytes > 0 && int64(len(body)) > r.maxBytes {
+			return
+		}
+
+		rb := &responseBody{text: string(body), size: int64(len(body))}
+		if !utf8.Valid(body) {
+			rb.text = base64.StdEncoding.EncodeToString(body)
+			rb.encoding = "base64"
+		}
+
+		r.mu.Lock()
+		r.bodies[ev.RequestID] = rb
+		r.mu.Unlock()
+	}()
+}
+
+// wait blocks until in-flight fetches have completed and returns the bodies
+// recorded, keyed by request.
+func (r *bodyRecorder) wait() map[network.RequestID]*responseBody {
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bodies
+}
+
+// allowed reports whether bodies of the given MIME type are to be recorded.
+// Patterns may use wildcards, e.g. "text/*", and match case-insensitively
+// without regard to parameters such as charset.
+func (r *bodyRecorder) allowed(mimeType string) bool {
+	if len(r.allowlist) == 0 {
+		return true
+	}
+	if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mt
+	}
+	mimeType = strings.ToLower(mimeType)
+	for _, pattern := range r.allowlist {
+		if matchWildcard(strings.ToLower(pattern), mimeType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chromedp/chromedp"
+)
+
+// execAllocatorOptions returns the options with which to launch a local
+// browser for opts, loading the unpacked extensions in extensionDirs.
+func execAllocatorOptions(opts Options, extensionDirs []string) ([]chromedp.ExecAllocatorOption, error) {
+	allocOpts := append(
+		chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+	)
+	if opts.ChromePath != "" {
+		allocOpts = append(allocOpts, chromedp.ExecPath(opts.ChromePath))
+	}
+	if opts.UserDataDir != "" {
+		allocOpts = append(allocOpts, chromedp.UserDataDir(opts.UserDataDir))
+	}
+	if len(extensionDirs) > 0 {
+		// Extensions are only supported by the new headless mode, and are
+		// otherwise disabled by chromedp's defaults.
+		dirs := strings.Join(extensionDirs, ",")
+		allocOpts = append(allocOpts,
+			chromedp.Flag("headless", "new"),
+			chromedp.Flag("disable-extensions", false),
+			chromedp.Flag("disable-extensions-except", dirs),
+			chromedp.Flag("load-extension", dirs),
+		)
+	}
+	if len(opts.HostRules) > 0 {
+		allocOpts = append(allocOpts, chromedp.Flag("host-resolver-rules", hostResolverRules(opts.HostRules)))
+	}
+	for _, f := range opts.ChromeFlags {
+		name, value, err := parseChromeFlag(f)
+		if err != nil {
+			return nil, err
+		}
+		allocOpts = append(allocOpts, chromedp.Flag(name, value))
+	}
+	return allocOpts, nil
+}
+
+// hostResolverRules formats rules for Chrome's --host-resolver-rules flag,
+// e.g. "MAP example.com 127.0.0.1, MAP cdn.example.com 10.0.0.2". Hosts are
+// sorted so that the flag is stable.
+func hostResolverRules(rules map[string]string) string {
+	hosts := make([]string, 0, len(rules))
+	for host := range rules {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	maps := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		maps = append(maps, fmt.Sprintf("MAP %s %s", host, rules[host]))
+	}
+	return strings.Join(maps, ", ")
+}
+
+// parseChromeFlag splits a command-line flag such as "--window-size=800,600"
+// into its name and value. A flag without a value is a boolean switch.
+func parseChromeFlag(flag string) (string, any, error) {
+	name, value, hasValue := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+	if name == "" {
+		return "", nil, fmt.Errorf("capture: invalid Chrome flag %q", flag)
+	}
+	if !hasValue {
+		return name, true, nil
+	}
+	return name, value, nil
+}
+
+// browserInstance is a running browser, either launched locally or reached
+// over a remote debugging connection, in which tabs can be opened.
+type browserInstance struct {
+	// ctx is the context of the browser's initial tab. New tabs are derived
+	// from it so that they share the underlying connection.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// runningBrowsers counts the browsers launched by startBrowser that have not
+// yet been shut down.
+var runningBrowsers atomic.Int64
+
+// RunningBrowsers reports the number of Chrome processes launched by this
+// package that are still running. Browsers connected to with
+// RemoteDebuggingURL are not counted.
+func RunningBrowsers() int {
+	return int(runningBrowsers.Load())
+}
+
+// startBrowser launches Chrome, or connects to opts.RemoteDebuggingURL when
+// set, and waits until the browser accepts commands. The browser is shut
+// down, or disconnected from, when ctx is done or cancel is called.
+func startBrowser(ctx context.Context, opts Options) (*browserInstance, error) {
+	if opts.RemoteDebuggingURL != "" {
+		// These options are applied when launching the browser, so cannot
+		// take effect in one that is already running.
+		switch {
+		case opts.UserDataDir != "":
+			return nil, fmt.Errorf("capture: UserDataDir cannot be used with RemoteDebuggingURL")
+		case len(opts.Extensions) > 0:
+			return nil, fmt.Errorf("capture: Extensions cannot be used with RemoteDebuggingURL")
+		case len(opts.HostRules) > 0:
+			return nil, fmt.Errorf("capture: HostRules cannot be used with RemoteDebuggingURL")
+		}
+	}
+
+	var allocCtx context.Context
+	var cancelAlloc context.CancelFunc
+	if opts.RemoteDebuggingURL != "" {
+		allocCtx, cancelAlloc = chromedp.NewRemoteAllocator(ctx, opts.RemoteDebuggingURL)
+	} else {
+		extensionDirs, cleanup, err := prepareExtensions(opts.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		allocOpts, err := execAllocatorOptions(opts, extensionDirs)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		var cancelExec context.CancelFunc
+		allocCtx, cancelExec = chromedp.NewExecAllocator(ctx, allocOpts...)
+		runningBrowsers.Add(1)
+		var once sync.Once
+		cancelAlloc = func() {
+			// Cancelling the allocator waits for the browser to exit, after
+			// which any unpacked extensions can be removed.
+			cancelExec()
+			cleanup()
+			once.Do(func() {
+				runningBrowsers.Add(-1)
+			})
+		}
+	}
+
+	// Running an empty action list is what launches or connects to the
+	// browser; a browser context can only be created once it is available.
+	connCtx, cancelConn := chromedp.NewContext(allocCtx, browserLogging(opts.Logger)...)
+	if err := chromedp.Run(connCtx); err != nil {
+		cancelConn()
+		cancelAlloc()
+		if opts.RemoteDebuggingURL != "" {
+			return nil, fmt.Errorf("capture: failed to connect to %s: %w", opts.RemoteDebuggingURL, err)
+		}
+		return nil, fmt.Errorf("capture: failed to start browser: %w", err)
+	}
+
+	return &browserInstance{
+		ctx: connCtx,
+		cancel: func() {
+			cancelConn()
+			cancelAlloc()
+		},
+	}, nil
+}
+
+// newTab opens a tab in a new browser context, so that its cookies, cache and
+// storage are isolated from every other tab in b. The tab is closed and its
+// browser context disposed of when ctx is done or the returned cancel func is
+// called; the browser itself keeps running.
+func (b *browserInstance) newTab(ctx context.Context) (context.Context, context.CancelFunc) {
+	tabCtx, cancelTab := chromedp.NewContext(b.ctx, chromedp.WithNewBrowserContext())
+	stop := context.AfterFunc(ctx, cancelTab)
+	return tabCtx, func() {
+		stop()
+		cancelTab()
+	}
+}
+
+// alive reports whether the browser is still usable. A browser that has
+// crashed or been disconnected from cannot be recovered.
+func (b *browserInstance) alive() bool {
+	return b.ctx.Err() == nil
+}
+
+// newTab starts or connects to a browser according to opts and returns the
+// context of a fresh tab in which to perform the capture. The returned cancel
+// func closes the tab and releases the browser.
+func newTab(ctx context.Context, opts Options) (context.Context, context.CancelFunc, error) {
+	b, err := startBrowser(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.RemoteDebuggingURL == "" {
+		// A browser launched for this capture alone needs no further
+		// isolation, so its initial tab is used directly. This is also what
+		// exposes the UserDataDir profile to the capture.
+		return b.ctx, b.cancel, nil
+	}
+
+	tabCtx, cancelTab := b.newTab(ctx)
+	return tabCtx, func() {
+		cancelTab()
+		b.cancel()
+	}, nil
+}
+
+// browserLogging routes chromedp's internal output to logger at debug level.
+// Most of it reports CDP events that cannot be unmarshalled — these arise
+// from version skew between the installed Chrome binary and the cdproto
+// definitions pinned in go.mod (e.g. unknown PrivateNetworkRequestPolicy
+// enum values, cookiePart parse errors). They are usually harmless, since the
+// affected events are simply dropped, but may explain an incomplete HAR. A
+// nil logger discards the output.
+func browserLogging(logger *slog.Logger) []chromedp.ContextOption {
+	logger = orDiscard(logger)
+	logf := func(format string, args ...any) {
+		logger.Debug(fmt.Sprintf(format, args...), "source", "chromedp")
+	}
+	return []chromedp.ContextOption{
+		chromedp.WithLogf(logf),
+		chromedp.WithErrorf(logf),
+		chromedp.WithDebugf(logf),
+	}
+}
+
+// orDiscard returns logger, or a logger that discards everything if it is
+// nil.
+func orDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return logger
+}
@@ -0,0 +1,1170 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/css"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/har"
+	"github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/cdproto/tracing"
+	"github.com/chromedp/chromedp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Version is the version of this package, recorded in HAR creator metadata
+// unless Options.CreatorVersion is set.
+const Version = "0.1.0"
+
+// ErrCancelled is returned by Capture, alongside the partial Result, when
+// the context passed to it is cancelled before the capture completes.
+var ErrCancelled = errors.New("cancelled")
+
+// LifecycleStage identifies a named point in the page loading process at
+// which a screenshot was taken.
+type LifecycleStage string
+
+const (
+	StageDocumentLoad         LifecycleStage = "load"
+	StageFirstContentfulPaint LifecycleStage = "firstContentfulPaint"
+	StageNetworkIdle          LifecycleStage = "networkIdle"
+
+	// StageInterval marks a screenshot taken periodically, as configured by
+	// Options.ScreenshotInterval, rather than at a lifecycle event.
+	StageInterval LifecycleStage = "interval"
+)
+
+// lifecycleOrder defines the canonical ordering of stages for sorting
+// screenshots that may have been collected concurrently.
+var lifecycleOrder = map[LifecycleStage]int{
+	StageDocumentLoad:         0,
+	StageFirstContentfulPaint: 1,
+	StageNetworkIdle:          2,
+	StageInterval:             3,
+}
+
+// Screenshot holds a PNG image captured at a particular lifecycle stage.
+type Screenshot struct {
+	Stage      LifecycleStage
+	CapturedAt time.Time
+	PNG        []byte
+}
+
+// Options controls the behaviour of a capture run.
+type Options struct {
+	// URL is the page to capture. Required.
+	URL string
+
+	// NavigationTimeout is the maximum duration to wait for the initial page
+	// navigation to complete. When it elapses, collection continues with
+	// whatever has been received so far — a navigation timeout is not fatal.
+	// Defaults to 10 seconds if zero.
+	NavigationTimeout time.Duration
+
+	// TotalTimeout is the maximum duration for the entire capture, measured
+	// from the moment Capture is called. This bounds both navigation and the
+	// subsequent wait for networkIdle. Defaults to 30 seconds if zero.
+	TotalTimeout time.Duration
+
+	// IncludePending records requests still awaiting a response when the
+	// capture is cut off by a timeout as entries with status 0, flagged
+	// _pending, rather than dropping them.
+	IncludePending bool
+
+	// IdleDuration and MaxInflightRequests define when the network is idle,
+	// and so when collection ends, in place of Chrome's networkIdle
+	// lifecycle event: idle once no more than MaxInflightRequests requests
+	// have been in flight for IdleDuration. Chrome's event requires 500ms
+	// with no requests in flight, but is only fired once per navigation, so
+	// misses beacons sent late. When both are zero Chrome's event is used;
+	// otherwise IdleDuration defaults to 500ms.
+	IdleDuration        time.Duration
+	MaxInflightRequests int
+
+	// Runs selects whether the URL is captured once, or twice in the same
+	// browser context to compare cold and warm cache performance. Defaults
+	// to RunCold.
+	Runs RunMode
+
+	// BrowserVersion is embedded into the HAR creator metadata. When empty,
+	// the string "unknown" is used. In practice you would retrieve this via
+	// the Browser.getVersion CDP command.
+	BrowserVersion string
+
+	// CreatorName, CreatorVersion and CreatorComment identify the tool that
+	// produced the HAR in its creator metadata, so that tools embedding this
+	// package can identify themselves. The name defaults to "har-capture"
+	// and the version to that of this package. The comment is followed by
+	// notes of any browser overrides in effect.
+	CreatorName    string
+	CreatorVersion string
+	CreatorComment string
+
+	// Screenshots controls whether PNG screenshots are captured at each
+	// lifecycle stage (load, firstContentfulPaint, networkIdle).
+	Screenshots bool
+
+	// ScreenshotInterval, when non-zero, takes an additional screenshot at
+	// this interval from navigation until the capture completes, producing a
+	// visual timeline of slow pages. These screenshots have StageInterval and
+	// are taken whether or not Screenshots is set.
+	ScreenshotInterval time.Duration
+
+	// ViewportWidth and ViewportHeight set the browser viewport dimensions.
+	// Defaults to 1920x1080 if either is zero.
+	ViewportWidth  int64
+	ViewportHeight int64
+
+	// DeviceScaleFactor is the ratio of device pixels to CSS pixels, e.g. 2
+	// or 3 to render high-DPI layouts and screenshots. Defaults to 1 if zero.
+	DeviceScaleFactor float64
+
+	// Mobile emulates a mobile device: the page is laid out using its meta
+	// viewport, and touch events are enabled.
+	Mobile bool
+
+	// Orientation sets the reported screen orientation. Left empty, no
+	// orientation is emulated.
+	Orientation Orientation
+
+	// Cookies are installed via Network.setCookies before navigation, allowing
+	// logged-in pages to be captured using session cookies obtained elsewhere.
+	Cookies []CookieSeed
+
+	// UserAgent and AcceptLanguage override the browser's defaults via
+	// Emulation.setUserAgentOverride, allowing captures to emulate specific
+	// browsers or locales. Either may be left empty. Any override is recorded
+	// in the HAR creator comment.
+	UserAgent      string
+	AcceptLanguage string
+
+	// Headers are sent with every request the page makes, via
+	// Network.setExtraHTTPHeaders.
+	Headers map[string]string
+
+	// DisableCache bypasses the browser cache via Network.setCacheDisabled so
+	// that cold-cache measurements are reproducible. Recorded in the HAR
+	// creator comment.
+	DisableCache bool
+
+	// Throttling emulates a slower network via
+	// Network.emulateNetworkConditions. Nil leaves the network unthrottled.
+	// Recorded in the HAR creator comment.
+	Throttling *NetworkConditions
+
+	// BlockURLs lists URL patterns that the browser refuses to load, applied
+	// via Network.setBlockedURLs. Patterns may use '*' as a wildcard, e.g.
+	// "*://*.doubleclick.net/*". Useful for excluding third-party trackers
+	// to measure first-party performance in isolation.
+	BlockURLs []string
+
+	// Mocks intercept matching requests via the Fetch domain, serving canned
+	// responses or rewriting real ones. Canned responses and rewrites are
+	// matched separately, each in order: the first matching canned response
+	// fulfils a request before it is sent, and otherwise the first matching
+	// rewrite applies to its response. Affected entries are flagged "_mocked"
+	// in the HAR.
+	Mocks []Mock
+
+	// Geolocation overrides the position reported to the page via
+	// Emulation.setGeolocationOverride, so location-aware pages can be
+	// captured as seen from a specific coordinate. Nil leaves it unchanged.
+	Geolocation *LatLng
+
+	// WaitForSelector and WaitForExpression delay completion beyond
+	// networkIdle until a CSS selector matches an element or a JavaScript
+	// expression evaluates truthy, respectively. This supports single-page
+	// applications that fetch data after the network first goes idle. Both
+	// remain bounded by TotalTimeout.
+	WaitForSelector   string
+	WaitForExpression string
+
+	// ScrollToBottom scrolls the page after document load so that lazily
+	// loaded images and infinite-scroll content are fetched and included in
+	// the HAR. The page is scrolled by ScrollStep pixels every ScrollDelay,
+	// which default to the viewport height and 250 milliseconds. A failure to
+	// scroll is logged to Logger rather than failing the capture.
+	ScrollToBottom bool
+	ScrollStep     int64
+	ScrollDelay    time.Duration
+
+	// Actions is a scripted user flow (clicks, typing, pauses, JavaScript)
+	// executed in order after document load, and after scrolling when
+	// ScrollToBottom is set, so that the capture records the network traffic
+	// of the interaction rather than only the initial page load. If an action
+	// fails, the capture is stopped and Capture returns its error.
+	Actions []Action
+
+	// FollowNavigations is the number of navigations after the initial page
+	// load to keep collecting across. Each navigation, whether a full document
+	// load or a soft navigation via history.pushState, produces its own HAR
+	// page. When non-zero, the capture does not complete until that many
+	// navigations have been observed, bounded by TotalTimeout.
+	FollowNavigations int
+
+	// FailOnException causes Capture to return an error wrapping
+	// ErrUncaughtException if the page throws an uncaught JavaScript
+	// exception, which is useful for smoke-testing deployments. Exceptions are
+	// recorded in Result.Exceptions regardless.
+	FailOnException bool
+
+	// Trace records a Chrome performance trace for the duration of the
+	// capture, returned in Result.Trace for deep-dive analysis in Perfetto or
+	// the DevTools Performance panel.
+	Trace bool
+
+	// Coverage records which bytes of each script and stylesheet were used
+	// during the capture, via precise JavaScript coverage and CSS rule usage
+	// tracking. Results are returned in Result.Coverage.
+	Coverage bool
+
+	// CapturePDF renders the page to PDF via Page.printToPDF once collection
+	// ends, for archival and compliance snapshots. Returned in Result.PDF.
+	CapturePDF bool
+
+	// CaptureSnapshot saves the fully rendered page as MHTML via
+	// Page.captureSnapshot once collection ends, so that its appearance is
+	// preserved after the origin changes. Returned in Result.Snapshot.
+	CaptureSnapshot bool
+
+	// Screencast records a frame of the viewport whenever its rendered output
+	// changes, producing a filmstrip of timestamped JPEGs in Result.Filmstrip
+	// so that loading progression can be reviewed next to the HAR waterfall.
+	Screencast bool
+
+	// RemoteDebuggingURL connects to an already-running browser instead of
+	// launching Chrome, e.g. "ws://127.0.0.1:9222" or "http://chrome:9222".
+	// This avoids per-capture startup and supports browser grids and
+	// headless-shell containers. Each capture runs in its own browser context.
+	RemoteDebuggingURL string
+
+	// ChromePath is the browser executable to launch. When empty the first
+	// Chrome or Chromium found in the usual locations is used.
+	ChromePath string
+
+	// ChromeFlags are extra command-line flags passed to the launched browser,
+	// e.g. "--no-sandbox" or "--proxy-server=http://proxy:3128". A flag given
+	// here overrides any default of the same name.
+	ChromeFlags []string
+
+	// UserDataDir is a Chrome profile directory to launch the browser with,
+	// so that a capture starts with the cookies, storage and extensions
+	// already held by that profile. Chrome locks the profile while running,
+	// so concurrent captures must not share a directory. It cannot be
+	// combined with RemoteDebuggingURL or used with a Pool, both of which
+	// capture in a fresh browser context that does not see the profile.
+	UserDataDir string
+
+	// Extensions are loaded into the launched browser, so that pages can be
+	// measured with, say, an ad blocker active. Each is the path of an
+	// unpacked extension directory or a CRX file. Like UserDataDir, they
+	// cannot be combined with RemoteDebuggingURL or used with a Pool, since
+	// extensions do not run in the fresh browser contexts those capture in.
+	Extensions []string
+
+	// HostRules resolves each host to the address it maps to instead of
+	// consulting DNS, so that a production hostname can be captured against
+	// a canary or local server. Hosts may use wildcards, e.g. "*.example.com".
+	// Like UserDataDir, it cannot be combined with RemoteDebuggingURL.
+	HostRules map[string]string
+
+	// FilterExtensionRequests omits requests issued by extensions from the
+	// HAR, leaving only those made by the page itself.
+	FilterExtensionRequests bool
+
+	// Hooks are called as the capture progresses. See Hooks.
+	Hooks Hooks
+
+	// CaptureBodies records response bodies as HAR content text. Bodies are
+	// attached when the HAR is assembled, so are absent from entries passed
+	// to Stream or Hooks.OnEntryCompleted, and are not captured at all by
+	// Stream.
+	CaptureBodies bool
+
+	// BodyMIMEAllowlist restricts CaptureBodies to responses whose MIME type
+	// matches one of these patterns, e.g. "application/json" or "text/*".
+	// Empty captures bodies of every type.
+	BodyMIMEAllowlist []string
+
+	// MaxBodyBytes omits any body larger than this many bytes once decoded.
+	// Zero imposes no limit.
+	MaxBodyBytes int64
+
+	// IncludeResourceTypes, when non-empty, records only entries of these
+	// resource types in the HAR, e.g. network.ResourceTypeXHR and
+	// network.ResourceTypeFetch for an API-focused archive.
+	IncludeResourceTypes []network.ResourceType
+
+	// ExcludeResourceTypes omits entries of these resource types from the
+	// HAR. It is applied after IncludeResourceTypes.
+	ExcludeResourceTypes []network.ResourceType
+
+	// IncludeDomains, when non-empty, records only entries whose host matches
+	// one of these patterns, where '*' matches any run of characters, e.g.
+	// "*.example.com". Unlike BlockURLs, requests to other hosts are still
+	// made; they are only left out of the HAR.
+	IncludeDomains []string
+
+	// ExcludeDomains omits entries whose host matches one of these patterns,
+	// e.g. analytics or font CDNs. It is applied after IncludeDomains.
+	ExcludeDomains []string
+
+	// Logger receives debug-level diagnostics: CDP protocol errors, events
+	// that could not be decoded, responses that could not be matched to a
+	// request, and outputs that could not be collected. These are essential
+	// for diagnosing an empty or incomplete HAR. Nil disables logging.
+	Logger *slog.Logger
+}
+
+// Result is the outcome of a capture run.
+type Result struct {
+	HAR HAR
+
+	// TTFB is the time-to-first-byte for the document request — the duration
+	// between the request being sent and the first response byte being received.
+	// Zero if the document response was not observed.
+	TTFB time.Duration
+
+	// Metrics summarises the network activity and load timings of the page.
+	Metrics *Metrics
+
+	// Screenshots contains PNG images captured at lifecycle stages, in
+	// lifecycle order. Empty if Options.Screenshots was false.
+	Screenshots []Screenshot
+
+	// WebVitals contains the Core Web Vitals observed during the capture. Nil
+	// if they could not be read from the page.
+	WebVitals *WebVitals
+
+	// ConsoleMessages contains the messages written to the browser console
+	// during the capture, in the order they were received. JavaScript
+	// warnings and errors often explain failed requests.
+	ConsoleMessages []ConsoleMessage
+
+	// Exceptions contains the uncaught JavaScript exceptions thrown by the
+	// page, in the order they were thrown.
+	Exceptions []Exception
+
+	// Trace is the Chrome performance trace in JSON Object Format. Nil if
+	// Options.Trace was false or the trace could not be completed.
+	Trace []byte
+
+	// Coverage reports used and unused bytes per script and stylesheet. Nil
+	// if Options.Coverage was false or coverage could not be collected.
+	Coverage []CoverageEntry
+
+	// PDF is the page rendered to PDF at the end of the capture. Nil if
+	// Options.CapturePDF was false or rendering failed.
+	PDF []byte
+
+	// Snapshot is the page saved as MHTML at the end of the capture. Nil if
+	// Options.CaptureSnapshot was false or the snapshot failed.
+	Snapshot []byte
+
+	// Filmstrip contains the frames recorded by the screencast, in the order
+	// they were rendered. Empty if Options.Screencast was false.
+	Filmstrip []Frame
+
+	// TimedOut is true when the capture was cut off by TotalTimeout rather
+	// than by a networkIdle event. The HAR contains whatever was collected up
+	// to that point; no entries are discarded.
+	TimedOut bool
+
+	// Cancelled is true when the capture was cut off because its context was
+	// cancelled. The HAR contains whatever was collected up to that point.
+	// TimedOut is never set alongside it.
+	Cancelled bool
+
+	// Warm is the second, warm-cache capture when Options.Runs is
+	// RunColdWarm; the Result holding it is the cold capture. Nil otherwise.
+	Warm *Result
+}
+
+// Capture navigates to the URL specified in opts, records all network
+// activity until the page reaches networkIdle or TotalTimeout elapses, and
+// returns a Result containing the assembled HAR.
+//
+// Capture is safe to call concurrently; each call creates an isolated browser
+// context.
+func Capture(ctx context.Context, opts Options) (*Result, error) {
+	return captureRuns(ctx, opts, func(ctx context.Context) (context.Context, context.CancelFunc, error) {
+		return newTab(ctx, opts)
+	}, nil)
+}
+
+// Stream performs a capture as Capture does, but passes each HAR entry to fn
+// as soon as its response is received instead of collecting it in the
+// Result. The HAR of the returned Result holds pages but no entries, so
+// memory use stays bounded however many requests the page makes.
+//
+// fn is called sequentially from the goroutine receiving browser events and
+// should return promptly; it is never called after Stream returns. With
+// RunColdWarm the entries of the cold run are passed before those of the warm
+// run.
+func Stream(ctx context.Context, opts Options, fn func(entry Entry)) (*Result, error) {
+	return captureRuns(ctx, opts, func(ctx context.Context) (context.Context, context.CancelFunc, error) {
+		return newTab(ctx, opts)
+	}, fn)
+}
+
+// tabOpener returns the context of a tab in which to perform a capture. The
+// tab must be closed when ctx is done or the returned cancel func is called.
+type tabOpener func(ctx context.Context) (context.Context, context.CancelFunc, error)
+
+// capture implements Capture and Stream, performing the capture in the tab
+// returned by open. When stream is non-nil entries are passed to it rather
+// than retained.
+func capture(ctx context.Context, opts Options, open tabOpener, stream func(Entry)) (*Result, error) {
+	ctx, span := otelTracer.Start(ctx, "capture", trace.WithAttributes(attribute.String("url.full", opts.URL)))
+	result, err := captureTab(ctx, opts, open, stream)
+	if result != nil && result.HAR.Log != nil {
+		span.SetAttributes(
+			attribute.Bool("capture.timed_out", result.TimedOut),
+			attribute.Int("capture.entries", len(result.HAR.Log.Entries)),
+		)
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// captureTab performs the capture for capture, within its span.
+func captureTab(ctx context.Context, opts Options, open tabOpener, stream func(Entry)) (*Result, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("capture: URL must not be empty")
+	}
+
+	navTimeout := opts.NavigationTimeout
+	if navTimeout == 0 {
+		navTimeout = 10 * time.Second
+	}
+
+	totalTimeout := opts.TotalTimeout
+	if totalTimeout == 0 {
+		totalTimeout = 30 * time.Second
+	}
+
+	browserVersion := opts.BrowserVersion
+	if browserVersion == "" {
+		browserVersion = "unknown"
+	}
+
+	viewportWidth := opts.ViewportWidth
+	viewportHeight := opts.ViewportHeight
+	if viewportWidth == 0 || viewportHeight == 0 {
+		viewportWidth = 1920
+		viewportHeight = 1080
+	}
+
+	scrollStep := opts.ScrollStep
+	if scrollStep == 0 {
+		scrollStep = viewportHeight
+	}
+
+	scrollDelay := opts.ScrollDelay
+	if scrollDelay == 0 {
+		scrollDelay = 250 * time.Millisecond
+	}
+
+	for _, a := range opts.Actions {
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	viewport, err := emulateViewport(viewportWidth, viewportHeight, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the pre-navigation actions up front so that invalid options are
+	// rejected before a browser is launched.
+	actions := []chromedp.Action{
+		viewport,
+		injectVitalsObserver(),
+	}
+	if len(opts.Cookies) > 0 {
+		action, err := setCookies(opts.Cookies, opts.URL)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	if opts.UserAgent != "" || opts.AcceptLanguage != "" {
+		actions = append(actions, emulation.SetUserAgentOverride(opts.UserAgent).
+			WithAcceptLanguage(opts.AcceptLanguage))
+	}
+	if len(opts.Headers) > 0 {
+		headers := make(network.Headers, len(opts.Headers))
+		for name, value := range opts.Headers {
+			if name == "" {
+				return nil, fmt.Errorf("capture: header name must not be empty")
+			}
+			headers[name] = value
+		}
+		actions = append(actions, network.SetExtraHTTPHeaders(headers))
+	}
+	if opts.DisableCache {
+		actions = append(actions, network.SetCacheDisabled(true))
+	}
+	if opts.Throttling != nil {
+		action, err := emulateNetworkConditions(*opts.Throttling)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	if len(opts.BlockURLs) > 0 {
+		actions = append(actions, network.SetBlockedURLS(opts.BlockURLs))
+	}
+	if opts.Geolocation != nil {
+		actions = append(actions, setGeolocation(*opts.Geolocation))
+	}
+
+	logger := orDiscard(opts.Logger).With("url", opts.URL)
+
+	store := newRequestStore(stream != nil)
+
+	var icpt *interceptor
+	if len(opts.Mocks) > 0 {
+		icpt = &interceptor{mocks: opts.Mocks, store: store}
+		actions = append(actions, icpt.enable())
+	}
+
+	var tr *tracer
+	if opts.Trace {
+		tr = newTracer()
+		actions = append(actions, tr.start())
+	}
+
+	var cov *coverageRecorder
+	if opts.Coverage {
+		cov = newCoverageRecorder()
+		actions = append(actions, cov.start())
+	}
+
+	var bodies *bodyRecorder
+	if opts.CaptureBodies && stream == nil {
+		bodies = newBodyRecorder(opts.BodyMIMEAllowlist, opts.MaxBodyBytes, logger)
+	}
+
+	var cast *screencastRecorder
+	if opts.Screencast {
+		cast = &screencastRecorder{}
+		actions = append(actions, cast.start())
+	}
+
+	actions = append(actions, chromedp.Navigate(opts.URL))
+
+	// totalCtx bounds the entire capture including browser startup.
+	totalCtx, cancelTotal := context.WithTimeout(ctx, totalTimeout)
+	defer cancelTotal()
+
+	// runCtx is cancelled early should a hook abort the capture.
+	runCtx, abort := context.WithCancel(totalCtx)
+	defer abort()
+	hooks := newHookRunner(opts.Hooks, abort)
+
+	filter := newEntryFilter(opts)
+	metrics := newMetricsRecorder()
+
+	var onEntry func(completedEntry)
+	if stream != nil || opts.Hooks.OnEntryCompleted != nil {
+		onEntry = func(e completedEntry) {
+			if !filter.keep(e) {
+				return
+			}
+			entry := buildEntry(e)
+			hooks.entryCompleted(entry)
+			if stream != nil {
+				stream(entry)
+			}
+		}
+	}
+	coll := newCollector(onEntry, stream != nil)
+
+	// browserCtx outlives totalCtx by finalizeTimeout so that the page can
+	// still be queried for a final screenshot and metrics after a timeout.
+	browserCtx, cancelBrowser := context.WithTimeout(ctx, totalTimeout+finalizeTimeout)
+	defer cancelBrowser()
+
+	tabCtx, cancelTab, err := open(browserCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancelTab()
+
+	// screenshotCollector gathers screenshots taken concurrently at each
+	// lifecycle stage.
+	sc := &screenshotCollector{onCapture: hooks.screenshot}
+
+	var idle *idleTracker
+	if opts.IdleDuration > 0 || opts.MaxInflightRequests > 0 {
+		idle = newIdleTracker(opts.IdleDuration, opts.MaxInflightRequests, func() {
+			if opts.Screenshots {
+				sc.capture(tabCtx, StageNetworkIdle)
+			}
+			coll.markDone()
+		})
+		defer idle.stop()
+	}
+	console := &consoleCollector{}
+	exceptions := &exceptionCollector{}
+
+	// Register wait conditions before navigating so that an early networkIdle
+	// cannot complete the capture before they have been checked.
+	conditions := waitConditions(opts)
+	satisfiers := make([]func(), len(conditions))
+	for i := range conditions {
+		satisfiers[i] = coll.addCondition()
+	}
+
+	// Scrolling and scripted actions begin at document load and must finish
+	// before the capture completes. The load lifecycle event fires for every
+	// frame, so only the first is acted upon.
+	afterLoad := opts.ScrollToBottom || len(opts.Actions) > 0
+	var afterLoadOnce sync.Once
+	afterLoadDone := func() {}
+	actionsErr := make(chan error, 1)
+	if afterLoad {
+		afterLoadDone = coll.addCondition()
+	}
+
+	tracker := &pageTracker{}
+	if opts.FollowNavigations > 0 {
+		navigated := coll.addCondition()
+		tracker.onNavigate = func(n int) {
+			if n == opts.FollowNavigations {
+				go settle(tabCtx, navigated)
+			}
+		}
+	}
+
+	// handleNetwork records the network events of the page and of its child
+	// targets. ctx is that of the target which emitted the event.
+	handleNetwork := func(ctx context.Context, ev any) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if opts.FilterExtensionRequests && fromExtension(ev) {
+				return
+			}
+			p := onRequest(ev, store, tracker, coll)
+			metrics.requestWillBeSent(ev, p != nil)
+			if idle != nil {
+				idle.requestWillBeSent(ev)
+			}
+			if p != nil {
+				hooks.navigationStart(p.Title)
+			}
+		case *network.EventResponseReceived:
+			if bodies != nil {
+				bodies.responseReceived(ev)
+			}
+			onResponse(ev, store, coll, logger)
+		case *network.EventRequestWillBeSentExtraInfo:
+			store.requestExtraInfo(ev)
+		case *network.EventResponseReceivedExtraInfo:
+			store.responseExtraInfo(ev)
+		case *network.EventLoadingFinished:
+			if bodies != nil {
+				bodies.loadingFinished(ctx, ev)
+			}
+			metrics.loadingFinished(ev)
+			store.loadingFinished(ev)
+			store.finished(ev.RequestID)
+			if idle != nil {
+				idle.finished(ev.RequestID)
+			}
+		case *network.EventLoadingFailed:
+			metrics.loadingFailed(ev)
+			store.finished(ev.RequestID)
+			if idle != nil {
+				idle.finished(ev.RequestID)
+			}
+		}
+	}
+
+	children := newChildTargets(tabCtx, handleNetwork, logger)
+	defer children.close()
+
+	chromedp.ListenTarget(tabCtx, func(ev any) {
+		handleNetwork(tabCtx, ev)
+
+		switch ev := ev.(type) {
+		case *target.EventAttachedToTarget:
+			// Attaching issues CDP commands, which must not block the
+			// listener goroutine.
+			go children.attach(tabCtx, ev.TargetInfo)
+		case *target.EventTargetCreated:
+			// Service workers are not auto-attached to the page, so are
+			// picked up as they are discovered instead.
+			if ev.TargetInfo.Type == "service_worker" {
+				go children.attach(tabCtx, ev.TargetInfo)
+			}
+		case *runtime.EventConsoleAPICalled:
+			console.consoleAPICalled(ev)
+		case *log.EventEntryAdded:
+			console.entryAdded(ev)
+		case *runtime.EventExceptionThrown:
+			exceptions.exceptionThrown(ev)
+		case *tracing.EventDataCollected:
+			if tr != nil {
+				tr.dataCollected(ev)
+			}
+		case *tracing.EventTracingComplete:
+			if tr != nil {
+				tr.complete()
+			}
+		case *css.EventStyleSheetAdded:
+			if cov != nil {
+				cov.styleSheetAdded(ev)
+			}
+		case *page.EventScreencastFrame:
+			if cast != nil {
+				cast.frame(tabCtx, ev)
+			}
+		case *fetch.EventRequestPaused:
+			// Resolving a paused request issues CDP commands, which must not
+			// block the listener goroutine.
+			if icpt != nil {
+				go icpt.handle(tabCtx, ev)
+			}
+		case *page.EventNavigatedWithinDocument:
+			if p := tracker.navigatedWithinDocument(ev); p != nil {
+				coll.send(*p)
+				hooks.navigationStart(p.Title)
+			}
+		case *page.EventLifecycleEvent:
+			if !tracker.isMainDocument(ev) {
+				return
+			}
+			metrics.lifecycleEvent(ev)
+			hooks.lifecycleStage(LifecycleStage(ev.Name))
+			if ev.Name == string(StageDocumentLoad) && afterLoad {
+				afterLoadOnce.Do(func() {
+					go func() {
+						defer afterLoadDone()
+						if opts.ScrollToBottom {
+							if err := scrollToBottom(tabCtx, scrollStep, scrollDelay); err != nil {
+								// Lazily loaded content may be missing from the
+								// HAR, but the capture is still of the page.
+								logger.Warn("failed to scroll to bottom", "error", err)
+							}
+						}
+						// A failed action means the flow recorded is not the
+						// one scripted, so the capture is stopped and fails.
+						// Actions cut short by the capture ending are not
+						// failures of their own.
+						if err := runActions(tabCtx, opts.Actions); err != nil && runCtx.Err() == nil {
+							actionsErr <- err
+							abort()
+						}
+					}()
+				})
+			}
+			switch ev.Name {
+			case string(StageDocumentLoad), string(StageFirstContentfulPaint):
+				if opts.Screenshots {
+					// Spawn immediately so the screenshot is taken at this
+					// point in the page lifecycle, not deferred to later.
+					sc.capture(tabCtx, LifecycleStage(ev.Name))
+				}
+			case string(StageNetworkIdle):
+				if idle != nil {
+					// The idle tracker decides instead.
+					return
+				}
+				if opts.Screenshots {
+					sc.capture(tabCtx, StageNetworkIdle)
+				}
+				coll.markDone()
+			}
+		}
+	})
+
+	stopInterval := func() {}
+	if opts.ScreenshotInterval > 0 {
+		stopInterval = sc.captureEvery(tabCtx, opts.ScreenshotInterval)
+		defer stopInterval()
+	}
+
+	// Navigate with its own shorter deadline. A timeout here is not fatal —
+	// events collected during a partial navigation are still valid HAR entries.
+	// Any other error (DNS failure, invalid URL) is a hard stop.
+	navCtx, cancelNav := context.WithTimeout(tabCtx, navTimeout)
+	defer cancelNav()
+	stopNav := context.AfterFunc(runCtx, cancelNav)
+	defer stopNav()
+
+	timedOut := false
+	navCtx, navSpan := otelTracer.Start(navCtx, "capture.navigate")
+	if err := chromedp.Run(navCtx, actions...); err != nil {
+		if !isTimeoutError(err) {
+			endSpan(navSpan, err)
+			return nil, fmt.Errorf("capture: navigation failed: %w", err)
+		}
+		logger.Debug("navigation timed out", "timeout", navTimeout)
+		timedOut = true
+		navSpan.SetAttributes(attribute.Bool("capture.timed_out", true))
+	}
+	navSpan.End()
+
+	for i, condition := range conditions {
+		go awaitCondition(tabCtx, condition, satisfiers[i])
+	}
+
+	_, collSpan := otelTracer.Start(runCtx, "capture.collect")
+	pages, completedEntries, collTimedOut := coll.wait(runCtx)
+	timedOut = timedOut || collTimedOut
+	collSpan.SetAttributes(
+		attribute.Int("capture.pages", len(pages)),
+		attribute.Bool("capture.timed_out", collTimedOut),
+	)
+	collSpan.End()
+	stopInterval()
+	if idle != nil {
+		idle.stop()
+	}
+
+	if err := hooks.aborted(); err != nil {
+		return nil, fmt.Errorf("capture: %w: %w", ErrAborted, err)
+	}
+	select {
+	case err := <-actionsErr:
+		return nil, fmt.Errorf("capture: %w", err)
+	default:
+	}
+
+	// A cancelled context is the caller giving up rather than the page
+	// running out of time, so is not reported as a timeout.
+	cancelled := errors.Is(ctx.Err(), context.Canceled)
+	if cancelled {
+		timedOut = false
+	}
+
+	if opts.IncludePending && timedOut {
+		for _, e := range store.drainPending() {
+			if onEntry != nil {
+				onEntry(e)
+			}
+			if stream == nil {
+				completedEntries = append(completedEntries, e)
+			}
+		}
+	}
+
+	// If we timed out before networkIdle, capture a final screenshot of
+	// whatever state the page reached.
+	if opts.Screenshots && timedOut {
+		sc.capture(tabCtx, StageNetworkIdle)
+	}
+
+	var filmstrip []Frame
+	if cast != nil {
+		filmstrip = cast.stop(tabCtx)
+	}
+
+	vitals := readWebVitals(tabCtx)
+
+	// Outputs that cannot be collected are dropped rather than failing a
+	// capture that has otherwise succeeded.
+	var pdf []byte
+	if opts.CapturePDF {
+		if pdf, err = printPDF(tabCtx); err != nil {
+			logger.Debug("failed to print PDF", "error", err)
+		}
+	}
+
+	var snapshot []byte
+	if opts.CaptureSnapshot {
+		if snapshot, err = captureSnapshot(tabCtx); err != nil {
+			logger.Debug("failed to capture snapshot", "error", err)
+		}
+	}
+
+	var coverage []CoverageEntry
+	if cov != nil {
+		if coverage, err = cov.stop(tabCtx); err != nil {
+			logger.Debug("failed to collect coverage", "error", err)
+		}
+	}
+
+	var trace []byte
+	if tr != nil {
+		if trace, err = tr.stop(tabCtx); err != nil {
+			logger.Debug("failed to complete trace", "error", err)
+		}
+	}
+
+	// Wait for all in-flight screenshot goroutines to finish before assembling
+	// the result.
+	screenshots := sc.wait()
+
+	thrown := exceptions.result()
+	if opts.FailOnException && len(thrown) > 0 {
+		return nil, fmt.Errorf("capture: %w: %s", ErrUncaughtException, thrown[0].Message)
+	}
+
+	if bodies != nil {
+		recorded := bodies.wait()
+		for i := range completedEntries {
+			completedEntries[i].body = recorded[completedEntries[i].request.requestID]
+		}
+	}
+
+	store.resolveExtra(completedEntries)
+
+	harEntries := filter.apply(completedEntries)
+	if stream != nil {
+		// Entries have already been delivered; any retained are only kept
+		// for the TTFB.
+		harEntries = nil
+	}
+
+	h := assembleHAR(pages, harEntries, browserVersion, creator(opts))
+	result := &Result{
+		HAR:             h,
+		TTFB:            extractTTFB(completedEntries),
+		Screenshots:     screenshots,
+		WebVitals:       vitals,
+		ConsoleMessages: console.result(),
+		Exceptions:      thrown,
+		Trace:           trace,
+		Coverage:        coverage,
+		PDF:             pdf,
+		Snapshot:        snapshot,
+		Filmstrip:       filmstrip,
+		Metrics:         metrics.result(filmstrip),
+		TimedOut:        timedOut,
+		Cancelled:       cancelled,
+	}
+	if cancelled {
+		return result, fmt.Errorf("capture: %w", ErrCancelled)
+	}
+	return result, nil
+}
+
+// creator returns the HAR creator metadata for a capture with opts.
+func creator(opts Options) *har.Creator {
+	c := &har.Creator{
+		Name:    opts.CreatorName,
+		Version: opts.CreatorVersion,
+		Comment: creatorComment(opts),
+	}
+	if c.Name == "" {
+		c.Name = "har-capture"
+	}
+	if c.Version == "" {
+		c.Version = Version
+	}
+	return c
+}
+
+// creatorComment prefixes the caller's comment to a summary of the options
+// that alter how the page was loaded, so that a HAR can be interpreted
+// without knowledge of how it was produced.
+func creatorComment(opts Options) string {
+	var notes []string
+	if opts.CreatorComment != "" {
+		notes = append(notes, opts.CreatorComment)
+	}
+	if opts.UserAgent != "" {
+		notes = append(notes, fmt.Sprintf("userAgent=%q", opts.UserAgent))
+	}
+	if opts.AcceptLanguage != "" {
+		notes = append(notes, fmt.Sprintf("acceptLanguage=%q", opts.AcceptLanguage))
+	}
+	if opts.DisableCache {
+		notes = append(notes, "cacheDisabled=true")
+	}
+	if opts.Throttling != nil {
+		notes = append(notes, fmt.Sprintf("throttling=%q", opts.Throttling.String()))
+	}
+	return strings.Join(notes, "; ")
+}
+
+// screenshotCollector takes screenshots concurrently at each lifecycle stage
+// and collects the results safely across goroutines.
+type screenshotCollector struct {
+	// onCapture, when set, is called with each screenshot once taken.
+	onCapture func(Screenshot)
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	results []Screenshot
+}
+
+// capture spawns a goroutine that takes a screenshot immediately and appends
+// the result to the collector. Safe to call from the CDP listener goroutine.
+func (sc *screenshotCollector) capture(ctx context.Context, stage LifecycleStage) {
+	sc.wg.Add(1)
+	go func() {
+		defer sc.wg.Done()
+		ctx, span := otelTracer.Start(ctx, "capture.screenshot", trace.WithAttributes(attribute.String("capture.stage", string(stage))))
+		var buf []byte
+		err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf))
+		endSpan(span, err)
+		if err != nil {
+			return
+		}
+		s := Screenshot{
+			Stage:      stage,
+			CapturedAt: time.Now(),
+			PNG:        buf,
+		}
+		sc.mu.Lock()
+		sc.results = append(sc.results, s)
+		sc.mu.Unlock()
+		if sc.onCapture != nil {
+			sc.onCapture(s)
+		}
+	}()
+}
+
+// captureEvery takes a screenshot every interval until the returned func is
+// called or ctx is done. The returned func is idempotent and, once it has
+// returned, no further screenshots are started.
+func (sc *screenshotCollector) captureEvery(ctx context.Context, interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sc.capture(ctx, StageInterval)
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// wait blocks until all in-flight screenshots have completed and returns them
+// sorted into canonical lifecycle order, with periodic screenshots last in
+// the order they were taken.
+func (sc *screenshotCollector) wait() []Screenshot {
+	sc.wg.Wait()
+	sort.SliceStable(sc.results, func(i, j int) bool {
+		a, b := sc.results[i], sc.results[j]
+		if lifecycleOrder[a.Stage] != lifecycleOrder[b.Stage] {
+			return lifecycleOrder[a.Stage] < lifecycleOrder[b.Stage]
+		}
+		return a.CapturedAt.Before(b.CapturedAt)
+	})
+	return sc.results
+}
+
+// extractTTFB finds the document request among completed entries and returns
+// the time between the request being sent and the first response byte received.
+// Chrome exposes this as ReceiveHeadersStart in ResourceTiming, in milliseconds
+// relative to requestTime.
+func extractTTFB(entries []completedEntry) time.Duration {
+	for _, e := range entries {
+		if e.request.resourceType != network.ResourceTypeDocument {
+			continue
+		}
+		t := e.response.Response.Timing
+		if t == nil || t.ReceiveHeadersStart < 0 {
+			return 0
+		}
+		return time.Duration(t.ReceiveHeadersStart * float64(time.Millisecond))
+	}
+	return 0
+}
+
+// onRequest processes an incoming request event. It registers the pending
+// request in the store and, for requests that start a new page, emits and
+// returns a har.Page.
+func onRequest(ev *network.EventRequestWillBeSent, store *requestStore, pages *pageTracker, coll *collector) *har.Page {
+	pageRef, p := pages.request(ev)
+
+	store.addRequest(pendingRequest{
+		requestID:    ev.RequestID,
+		method:       ev.Request.Method,
+		url:          ev.Request.URL,
+		headers:      ev.Request.Headers,
+		wallTime:     ev.WallTime.Time(),
+		resourceType: ev.Type,
+		pageRef:      pageRef,
+	})
+
+	if p != nil {
+		coll.send(*p)
+	}
+	return p
+}
+
+// onResponse attempts to correlate the response with its pending request and,
+// on success, emits a completedEntry.
+func onResponse(ev *network.EventResponseReceived, store *requestStore, coll *collector, logger *slog.Logger) {
+	entry, ok := store.correlate(ev)
+	if !ok {
+		// The request was either never seen or already correlated — skip.
+		logger.Debug("response without a pending request", "request_id", ev.RequestID, "response_url", ev.Response.URL)
+		return
+	}
+	coll.send(entry)
+}
+
+// finalizeTimeout is the grace period after TotalTimeout during which the
+// browser is kept alive to take a final screenshot and read page metrics.
+const finalizeTimeout = 5 * time.Second
+
+// navigationSettleTime mirrors the quiet period of Chrome's networkIdle
+// heuristic. It gives requests issued by the final followed navigation time to
+// be recorded before the capture completes.
+const navigationSettleTime = 500 * time.Millisecond
+
+// settle calls done after navigationSettleTime, unless ctx is done first.
+func settle(ctx context.Context, done func()) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(navigationSettleTime):
+		done()
+	}
+}
+
+// isTimeoutError reports whether err stems from a context deadline or
+// cancellation. Used to distinguish a navigation cut short (graceful) from a
+// hard failure such as a DNS error. Whether the caller cancelled the capture
+// is determined separately.
+func isTimeoutError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// onceCloser ensures a channel is closed at most once, guarding against the
+// case where networkIdle fires multiple times.
+type onceCloser struct {
+	ch   chan struct{}
+	done bool
+}
+
+func (o *onceCloser) close() {
+	if !o.done {
+		o.done = true
+		close(o.ch)
+	}
+}
@@ -0,0 +1,167 @@
+package capture
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chromedp/cdproto/har"
+	"github.com/chromedp/cdproto/network"
+)
+
+// collector accumulates network events emitted by the CDP listener and
+// decides when the capture is complete. Events are recorded as they arrive,
+// so that the listener never blocks however chatty the page is.
+//
+// Typical usage:
+//
+//	coll := newCollector(nil, false)
+//	chromedp.ListenTarget(ctx, func(ev any) {
+//	    // forward events via coll.send and coll.markDone
+//	})
+//	pages, entries, timedOut := coll.wait(totalCtx)
+type collector struct {
+	doneCh   chan struct{}
+	doneOnce *onceCloser
+
+	// onEntry, when set, receives each completed entry as it arrives.
+	// delivering counts the calls to it in progress, which wait allows to
+	// finish before returning.
+	onEntry    func(completedEntry)
+	delivering sync.WaitGroup
+
+	// streaming discards completed entries once passed to onEntry rather
+	// than retaining them for wait.
+	streaming bool
+
+	// mu guards every field below. idle and conditions together decide when
+	// doneCh is closed: the page must have reached networkIdle and every
+	// registered wait condition must have been satisfied.
+	mu         sync.Mutex
+	idle       bool
+	conditions int
+	pages      []har.Page
+	entries    []completedEntry
+	stopped    bool
+}
+
+// newCollector returns a collector that passes each completed entry to
+// onEntry, if non-nil, and retains it for wait unless streaming.
+func newCollector(onEntry func(completedEntry), streaming bool) *collector {
+	doneCh := make(chan struct{})
+	return &collector{
+		doneCh:    doneCh,
+		doneOnce:  &onceCloser{ch: doneCh},
+		onEntry:   onEntry,
+		streaming: streaming,
+	}
+}
+
+// send delivers an event into the collector. Safe to call from the CDP
+// listener goroutine. Events sent after wait has returned are discarded.
+//
+// onEntry is called once c.mu has been released, so that a slow consumer
+// holds up only the goroutine sending the entry and may itself call back
+// into the collector.
+func (c *collector) send(v any) {
+	d, deliver := c.record(v)
+	if !deliver {
+		return
+	}
+	defer c.delivering.Done()
+	c.onEntry(d)
+}
+
+// record retains v as wait should return it, and reports whether it is an
+// entry that must then be passed to onEntry. If so, c.delivering has been
+// incremented on its behalf.
+func (c *collector) record(v any) (completedEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return completedEntry{}, false
+	}
+
+	switch d := v.(type) {
+	case har.Page:
+		c.pages = append(c.pages, d)
+	case completedEntry:
+		// The first document is still retained when streaming, since the
+		// TTFB of the capture is derived from it.
+		if !c.streaming || (!c.hasDocument() && d.request.resourceType == network.ResourceTypeDocument) {
+			c.entries = append(c.entries, d)
+		}
+		if c.onEntry != nil {
+			c.delivering.Add(1)
+			return d, true
+		}
+	}
+	return completedEntry{}, false
+}
+
+// hasDocument reports whether a document entry has been retained. c.mu must
+// be held.
+func (c *collector) hasDocument() bool {
+	for _, e := range c.entries {
+		if e.request.resourceType == network.ResourceTypeDocument {
+			return true
+		}
+	}
+	return false
+}
+
+// markDone signals that the page has reached networkIdle. Idempotent.
+func (c *collector) markDone() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.idle = true
+	c.closeIfReady()
+}
+
+// addCondition registers an additional condition that must be satisfied
+// before the collector is done, and returns the func that satisfies it. The
+// returned func is idempotent and safe to call from any goroutine.
+func (c *collector) addCondition() func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conditions++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.conditions--
+			c.closeIfReady()
+		})
+	}
+}
+
+// closeIfReady closes doneCh once networkIdle has been reached and no
+// conditions are outstanding. c.mu must be held.
+func (c *collector) closeIfReady() {
+	if c.idle && c.conditions == 0 {
+		c.doneOnce.close()
+	}
+}
+
+// wait blocks until either networkIdle is signalled via markDone (and any
+// registered conditions are satisfied) or ctx is cancelled, then returns the
+// collected slices. A context cancellation is treated as a graceful cutoff —
+// timedOut will be true but the collected data is still returned.
+func (c *collector) wait(ctx context.Context) (pages []har.Page, entries []completedEntry, timedOut bool) {
+	select {
+	case <-c.doneCh:
+	case <-ctx.Done():
+		timedOut = true
+	}
+
+	c.mu.Lock()
+	c.stopped = true
+	pages, entries = c.pages, c.entries
+	c.mu.Unlock()
+
+	// No entry is passed to onEntry once stopped is set, so that none is
+	// delivered after wait returns.
+	c.delivering.Wait()
+	return pages, entries, timedOut
+}
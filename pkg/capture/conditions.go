@@ -0,0 +1,47 @@
+package capture
+
+import (
+	"context"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// conditionRetryInterval is how long to wait before re-checking a wait
+// condition whose check failed, e.g. because a navigation destroyed the
+// execution context it was running in.
+const conditionRetryInterval = 100 * time.Millisecond
+
+// waitConditions returns the actions that must succeed before a capture is
+// considered complete, derived from opts.WaitForSelector and
+// opts.WaitForExpression.
+func waitConditions(opts Options) []chromedp.Action {
+	var conditions []chromedp.Action
+	if opts.WaitForSelector != "" {
+		conditions = append(conditions, chromedp.WaitReady(opts.WaitForSelector, chromedp.ByQuery))
+	}
+	if opts.WaitForExpression != "" {
+		var truthy any
+		conditions = append(conditions, chromedp.Poll(opts.WaitForExpression, &truthy,
+			chromedp.WithPollingInterval(conditionRetryInterval),
+			chromedp.WithPollingTimeout(0), // Bounded by ctx instead.
+		))
+	}
+	return conditions
+}
+
+// awaitCondition runs condition until it succeeds or ctx is done, calling
+// satisfy on success.
+func awaitCondition(ctx context.Context, condition chromedp.Action, satisfy func()) {
+	for {
+		if err := chromedp.Run(ctx, condition); err == nil {
+			satisfy()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(conditionRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package capture
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// ConsoleMessage is a message written to the browser console during a
+// capture, either by the page via the console API or by the browser itself
+// (e.g. network errors, deprecation warnings).
+type ConsoleMessage struct {
+	// Source is "console-api" for calls made by the page, otherwise the
+	// browser subsystem that produced the message, e.g. "network".
+	Source string `json:"source"`
+
+	// Level is the severity, e.g. "log", "info", "warning" or "error".
+	Level string `json:"level"`
+
+	Text       string    `json:"text"`
+	URL        string    `json:"url,omitempty"`
+	LineNumber int64     `json:"line_number,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// consoleCollector gathers console messages across goroutines.
+type consoleCollector struct {
+	mu       sync.Mutex
+	messages []ConsoleMessage
+}
+
+// consoleAPICalled records a call to console.* made by the page.
+func (c *consoleCollector) consoleAPICalled(ev *runtime.EventConsoleAPICalled) {
+	args := make([]string, 0, len(ev.Args))
+	for _, arg := range ev.Args {
+		args = append(args, remoteObjectString(arg))
+	}
+
+	m := ConsoleMessage{
+		Source: "console-api",
+		Level:  string(ev.Type),
+		Text:   strings.Join(args, " "),
+	}
+	if ev.Timestamp != nil {
+		m.Timestamp = ev.Timestamp.Time()
+	}
+	if ev.StackTrace != nil && len(ev.StackTrace.CallFrames) > 0 {
+		frame := ev.StackTrace.CallFrames[0]
+		m.URL = frame.URL
+		m.LineNumber = frame.LineNumber + 1 // CDP line numbers are 0-based.
+	}
+
+	c.add(m)
+}
+
+// entryAdded records a message emitted by the browser via the Log domain.
+func (c *consoleCollector) entryAdded(ev *log.EventEntryAdded) {
+	e := ev.Entry
+	if e == nil {
+		return
+	}
+
+	m := ConsoleMessage{
+		Source:     string(e.Source),
+		Level:      string(e.Level),
+		Text:       e.Text,
+		URL:        e.URL,
+		LineNumber: e.LineNumber,
+	}
+	if e.Timestamp != nil {
+		m.Timestamp = e.Timestamp.Time()
+	}
+
+	c.add(m)
+}
+
+func (c *consoleCollector) add(m ConsoleMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, m)
+}
+
+// result returns the collected messages in the order they were received.
+func (c *consoleCollector) result() []ConsoleMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.messages
+}
+
+// remoteObjectString renders a console argument the way DevTools would
+// display it in a single line.
+func remoteObjectString(o *runtime.RemoteObject) string {
+	if o == nil {
+		return ""
+	}
+	if len(o.Value) > 0 {
+		var s string
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	if o.UnserializableValue != "" {
+		return string(o.UnserializableValue)
+	}
+	if o.Description != "" {
+		return o.Description
+	}
+	return string(o.Type)
+}
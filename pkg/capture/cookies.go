@@ -0,0 +1,74 @@
+package capture
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// CookieSeed describes a cookie to install in the browser before navigation.
+// It is typically used to inject a session cookie obtained elsewhere so that
+// authenticated pages can be captured.
+type CookieSeed struct {
+	Name  string
+	Value string
+
+	// Domain and Path scope the cookie. When Domain is empty the cookie is
+	// associated with URL instead, which itself defaults to the capture URL.
+	Domain string
+	Path   string
+	URL    string
+
+	Secure   bool
+	HTTPOnly bool
+
+	// SameSite is one of "Strict", "Lax" or "None". Empty leaves the browser
+	// default in place.
+	SameSite string
+
+	// Expires is the cookie expiry. The zero value produces a session cookie.
+	Expires time.Time
+}
+
+// setCookies returns an action that installs seeds via Network.setCookies.
+// Seeds without a Domain or URL are scoped to pageURL.
+func setCookies(seeds []CookieSeed, pageURL string) (chromedp.Action, error) {
+	params := make([]*network.CookieParam, 0, len(seeds))
+	for _, s := range seeds {
+		if s.Name == "" {
+			return nil, fmt.Errorf("capture: cookie name must not be empty")
+		}
+
+		p := &network.CookieParam{
+			Name:     s.Name,
+			Value:    s.Value,
+			URL:      s.URL,
+			Domain:   s.Domain,
+			Path:     s.Path,
+			Secure:   s.Secure,
+			HTTPOnly: s.HTTPOnly,
+		}
+		if p.Domain == "" && p.URL == "" {
+			p.URL = pageURL
+		}
+
+		switch s.SameSite {
+		case "":
+		case string(network.CookieSameSiteStrict), string(network.CookieSameSiteLax), string(network.CookieSameSiteNone):
+			p.SameSite = network.CookieSameSite(s.SameSite)
+		default:
+			return nil, fmt.Errorf("capture: cookie %q has invalid SameSite value %q", s.Name, s.SameSite)
+		}
+
+		if !s.Expires.IsZero() {
+			expires := cdp.TimeSinceEpoch(s.Expires)
+			p.Expires = &expires
+		}
+
+		params = append(params, p)
+	}
+	return network.SetCookies(params), nil
+}
@@ -0,0 +1,210 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/chromedp/cdproto/css"
+	"github.com/chromedp/cdproto/profiler"
+	"github.com/chromedp/chromedp"
+)
+
+// CoverageType identifies the kind of resource a CoverageEntry describes.
+type CoverageType string
+
+const (
+	CoverageJS  CoverageType = "js"
+	CoverageCSS CoverageType = "css"
+)
+
+// CoverageEntry reports how much of a script or stylesheet was used during
+// the capture. Large unused byte counts indicate dead code shipped to the
+// browser.
+type CoverageEntry struct {
+	URL         string       `json:"url"`
+	Type        CoverageType `json:"type"`
+	TotalBytes  int64        `json:"total_bytes"`
+	UsedBytes   int64        `json:"used_bytes"`
+	UnusedBytes int64        `json:"unused_bytes"`
+}
+
+// byteRange is a half-open [start, end) range of offsets into a resource.
+type byteRange struct {
+	start, end int64
+}
+
+// coverageRecorder tracks JavaScript and CSS coverage for a capture.
+type coverageRecorder struct {
+	mu          sync.Mutex
+	styleSheets map[css.StyleSheetID]*css.StyleSheetHeader
+}
+
+func newCoverageRecorder() *coverageRecorder {
+	return &coverageRecorder{styleSheets: make(map[css.StyleSheetID]*css.StyleSheetHeader)}
+}
+
+// start returns the action that begins precise JavaScript coverage and CSS
+// rule usage tracking.
+func (r *coverageRecorder) start() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := profiler.Enable().Do(ctx); err != nil {
+			return err
+		}
+		if _, err := profiler.StartPreciseCoverage().WithDetailed(true).Do(ctx); err != nil {
+			return err
+		}
+		return css.StartRuleUsageTracking().Do(ctx)
+	})
+}
+
+// styleSheetAdded records a stylesheet's URL and length, which rule usage
+// reports do not include. Safe to call from the CDP listener goroutine.
+func (r *coverageRecorder) styleSheetAdded(ev *css.EventStyleSheetAdded) {
+	if ev.Header == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.styleSheets[ev.Header.StyleSheetID] = ev.Header
+}
+
+// stop collects coverage gathered so far and returns one entry per script
+// and stylesheet, sorted by URL.
+func (r *coverageRecorder) stop(ctx context.Context) ([]CoverageEntry, error) {
+	var scripts []*profiler.ScriptCoverage
+	var rules []*css.RuleUsage
+	if err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		if scripts, _, err = profiler.TakePreciseCoverage().Do(ctx); err != nil {
+			return err
+		}
+		rules, err = css.StopRuleUsageTracking().Do(ctx)
+		return err
+	})); err != nil {
+		return nil, fmt.Errorf("capture: failed to collect coverage: %w", err)
+	}
+
+	entries := make([]CoverageEntry, 0, len(scripts))
+	for _, s := range scripts {
+		// Scripts without a URL are evaluated snippets, including our own.
+		if s.URL == "" {
+			continue
+		}
+		total, used := scriptCoverage(s)
+		entries = append(entries, newCoverageEntry(s.URL, CoverageJS, total, used))
+	}
+	entries = append(entries, r.styleSheetCoverage(rules)...)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+	return entries, nil
+}
+
+// styleSheetCoverage aggregates rule usage into one entry per stylesheet.
+func (r *coverageRecorder) styleSheetCoverage(rules []*css.RuleUsage) []CoverageEntry {
+	used := make(map[css.StyleSheetID][]byteRange)
+	for _, rule := range rules {
+		if rule.Used {
+			used[rule.StyleSheetID] = append(used[rule.StyleSheetID], byteRange{
+				start: int64(rule.StartOffset),
+				end:   int64(rule.EndOffset),
+			})
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]CoverageEntry, 0, len(r.styleSheets))
+	for id, h := range r.styleSheets {
+		url := h.SourceURL
+		if url == "" {
+			continue // Constructed stylesheets have no resource to attribute.
+		}
+		entries = append(entries, newCoverageEntry(url, CoverageCSS, int64(h.Length), rangesLength(used[id])))
+	}
+	return entries
+}
+
+func newCoverageEntry(url string, typ CoverageType, total, used int64) CoverageEntry {
+	return CoverageEntry{
+		URL:         url,
+		Type:        typ,
+		TotalBytes:  total,
+		UsedBytes:   used,
+		UnusedBytes: total - used,
+	}
+}
+
+// scriptCoverage computes the size of a script and the number of bytes that
+// were executed. Block coverage ranges nest, with inner ranges overriding the
+// count of the ranges that contain them, so they are flattened into disjoint
+// ranges before measuring.
+func scriptCoverage(s *profiler.ScriptCoverage) (total, used int64) {
+	type point struct {
+		offset int64
+		end    bool
+		r      *profiler.CoverageRange
+	}
+
+	var points []point
+	for _, fn := range s.Functions {
+		for _, r := range fn.Ranges {
+			points = append(points, point{offset: r.StartOffset, r: r}, point{offset: r.EndOffset, end: true, r: r})
+			total = max(total, r.EndOffset)
+		}
+	}
+
+	// Order by offset; at equal offsets, ends precede starts, outer ranges
+	// start first and inner ranges end first.
+	sort.Slice(points, func(i, j int) bool {
+		a, b := points[i], points[j]
+		if a.offset != b.offset {
+			return a.offset < b.offset
+		}
+		if a.end != b.end {
+			return a.end
+		}
+		aLen := a.r.EndOffset - a.r.StartOffset
+		bLen := b.r.EndOffset - b.r.StartOffset
+		if a.end {
+			return aLen < bLen
+		}
+		return aLen > bLen
+	})
+
+	var counts []int64
+	last := int64(0)
+	for _, p := range points {
+		if len(counts) > 0 && counts[len(counts)-1] > 0 && last < p.offset {
+			used += p.offset - last
+		}
+		last = p.offset
+		if p.end {
+			counts = counts[:len(counts)-1]
+		} else {
+			counts = append(counts, p.r.Count)
+		}
+	}
+	return total, used
+}
+
+// rangesLength returns the number of bytes covered by the union of ranges.
+func rangesLength(ranges []byteRange) int64 {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	var n int64
+	var cur byteRange
+	for i, r := range ranges {
+		if i == 0 || r.start > cur.end {
+			n += cur.end - cur.start
+			cur = r
+			continue
+		}
+		cur.end = max(cur.end, r.end)
+	}
+	return n + cur.end - cur.start
+}
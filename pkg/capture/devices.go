@@ -0,0 +1,53 @@
+package capture
+
+// Device describes the screen and browser of a device to emulate.
+type Device struct {
+	Width       int64
+	Height      int64
+	ScaleFactor float64
+	Mobile      bool
+	UserAgent   string
+}
+
+// Devices are common device presets, keyed by name, with the dimensions and
+// user agents used by Chrome DevTools.
+var Devices = map[string]Device{
+	"iphone-14": {
+		Width:       390,
+		Height:      844,
+		ScaleFactor: 3,
+		Mobile:      true,
+		UserAgent:   "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"pixel-7": {
+		Width:       412,
+		Height:      915,
+		ScaleFactor: 2.625,
+		Mobile:      true,
+		UserAgent:   "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
+	},
+	"galaxy-s20": {
+		Width:       360,
+		Height:      800,
+		ScaleFactor: 3,
+		Mobile:      true,
+		UserAgent:   "Mozilla/5.0 (Linux; Android 10; SM-G981B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
+	},
+	"ipad-air": {
+		Width:       820,
+		Height:      1180,
+		ScaleFactor: 2,
+		Mobile:      true,
+		UserAgent:   "Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"laptop": {
+		Width:       1366,
+		Height:      768,
+		ScaleFactor: 1,
+	},
+	"desktop": {
+		Width:       1920,
+		Height:      1080,
+		ScaleFactor: 1,
+	},
+}
@@ -0,0 +1,23 @@
+// Package capture provides a HAR (HTTP Archive) capturer built on top of the
+// Chrome DevTools Protocol (CDP). It is transport-agnostic: callers receive a
+// HAR value (mirroring har.HAR, with custom entry fields) and may serialise
+// or forward it however they choose.
+//
+// A capture is configured with Options, either directly or built from
+// functional options by NewOptions, and performed by Capture, Stream or a
+// Pool of browsers:
+//
+//	result, err := capture.Capture(ctx, capture.NewOptions("https://example.com",
+//		capture.WithTimeouts(10*time.Second, 30*time.Second),
+//		capture.WithScreenshots(),
+//	))
+//
+// # Compatibility
+//
+// This package follows semantic versioning. Within a major version of the
+// module, exported identifiers are neither removed nor changed in ways that
+// break callers. New fields may be added to Options and Result, and new
+// functional options added; the zero value of any new Options field keeps
+// the existing behaviour. Custom HAR fields, prefixed with an underscore, are
+// likewise only added to.
+package capture
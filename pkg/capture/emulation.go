@@ -0,0 +1,81 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// LatLng is a geographic coordinate used to override the browser's reported
+// position.
+type LatLng struct {
+	Latitude  float64
+	Longitude float64
+
+	// Accuracy is the reported accuracy radius in metres. Defaults to 1 if
+	// zero.
+	Accuracy float64
+}
+
+// setGeolocation returns an action that overrides the reported position and
+// grants the geolocation permission, so that pages can read it without a
+// prompt.
+func setGeolocation(ll LatLng) chromedp.Action {
+	accuracy := ll.Accuracy
+	if accuracy == 0 {
+		accuracy = 1
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// Browser.grantPermissions is a browser-level command, so it cannot be
+		// executed against the tab target.
+		c := chromedp.FromContext(ctx)
+		grant := browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation})
+		if err := grant.Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+			return err
+		}
+
+		return emulation.SetGeolocationOverride().
+			WithLatitude(ll.Latitude).
+			WithLongitude(ll.Longitude).
+			WithAccuracy(accuracy).
+			Do(ctx)
+	})
+}
+
+// Orientation is a screen orientation to emulate.
+type Orientation string
+
+const (
+	OrientationPortrait  Orientation = "portrait"
+	OrientationLandscape Orientation = "landscape"
+)
+
+// emulateViewport returns the action that sizes the viewport to width by
+// height, with the scale factor, mobile and orientation settings of opts.
+func emulateViewport(width, height int64, opts Options) (chromedp.Action, error) {
+	var emulate []chromedp.EmulateViewportOption
+	if opts.DeviceScaleFactor != 0 {
+		if opts.DeviceScaleFactor < 0 {
+			return nil, fmt.Errorf("capture: device scale factor must be positive, got %g", opts.DeviceScaleFactor)
+		}
+		emulate = append(emulate, chromedp.EmulateScale(opts.DeviceScaleFactor))
+	}
+	if opts.Mobile {
+		emulate = append(emulate, chromedp.EmulateMobile, chromedp.EmulateTouch)
+	}
+	switch opts.Orientation {
+	case "":
+	case OrientationPortrait:
+		emulate = append(emulate, chromedp.EmulatePortrait)
+	case OrientationLandscape:
+		emulate = append(emulate, chromedp.EmulateLandscape)
+	default:
+		return nil, fmt.Errorf("capture: unknown orientation %q", opts.Orientation)
+	}
+	return chromedp.EmulateViewport(width, height, emulate...), nil
+}
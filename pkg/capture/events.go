@@ -0,0 +1,196 @@
+package capture
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// pendingRequest holds the request side of a network event whilst we await
+// the corresponding response. RequestID is the correlation key.
+type pendingRequest struct {
+	requestID    network.RequestID
+	method       string
+	url          string
+	headers      network.Headers
+	wallTime     time.Time
+	resourceType network.ResourceType
+	pageRef      string
+}
+
+// completedEntry holds a fully correlated request+response pair ready for
+// HAR assembly.
+type completedEntry struct {
+	request  pendingRequest
+	response *network.EventResponseReceived
+
+	// mocked is true when the response was served or rewritten by a Mock.
+	mocked bool
+
+	// pending is true when no response had been received by the time the
+	// capture was cut off. The response is then a placeholder with status 0.
+	pending bool
+
+	// body is the recorded response body, or nil if bodies were not
+	// captured for this entry.
+	body *responseBody
+
+	// extra is what the ExtraInfo events reported for the exchange.
+	extra extraInfo
+}
+
+// extraInfo holds what is reported of an exchange beyond the basic request
+// and response events: the headers as actually sent and received on the wire,
+// from the ExtraInfo events, and the bytes transferred, once loading
+// finishes. Any field may be empty, since the events are not always sent.
+type extraInfo struct {
+	requestHeaders      network.Headers
+	responseHeaders     network.Headers
+	responseHeadersText string
+
+	// transferSize is the number of bytes received for the exchange,
+	// including headers, or nil if it did not finish loading.
+	transferSize *int64
+}
+
+// requestStore correlates requests and responses by RequestID in a
+// concurrency-safe manner.
+type requestStore struct {
+	mu      sync.Mutex
+	pending map[network.RequestID]pendingRequest
+	mocked  map[network.RequestID]bool
+
+	// extra is keyed by request rather than held on the pending request
+	// because ExtraInfo events may arrive before the request event or after
+	// the response event.
+	extra map[network.RequestID]extraInfo
+
+	// pruneExtra discards extra info once a request finishes loading. It is
+	// set when entries are streamed and so never resolved at assembly.
+	pruneExtra bool
+}
+
+func newRequestStore(pruneExtra bool) *requestStore {
+	return &requestStore{
+		pending:    make(map[network.RequestID]pendingRequest),
+		mocked:     make(map[network.RequestID]bool),
+		extra:      make(map[network.RequestID]extraInfo),
+		pruneExtra: pruneExtra,
+	}
+}
+
+func (s *requestStore) addRequest(r pendingRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[r.requestID] = r
+}
+
+// markMocked records that the request with the given ID was handled by a
+// Mock. It must be called before the response is released to the page.
+func (s *requestStore) markMocked(id network.RequestID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mocked[id] = true
+}
+
+// requestExtraInfo records the wire headers of a request.
+func (s *requestStore) requestExtraInfo(ev *network.EventRequestWillBeSentExtraInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x := s.extra[ev.RequestID]
+	x.requestHeaders = ev.Headers
+	s.extra[ev.RequestID] = x
+}
+
+// responseExtraInfo records the wire headers of a response.
+func (s *requestStore) responseExtraInfo(ev *network.EventResponseReceivedExtraInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x := s.extra[ev.RequestID]
+	x.responseHeaders = ev.Headers
+	x.responseHeadersText = ev.HeadersText
+	s.extra[ev.RequestID] = x
+}
+
+// loadingFinished records the number of bytes received for a request.
+func (s *requestStore) loadingFinished(ev *network.EventLoadingFinished) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x := s.extra[ev.RequestID]
+	size := int64(ev.EncodedDataLength)
+	x.transferSize = &size
+	s.extra[ev.RequestID] = x
+}
+
+// finished notes that a request has finished loading, successfully or not,
+// after which no further extra info is expected for it.
+func (s *requestStore) finished(id network.RequestID) {
+	if !s.pruneExtra {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.extra, id)
+}
+
+// resolveExtra updates entries with extra info that arrived after their
+// responses were correlated.
+func (s *requestStore) resolveExtra(entries []completedEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range entries {
+		if x, ok := s.extra[entries[i].request.requestID]; ok {
+			entries[i].extra = x
+		}
+	}
+}
+
+// correlate attempts to pair a response event with its pending request.
+// Returns the completed entry and true if found, otherwise false.
+func (s *requestStore) correlate(ev *network.EventResponseReceived) (completedEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, ok := s.pending[ev.RequestID]
+	if !ok {
+		return completedEntry{}, false
+	}
+
+	delete(s.pending, ev.RequestID)
+
+	mocked := s.mocked[ev.RequestID]
+	delete(s.mocked, ev.RequestID)
+
+	return completedEntry{request: req, response: ev, mocked: mocked, extra: s.extra[ev.RequestID]}, true
+}
+
+// drainPending removes the requests still awaiting a response and returns
+// them as pending entries, in the order they were sent.
+func (s *requestStore) drainPending() []completedEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]completedEntry, 0, len(s.pending))
+	for id, req := range s.pending {
+		entries = append(entries, completedEntry{
+			request: req,
+			response: &network.EventResponseReceived{
+				RequestID: id,
+				Type:      req.resourceType,
+				Response:  &network.Response{URL: req.url},
+			},
+			mocked:  s.mocked[id],
+			pending: true,
+			extra:   s.extra[id],
+		})
+		delete(s.pending, id)
+		delete(s.mocked, id)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].request.wallTime.Before(entries[j].request.wallTime)
+	})
+	return entries
+}
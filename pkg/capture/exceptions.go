@@ -0,0 +1,90 @@
+package capture
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// ErrUncaughtException is returned by Capture, wrapped with the exception
+// message, when Options.FailOnException is set and the page throws.
+var ErrUncaughtException = errors.New("uncaught exception")
+
+// Exception is an uncaught JavaScript exception thrown by the page.
+type Exception struct {
+	Message      string       `json:"message"`
+	URL          string       `json:"url,omitempty"`
+	LineNumber   int64        `json:"line_number"`
+	ColumnNumber int64        `json:"column_number"`
+	StackTrace   []StackFrame `json:"stack_trace,omitempty"`
+	Timestamp    time.Time    `json:"timestamp"`
+}
+
+// StackFrame is a single call frame of an exception's stack trace. Line and
+// column numbers are 1-based.
+type StackFrame struct {
+	FunctionName string `json:"function_name,omitempty"`
+	URL          string `json:"url,omitempty"`
+	LineNumber   int64  `json:"line_number"`
+	ColumnNumber int64  `json:"column_number"`
+}
+
+// exceptionCollector gathers uncaught exceptions across goroutines.
+type exceptionCollector struct {
+	mu         sync.Mutex
+	exceptions []Exception
+}
+
+// exceptionThrown records an uncaught exception reported by the Runtime
+// domain.
+func (c *exceptionCollector) exceptionThrown(ev *runtime.EventExceptionThrown) {
+	d := ev.ExceptionDetails
+	if d == nil {
+		return
+	}
+
+	e := Exception{
+		Message:      exceptionMessage(d),
+		URL:          d.URL,
+		LineNumber:   d.LineNumber + 1, // CDP positions are 0-based.
+		ColumnNumber: d.ColumnNumber + 1,
+	}
+	if ev.Timestamp != nil {
+		e.Timestamp = ev.Timestamp.Time()
+	}
+	if d.StackTrace != nil {
+		for _, f := range d.StackTrace.CallFrames {
+			e.StackTrace = append(e.StackTrace, StackFrame{
+				FunctionName: f.FunctionName,
+				URL:          f.URL,
+				LineNumber:   f.LineNumber + 1,
+				ColumnNumber: f.ColumnNumber + 1,
+			})
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exceptions = append(c.exceptions, e)
+}
+
+// result returns the collected exceptions in the order they were thrown.
+func (c *exceptionCollector) result() []Exception {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exceptions
+}
+
+// exceptionMessage prefers the first line of the exception object's
+// description (e.g. "TypeError: x is undefined"), since d.Text is often only
+// "Uncaught".
+func exceptionMessage(d *runtime.ExceptionDetails) string {
+	if d.Exception != nil && d.Exception.Description != "" {
+		msg, _, _ := strings.Cut(d.Exception.Description, "\n")
+		return msg
+	}
+	return d.Text
+}
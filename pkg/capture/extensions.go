@@ -0,0 +1,154 @@
+package capture
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// extensionScheme prefixes the URL of every resource served by an extension.
+const extensionScheme = "chrome-extension://"
+
+// prepareExtensions returns the unpacked directories from which to load the
+// extensions at paths. Directories are used as they are; CRX files are
+// unpacked into a temporary directory that cleanup removes.
+func prepareExtensions(paths []string) (dirs []string, cleanup func(), err error) {
+	cleanup = func() {}
+	if len(paths) == 0 {
+		return nil, cleanup, nil
+	}
+
+	var tmp string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("capture: extension %s: %w", p, err)
+		}
+		if info.IsDir() {
+			dirs = append(dirs, p)
+			continue
+		}
+
+		if tmp == "" {
+			tmp, err = os.MkdirTemp("", "har-capture-extensions-")
+			if err != nil {
+				return nil, nil, fmt.Errorf("capture: failed to create extension directory: %w", err)
+			}
+			cleanup = func() { _ = os.RemoveAll(tmp) }
+		}
+
+		dir := filepath.Join(tmp, fmt.Sprintf("%d", len(dirs)))
+		if err := unpackCRX(p, dir); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("capture: extension %s: %w", p, err)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, cleanup, nil
+}
+
+// unpackCRX extracts the CRX file at path into dir. A CRX file is a zip
+// archive preceded by a header carrying the extension's signature.
+func unpackCRX(path, dir string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) < 12 || string(data[:4]) != "Cr24" {
+		return fmt.Errorf("not a CRX file")
+	}
+
+	var offset uint64
+	switch version := binary.LittleEndian.Uint32(data[4:8]); version {
+	case 2:
+		// Version 2 stores the public key and signature lengths directly.
+		if len(data) < 16 {
+			return fmt.Errorf("truncated CRX header")
+		}
+		offset = 16 + uint64(binary.LittleEndian.Uint32(data[8:12])) + uint64(binary.LittleEndian.Uint32(data[12:16]))
+	case 3:
+		offset = 12 + uint64(binary.LittleEndian.Uint32(data[8:12]))
+	default:
+		return fmt.Errorf("unsupported CRX version %d", version)
+	}
+	if offset > uint64(len(data)) {
+		return fmt.Errorf("truncated CRX header")
+	}
+
+	archive := data[offset:]
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if err := extractFile(f, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes the archived file f beneath dir, refusing any name that
+// would escape it.
+func extractFile(f *zip.File, dir string) error {
+	name := filepath.Join(dir, filepath.FromSlash(f.Name))
+	if !strings.HasPrefix(name, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return fmt.Errorf("illegal file name %q in archive", f.Name)
+	}
+
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(name, 0o755)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// fromExtension reports whether the request was issued by an extension
+// rather than by the page: either an extension resource is being fetched, or
+// extension code appears in the initiator's call stack.
+func fromExtension(ev *network.EventRequestWillBeSent) bool {
+	if strings.HasPrefix(ev.Request.URL, extensionScheme) || strings.HasPrefix(ev.DocumentURL, extensionScheme) {
+		return true
+	}
+
+	initiator := ev.Initiator
+	if initiator == nil {
+		return false
+	}
+	if strings.HasPrefix(initiator.URL, extensionScheme) {
+		return true
+	}
+	for stack := initiator.Stack; stack != nil; stack = stack.Parent {
+		for _, frame := range stack.CallFrames {
+			if strings.HasPrefix(frame.URL, extensionScheme) {
+				return true
+			}
+		}
+	}
+	return false
+}
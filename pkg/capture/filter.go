@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// entryFilter decides which completed entries are recorded in the HAR, as
+// configured by the filtering fields of Options. Filtered entries are still
+// requested by the browser and still count towards the TTFB.
+type entryFilter struct {
+	includeTypes   []network.ResourceType
+	excludeTypes   []network.ResourceType
+	includeDomains []string
+	excludeDomains []string
+}
+
+func newEntryFilter(opts Options) *entryFilter {
+	return &entryFilter{
+		includeTypes:   opts.IncludeResourceTypes,
+		excludeTypes:   opts.ExcludeResourceTypes,
+		includeDomains: opts.IncludeDomains,
+		excludeDomains: opts.ExcludeDomains,
+	}
+}
+
+// keep reports whether e is to be recorded.
+func (f *entryFilter) keep(e completedEntry) bool {
+	t := e.request.resourceType
+	if len(f.includeTypes) > 0 && !containsResourceType(f.includeTypes, t) {
+		return false
+	}
+	if containsResourceType(f.excludeTypes, t) {
+		return false
+	}
+
+	if len(f.includeDomains) == 0 && len(f.excludeDomains) == 0 {
+		return true
+	}
+	u, err := url.Parse(e.request.url)
+	if err != nil {
+		return len(f.includeDomains) == 0
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(f.includeDomains) > 0 && !matchesDomain(f.includeDomains, host) {
+		return false
+	}
+	return !matchesDomain(f.excludeDomains, host)
+}
+
+// apply returns the entries to be recorded, in their original order.
+func (f *entryFilter) apply(entries []completedEntry) []completedEntry {
+	kept := make([]completedEntry, 0, len(entries))
+	for _, e := range entries {
+		if f.keep(e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// matchesDomain reports whether host matches any of the glob patterns,
+// compared case-insensitively.
+func matchesDomain(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if matchWildcard(strings.ToLower(pattern), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsResourceType reports whether t is among types, compared
+// case-insensitively so that "xhr" matches network.ResourceTypeXHR.
+func containsResourceType(types []network.ResourceType, t network.ResourceType) bool {
+	for _, candidate := range types {
+		if strings.EqualFold(string(candidate), string(t)) {
+			return true
+		}
+	}
+	return false
+}
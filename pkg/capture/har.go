@@ -0,0 +1,407 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/har"
+	"github.com/chromedp/cdproto/network"
+)
+
+// HAR is the archive produced by a capture. It mirrors har.HAR, but its
+// entries may carry the custom, underscore-prefixed fields permitted by the
+// HAR 1.2 spec, which the cdproto types cannot represent. It marshals to
+// standard HAR JSON.
+type HAR struct {
+	Log *Log `json:"log"`
+}
+
+// Log mirrors har.Log, holding Entry values in place of har.Entry.
+type Log struct {
+	Version string       `json:"version"`
+	Creator *har.Creator `json:"creator"`
+	Browser *har.Creator `json:"browser,omitempty"`
+	Pages   []*har.Page  `json:"pages,omitempty"`
+	Entries []*Entry     `json:"entries"`
+	Comment string       `json:"comment,omitempty"`
+}
+
+// Entry is a HAR entry together with any custom fields recorded against it.
+type Entry struct {
+	har.Entry
+	EntryCustom
+}
+
+// EntryCustom lists the custom fields that may be recorded against an entry.
+type EntryCustom struct {
+	// Mocked is true when the response was served or rewritten by a Mock.
+	Mocked bool `json:"_mocked,omitempty"`
+
+	// FromServiceWorker is true when the response was provided by a service
+	// worker rather than fetched by the page. The worker's own fetches, if
+	// any, are recorded as entries of their own.
+	FromServiceWorker bool `json:"_fromServiceWorker,omitempty"`
+
+	// Pending is true when the request was still awaiting a response when
+	// the capture was cut off. Its response has status 0.
+	Pending bool `json:"_pending,omitempty"`
+
+	// RepeatCount is the number of identical requests an entry stands for
+	// when repeats have been collapsed into it, e.g. by `har dedupe`.
+	RepeatCount int `json:"_repeatCount,omitempty"`
+
+	// TransferSize is the number of bytes received for the response,
+	// including its headers, as Chrome's own HAR export records it. It is nil
+	// when unknown: for entries passed to Stream or Hooks, which are
+	// delivered before the response finishes loading, and for responses that
+	// never finished.
+	TransferSize *int64 `json:"_transferSize,omitempty"`
+}
+
+// MarshalJSON encodes e as a HAR entry object with its custom fields.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(e.Entry)
+	if err != nil {
+		return nil, err
+	}
+	c, err := json.Marshal(e.EntryCustom)
+	if err != nil {
+		return nil, err
+	}
+	if len(c) <= 2 {
+		return b, nil // No custom fields set.
+	}
+
+	// Both are objects, so the custom fields are appended to the entry by
+	// joining them in place of the entry's closing brace.
+	out := make([]byte, 0, len(b)+len(c))
+	out = append(out, b[:len(b)-1]...)
+	out = append(out, ',')
+	return append(out, c[1:]...), nil
+}
+
+// UnmarshalJSON decodes a HAR entry object, keeping its custom fields.
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &e.Entry); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &e.EntryCustom)
+}
+
+// assembleHAR constructs a HAR from a slice of completed entries and a page
+// map (keyed by page ref string), identifying creator as the tool that
+// produced it. Entries are ordered by start time, so that captures of the
+// same page can be compared.
+func assembleHAR(pages []har.Page, entries []completedEntry, browserVersion string, creator *har.Creator) HAR {
+	h := HAR{
+		Log: &Log{
+			Version: "1.2",
+			Browser: &har.Creator{
+				Name:    "Google Chrome",
+				Version: browserVersion,
+			},
+			Creator: creator,
+			Pages:   make([]*har.Page, 0, len(pages)),
+			Entries: make([]*Entry, 0, len(entries)),
+		},
+	}
+
+	for i := range pages {
+		p := pages[i]
+		h.Log.Pages = append(h.Log.Pages, &p)
+	}
+
+	for _, e := range sortEntries(entries) {
+		entry := buildEntry(e)
+		h.Log.Entries = append(h.Log.Entries, &entry)
+	}
+
+	return h
+}
+
+// sortEntries returns a copy of entries ordered by the time each request was
+// sent. Requests sent at the same instant are ordered by request ID.
+func sortEntries(entries []completedEntry) []completedEntry {
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b completedEntry) int {
+		if c := a.request.wallTime.Compare(b.request.wallTime); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.request.requestID), string(b.request.requestID))
+	})
+	return sorted
+}
+
+func buildEntry(e completedEntry) Entry {
+	req := e.request
+	resp := e.response
+
+	// Prefer the headers reported by the ExtraInfo events, which are those
+	// actually sent and received, including any added by the network stack
+	// such as Cookie.
+	reqHeaders := req.headers
+	if e.extra.requestHeaders != nil {
+		reqHeaders = e.extra.requestHeaders
+	}
+	respHeaders := resp.Response.Headers
+	if e.extra.responseHeaders != nil {
+		respHeaders = e.extra.responseHeaders
+	}
+
+	entry := Entry{Entry: har.Entry{
+		Pageref:         req.pageRef,
+		StartedDateTime: req.wallTime.Format(time.RFC3339Nano),
+		Request: &har.Request{
+			Method:      req.method,
+			URL:         req.url,
+			HTTPVersion: httpVersion(resp.Response),
+			Headers:     headersToHAR(reqHeaders),
+			QueryString: []*har.NameValuePair{},
+			Cookies:     requestCookies(reqHeaders),
+			HeadersSize: requestHeadersSize(e),
+			BodySize:    -1,
+		},
+		Response: &har.Response{
+			Status:      int64(resp.Response.Status),
+			StatusText:  resp.Response.StatusText,
+			HTTPVersion: httpVersion(resp.Response),
+			Headers:     headersToHAR(respHeaders),
+			Cookies:     responseCookies(respHeaders),
+			Content: &har.Content{
+				MimeType: mimeType(resp.Response),
+				Size:     0, // Populated separately if body capture is enabled.
+			},
+			RedirectURL: redirectURL(respHeaders),
+			HeadersSize: responseHeadersSize(e),
+			BodySize:    -1,
+		},
+		Cache:   &har.Cache{},
+		Timings: buildTimings(resp.Response.Timing),
+	}, EntryCustom: EntryCustom{
+		Mocked:            e.mocked,
+		FromServiceWorker: resp.Response.FromServiceWorker,
+		Pending:           e.pending,
+	}}
+
+	if size := e.extra.transferSize; size != nil {
+		entry.TransferSize = size
+		entry.Response.BodySize = responseBodySize(*size, entry.Response.HeadersSize)
+	}
+
+	if e.body != nil {
+		entry.Response.Content.Size = e.body.size
+		entry.Response.Content.Text = e.body.text
+		entry.Response.Content.Encoding = e.body.encoding
+	}
+
+	// Total time is the sum of all non-negative timings.
+	entry.Time = totalTime(entry.Timings)
+
+	return entry
+}
+
+// httpVersion returns the protocol of resp, which the HAR spec requires, or
+// "unknown" if no response was received to report it.
+func httpVersion(resp *network.Response) string {
+	if resp.Protocol == "" {
+		return "unknown"
+	}
+	return resp.Protocol
+}
+
+// mimeType returns the MIME type of resp, or "x-unknown", as browsers
+// record it, if none was reported.
+func mimeType(resp *network.Response) string {
+	if resp.MimeType == "" {
+		return "x-unknown"
+	}
+	return resp.MimeType
+}
+
+// requestHeadersSize returns the size in bytes of the request line and
+// headers as sent, or -1 if unknown. Chrome does not report the raw request
+// text, but for HTTP/1.x it can be reconstructed exactly from the wire
+// headers. Later protocols compress headers, so have no meaningful size.
+func requestHeadersSize(e completedEntry) int64 {
+	resp := e.response.Response
+	if e.extra.requestHeaders == nil || !strings.HasPrefix(strings.ToLower(resp.Protocol), "http/1") {
+		return -1
+	}
+	u, err := url.Parse(e.request.url)
+	if err != nil {
+		return -1
+	}
+
+	// Request line, e.g. "GET /path?q HTTP/1.1\r\n".
+	size := len(e.request.method) + 1 + len(u.RequestURI()) + 1 + len(resp.Protocol) + 2
+	for name, value := range map[string]any(e.extra.requestHeaders) {
+		// Chrome joins repeated headers with newlines; each is a line of its
+		// own on the wire.
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			size += len(name) + 2 + len(v) + 2
+		}
+	}
+	return int64(size + 2) // The blank line ending the headers.
+}
+
+// responseHeadersSize returns the size in bytes of the status line and
+// headers as received, or -1 if the raw text is unavailable, as it is for
+// HTTP/2 and QUIC.
+func responseHeadersSize(e completedEntry) int64 {
+	if e.extra.responseHeadersText == "" {
+		return -1
+	}
+	return int64(len(e.extra.responseHeadersText))
+}
+
+// responseBodySize returns the size in bytes of the response body as
+// received, given the transfer size of the whole response and the size of
+// its headers, or -1 if the header size, and so the body size, is unknown.
+func responseBodySize(transferSize, headersSize int64) int64 {
+	if headersSize < 0 || transferSize < headersSize {
+		return -1
+	}
+	return transferSize - headersSize
+}
+
+// buildTimings converts Chrome's timing of an exchange to HAR timings. The
+// send, wait and receive phases are required by the HAR spec, so are 0 when
+// unknown, as they are for a request still awaiting its response; the others
+// are -1 when they did not occur.
+func buildTimings(t *network.ResourceTiming) *har.Timings {
+	if t == nil {
+		return &har.Timings{Blocked: -1, DNS: -1, Connect: -1, Ssl: -1}
+	}
+
+	// Chrome's ResourceTiming values are in milliseconds relative to
+	// requestTime. A value of -1 means the phase did not occur.
+	dns := phaseOrBlocked(t.DNSStart, t.DNSEnd)
+	connect := phaseOrBlocked(t.ConnectStart, t.ConnectEnd)
+	ssl := phaseOrBlocked(t.SslStart, t.SslEnd)
+	send := max(phaseOrBlocked(t.SendStart, t.SendEnd), 0)
+
+	// Wait = from send end to first byte received (receiveHeadersEnd).
+	wait := float64(0)
+	if t.SendEnd >= 0 && t.ReceiveHeadersEnd >= 0 {
+		wait = max(t.ReceiveHeadersEnd-t.SendEnd, 0)
+	}
+
+	return &har.Timings{
+		Blocked: -1,
+		DNS:     dns,
+		Connect: connect,
+		Ssl:     ssl,
+		Send:    send,
+		Wait:    wait,
+		Receive: 0, // Requires body download tracking; not available here.
+	}
+}
+
+func phaseOrBlocked(start, end float64) float64 {
+	if start < 0 || end < 0 {
+		return -1
+	}
+	return end - start
+}
+
+func totalTime(t *har.Timings) float64 {
+	total := float64(0)
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v > 0 {
+			total += v
+		}
+	}
+	return total
+}
+
+func redirectURL(headers network.Headers) string {
+	for k, v := range map[string]any(headers) {
+		if k == "Location" || k == "location" {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// headersToHAR converts headers to HAR name-value pairs, sorted by name.
+// Chrome joins the values of a header repeated on the wire, such as
+// Set-Cookie, with newlines; each value becomes a pair of its own.
+func headersToHAR(headers network.Headers) []*har.NameValuePair {
+	pairs := make([]*har.NameValuePair, 0, len(headers))
+	for name, values := range map[string]any(headers) {
+		for _, value := range headerValues(values) {
+			pairs = append(pairs, &har.NameValuePair{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Name < pairs[j].Name
+	})
+	return pairs
+}
+
+// headerValues splits a header value as reported by Chrome into the values
+// of the individual header lines.
+func headerValues(values any) []string {
+	if arr, ok := values.([]string); ok {
+		return arr
+	}
+	return strings.Split(fmt.Sprint(values), "\n")
+}
+
+// lookupHeaderValues returns the values of the named header, matched
+// case-insensitively.
+func lookupHeaderValues(headers network.Headers, name string) []string {
+	var values []string
+	for k, v := range map[string]any(headers) {
+		if strings.EqualFold(k, name) {
+			values = append(values, headerValues(v)...)
+		}
+	}
+	return values
+}
+
+// requestCookies parses the cookies sent in the Cookie header.
+func requestCookies(headers network.Headers) []*har.Cookie {
+	cookies := []*har.Cookie{}
+	for _, line := range lookupHeaderValues(headers, "Cookie") {
+		parsed, err := http.ParseCookie(line)
+		if err != nil {
+			continue
+		}
+		for _, c := range parsed {
+			cookies = append(cookies, &har.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return cookies
+}
+
+// responseCookies parses the cookies set by each Set-Cookie header line.
+// Lines that fail to parse are skipped.
+func responseCookies(headers network.Headers) []*har.Cookie {
+	cookies := []*har.Cookie{}
+	for _, line := range lookupHeaderValues(headers, "Set-Cookie") {
+		c, err := http.ParseSetCookie(line)
+		if err != nil {
+			continue
+		}
+		hc := &har.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			hc.Expires = c.Expires.Format(time.RFC3339Nano)
+		}
+		cookies = append(cookies, hc)
+	}
+	return cookies
+}
@@ -0,0 +1,94 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrAborted is returned by Capture, wrapped with the hook's error, when a
+// hook in Options.Hooks aborts the capture.
+var ErrAborted = errors.New("aborted by hook")
+
+// Hooks are callbacks invoked as a capture progresses, so that an embedding
+// application can report progress, annotate its own records, or stop the
+// capture based on in-flight data. Any hook may be nil.
+//
+// A hook that returns an error aborts the capture: no further hooks are
+// called and Capture fails with an error wrapping ErrAborted. Hooks are never
+// called concurrently, but they hold up event processing and so should
+// return promptly.
+type Hooks struct {
+	// OnNavigationStart is called with the URL of every page as it starts
+	// loading, including same-document navigations that start a new page.
+	OnNavigationStart func(url string) error
+
+	// OnLifecycleStage is called for each lifecycle event of the main
+	// document, such as StageDocumentLoad or StageNetworkIdle.
+	OnLifecycleStage func(stage LifecycleStage) error
+
+	// OnEntryCompleted is called with each HAR entry once its response has
+	// been received.
+	OnEntryCompleted func(entry Entry) error
+
+	// OnScreenshot is called with each screenshot once it has been taken.
+	OnScreenshot func(s Screenshot) error
+}
+
+// hookRunner serialises calls to Hooks and cancels the capture when one of
+// them fails.
+type hookRunner struct {
+	hooks Hooks
+	abort context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+func newHookRunner(hooks Hooks, abort context.CancelFunc) *hookRunner {
+	return &hookRunner{hooks: hooks, abort: abort}
+}
+
+func (r *hookRunner) navigationStart(url string) {
+	if r.hooks.OnNavigationStart != nil {
+		r.call(func() error { return r.hooks.OnNavigationStart(url) })
+	}
+}
+
+func (r *hookRunner) lifecycleStage(stage LifecycleStage) {
+	if r.hooks.OnLifecycleStage != nil {
+		r.call(func() error { return r.hooks.OnLifecycleStage(stage) })
+	}
+}
+
+func (r *hookRunner) entryCompleted(entry Entry) {
+	if r.hooks.OnEntryCompleted != nil {
+		r.call(func() error { return r.hooks.OnEntryCompleted(entry) })
+	}
+}
+
+func (r *hookRunner) screenshot(s Screenshot) {
+	if r.hooks.OnScreenshot != nil {
+		r.call(func() error { return r.hooks.OnScreenshot(s) })
+	}
+}
+
+// call invokes fn unless an earlier hook has already aborted the capture.
+func (r *hookRunner) call(fn func() error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err := fn(); err != nil {
+		r.err = err
+		r.abort()
+	}
+}
+
+// aborted returns the error with which a hook aborted the capture, or nil.
+func (r *hookRunner) aborted() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
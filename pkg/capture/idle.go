@@ -0,0 +1,104 @@
+package capture
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// defaultIdleDuration matches the quiet period of Chrome's own networkIdle
+// lifecycle event.
+const defaultIdleDuration = 500 * time.Millisecond
+
+// idleTracker decides when the network is idle from the requests in flight,
+// in place of Chrome's networkIdle lifecycle event. The network is idle once
+// no more than maxInflight requests have been in flight for duration. Nothing
+// is considered idle before the first request is sent.
+type idleTracker struct {
+	duration    time.Duration
+	maxInflight int
+	onIdle      func()
+
+	mu       sync.Mutex
+	inflight map[network.RequestID]bool
+	started  bool
+	stopped  bool
+	timer    *time.Timer
+	idle     bool
+}
+
+// newIdleTracker returns an idleTracker that calls onIdle, at most once,
+// when the network becomes idle.
+func newIdleTracker(duration time.Duration, maxInflight int, onIdle func()) *idleTracker {
+	if duration == 0 {
+		duration = defaultIdleDuration
+	}
+	return &idleTracker{
+		duration:    duration,
+		maxInflight: maxInflight,
+		onIdle:      onIdle,
+		inflight:    make(map[network.RequestID]bool),
+	}
+}
+
+// requestWillBeSent records a request as in flight. A redirect reuses the ID
+// of the request it follows, so is not counted again.
+func (t *idleTracker) requestWillBeSent(ev *network.EventRequestWillBeSent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inflight[ev.RequestID] = true
+	t.started = true
+	t.update()
+}
+
+// finished records that a request has finished loading, successfully or not.
+func (t *idleTracker) finished(id network.RequestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.inflight, id)
+	t.update()
+}
+
+// stop prevents onIdle from being called from now on. A call to onIdle
+// already under way completes before stop returns.
+func (t *idleTracker) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// update starts the idle timer when few enough requests are in flight, and
+// stops it when too many are. t.mu must be held.
+func (t *idleTracker) update() {
+	if !t.started || t.stopped {
+		return
+	}
+	if len(t.inflight) > t.maxInflight {
+		if t.timer != nil {
+			t.timer.Stop()
+			t.timer = nil
+		}
+		return
+	}
+	if t.timer == nil {
+		var timer *time.Timer
+		timer = time.AfterFunc(t.duration, func() { t.fire(timer) })
+		t.timer = timer
+	}
+}
+
+// fire calls onIdle unless it has already been called, t was stopped or
+// timer was stopped after it expired but before fire was called.
+func (t *idleTracker) fire(timer *time.Timer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.idle || t.stopped || t.timer != timer {
+		return
+	}
+	t.idle = true
+	t.onIdle()
+}
@@ -0,0 +1,175 @@
+package capture
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+)
+
+// Mock registers a canned response or rewrite rule for requests whose URL
+// matches URLPattern. Exchanges handled by a Mock still appear in the HAR,
+// flagged with the custom field "_mocked".
+type Mock struct {
+	// URLPattern is matched against the full request URL. '*' matches zero or
+	// more characters and '?' matches exactly one.
+	URLPattern string
+
+	// Rewrite controls how the rule is applied. When false the request never
+	// leaves the browser and is fulfilled with the canned response below. When
+	// true the request reaches the origin and the non-zero fields below
+	// replace those of the real response.
+	Rewrite bool
+
+	// Status is the response status code. Defaults to 200 for canned
+	// responses; for rewrites zero keeps the origin's status.
+	Status int
+
+	// Headers are set on the response, replacing any origin header of the
+	// same name when rewriting.
+	Headers map[string]string
+
+	// Body is the response body. For rewrites nil keeps the origin's body.
+	Body []byte
+}
+
+// interceptor applies Mocks to requests paused by the Fetch domain.
+type interceptor struct {
+	mocks []Mock
+	store *requestStore
+}
+
+// enable returns the action that turns on request interception for every
+// registered pattern, at the stage appropriate to each rule.
+func (i *interceptor) enable() chromedp.Action {
+	patterns := make([]*fetch.RequestPattern, 0, len(i.mocks))
+	for _, m := range i.mocks {
+		stage := fetch.RequestStageRequest
+		if m.Rewrite {
+			stage = fetch.RequestStageResponse
+		}
+		patterns = append(patterns, &fetch.RequestPattern{
+			URLPattern:   m.URLPattern,
+			RequestStage: stage,
+		})
+	}
+	return fetch.Enable().WithPatterns(patterns)
+}
+
+// handle resolves a paused request. It must not be called from the CDP
+// listener goroutine directly, since it issues CDP commands of its own.
+func (i *interceptor) handle(ctx context.Context, ev *fetch.EventRequestPaused) {
+	// A request is paused at the response stage when the status code or an
+	// error reason has been reported.
+	responseStage := ev.ResponseStatusCode != 0 || ev.ResponseErrorReason != ""
+
+	m, ok := i.match(ev.Request.URL, responseStage)
+	if !ok {
+		_ = chromedp.Run(ctx, fetch.ContinueRequest(ev.RequestID))
+		return
+	}
+
+	if ev.NetworkID != "" {
+		i.store.markMocked(ev.NetworkID)
+	}
+
+	if !m.Rewrite {
+		status := m.Status
+		if status == 0 {
+			status = 200
+		}
+		_ = chromedp.Run(ctx, fetch.FulfillRequest(ev.RequestID, int64(status)).
+			WithResponseHeaders(mergeHeaders(nil, m.Headers)).
+			WithBody(base64.StdEncoding.EncodeToString(m.Body)))
+		return
+	}
+
+	if ev.ResponseErrorReason != "" {
+		// There is no response to rewrite; let the failure propagate.
+		_ = chromedp.Run(ctx, fetch.ContinueRequest(ev.RequestID))
+		return
+	}
+
+	status := ev.ResponseStatusCode
+	if m.Status != 0 {
+		status = int64(m.Status)
+	}
+	headers := mergeHeaders(ev.ResponseHeaders, m.Headers)
+
+	if m.Body == nil {
+		_ = chromedp.Run(ctx, fetch.ContinueResponse(ev.RequestID).
+			WithResponseCode(status).
+			WithResponseHeaders(headers))
+		return
+	}
+	_ = chromedp.Run(ctx, fetch.FulfillRequest(ev.RequestID, status).
+		WithResponseHeaders(headers).
+		WithBody(base64.StdEncoding.EncodeToString(m.Body)))
+}
+
+// match returns the first Mock whose pattern matches url and whose stage
+// agrees with the stage at which the request was paused.
+func (i *interceptor) match(url string, responseStage bool) (Mock, bool) {
+	for _, m := range i.mocks {
+		if m.Rewrite == responseStage && matchWildcard(m.URLPattern, url) {
+			return m, true
+		}
+	}
+	return Mock{}, false
+}
+
+// mergeHeaders overlays overrides onto base, replacing headers of the same
+// name case-insensitively.
+func mergeHeaders(base []*fetch.HeaderEntry, overrides map[string]string) []*fetch.HeaderEntry {
+	merged := make([]*fetch.HeaderEntry, 0, len(base)+len(overrides))
+	for _, h := range base {
+		if _, ok := lookupHeader(overrides, h.Name); ok {
+			continue
+		}
+		merged = append(merged, h)
+	}
+	for name, value := range overrides {
+		merged = append(merged, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return merged
+}
+
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// matchWildcard reports whether s matches pattern, where '*' matches zero or
+// more characters and '?' matches exactly one. Unlike path.Match, '*' also
+// matches '/', which makes it suitable for whole URLs.
+func matchWildcard(pattern, s string) bool {
+	// Classic greedy matching with backtracking to the most recent '*'.
+	p, i := 0, 0
+	star, mark := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, i
+			p++
+		case star >= 0:
+			p = star + 1
+			mark++
+			i = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,195 @@
+package capture
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+)
+
+// Metrics summarises the network activity and load timings of a capture. It
+// covers every request made by the page, including any filtered from the
+// HAR, so that consumers need not derive the figures themselves. Times are in
+// milliseconds.
+type Metrics struct {
+	// Requests is the number of requests issued, counting each redirect.
+	Requests int `json:"requests"`
+
+	// TransferredBytes is the total size of all responses as transferred
+	// over the network, including headers and before decompression.
+	TransferredBytes int64 `json:"transferred_bytes"`
+
+	// TransferredBytesByType breaks TransferredBytes down by resource type,
+	// e.g. "Script" or "Image".
+	TransferredBytesByType map[network.ResourceType]int64 `json:"transferred_bytes_by_type"`
+
+	// TotalTime spans from the first request being sent to the last response
+	// finishing loading.
+	TotalTime float64 `json:"total_time_ms"`
+
+	// DOMContentLoaded and OnLoad are when those events fired in the main
+	// document, relative to the start of its navigation. When navigations are
+	// followed they describe the last page loaded. Zero if they never fired.
+	DOMContentLoaded float64 `json:"dom_content_loaded_ms"`
+	OnLoad           float64 `json:"on_load_ms"`
+
+	// FirstPaint and FirstContentfulPaint are when the main document first
+	// rendered anything and first rendered content, relative to the start of
+	// its navigation. Zero if they never occurred.
+	FirstPaint           float64 `json:"first_paint_ms"`
+	FirstContentfulPaint float64 `json:"first_contentful_paint_ms"`
+
+	// SpeedIndex approximates how quickly the visible content of the page
+	// was populated, from the screencast. Lower is better. Zero unless
+	// Options.Screencast was set.
+	SpeedIndex float64 `json:"speed_index_ms"`
+}
+
+// metricsRecorder accumulates Metrics from network and lifecycle events.
+type metricsRecorder struct {
+	mu sync.Mutex
+
+	// types remembers the resource type of each request until it finishes
+	// loading, since loadingFinished does not repeat it.
+	types map[network.RequestID]network.ResourceType
+
+	requests    int
+	transferred int64
+	byType      map[network.ResourceType]int64
+	first, last time.Time
+
+	// navigationStart is on the monotonic clock of the other timings, while
+	// navigationWallTime is the same instant on the wall clock, which
+	// screencast frames are timestamped against.
+	navigationStart      time.Time
+	navigationWallTime   time.Time
+	domContentLoaded     time.Time
+	load                 time.Time
+	firstPaint           time.Time
+	firstContentfulPaint time.Time
+}
+
+func newMetricsRecorder() *metricsRecorder {
+	return &metricsRecorder{
+		types:  make(map[network.RequestID]network.ResourceType),
+		byType: make(map[network.ResourceType]int64),
+	}
+}
+
+// requestWillBeSent counts a request. navigation is true when the request
+// starts a new page, from which load timings are measured.
+func (m *metricsRecorder) requestWillBeSent(ev *network.EventRequestWillBeSent, navigation bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	m.types[ev.RequestID] = ev.Type
+
+	ts := monotonic(ev.Timestamp)
+	if m.first.IsZero() || ts.Before(m.first) {
+		m.first = ts
+	}
+	if navigation {
+		m.navigationStart = ts
+		if ev.WallTime != nil {
+			m.navigationWallTime = ev.WallTime.Time()
+		}
+		m.domContentLoaded = time.Time{}
+		m.load = time.Time{}
+		m.firstPaint = time.Time{}
+		m.firstContentfulPaint = time.Time{}
+	}
+}
+
+// loadingFinished adds the transfer size of a completed response.
+func (m *metricsRecorder) loadingFinished(ev *network.EventLoadingFinished) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	size := int64(ev.EncodedDataLength)
+	m.transferred += size
+	m.byType[m.types[ev.RequestID]] += size
+	delete(m.types, ev.RequestID)
+
+	if ts := monotonic(ev.Timestamp); ts.After(m.last) {
+		m.last = ts
+	}
+}
+
+// loadingFailed forgets a request that will not finish loading.
+func (m *metricsRecorder) loadingFailed(ev *network.EventLoadingFailed) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.types, ev.RequestID)
+}
+
+// lifecycleEvent records the load timings of the main document.
+func (m *metricsRecorder) lifecycleEvent(ev *page.EventLifecycleEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch ev.Name {
+	case "DOMContentLoaded":
+		m.domContentLoaded = monotonic(ev.Timestamp)
+	case string(StageDocumentLoad):
+		m.load = monotonic(ev.Timestamp)
+	case "firstPaint":
+		m.firstPaint = monotonic(ev.Timestamp)
+	case string(StageFirstContentfulPaint):
+		m.firstContentfulPaint = monotonic(ev.Timestamp)
+	}
+}
+
+// result returns the metrics recorded so far, with the Speed Index computed
+// from frames if there are any.
+func (m *metricsRecorder) result(frames []Frame) *Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byType := make(map[network.ResourceType]int64, len(m.byType))
+	for t, n := range m.byType {
+		if t == "" {
+			t = network.ResourceTypeOther
+		}
+		byType[t] += n
+	}
+
+	metrics := &Metrics{
+		Requests:               m.requests,
+		TransferredBytes:       m.transferred,
+		TransferredBytesByType: byType,
+		DOMContentLoaded:       milliseconds(sinceStart(m.navigationStart, m.domContentLoaded)),
+		OnLoad:                 milliseconds(sinceStart(m.navigationStart, m.load)),
+		FirstPaint:             milliseconds(sinceStart(m.navigationStart, m.firstPaint)),
+		FirstContentfulPaint:   milliseconds(sinceStart(m.navigationStart, m.firstContentfulPaint)),
+	}
+	if len(frames) > 0 && !m.navigationWallTime.IsZero() {
+		metrics.SpeedIndex = milliseconds(speedIndex(frames, m.navigationWallTime))
+	}
+	if !m.first.IsZero() && m.last.After(m.first) {
+		metrics.TotalTime = milliseconds(m.last.Sub(m.first))
+	}
+	return metrics
+}
+
+// sinceStart returns the time from start to t, or zero if either is unknown.
+func sinceStart(start, t time.Time) time.Duration {
+	if start.IsZero() || t.IsZero() || t.Before(start) {
+		return 0
+	}
+	return t.Sub(start)
+}
+
+// milliseconds returns d as a fractional number of milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func monotonic(t *cdp.MonotonicTime) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time()
+}
@@ -0,0 +1,189 @@
+package capture
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option sets a field of Options. Options not covered by a functional option
+// can be set on the struct returned by NewOptions.
+type Option func(*Options)
+
+// NewOptions returns the Options for a capture of url, with opts applied in
+// order.
+func NewOptions(url string, opts ...Option) Options {
+	o := Options{URL: url}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTimeouts sets Options.NavigationTimeout and Options.TotalTimeout.
+func WithTimeouts(navigation, total time.Duration) Option {
+	return func(o *Options) {
+		o.NavigationTimeout = navigation
+		o.TotalTimeout = total
+	}
+}
+
+// WithViewport sets Options.ViewportWidth and Options.ViewportHeight.
+func WithViewport(width, height int64) Option {
+	return func(o *Options) {
+		o.ViewportWidth = width
+		o.ViewportHeight = height
+	}
+}
+
+// WithUserAgent sets Options.UserAgent.
+func WithUserAgent(userAgent string) Option {
+	return func(o *Options) {
+		o.UserAgent = userAgent
+	}
+}
+
+// WithDevice sets the viewport, scale factor, mobile emulation and user
+// agent to those of d.
+func WithDevice(d Device) Option {
+	return func(o *Options) {
+		o.ViewportWidth = d.Width
+		o.ViewportHeight = d.Height
+		o.DeviceScaleFactor = d.ScaleFactor
+		o.Mobile = d.Mobile
+		o.UserAgent = d.UserAgent
+	}
+}
+
+// WithHeaders adds to Options.Headers.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *Options) {
+		if o.Headers == nil {
+			o.Headers = make(map[string]string, len(headers))
+		}
+		for name, value := range headers {
+			o.Headers[name] = value
+		}
+	}
+}
+
+// WithThrottling sets Options.Throttling.
+func WithThrottling(nc NetworkConditions) Option {
+	return func(o *Options) {
+		o.Throttling = &nc
+	}
+}
+
+// WithCookies adds to Options.Cookies.
+func WithCookies(cookies ...CookieSeed) Option {
+	return func(o *Options) {
+		o.Cookies = append(o.Cookies, cookies...)
+	}
+}
+
+// WithBlockedURLs adds to Options.BlockURLs.
+func WithBlockedURLs(patterns ...string) Option {
+	return func(o *Options) {
+		o.BlockURLs = append(o.BlockURLs, patterns...)
+	}
+}
+
+// WithMocks adds to Options.Mocks.
+func WithMocks(mocks ...Mock) Option {
+	return func(o *Options) {
+		o.Mocks = append(o.Mocks, mocks...)
+	}
+}
+
+// WithActions adds to Options.Actions.
+func WithActions(actions ...Action) Option {
+	return func(o *Options) {
+		o.Actions = append(o.Actions, actions...)
+	}
+}
+
+// WithScreenshots sets Options.Screenshots.
+func WithScreenshots() Option {
+	return func(o *Options) {
+		o.Screenshots = true
+	}
+}
+
+// WithBodies sets Options.CaptureBodies, restricted to the given MIME types
+// and size as Options.BodyMIMEAllowlist and Options.MaxBodyBytes describe.
+func WithBodies(mimeAllowlist []string, maxBytes int64) Option {
+	return func(o *Options) {
+		o.CaptureBodies = true
+		o.BodyMIMEAllowlist = mimeAllowlist
+		o.MaxBodyBytes = maxBytes
+	}
+}
+
+// WithTrace sets Options.Trace.
+func WithTrace() Option {
+	return func(o *Options) {
+		o.Trace = true
+	}
+}
+
+// WithCoverage sets Options.Coverage.
+func WithCoverage() Option {
+	return func(o *Options) {
+		o.Coverage = true
+	}
+}
+
+// WithPDF sets Options.CapturePDF.
+func WithPDF() Option {
+	return func(o *Options) {
+		o.CapturePDF = true
+	}
+}
+
+// WithSnapshot sets Options.CaptureSnapshot.
+func WithSnapshot() Option {
+	return func(o *Options) {
+		o.CaptureSnapshot = true
+	}
+}
+
+// WithFilmstrip sets Options.Screencast.
+func WithFilmstrip() Option {
+	return func(o *Options) {
+		o.Screencast = true
+	}
+}
+
+// WithRuns sets Options.Runs.
+func WithRuns(mode RunMode) Option {
+	return func(o *Options) {
+		o.Runs = mode
+	}
+}
+
+// WithRemoteBrowser sets Options.RemoteDebuggingURL.
+func WithRemoteBrowser(url string) Option {
+	return func(o *Options) {
+		o.RemoteDebuggingURL = url
+	}
+}
+
+// WithChromePath sets Options.ChromePath.
+func WithChromePath(path string) Option {
+	return func(o *Options) {
+		o.ChromePath = path
+	}
+}
+
+// WithHooks sets Options.Hooks.
+func WithHooks(hooks Hooks) Option {
+	return func(o *Options) {
+		o.Hooks = hooks
+	}
+}
+
+// WithLogger sets Options.Logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
@@ -0,0 +1,87 @@
+package capture
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/har"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+)
+
+// pageTracker follows navigations of the main frame, both full document loads
+// and soft navigations via the History API, so that each produces its own
+// har.Page and subsequent entries are attributed to the page current at the
+// time they were requested.
+type pageTracker struct {
+	mu        sync.Mutex
+	mainFrame cdp.FrameID
+	loader    cdp.LoaderID
+	current   string
+	count     int
+
+	// onNavigate, if set, is called with the number of navigations observed
+	// after the initial page load each time a further navigation occurs.
+	onNavigate func(n int)
+}
+
+// request returns the page ref to attribute ev to. If ev starts a new
+// main-frame document load, the corresponding har.Page is returned too.
+func (t *pageTracker) request(ev *network.EventRequestWillBeSent) (string, *har.Page) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// A redirect reuses the request ID of the navigation it continues, so it
+	// is not a new page.
+	isNavigation := ev.Type == network.ResourceTypeDocument &&
+		ev.RedirectResponse == nil &&
+		(t.mainFrame == "" || ev.FrameID == t.mainFrame)
+	if !isNavigation {
+		return t.current, nil
+	}
+
+	t.mainFrame = ev.FrameID
+	t.loader = ev.LoaderID
+	return t.next(ev.WallTime.Time(), ev.Request.URL)
+}
+
+// navigatedWithinDocument handles a soft navigation, returning the new page
+// or nil if the event belongs to a subframe.
+func (t *pageTracker) navigatedWithinDocument(ev *page.EventNavigatedWithinDocument) *har.Page {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.mainFrame == "" || ev.FrameID != t.mainFrame {
+		return nil
+	}
+	_, p := t.next(time.Now(), ev.URL)
+	return p
+}
+
+// isMainDocument reports whether a lifecycle event belongs to the document
+// currently loaded in the main frame. Events for subframes and for the blank
+// page that precedes the first navigation are excluded.
+func (t *pageTracker) isMainDocument(ev *page.EventLifecycleEvent) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loader != "" && ev.LoaderID == t.loader
+}
+
+// next starts a new page. t.mu must be held.
+func (t *pageTracker) next(started time.Time, url string) (string, *har.Page) {
+	t.count++
+	t.current = fmt.Sprintf("page_%d", t.count)
+
+	if t.count > 1 && t.onNavigate != nil {
+		t.onNavigate(t.count - 1)
+	}
+
+	return t.current, &har.Page{
+		ID:              t.current,
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Title:           url,
+		PageTimings:     &har.PageTimings{},
+	}
+}
@@ -0,0 +1,128 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPoolClosed is returned by Pool.Capture once the pool has been closed.
+var ErrPoolClosed = errors.New("capture: pool is closed")
+
+// Pool keeps a fixed number of browsers running so that captures do not pay
+// for a browser launch each time. Every capture runs in its own incognito
+// browser context, isolating cookies, cache and storage from other captures
+// sharing the same browser.
+//
+// A Pool is safe for concurrent use. Captures beyond the pool size wait for
+// a browser to become free.
+type Pool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	opts   Options
+	idle   chan *browserInstance
+}
+
+// NewPool starts size browsers configured from the browser-level fields of
+// opts, such as RemoteDebuggingURL and ChromeFlags; all other fields are
+// ignored. The browsers run until Close is called or ctx is done.
+func NewPool(ctx context.Context, size int, opts Options) (*Pool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("capture: pool size must be at least 1, got %d", size)
+	}
+	if opts.UserDataDir != "" {
+		return nil, fmt.Errorf("capture: UserDataDir cannot be used with a Pool")
+	}
+	if len(opts.Extensions) > 0 {
+		return nil, fmt.Errorf("capture: Extensions cannot be used with a Pool")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pool{
+		ctx:    ctx,
+		cancel: cancel,
+		opts:   opts,
+		idle:   make(chan *browserInstance, size),
+	}
+
+	for range size {
+		b, err := startBrowser(ctx, opts)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.idle <- b
+	}
+	return p, nil
+}
+
+// Capture behaves like the package-level Capture but runs in a browser taken
+// from the pool, blocking until one is free or ctx is done. Browser-level
+// fields of opts are ignored in favour of those the pool was created with.
+func (p *Pool) Capture(ctx context.Context, opts Options) (*Result, error) {
+	b, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(b)
+
+	return captureRuns(ctx, opts, func(ctx context.Context) (context.Context, context.CancelFunc, error) {
+		tabCtx, cancelTab := b.newTab(ctx)
+		return tabCtx, cancelTab, nil
+	}, nil)
+}
+
+// Close shuts down every browser in the pool. Captures in progress are
+// cancelled, and subsequent calls to Capture return ErrPoolClosed.
+func (p *Pool) Close() {
+	p.cancel()
+	for {
+		select {
+		case b := <-p.idle:
+			b.cancel()
+		default:
+			return
+		}
+	}
+}
+
+// acquire takes an idle browser from the pool, replacing it first if it has
+// crashed since it was last used.
+func (p *Pool) acquire(ctx context.Context) (*browserInstance, error) {
+	var b *browserInstance
+	select {
+	case b = <-p.idle:
+	case <-p.ctx.Done():
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if p.ctx.Err() != nil {
+		b.cancel()
+		return nil, ErrPoolClosed
+	}
+	if b.alive() {
+		return b, nil
+	}
+
+	b.cancel()
+	replacement, err := startBrowser(p.ctx, p.opts)
+	if err != nil {
+		// Return the dead browser so that the slot is not lost; the next
+		// acquire will attempt the restart again.
+		p.idle <- b
+		return nil, err
+	}
+	return replacement, nil
+}
+
+// release returns b to the pool, or shuts it down if the pool has been
+// closed in the meantime.
+func (p *Pool) release(b *browserInstance) {
+	if p.ctx.Err() != nil {
+		b.cancel()
+		return
+	}
+	p.idle <- b
+}
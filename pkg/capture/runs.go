@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// RunMode selects how many times a URL is captured, and with what state.
+type RunMode string
+
+const (
+	// RunCold captures the URL once in a fresh browser context. This is the
+	// default.
+	RunCold RunMode = "cold"
+
+	// RunColdWarm captures the URL twice in the same browser context: first
+	// with an empty cache, then again with the cache, cookies and storage
+	// left by the first run. The second capture is returned in Result.Warm.
+	RunColdWarm RunMode = "cold-warm"
+)
+
+// captureRuns performs the runs of opts.Runs, each as capture does, in tabs
+// opened alongside the one returned by open.
+func captureRuns(ctx context.Context, opts Options, open tabOpener, stream func(Entry)) (*Result, error) {
+	switch opts.Runs {
+	case "", RunCold:
+		return capture(ctx, opts, open, stream)
+	case RunColdWarm:
+	default:
+		return nil, fmt.Errorf("capture: unknown run mode %q", opts.Runs)
+	}
+
+	var sharedCtx context.Context
+	cancelShared := func() {}
+	defer func() { cancelShared() }()
+
+	// The first tab opened holds the browser context shared by both runs.
+	// Each run then has a tab of its own in that browser context, so that
+	// closing it leaves the cache intact for the next. The first tab must
+	// outlive the run that opens it, so is not bound to its context.
+	inShared := func(ctx context.Context) (context.Context, context.CancelFunc, error) {
+		if sharedCtx == nil {
+			tabCtx, cancelTab, err := open(context.WithoutCancel(ctx))
+			if err != nil {
+				return nil, nil, err
+			}
+			cancelShared = cancelTab
+
+			// Running the tab creates its browser context. The cache may
+			// outlive browser contexts in a user profile, so is cleared
+			// explicitly.
+			if err := chromedp.Run(tabCtx, network.ClearBrowserCache()); err != nil {
+				return nil, nil, fmt.Errorf("capture: failed to prepare browser context: %w", err)
+			}
+			sharedCtx = tabCtx
+		}
+
+		tabCtx, cancelTab := chromedp.NewContext(sharedCtx)
+		stop := context.AfterFunc(ctx, cancelTab)
+		return tabCtx, func() {
+			stop()
+			cancelTab()
+		}, nil
+	}
+
+	cold, err := capture(ctx, opts, inShared, stream)
+	if err != nil {
+		return cold, err
+	}
+	warm, err := capture(ctx, opts, inShared, stream)
+	if err != nil {
+		return cold, err
+	}
+	cold.Warm = warm
+	return cold, nil
+}
@@ -0,0 +1,89 @@
+package capture
+
+import (
+	"context"
+	"encoding/base64"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Frame is a JPEG image of the viewport recorded by the screencast.
+type Frame struct {
+	CapturedAt time.Time
+	JPEG       []byte
+}
+
+// screencastQuality is the JPEG compression quality of filmstrip frames,
+// balancing legibility against artefact size.
+const screencastQuality = 60
+
+// screencastRecorder records frames emitted by Page.startScreencast. Chrome
+// only emits a frame when the rendered output changes, and withholds further
+// frames until the previous one has been acknowledged.
+type screencastRecorder struct {
+	wg sync.WaitGroup
+
+	// mu guards every field below. Frames delivered once stopped is set are
+	// discarded, so that none are added to wg while stop waits on it.
+	mu      sync.Mutex
+	stopped bool
+	frames  []Frame
+}
+
+// start returns the action that begins the screencast.
+func (r *screencastRecorder) start() chromedp.Action {
+	return page.StartScreencast().
+		WithFormat(page.ScreencastFormatJpeg).
+		WithQuality(screencastQuality)
+}
+
+// frame records a screencast frame and acknowledges it so that the next one
+// is sent. Safe to call from the CDP listener goroutine.
+func (r *screencastRecorder) frame(ctx context.Context, ev *page.EventScreencastFrame) {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	// Acknowledging issues a CDP command, which must not block the listener.
+	r.wg.Add(1)
+	r.mu.Unlock()
+	go func() {
+		defer r.wg.Done()
+		_ = chromedp.Run(ctx, page.ScreencastFrameAck(ev.SessionID))
+	}()
+
+	buf, err := base64.StdEncoding.DecodeString(ev.Data)
+	if err != nil {
+		return
+	}
+
+	capturedAt := time.Now()
+	if ev.Metadata != nil && ev.Metadata.Timestamp != nil {
+		capturedAt = ev.Metadata.Timestamp.Time()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.stopped {
+		r.frames = append(r.frames, Frame{CapturedAt: capturedAt, JPEG: buf})
+	}
+}
+
+// stop ends the screencast and returns the frames recorded, in the order
+// they were rendered.
+func (r *screencastRecorder) stop(ctx context.Context) []Frame {
+	_ = chromedp.Run(ctx, page.StopScreencast())
+
+	r.mu.Lock()
+	r.stopped = true
+	frames := slices.Clone(r.frames)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return frames
+}
@@ -0,0 +1,53 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// maxScrollSteps bounds auto-scrolling on pages with infinite scroll, which
+// would otherwise keep growing until TotalTimeout elapses.
+const maxScrollSteps = 100
+
+// scrollToBottom scrolls the page down by step pixels every delay until the
+// bottom of the document is reached, then waits for any images brought into
+// view to finish loading. This ensures lazily loaded resources are fetched
+// and recorded before the capture completes.
+func scrollToBottom(ctx context.Context, step int64, delay time.Duration) error {
+	for i := 0; i < maxScrollSteps; i++ {
+		var atBottom bool
+		expr := fmt.Sprintf(`(() => {
+			window.scrollBy(0, %d);
+			const el = document.scrollingElement || document.documentElement;
+			return window.scrollY + window.innerHeight >= el.scrollHeight;
+		})()`, step)
+		if err := chromedp.Run(ctx, chromedp.Evaluate(expr, &atBottom)); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if atBottom {
+			break
+		}
+	}
+
+	// Images that entered the viewport may still be downloading; wait for them
+	// so their entries are complete.
+	const awaitImages = `Promise.all(Array.from(document.images)
+		.filter(img => !img.complete)
+		.map(img => new Promise(resolve => { img.onload = img.onerror = resolve; })))`
+	return chromedp.Run(ctx, chromedp.Evaluate(awaitImages, nil,
+		func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		},
+	))
+}
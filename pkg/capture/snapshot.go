@@ -0,0 +1,37 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// printPDF renders the current page state to PDF, including background
+// graphics so that the document resembles what was displayed on screen.
+func printPDF(ctx context.Context) ([]byte, error) {
+	var buf []byte
+	if err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, _, err = page.PrintToPDF().WithPrintBackground(true).Do(ctx)
+		return err
+	})); err != nil {
+		return nil, fmt.Errorf("capture: failed to print PDF: %w", err)
+	}
+	return buf, nil
+}
+
+// captureSnapshot serialises the rendered page, with its stylesheets, images
+// and frames, into a single MHTML document.
+func captureSnapshot(ctx context.Context) ([]byte, error) {
+	var data string
+	if err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		data, err = page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+		return err
+	})); err != nil {
+		return nil, fmt.Errorf("capture: failed to capture snapshot: %w", err)
+	}
+	return []byte(data), nil
+}
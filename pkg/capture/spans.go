@@ -0,0 +1,21 @@
+package capture
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelTracer records the stages of each capture as OpenTelemetry spans, as
+// children of any span in the context passed to Capture. Spans are discarded
+// unless the application has installed a tracer provider.
+var otelTracer = otel.Tracer("github.com/tomasbasham/har-capture/pkg/capture")
+
+// endSpan ends span, recording err, if any, as its status.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"time"
+)
+
+// histogram counts the pixels of an image at each intensity, per channel.
+type histogram [3][256]int
+
+// speedIndex approximates the Speed Index of a page load from its
+// screencast frames: the area above the visual completeness curve, from
+// start until the last frame. Visual completeness is measured, as by
+// Speedline, as how far each frame's colour histogram has moved from that of
+// the first frame towards that of the last. The page is treated as blank
+// until the first frame is rendered. Zero if there are no frames after start.
+func speedIndex(frames []Frame, start time.Time) time.Duration {
+	var times []time.Time
+	var hists []histogram
+	for _, f := range frames {
+		if f.CapturedAt.Before(start) {
+			continue
+		}
+		img, err := jpeg.Decode(bytes.NewReader(f.JPEG))
+		if err != nil {
+			continue
+		}
+		times = append(times, f.CapturedAt)
+		hists = append(hists, colourHistogram(img))
+	}
+	if len(hists) == 0 {
+		return 0
+	}
+
+	first, last := hists[0], hists[len(hists)-1]
+	total := histogramDistance(first, last)
+
+	// Until the first frame nothing has been rendered.
+	index := times[0].Sub(start)
+	for i := 0; i < len(hists)-1; i++ {
+		complete := 1.0
+		if total > 0 {
+			complete = 1 - float64(histogramDistance(hists[i], last))/float64(total)
+		}
+		complete = min(max(complete, 0), 1)
+		index += time.Duration((1 - complete) * float64(times[i+1].Sub(times[i])))
+	}
+	return index
+}
+
+func colourHistogram(img image.Image) histogram {
+	var h histogram
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			h[0][r>>8]++
+			h[1][g>>8]++
+			h[2][bl>>8]++
+		}
+	}
+	return h
+}
+
+func histogramDistance(a, b histogram) int {
+	d := 0
+	for c := range a {
+		for i := range a[c] {
+			diff := a[c][i] - b[c][i]
+			if diff < 0 {
+				diff = -diff
+			}
+			d += diff
+		}
+	}
+	return d
+}
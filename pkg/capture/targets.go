@@ -0,0 +1,189 @@
+package capture
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// childTargetTypes are the kinds of target whose network traffic belongs to
+// the page but is reported on a target of its own: out-of-process iframes
+// and workers.
+var childTargetTypes = map[string]bool{
+	"iframe":         true,
+	"worker":         true,
+	"shared_worker":  true,
+	"service_worker": true,
+}
+
+// childTargets attaches to the targets spawned by a page so that their events
+// reach the same handler as the page's own. chromedp auto-attaches to such
+// targets but discards their events, so each is attached to again through a
+// context of its own.
+//
+// Targets are attached to without pausing them, so requests a target makes
+// before the attachment completes are not seen.
+//
+// chromedp closes the target of a context once the context is cancelled,
+// including by its parent. A child target belongs to the page, and a service
+// worker to every page of its origin, so the contexts do not inherit the
+// cancellation of the page's and are detached from by close instead.
+type childTargets struct {
+	// browserContextID is that of the page, resolved on first use if the
+	// page was opened in the default browser context. Targets are only
+	// attached to when they share it, as service workers are discovered
+	// browser-wide.
+	browserContextID cdp.BrowserContextID
+	resolveOnce      sync.Once
+
+	// handle receives the events of every child target, along with the
+	// context of the target that emitted them.
+	handle func(ctx context.Context, ev any)
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	closed   bool
+	attached map[target.ID]bool
+	children []childTarget
+}
+
+// childTarget is the context of an attached child target.
+type childTarget struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newChildTargets(tabCtx context.Context, handle func(ctx context.Context, ev any), logger *slog.Logger) *childTargets {
+	c := &childTargets{
+		handle:   handle,
+		logger:   logger,
+		attached: make(map[target.ID]bool),
+	}
+	if cc := chromedp.FromContext(tabCtx); cc != nil {
+		c.browserContextID = cc.BrowserContextID
+	}
+	return c
+}
+
+// attach starts listening to the target described by info if it is a child
+// of the page not already attached to. It must not be called from the CDP
+// listener goroutine directly, since attaching issues CDP commands.
+func (c *childTargets) attach(tabCtx context.Context, info *target.Info) {
+	if info == nil || !childTargetTypes[info.Type] {
+		return
+	}
+	if info.BrowserContextID != c.pageBrowserContext(tabCtx) {
+		return
+	}
+
+	c.mu.Lock()
+	if c.closed || c.attached[info.TargetID] {
+		c.mu.Unlock()
+		return
+	}
+	c.attached[info.TargetID] = true
+	c.mu.Unlock()
+
+	parent, cancelParent := detachedContext(tabCtx)
+	ctx, cancel := chromedp.NewContext(parent, chromedp.WithTargetID(info.TargetID))
+	child := childTarget{ctx: ctx, cancel: func() {
+		cancel()
+		cancelParent()
+	}}
+
+	chromedp.ListenTarget(ctx, func(ev any) {
+		c.handle(ctx, ev)
+	})
+
+	// Running an empty action list attaches to the target and enables its
+	// network domain. A target that has already gone away is ignored.
+	if err := chromedp.Run(ctx); err != nil {
+		c.logger.Debug("failed to attach to child target", "type", info.Type, "target_url", info.URL, "error", err)
+	}
+
+	c.mu.Lock()
+	closed := c.closed
+	if !closed {
+		c.children = append(c.children, child)
+	}
+	c.mu.Unlock()
+
+	// The page finished while the target was being attached to.
+	if closed {
+		c.release(child)
+	}
+}
+
+// pageBrowserContext returns the ID of the browser context of the page, or
+// an empty ID if it cannot be determined, which no target shares.
+func (c *childTargets) pageBrowserContext(tabCtx context.Context) cdp.BrowserContextID {
+	c.resolveOnce.Do(func() {
+		if c.browserContextID != "" {
+			return
+		}
+		cc := chromedp.FromContext(tabCtx)
+		if cc == nil || cc.Browser == nil || cc.Target == nil {
+			return
+		}
+		info, err := target.GetTargetInfo().WithTargetID(cc.Target.TargetID).Do(cdp.WithExecutor(tabCtx, cc.Browser))
+		if err != nil {
+			c.logger.Debug("failed to read browser context of page", "error", err)
+			return
+		}
+		c.browserContextID = info.BrowserContextID
+	})
+	return c.browserContextID
+}
+
+// close detaches from every child target, leaving the targets running.
+func (c *childTargets) close() {
+	c.mu.Lock()
+	c.closed = true
+	children := c.children
+	c.children = nil
+	c.mu.Unlock()
+
+	for _, child := range children {
+		c.release(child)
+	}
+}
+
+// release detaches from the target of child and cancels its context. Once
+// detached, the context no longer has a target for chromedp to close.
+func (c *childTargets) release(child childTarget) {
+	if cc := chromedp.FromContext(child.ctx); cc != nil && cc.Target != nil {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(child.ctx), detachTimeout)
+		err := target.DetachFromTarget().WithSessionID(cc.Target.SessionID).Do(cdp.WithExecutor(ctx, cc.Browser))
+		cancel()
+		if err != nil {
+			c.logger.Debug("failed to detach from child target", "error", err)
+		}
+		cc.Target = nil
+	}
+	child.cancel()
+}
+
+// detachTimeout bounds detaching from a child target.
+const detachTimeout = time.Second
+
+// detachedContext returns a context with the values of tabCtx but not its
+// cancellation. It is done once cancelled or once the connection to the
+// browser is lost, so that nothing waits on a browser that has gone.
+func detachedContext(tabCtx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(tabCtx))
+	if cc := chromedp.FromContext(tabCtx); cc != nil && cc.Browser != nil {
+		go func() {
+			select {
+			case <-cc.Browser.LostConnection:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
@@ -0,0 +1,77 @@
+package capture
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// NetworkConditions throttle the browser's network to emulate a slower
+// connection.
+type NetworkConditions struct {
+	// Latency is added to every request, from sending it to receiving the
+	// response headers.
+	Latency time.Duration
+
+	// DownloadThroughput and UploadThroughput cap the transfer rate in bytes
+	// per second. Zero disables the cap.
+	DownloadThroughput int64
+	UploadThroughput   int64
+
+	// Offline fails every request as though the network were disconnected.
+	Offline bool
+}
+
+// NetworkPresets are the connection profiles offered by Chrome DevTools,
+// keyed by name.
+var NetworkPresets = map[string]NetworkConditions{
+	"slow-3g": {
+		Latency:            2000 * time.Millisecond,
+		DownloadThroughput: 500 * 1000 / 8 * 80 / 100,
+		UploadThroughput:   500 * 1000 / 8 * 80 / 100,
+	},
+	"fast-3g": {
+		Latency:            563 * time.Millisecond,
+		DownloadThroughput: 1600 * 1000 / 8 * 90 / 100,
+		UploadThroughput:   750 * 1000 / 8 * 90 / 100,
+	},
+	"4g": {
+		Latency:            170 * time.Millisecond,
+		DownloadThroughput: 9000 * 1000 / 8 * 90 / 100,
+		UploadThroughput:   9000 * 1000 / 8 * 90 / 100,
+	},
+	"offline": {
+		Offline: true,
+	},
+}
+
+// emulateNetworkConditions returns the action that applies nc via
+// Network.emulateNetworkConditions.
+func emulateNetworkConditions(nc NetworkConditions) (chromedp.Action, error) {
+	if nc.Latency < 0 || nc.DownloadThroughput < 0 || nc.UploadThroughput < 0 {
+		return nil, fmt.Errorf("capture: network conditions must not be negative")
+	}
+
+	// The protocol treats -1 as unthrottled, whereas 0 would stall every
+	// transfer.
+	download, upload := float64(nc.DownloadThroughput), float64(nc.UploadThroughput)
+	if download == 0 {
+		download = -1
+	}
+	if upload == 0 {
+		upload = -1
+	}
+
+	latency := float64(nc.Latency) / float64(time.Millisecond)
+	return network.EmulateNetworkConditions(nc.Offline, latency, download, upload), nil
+}
+
+// String summarises nc for the HAR creator comment.
+func (nc NetworkConditions) String() string {
+	if nc.Offline {
+		return "offline"
+	}
+	return fmt.Sprintf("latency=%s down=%dB/s up=%dB/s", nc.Latency, nc.DownloadThroughput, nc.UploadThroughput)
+}
@@ -0,0 +1,94 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/cdproto/tracing"
+	"github.com/chromedp/chromedp"
+)
+
+// traceCategories mirrors the categories recorded by the DevTools Performance
+// panel, minus screenshots, which would inflate the trace considerably.
+var traceCategories = []string{
+	"-*",
+	"devtools.timeline",
+	"disabled-by-default-devtools.timeline",
+	"disabled-by-default-devtools.timeline.frame",
+	"disabled-by-default-devtools.timeline.stack",
+	"disabled-by-default-v8.cpu_profiler",
+	"v8.execute",
+	"blink.console",
+	"blink.user_timing",
+	"latencyInfo",
+	"loading",
+	"toplevel",
+}
+
+// tracer records a Chrome performance trace, accumulating the trace events
+// delivered by the Tracing domain until tracing completes.
+type tracer struct {
+	mu     sync.Mutex
+	events [][]byte
+
+	// doneOnce guards done, as tracingComplete may be delivered more than
+	// once.
+	doneOnce sync.Once
+	done     chan struct{}
+}
+
+func newTracer() *tracer {
+	return &tracer{done: make(chan struct{})}
+}
+
+// start returns the action that begins tracing. Trace events are reported
+// back through dataCollected events rather than a stream.
+func (t *tracer) start() chromedp.Action {
+	return tracing.Start().
+		WithTransferMode(tracing.TransferModeReportEvents).
+		WithTraceConfig(&tracing.TraceConfig{
+			RecordMode:         tracing.RecordModeRecordUntilFull,
+			IncludedCategories: traceCategories,
+		})
+}
+
+// dataCollected buffers a batch of trace events. Safe to call from the CDP
+// listener goroutine.
+func (t *tracer) dataCollected(ev *tracing.EventDataCollected) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, v := range ev.Value {
+		t.events = append(t.events, v)
+	}
+}
+
+// complete signals that every buffered event has been delivered. Safe to
+// call more than once.
+func (t *tracer) complete() {
+	t.doneOnce.Do(func() { close(t.done) })
+}
+
+// stop ends tracing and returns the trace in the JSON Object Format
+// understood by Perfetto and the DevTools Performance panel.
+func (t *tracer) stop(ctx context.Context) ([]byte, error) {
+	if err := chromedp.Run(ctx, tracing.End()); err != nil {
+		return nil, fmt.Errorf("capture: failed to end tracing: %w", err)
+	}
+
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("capture: trace incomplete: %w", ctx.Err())
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"traceEvents":[`)
+	buf.Write(bytes.Join(t.events, []byte(",")))
+	buf.WriteString(`]}`)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,120 @@
+package capture
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// WebVitals holds the Core Web Vitals observed during a capture. Times are in
+// milliseconds, as reported by the Performance APIs. Metrics that did not
+// occur (e.g. INP on a page with no interactions) are zero.
+type WebVitals struct {
+	// LCP is the Largest Contentful Paint: when the largest image or text
+	// block in the viewport was rendered, relative to navigation start.
+	LCP float64 `json:"lcp_ms"`
+
+	// CLS is the Cumulative Layout Shift: the largest session window of
+	// unexpected layout shifts.
+	CLS float64 `json:"cls"`
+
+	// INP is the Interaction to Next Paint: the longest latency of any user
+	// interaction, such as those performed by scripted Actions.
+	INP float64 `json:"inp_ms"`
+
+	// FID is the First Input Delay: the delay before the first interaction
+	// was processed.
+	FID float64 `json:"fid_ms"`
+
+	// TBT is the Total Blocking Time: the sum of the portions of long tasks
+	// beyond 50ms that occurred after First Contentful Paint.
+	TBT float64 `json:"tbt_ms"`
+}
+
+// vitalsScript is injected into every document before any page script runs.
+// It observes the performance entries needed to compute the Core Web Vitals
+// and exposes a function returning them in milliseconds.
+const vitalsScript = `(() => {
+	const state = { lcp: 0, cls: 0, inp: 0, fid: 0, fcp: -1, longTasks: [] };
+	let session = 0, sessionFirst = 0, sessionLast = 0;
+
+	const observe = (type, fn, opts) => {
+		try {
+			new PerformanceObserver(list => list.getEntries().forEach(fn))
+				.observe(Object.assign({ type, buffered: true }, opts));
+		} catch (e) {
+			// Entry type unsupported by this browser; the metric stays zero.
+		}
+	};
+
+	observe('paint', e => {
+		if (e.name === 'first-contentful-paint') state.fcp = e.startTime;
+	});
+	observe('largest-contentful-paint', e => {
+		state.lcp = e.renderTime || e.loadTime || e.startTime;
+	});
+	observe('layout-shift', e => {
+		if (e.hadRecentInput) return;
+		if (session && e.startTime - sessionLast < 1000 && e.startTime - sessionFirst < 5000) {
+			session += e.value;
+		} else {
+			session = e.value;
+			sessionFirst = e.startTime;
+		}
+		sessionLast = e.startTime;
+		state.cls = Math.max(state.cls, session);
+	});
+	observe('first-input', e => {
+		state.fid = e.processingStart - e.startTime;
+	});
+	observe('event', e => {
+		if (e.interactionId) state.inp = Math.max(state.inp, e.duration);
+	}, { durationThreshold: 16 });
+	observe('longtask', e => {
+		state.longTasks.push({ start: e.startTime, duration: e.duration });
+	});
+
+	window.__harCaptureVitals = () => ({
+		lcp: state.lcp,
+		cls: state.cls,
+		inp: state.inp,
+		fid: state.fid,
+		tbt: state.longTasks
+			.filter(t => state.fcp >= 0 && t.start >= state.fcp)
+			.reduce((sum, t) => sum + Math.max(0, t.duration - 50), 0),
+	});
+})();`
+
+// injectVitalsObserver returns an action that installs vitalsScript in every
+// document subsequently loaded by the tab.
+func injectVitalsObserver() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(vitalsScript).Do(ctx)
+		return err
+	})
+}
+
+// readWebVitals retrieves the metrics gathered by vitalsScript. It returns
+// nil if the script is not present, e.g. because no document loaded.
+func readWebVitals(ctx context.Context) *WebVitals {
+	var raw *struct {
+		LCP float64 `json:"lcp"`
+		CLS float64 `json:"cls"`
+		INP float64 `json:"inp"`
+		FID float64 `json:"fid"`
+		TBT float64 `json:"tbt"`
+	}
+	const expr = `window.__harCaptureVitals ? window.__harCaptureVitals() : null`
+	if err := chromedp.Run(ctx, chromedp.Evaluate(expr, &raw)); err != nil || raw == nil {
+		return nil
+	}
+
+	return &WebVitals{
+		LCP: raw.LCP,
+		CLS: raw.CLS,
+		INP: raw.INP,
+		FID: raw.FID,
+		TBT: raw.TBT,
+	}
+}
@@ -0,0 +1,1644 @@
+// The HAR capture API over gRPC. It mirrors the HTTP API, and shares its
+// operations: a capture created over one may be retrieved over the other.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: pkg/capturepb/capture.proto
+
+package capturepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Status is the lifecycle stage of a capture operation.
+type Status int32
+
+const (
+	Status_STATUS_UNSPECIFIED Status = 0
+	Status_STATUS_PENDING     Status = 1
+	Status_STATUS_RUNNING     Status = 2
+	Status_STATUS_COMPLETE    Status = 3
+	Status_STATUS_FAILED      Status = 4
+	Status_STATUS_CANCELLING  Status = 5
+	Status_STATUS_CANCELLED   Status = 6
+	Status_STATUS_INTERRUPTED Status = 7
+)
+
+// Enum value maps for Status.
+var (
+	Status_name = map[int32]string{
+		0: "STATUS_UNSPECIFIED",
+		1: "STATUS_PENDING",
+		2: "STATUS_RUNNING",
+		3: "STATUS_COMPLETE",
+		4: "STATUS_FAILED",
+		5: "STATUS_CANCELLING",
+		6: "STATUS_CANCELLED",
+		7: "STATUS_INTERRUPTED",
+	}
+	Status_value = map[string]int32{
+		"STATUS_UNSPECIFIED": 0,
+		"STATUS_PENDING":     1,
+		"STATUS_RUNNING":     2,
+		"STATUS_COMPLETE":    3,
+		"STATUS_FAILED":      4,
+		"STATUS_CANCELLING":  5,
+		"STATUS_CANCELLED":   6,
+		"STATUS_INTERRUPTED": 7,
+	}
+)
+
+func (x Status) Enum() *Status {
+	p := new(Status)
+	*p = x
+	return p
+}
+
+func (x Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_capturepb_capture_proto_enumTypes[0].Descriptor()
+}
+
+func (Status) Type() protoreflect.EnumType {
+	return &file_pkg_capturepb_capture_proto_enumTypes[0]
+}
+
+func (x Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Status.Descriptor instead.
+func (Status) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{0}
+}
+
+type CaptureEvent_Type int32
+
+const (
+	CaptureEvent_TYPE_UNSPECIFIED CaptureEvent_Type = 0
+	// The status of the operation changed.
+	CaptureEvent_TYPE_STATUS CaptureEvent_Type = 1
+	// The page navigated to url.
+	CaptureEvent_TYPE_NAVIGATION CaptureEvent_Type = 2
+	// The capture has collected entries HAR entries.
+	CaptureEvent_TYPE_ENTRIES CaptureEvent_Type = 3
+	// The artefact is being uploaded.
+	CaptureEvent_TYPE_UPLOADING CaptureEvent_Type = 4
+)
+
+// Enum value maps for CaptureEvent_Type.
+var (
+	CaptureEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_STATUS",
+		2: "TYPE_NAVIGATION",
+		3: "TYPE_ENTRIES",
+		4: "TYPE_UPLOADING",
+	}
+	CaptureEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_STATUS":      1,
+		"TYPE_NAVIGATION":  2,
+		"TYPE_ENTRIES":     3,
+		"TYPE_UPLOADING":   4,
+	}
+)
+
+func (x CaptureEvent_Type) Enum() *CaptureEvent_Type {
+	p := new(CaptureEvent_Type)
+	*p = x
+	return p
+}
+
+func (x CaptureEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CaptureEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_capturepb_capture_proto_enumTypes[1].Descriptor()
+}
+
+func (CaptureEvent_Type) Type() protoreflect.EnumType {
+	return &file_pkg_capturepb_capture_proto_enumTypes[1]
+}
+
+func (x CaptureEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CaptureEvent_Type.Descriptor instead.
+func (CaptureEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{14, 0}
+}
+
+type CreateCaptureRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// URL of the page to capture.
+	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// Bounds on navigation and on the whole capture, at most 10 minutes.
+	NavigationTimeout *durationpb.Duration `protobuf:"bytes,2,opt,name=navigation_timeout,json=navigationTimeout,proto3" json:"navigation_timeout,omitempty"`
+	TotalTimeout      *durationpb.Duration `protobuf:"bytes,3,opt,name=total_timeout,json=totalTimeout,proto3" json:"total_timeout,omitempty"`
+	// Take screenshots at each lifecycle stage.
+	Screenshots bool `protobuf:"varint,4,opt,name=screenshots,proto3" json:"screenshots,omitempty"`
+	// Device preset, such as "iphone-14". viewport and user_agent, when also
+	// given, take precedence.
+	Device         string    `protobuf:"bytes,5,opt,name=device,proto3" json:"device,omitempty"`
+	Viewport       *Viewport `protobuf:"bytes,6,opt,name=viewport,proto3" json:"viewport,omitempty"`
+	UserAgent      string    `protobuf:"bytes,7,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	AcceptLanguage string    `protobuf:"bytes,8,opt,name=accept_language,json=acceptLanguage,proto3" json:"accept_language,omitempty"`
+	// Headers sent with every request the page makes.
+	Headers    map[string]string `protobuf:"bytes,9,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Cookies    []*Cookie         `protobuf:"bytes,10,rep,name=cookies,proto3" json:"cookies,omitempty"`
+	Throttling *Throttling       `protobuf:"bytes,11,opt,name=throttling,proto3" json:"throttling,omitempty"`
+	// URL patterns the browser refuses to load; "*" is a wildcard.
+	BlockUrls []string `protobuf:"bytes,12,rep,name=block_urls,json=blockUrls,proto3" json:"block_urls,omitempty"`
+	Bodies    *Bodies  `protobuf:"bytes,13,opt,name=bodies,proto3" json:"bodies,omitempty"`
+	Wait      *Wait    `protobuf:"bytes,14,opt,name=wait,proto3" json:"wait,omitempty"`
+	// Names the request, so that repeating it returns the operation it
+	// created rather than starting another.
+	IdempotencyKey string `protobuf:"bytes,15,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// Sent the operation, with an HTTP POST, once it has finished.
+	CallbackUrl string `protobuf:"bytes,16,opt,name=callback_url,json=callbackUrl,proto3" json:"callback_url,omitempty"`
+	// Named set of options defined by the server, applied before the other
+	// options of the request, which take precedence.
+	Profile       string `protobuf:"bytes,17,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCaptureRequest) Reset() {
+	*x = CreateCaptureRequest{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCaptureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCaptureRequest) ProtoMessage() {}
+
+func (x *CreateCaptureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCaptureRequest.ProtoReflect.Descriptor instead.
+func (*CreateCaptureRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateCaptureRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CreateCaptureRequest) GetNavigationTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.NavigationTimeout
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetTotalTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.TotalTimeout
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetScreenshots() bool {
+	if x != nil {
+		return x.Screenshots
+	}
+	return false
+}
+
+func (x *CreateCaptureRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *CreateCaptureRequest) GetViewport() *Viewport {
+	if x != nil {
+		return x.Viewport
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *CreateCaptureRequest) GetAcceptLanguage() string {
+	if x != nil {
+		return x.AcceptLanguage
+	}
+	return ""
+}
+
+func (x *CreateCaptureRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetCookies() []*Cookie {
+	if x != nil {
+		return x.Cookies
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetThrottling() *Throttling {
+	if x != nil {
+		return x.Throttling
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetBlockUrls() []string {
+	if x != nil {
+		return x.BlockUrls
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetBodies() *Bodies {
+	if x != nil {
+		return x.Bodies
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetWait() *Wait {
+	if x != nil {
+		return x.Wait
+	}
+	return nil
+}
+
+func (x *CreateCaptureRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *CreateCaptureRequest) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+func (x *CreateCaptureRequest) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+type Viewport struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Width             int64                  `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height            int64                  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	DeviceScaleFactor float64                `protobuf:"fixed64,3,opt,name=device_scale_factor,json=deviceScaleFactor,proto3" json:"device_scale_factor,omitempty"`
+	Mobile            bool                   `protobuf:"varint,4,opt,name=mobile,proto3" json:"mobile,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Viewport) Reset() {
+	*x = Viewport{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Viewport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Viewport) ProtoMessage() {}
+
+func (x *Viewport) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Viewport.ProtoReflect.Descriptor instead.
+func (*Viewport) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Viewport) GetWidth() int64 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Viewport) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Viewport) GetDeviceScaleFactor() float64 {
+	if x != nil {
+		return x.DeviceScaleFactor
+	}
+	return 0
+}
+
+func (x *Viewport) GetMobile() bool {
+	if x != nil {
+		return x.Mobile
+	}
+	return false
+}
+
+type Cookie struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Name     string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value    string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Domain   string                 `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
+	Path     string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	Url      string                 `protobuf:"bytes,5,opt,name=url,proto3" json:"url,omitempty"`
+	Secure   bool                   `protobuf:"varint,6,opt,name=secure,proto3" json:"secure,omitempty"`
+	HttpOnly bool                   `protobuf:"varint,7,opt,name=http_only,json=httpOnly,proto3" json:"http_only,omitempty"`
+	// Strict, Lax or None.
+	SameSite      string                 `protobuf:"bytes,8,opt,name=same_site,json=sameSite,proto3" json:"same_site,omitempty"`
+	ExpireTime    *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=expire_time,json=expireTime,proto3" json:"expire_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cookie) Reset() {
+	*x = Cookie{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cookie) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cookie) ProtoMessage() {}
+
+func (x *Cookie) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cookie.ProtoReflect.Descriptor instead.
+func (*Cookie) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Cookie) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Cookie) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Cookie) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *Cookie) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Cookie) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Cookie) GetSecure() bool {
+	if x != nil {
+		return x.Secure
+	}
+	return false
+}
+
+func (x *Cookie) GetHttpOnly() bool {
+	if x != nil {
+		return x.HttpOnly
+	}
+	return false
+}
+
+func (x *Cookie) GetSameSite() string {
+	if x != nil {
+		return x.SameSite
+	}
+	return ""
+}
+
+func (x *Cookie) GetExpireTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpireTime
+	}
+	return nil
+}
+
+// Throttling emulates network conditions, either a preset or explicit ones.
+type Throttling struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of "slow-3g", "fast-3g", "4g" or "offline".
+	Preset  string               `protobuf:"bytes,1,opt,name=preset,proto3" json:"preset,omitempty"`
+	Latency *durationpb.Duration `protobuf:"bytes,2,opt,name=latency,proto3" json:"latency,omitempty"`
+	// Bytes per second; 0 is unlimited.
+	DownloadThroughput int64 `protobuf:"varint,3,opt,name=download_throughput,json=downloadThroughput,proto3" json:"download_throughput,omitempty"`
+	UploadThroughput   int64 `protobuf:"varint,4,opt,name=upload_throughput,json=uploadThroughput,proto3" json:"upload_throughput,omitempty"`
+	Offline            bool  `protobuf:"varint,5,opt,name=offline,proto3" json:"offline,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Throttling) Reset() {
+	*x = Throttling{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Throttling) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Throttling) ProtoMessage() {}
+
+func (x *Throttling) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Throttling.ProtoReflect.Descriptor instead.
+func (*Throttling) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Throttling) GetPreset() string {
+	if x != nil {
+		return x.Preset
+	}
+	return ""
+}
+
+func (x *Throttling) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *Throttling) GetDownloadThroughput() int64 {
+	if x != nil {
+		return x.DownloadThroughput
+	}
+	return 0
+}
+
+func (x *Throttling) GetUploadThroughput() int64 {
+	if x != nil {
+		return x.UploadThroughput
+	}
+	return 0
+}
+
+func (x *Throttling) GetOffline() bool {
+	if x != nil {
+		return x.Offline
+	}
+	return false
+}
+
+// Bodies records response bodies in the HAR.
+type Bodies struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// MIME types whose bodies are recorded; all if empty.
+	MimeTypes []string `protobuf:"bytes,1,rep,name=mime_types,json=mimeTypes,proto3" json:"mime_types,omitempty"`
+	// Largest body recorded; defaults to 1 MiB.
+	MaxBytes      int64 `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bodies) Reset() {
+	*x = Bodies{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bodies) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bodies) ProtoMessage() {}
+
+func (x *Bodies) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bodies.ProtoReflect.Descriptor instead.
+func (*Bodies) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Bodies) GetMimeTypes() []string {
+	if x != nil {
+		return x.MimeTypes
+	}
+	return nil
+}
+
+func (x *Bodies) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+// Wait delays completion until the page reaches some state.
+type Wait struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Selector       string                 `protobuf:"bytes,1,opt,name=selector,proto3" json:"selector,omitempty"`
+	Expression     string                 `protobuf:"bytes,2,opt,name=expression,proto3" json:"expression,omitempty"`
+	ScrollToBottom bool                   `protobuf:"varint,3,opt,name=scroll_to_bottom,json=scrollToBottom,proto3" json:"scroll_to_bottom,omitempty"`
+	// Time without network activity after which the page is idle.
+	IdleDuration  *durationpb.Duration `protobuf:"bytes,4,opt,name=idle_duration,json=idleDuration,proto3" json:"idle_duration,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Wait) Reset() {
+	*x = Wait{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Wait) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Wait) ProtoMessage() {}
+
+func (x *Wait) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Wait.ProtoReflect.Descriptor instead.
+func (*Wait) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Wait) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+func (x *Wait) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *Wait) GetScrollToBottom() bool {
+	if x != nil {
+		return x.ScrollToBottom
+	}
+	return false
+}
+
+func (x *Wait) GetIdleDuration() *durationpb.Duration {
+	if x != nil {
+		return x.IdleDuration
+	}
+	return nil
+}
+
+type GetCaptureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCaptureRequest) Reset() {
+	*x = GetCaptureRequest{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCaptureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCaptureRequest) ProtoMessage() {}
+
+func (x *GetCaptureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCaptureRequest.ProtoReflect.Descriptor instead.
+func (*GetCaptureRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetCaptureRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListCapturesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only operations with this status, if set.
+	Status Status `protobuf:"varint,1,opt,name=status,proto3,enum=harcapture.v1.Status" json:"status,omitempty"`
+	// Only operations whose URL contains this, if set.
+	Url string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// At most 500; defaults to 50.
+	PageSize  int32  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken string `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Only operations created at or after, and before, these times, if set.
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCapturesRequest) Reset() {
+	*x = ListCapturesRequest{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCapturesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCapturesRequest) ProtoMessage() {}
+
+func (x *ListCapturesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCapturesRequest.ProtoReflect.Descriptor instead.
+func (*ListCapturesRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListCapturesRequest) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_STATUS_UNSPECIFIED
+}
+
+func (x *ListCapturesRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *ListCapturesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListCapturesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListCapturesRequest) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *ListCapturesRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+type ListCapturesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Captures      []*Capture             `protobuf:"bytes,1,rep,name=captures,proto3" json:"captures,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCapturesResponse) Reset() {
+	*x = ListCapturesResponse{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCapturesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCapturesResponse) ProtoMessage() {}
+
+func (x *ListCapturesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCapturesResponse.ProtoReflect.Descriptor instead.
+func (*ListCapturesResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListCapturesResponse) GetCaptures() []*Capture {
+	if x != nil {
+		return x.Captures
+	}
+	return nil
+}
+
+func (x *ListCapturesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type WatchCaptureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchCaptureRequest) Reset() {
+	*x = WatchCaptureRequest{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCaptureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCaptureRequest) ProtoMessage() {}
+
+func (x *WatchCaptureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCaptureRequest.ProtoReflect.Descriptor instead.
+func (*WatchCaptureRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchCaptureRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// Capture is a capture operation.
+type Capture struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status     Status                 `protobuf:"varint,2,opt,name=status,proto3,enum=harcapture.v1.Status" json:"status,omitempty"`
+	Url        string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	CreateTime *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=create_time,json=createTime,proto3" json:"create_time,omitempty"`
+	UpdateTime *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=update_time,json=updateTime,proto3" json:"update_time,omitempty"`
+	// Who created the operation, when requests are authenticated.
+	Subject string `protobuf:"bytes,6,opt,name=subject,proto3" json:"subject,omitempty"`
+	// Time to first byte of the main document.
+	Ttfb *durationpb.Duration `protobuf:"bytes,7,opt,name=ttfb,proto3" json:"ttfb,omitempty"`
+	// Whether the capture ran out of time, keeping what it had collected.
+	TimedOut  bool        `protobuf:"varint,8,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`
+	WebVitals *WebVitals  `protobuf:"bytes,9,opt,name=web_vitals,json=webVitals,proto3" json:"web_vitals,omitempty"`
+	Attempts  []*Attempt  `protobuf:"bytes,10,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	Artefacts []*Artefact `protobuf:"bytes,11,rep,name=artefacts,proto3" json:"artefacts,omitempty"`
+	// Why the operation failed.
+	Error string `protobuf:"bytes,12,opt,name=error,proto3" json:"error,omitempty"`
+	// The tenant the operation belongs to, in multi-tenant deployments.
+	Tenant        string `protobuf:"bytes,13,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Capture) Reset() {
+	*x = Capture{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Capture) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capture) ProtoMessage() {}
+
+func (x *Capture) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capture.ProtoReflect.Descriptor instead.
+func (*Capture) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Capture) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Capture) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_STATUS_UNSPECIFIED
+}
+
+func (x *Capture) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Capture) GetCreateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreateTime
+	}
+	return nil
+}
+
+func (x *Capture) GetUpdateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdateTime
+	}
+	return nil
+}
+
+func (x *Capture) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *Capture) GetTtfb() *durationpb.Duration {
+	if x != nil {
+		return x.Ttfb
+	}
+	return nil
+}
+
+func (x *Capture) GetTimedOut() bool {
+	if x != nil {
+		return x.TimedOut
+	}
+	return false
+}
+
+func (x *Capture) GetWebVitals() *WebVitals {
+	if x != nil {
+		return x.WebVitals
+	}
+	return nil
+}
+
+func (x *Capture) GetAttempts() []*Attempt {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+func (x *Capture) GetArtefacts() []*Artefact {
+	if x != nil {
+		return x.Artefacts
+	}
+	return nil
+}
+
+func (x *Capture) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Capture) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+// WebVitals are the Core Web Vitals read from the page.
+type WebVitals struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lcp           *durationpb.Duration   `protobuf:"bytes,1,opt,name=lcp,proto3" json:"lcp,omitempty"`
+	Cls           float64                `protobuf:"fixed64,2,opt,name=cls,proto3" json:"cls,omitempty"`
+	Inp           *durationpb.Duration   `protobuf:"bytes,3,opt,name=inp,proto3" json:"inp,omitempty"`
+	Fid           *durationpb.Duration   `protobuf:"bytes,4,opt,name=fid,proto3" json:"fid,omitempty"`
+	Tbt           *durationpb.Duration   `protobuf:"bytes,5,opt,name=tbt,proto3" json:"tbt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WebVitals) Reset() {
+	*x = WebVitals{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebVitals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebVitals) ProtoMessage() {}
+
+func (x *WebVitals) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebVitals.ProtoReflect.Descriptor instead.
+func (*WebVitals) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WebVitals) GetLcp() *durationpb.Duration {
+	if x != nil {
+		return x.Lcp
+	}
+	return nil
+}
+
+func (x *WebVitals) GetCls() float64 {
+	if x != nil {
+		return x.Cls
+	}
+	return 0
+}
+
+func (x *WebVitals) GetInp() *durationpb.Duration {
+	if x != nil {
+		return x.Inp
+	}
+	return nil
+}
+
+func (x *WebVitals) GetFid() *durationpb.Duration {
+	if x != nil {
+		return x.Fid
+	}
+	return nil
+}
+
+func (x *WebVitals) GetTbt() *durationpb.Duration {
+	if x != nil {
+		return x.Tbt
+	}
+	return nil
+}
+
+type Attempt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Number        int32                  `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	StartTime     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	FinishTime    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=finish_time,json=finishTime,proto3" json:"finish_time,omitempty"`
+	TimedOut      bool                   `protobuf:"varint,4,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Attempt) Reset() {
+	*x = Attempt{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attempt) ProtoMessage() {}
+
+func (x *Attempt) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attempt.ProtoReflect.Descriptor instead.
+func (*Attempt) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Attempt) GetNumber() int32 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *Attempt) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *Attempt) GetFinishTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishTime
+	}
+	return nil
+}
+
+func (x *Attempt) GetTimedOut() bool {
+	if x != nil {
+		return x.TimedOut
+	}
+	return false
+}
+
+func (x *Attempt) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Artefact struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	SignedUrl     string                 `protobuf:"bytes,2,opt,name=signed_url,json=signedUrl,proto3" json:"signed_url,omitempty"`
+	ExpireTime    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expire_time,json=expireTime,proto3" json:"expire_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Artefact) Reset() {
+	*x = Artefact{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Artefact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Artefact) ProtoMessage() {}
+
+func (x *Artefact) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Artefact.ProtoReflect.Descriptor instead.
+func (*Artefact) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Artefact) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Artefact) GetSignedUrl() string {
+	if x != nil {
+		return x.SignedUrl
+	}
+	return ""
+}
+
+func (x *Artefact) GetExpireTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpireTime
+	}
+	return nil
+}
+
+// CaptureEvent reports the progress of an operation.
+type CaptureEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          CaptureEvent_Type      `protobuf:"varint,1,opt,name=type,proto3,enum=harcapture.v1.CaptureEvent_Type" json:"type,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Status        Status                 `protobuf:"varint,3,opt,name=status,proto3,enum=harcapture.v1.Status" json:"status,omitempty"`
+	Url           string                 `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	Entries       int32                  `protobuf:"varint,5,opt,name=entries,proto3" json:"entries,omitempty"`
+	Artefact      string                 `protobuf:"bytes,6,opt,name=artefact,proto3" json:"artefact,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CaptureEvent) Reset() {
+	*x = CaptureEvent{}
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CaptureEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptureEvent) ProtoMessage() {}
+
+func (x *CaptureEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_capturepb_capture_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptureEvent.ProtoReflect.Descriptor instead.
+func (*CaptureEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_capturepb_capture_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CaptureEvent) GetType() CaptureEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return CaptureEvent_TYPE_UNSPECIFIED
+}
+
+func (x *CaptureEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *CaptureEvent) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_STATUS_UNSPECIFIED
+}
+
+func (x *CaptureEvent) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CaptureEvent) GetEntries() int32 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+func (x *CaptureEvent) GetArtefact() string {
+	if x != nil {
+		return x.Artefact
+	}
+	return ""
+}
+
+var File_pkg_capturepb_capture_proto protoreflect.FileDescriptor
+
+const file_pkg_capturepb_capture_proto_rawDesc = "" +
+	"\n" +
+	"\x1bpkg/capturepb/capture.proto\x12\rharcapture.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xba\x06\n" +
+	"\x14CreateCaptureRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12H\n" +
+	"\x12navigation_timeout\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x11navigationTimeout\x12>\n" +
+	"\rtotal_timeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\ftotalTimeout\x12 \n" +
+	"\vscreenshots\x18\x04 \x01(\bR\vscreenshots\x12\x16\n" +
+	"\x06device\x18\x05 \x01(\tR\x06device\x123\n" +
+	"\bviewport\x18\x06 \x01(\v2\x17.harcapture.v1.ViewportR\bviewport\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\a \x01(\tR\tuserAgent\x12'\n" +
+	"\x0faccept_language\x18\b \x01(\tR\x0eacceptLanguage\x12J\n" +
+	"\aheaders\x18\t \x03(\v20.harcapture.v1.CreateCaptureRequest.HeadersEntryR\aheaders\x12/\n" +
+	"\acookies\x18\n" +
+	" \x03(\v2\x15.harcapture.v1.CookieR\acookies\x129\n" +
+	"\n" +
+	"throttling\x18\v \x01(\v2\x19.harcapture.v1.ThrottlingR\n" +
+	"throttling\x12\x1d\n" +
+	"\n" +
+	"block_urls\x18\f \x03(\tR\tblockUrls\x12-\n" +
+	"\x06bodies\x18\r \x01(\v2\x15.harcapture.v1.BodiesR\x06bodies\x12'\n" +
+	"\x04wait\x18\x0e \x01(\v2\x13.harcapture.v1.WaitR\x04wait\x12'\n" +
+	"\x0fidempotency_key\x18\x0f \x01(\tR\x0eidempotencyKey\x12!\n" +
+	"\fcallback_url\x18\x10 \x01(\tR\vcallbackUrl\x12\x18\n" +
+	"\aprofile\x18\x11 \x01(\tR\aprofile\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x80\x01\n" +
+	"\bViewport\x12\x14\n" +
+	"\x05width\x18\x01 \x01(\x03R\x05width\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x03R\x06height\x12.\n" +
+	"\x13device_scale_factor\x18\x03 \x01(\x01R\x11deviceScaleFactor\x12\x16\n" +
+	"\x06mobile\x18\x04 \x01(\bR\x06mobile\"\xff\x01\n" +
+	"\x06Cookie\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x16\n" +
+	"\x06domain\x18\x03 \x01(\tR\x06domain\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\x12\x10\n" +
+	"\x03url\x18\x05 \x01(\tR\x03url\x12\x16\n" +
+	"\x06secure\x18\x06 \x01(\bR\x06secure\x12\x1b\n" +
+	"\thttp_only\x18\a \x01(\bR\bhttpOnly\x12\x1b\n" +
+	"\tsame_site\x18\b \x01(\tR\bsameSite\x12;\n" +
+	"\vexpire_time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expireTime\"\xd1\x01\n" +
+	"\n" +
+	"Throttling\x12\x16\n" +
+	"\x06preset\x18\x01 \x01(\tR\x06preset\x123\n" +
+	"\alatency\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\alatency\x12/\n" +
+	"\x13download_throughput\x18\x03 \x01(\x03R\x12downloadThroughput\x12+\n" +
+	"\x11upload_throughput\x18\x04 \x01(\x03R\x10uploadThroughput\x12\x18\n" +
+	"\aoffline\x18\x05 \x01(\bR\aoffline\"D\n" +
+	"\x06Bodies\x12\x1d\n" +
+	"\n" +
+	"mime_types\x18\x01 \x03(\tR\tmimeTypes\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\"\xac\x01\n" +
+	"\x04Wait\x12\x1a\n" +
+	"\bselector\x18\x01 \x01(\tR\bselector\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x02 \x01(\tR\n" +
+	"expression\x12(\n" +
+	"\x10scroll_to_bottom\x18\x03 \x01(\bR\x0escrollToBottom\x12>\n" +
+	"\ridle_duration\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\fidleDuration\"#\n" +
+	"\x11GetCaptureRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x96\x02\n" +
+	"\x13ListCapturesRequest\x12-\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x15.harcapture.v1.StatusR\x06status\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\x12?\n" +
+	"\rcreated_after\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12A\n" +
+	"\x0ecreated_before\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBefore\"r\n" +
+	"\x14ListCapturesResponse\x122\n" +
+	"\bcaptures\x18\x01 \x03(\v2\x16.harcapture.v1.CaptureR\bcaptures\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"%\n" +
+	"\x13WatchCaptureRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x8c\x04\n" +
+	"\aCapture\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12-\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x15.harcapture.v1.StatusR\x06status\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12;\n" +
+	"\vcreate_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"createTime\x12;\n" +
+	"\vupdate_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"updateTime\x12\x18\n" +
+	"\asubject\x18\x06 \x01(\tR\asubject\x12-\n" +
+	"\x04ttfb\x18\a \x01(\v2\x19.google.protobuf.DurationR\x04ttfb\x12\x1b\n" +
+	"\ttimed_out\x18\b \x01(\bR\btimedOut\x127\n" +
+	"\n" +
+	"web_vitals\x18\t \x01(\v2\x18.harcapture.v1.WebVitalsR\twebVitals\x122\n" +
+	"\battempts\x18\n" +
+	" \x03(\v2\x16.harcapture.v1.AttemptR\battempts\x125\n" +
+	"\tartefacts\x18\v \x03(\v2\x17.harcapture.v1.ArtefactR\tartefacts\x12\x14\n" +
+	"\x05error\x18\f \x01(\tR\x05error\x12\x16\n" +
+	"\x06tenant\x18\r \x01(\tR\x06tenant\"\xd1\x01\n" +
+	"\tWebVitals\x12+\n" +
+	"\x03lcp\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\x03lcp\x12\x10\n" +
+	"\x03cls\x18\x02 \x01(\x01R\x03cls\x12+\n" +
+	"\x03inp\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x03inp\x12+\n" +
+	"\x03fid\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x03fid\x12+\n" +
+	"\x03tbt\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\x03tbt\"\xcc\x01\n" +
+	"\aAttempt\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\x05R\x06number\x129\n" +
+	"\n" +
+	"start_time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12;\n" +
+	"\vfinish_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishTime\x12\x1b\n" +
+	"\ttimed_out\x18\x04 \x01(\bR\btimedOut\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"z\n" +
+	"\bArtefact\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"signed_url\x18\x02 \x01(\tR\tsignedUrl\x12;\n" +
+	"\vexpire_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expireTime\"\xd5\x02\n" +
+	"\fCaptureEvent\x124\n" +
+	"\x04type\x18\x01 \x01(\x0e2 .harcapture.v1.CaptureEvent.TypeR\x04type\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12-\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x15.harcapture.v1.StatusR\x06status\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\x12\x18\n" +
+	"\aentries\x18\x05 \x01(\x05R\aentries\x12\x1a\n" +
+	"\bartefact\x18\x06 \x01(\tR\bartefact\"h\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x0f\n" +
+	"\vTYPE_STATUS\x10\x01\x12\x13\n" +
+	"\x0fTYPE_NAVIGATION\x10\x02\x12\x10\n" +
+	"\fTYPE_ENTRIES\x10\x03\x12\x12\n" +
+	"\x0eTYPE_UPLOADING\x10\x04*\xb5\x01\n" +
+	"\x06Status\x12\x16\n" +
+	"\x12STATUS_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0eSTATUS_PENDING\x10\x01\x12\x12\n" +
+	"\x0eSTATUS_RUNNING\x10\x02\x12\x13\n" +
+	"\x0fSTATUS_COMPLETE\x10\x03\x12\x11\n" +
+	"\rSTATUS_FAILED\x10\x04\x12\x15\n" +
+	"\x11STATUS_CANCELLING\x10\x05\x12\x14\n" +
+	"\x10STATUS_CANCELLED\x10\x06\x12\x16\n" +
+	"\x12STATUS_INTERRUPTED\x10\a2\xd2\x02\n" +
+	"\x0eCaptureService\x12L\n" +
+	"\rCreateCapture\x12#.harcapture.v1.CreateCaptureRequest\x1a\x16.harcapture.v1.Capture\x12F\n" +
+	"\n" +
+	"GetCapture\x12 .harcapture.v1.GetCaptureRequest\x1a\x16.harcapture.v1.Capture\x12W\n" +
+	"\fListCaptures\x12\".harcapture.v1.ListCapturesRequest\x1a#.harcapture.v1.ListCapturesResponse\x12Q\n" +
+	"\fWatchCapture\x12\".harcapture.v1.WatchCaptureRequest\x1a\x1b.harcapture.v1.CaptureEvent0\x01B2Z0github.com/tomasbasham/har-capture/pkg/capturepbb\x06proto3"
+
+var (
+	file_pkg_capturepb_capture_proto_rawDescOnce sync.Once
+	file_pkg_capturepb_capture_proto_rawDescData []byte
+)
+
+func file_pkg_capturepb_capture_proto_rawDescGZIP() []byte {
+	file_pkg_capturepb_capture_proto_rawDescOnce.Do(func() {
+		file_pkg_capturepb_capture_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_capturepb_capture_proto_rawDesc), len(file_pkg_capturepb_capture_proto_rawDesc)))
+	})
+	return file_pkg_capturepb_capture_proto_rawDescData
+}
+
+var file_pkg_capturepb_capture_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_pkg_capturepb_capture_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_pkg_capturepb_capture_proto_goTypes = []any{
+	(Status)(0),                   // 0: harcapture.v1.Status
+	(CaptureEvent_Type)(0),        // 1: harcapture.v1.CaptureEvent.Type
+	(*CreateCaptureRequest)(nil),  // 2: harcapture.v1.CreateCaptureRequest
+	(*Viewport)(nil),              // 3: harcapture.v1.Viewport
+	(*Cookie)(nil),                // 4: harcapture.v1.Cookie
+	(*Throttling)(nil),            // 5: harcapture.v1.Throttling
+	(*Bodies)(nil),                // 6: harcapture.v1.Bodies
+	(*Wait)(nil),                  // 7: harcapture.v1.Wait
+	(*GetCaptureRequest)(nil),     // 8: harcapture.v1.GetCaptureRequest
+	(*ListCapturesRequest)(nil),   // 9: harcapture.v1.ListCapturesRequest
+	(*ListCapturesResponse)(nil),  // 10: harcapture.v1.ListCapturesResponse
+	(*WatchCaptureRequest)(nil),   // 11: harcapture.v1.WatchCaptureRequest
+	(*Capture)(nil),               // 12: harcapture.v1.Capture
+	(*WebVitals)(nil),             // 13: harcapture.v1.WebVitals
+	(*Attempt)(nil),               // 14: harcapture.v1.Attempt
+	(*Artefact)(nil),              // 15: harcapture.v1.Artefact
+	(*CaptureEvent)(nil),          // 16: harcapture.v1.CaptureEvent
+	nil,                           // 17: harcapture.v1.CreateCaptureRequest.HeadersEntry
+	(*durationpb.Duration)(nil),   // 18: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 19: google.protobuf.Timestamp
+}
+var file_pkg_capturepb_capture_proto_depIdxs = []int32{
+	18, // 0: harcapture.v1.CreateCaptureRequest.navigation_timeout:type_name -> google.protobuf.Duration
+	18, // 1: harcapture.v1.CreateCaptureRequest.total_timeout:type_name -> google.protobuf.Duration
+	3,  // 2: harcapture.v1.CreateCaptureRequest.viewport:type_name -> harcapture.v1.Viewport
+	17, // 3: harcapture.v1.CreateCaptureRequest.headers:type_name -> harcapture.v1.CreateCaptureRequest.HeadersEntry
+	4,  // 4: harcapture.v1.CreateCaptureRequest.cookies:type_name -> harcapture.v1.Cookie
+	5,  // 5: harcapture.v1.CreateCaptureRequest.throttling:type_name -> harcapture.v1.Throttling
+	6,  // 6: harcapture.v1.CreateCaptureRequest.bodies:type_name -> harcapture.v1.Bodies
+	7,  // 7: harcapture.v1.CreateCaptureRequest.wait:type_name -> harcapture.v1.Wait
+	19, // 8: harcapture.v1.Cookie.expire_time:type_name -> google.protobuf.Timestamp
+	18, // 9: harcapture.v1.Throttling.latency:type_name -> google.protobuf.Duration
+	18, // 10: harcapture.v1.Wait.idle_duration:type_name -> google.protobuf.Duration
+	0,  // 11: harcapture.v1.ListCapturesRequest.status:type_name -> harcapture.v1.Status
+	19, // 12: harcapture.v1.ListCapturesRequest.created_after:type_name -> google.protobuf.Timestamp
+	19, // 13: harcapture.v1.ListCapturesRequest.created_before:type_name -> google.protobuf.Timestamp
+	12, // 14: harcapture.v1.ListCapturesResponse.captures:type_name -> harcapture.v1.Capture
+	0,  // 15: harcapture.v1.Capture.status:type_name -> harcapture.v1.Status
+	19, // 16: harcapture.v1.Capture.create_time:type_name -> google.protobuf.Timestamp
+	19, // 17: harcapture.v1.Capture.update_time:type_name -> google.protobuf.Timestamp
+	18, // 18: harcapture.v1.Capture.ttfb:type_name -> google.protobuf.Duration
+	13, // 19: harcapture.v1.Capture.web_vitals:type_name -> harcapture.v1.WebVitals
+	14, // 20: harcapture.v1.Capture.attempts:type_name -> harcapture.v1.Attempt
+	15, // 21: harcapture.v1.Capture.artefacts:type_name -> harcapture.v1.Artefact
+	18, // 22: harcapture.v1.WebVitals.lcp:type_name -> google.protobuf.Duration
+	18, // 23: harcapture.v1.WebVitals.inp:type_name -> google.protobuf.Duration
+	18, // 24: harcapture.v1.WebVitals.fid:type_name -> google.protobuf.Duration
+	18, // 25: harcapture.v1.WebVitals.tbt:type_name -> google.protobuf.Duration
+	19, // 26: harcapture.v1.Attempt.start_time:type_name -> google.protobuf.Timestamp
+	19, // 27: harcapture.v1.Attempt.finish_time:type_name -> google.protobuf.Timestamp
+	19, // 28: harcapture.v1.Artefact.expire_time:type_name -> google.protobuf.Timestamp
+	1,  // 29: harcapture.v1.CaptureEvent.type:type_name -> harcapture.v1.CaptureEvent.Type
+	19, // 30: harcapture.v1.CaptureEvent.time:type_name -> google.protobuf.Timestamp
+	0,  // 31: harcapture.v1.CaptureEvent.status:type_name -> harcapture.v1.Status
+	2,  // 32: harcapture.v1.CaptureService.CreateCapture:input_type -> harcapture.v1.CreateCaptureRequest
+	8,  // 33: harcapture.v1.CaptureService.GetCapture:input_type -> harcapture.v1.GetCaptureRequest
+	9,  // 34: harcapture.v1.CaptureService.ListCaptures:input_type -> harcapture.v1.ListCapturesRequest
+	11, // 35: harcapture.v1.CaptureService.WatchCapture:input_type -> harcapture.v1.WatchCaptureRequest
+	12, // 36: harcapture.v1.CaptureService.CreateCapture:output_type -> harcapture.v1.Capture
+	12, // 37: harcapture.v1.CaptureService.GetCapture:output_type -> harcapture.v1.Capture
+	10, // 38: harcapture.v1.CaptureService.ListCaptures:output_type -> harcapture.v1.ListCapturesResponse
+	16, // 39: harcapture.v1.CaptureService.WatchCapture:output_type -> harcapture.v1.CaptureEvent
+	36, // [36:40] is the sub-list for method output_type
+	32, // [32:36] is the sub-list for method input_type
+	32, // [32:32] is the sub-list for extension type_name
+	32, // [32:32] is the sub-list for extension extendee
+	0,  // [0:32] is the sub-list for field type_name
+}
+
+func init() { file_pkg_capturepb_capture_proto_init() }
+func file_pkg_capturepb_capture_proto_init() {
+	if File_pkg_capturepb_capture_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_capturepb_capture_proto_rawDesc), len(file_pkg_capturepb_capture_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pkg_capturepb_capture_proto_goTypes,
+		DependencyIndexes: file_pkg_capturepb_capture_proto_depIdxs,
+		EnumInfos:         file_pkg_capturepb_capture_proto_enumTypes,
+		MessageInfos:      file_pkg_capturepb_capture_proto_msgTypes,
+	}.Build()
+	File_pkg_capturepb_capture_proto = out.File
+	file_pkg_capturepb_capture_proto_goTypes = nil
+	file_pkg_capturepb_capture_proto_depIdxs = nil
+}
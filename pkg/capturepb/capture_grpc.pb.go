@@ -0,0 +1,258 @@
+// The HAR capture API over gRPC. It mirrors the HTTP API, and shares its
+// operations: a capture created over one may be retrieved over the other.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pkg/capturepb/capture.proto
+
+package capturepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CaptureService_CreateCapture_FullMethodName = "/harcapture.v1.CaptureService/CreateCapture"
+	CaptureService_GetCapture_FullMethodName    = "/harcapture.v1.CaptureService/GetCapture"
+	CaptureService_ListCaptures_FullMethodName  = "/harcapture.v1.CaptureService/ListCaptures"
+	CaptureService_WatchCapture_FullMethodName  = "/harcapture.v1.CaptureService/WatchCapture"
+)
+
+// CaptureServiceClient is the client API for CaptureService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CaptureService captures HTTP Archives of web pages asynchronously. Each
+// capture is an operation that is polled, or watched, until it finishes.
+type CaptureServiceClient interface {
+	// CreateCapture enqueues a capture and returns its operation at once.
+	CreateCapture(ctx context.Context, in *CreateCaptureRequest, opts ...grpc.CallOption) (*Capture, error)
+	// GetCapture returns an operation.
+	GetCapture(ctx context.Context, in *GetCaptureRequest, opts ...grpc.CallOption) (*Capture, error)
+	// ListCaptures lists operations, most recent first, a page at a time.
+	ListCaptures(ctx context.Context, in *ListCapturesRequest, opts ...grpc.CallOption) (*ListCapturesResponse, error)
+	// WatchCapture streams the progress of an operation. The stream begins
+	// with the current status and ends once the operation has finished.
+	WatchCapture(ctx context.Context, in *WatchCaptureRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CaptureEvent], error)
+}
+
+type captureServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCaptureServiceClient(cc grpc.ClientConnInterface) CaptureServiceClient {
+	return &captureServiceClient{cc}
+}
+
+func (c *captureServiceClient) CreateCapture(ctx context.Context, in *CreateCaptureRequest, opts ...grpc.CallOption) (*Capture, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Capture)
+	err := c.cc.Invoke(ctx, CaptureService_CreateCapture_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *captureServiceClient) GetCapture(ctx context.Context, in *GetCaptureRequest, opts ...grpc.CallOption) (*Capture, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Capture)
+	err := c.cc.Invoke(ctx, CaptureService_GetCapture_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *captureServiceClient) ListCaptures(ctx context.Context, in *ListCapturesRequest, opts ...grpc.CallOption) (*ListCapturesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCapturesResponse)
+	err := c.cc.Invoke(ctx, CaptureService_ListCaptures_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *captureServiceClient) WatchCapture(ctx context.Context, in *WatchCaptureRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CaptureEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CaptureService_ServiceDesc.Streams[0], CaptureService_WatchCapture_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchCaptureRequest, CaptureEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CaptureService_WatchCaptureClient = grpc.ServerStreamingClient[CaptureEvent]
+
+// CaptureServiceServer is the server API for CaptureService service.
+// All implementations must embed UnimplementedCaptureServiceServer
+// for forward compatibility.
+//
+// CaptureService captures HTTP Archives of web pages asynchronously. Each
+// capture is an operation that is polled, or watched, until it finishes.
+type CaptureServiceServer interface {
+	// CreateCapture enqueues a capture and returns its operation at once.
+	CreateCapture(context.Context, *CreateCaptureRequest) (*Capture, error)
+	// GetCapture returns an operation.
+	GetCapture(context.Context, *GetCaptureRequest) (*Capture, error)
+	// ListCaptures lists operations, most recent first, a page at a time.
+	ListCaptures(context.Context, *ListCapturesRequest) (*ListCapturesResponse, error)
+	// WatchCapture streams the progress of an operation. The stream begins
+	// with the current status and ends once the operation has finished.
+	WatchCapture(*WatchCaptureRequest, grpc.ServerStreamingServer[CaptureEvent]) error
+	mustEmbedUnimplementedCaptureServiceServer()
+}
+
+// UnimplementedCaptureServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCaptureServiceServer struct{}
+
+func (UnimplementedCaptureServiceServer) CreateCapture(context.Context, *CreateCaptureRequest) (*Capture, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCapture not implemented")
+}
+func (UnimplementedCaptureServiceServer) GetCapture(context.Context, *GetCaptureRequest) (*Capture, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapture not implemented")
+}
+func (UnimplementedCaptureServiceServer) ListCaptures(context.Context, *ListCapturesRequest) (*ListCapturesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCaptures not implemented")
+}
+func (UnimplementedCaptureServiceServer) WatchCapture(*WatchCaptureRequest, grpc.ServerStreamingServer[CaptureEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCapture not implemented")
+}
+func (UnimplementedCaptureServiceServer) mustEmbedUnimplementedCaptureServiceServer() {}
+func (UnimplementedCaptureServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeCaptureServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CaptureServiceServer will
+// result in compilation errors.
+type UnsafeCaptureServiceServer interface {
+	mustEmbedUnimplementedCaptureServiceServer()
+}
+
+func RegisterCaptureServiceServer(s grpc.ServiceRegistrar, srv CaptureServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCaptureServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CaptureService_ServiceDesc, srv)
+}
+
+func _CaptureService_CreateCapture_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCaptureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CaptureServiceServer).CreateCapture(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CaptureService_CreateCapture_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CaptureServiceServer).CreateCapture(ctx, req.(*CreateCaptureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CaptureService_GetCapture_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCaptureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CaptureServiceServer).GetCapture(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CaptureService_GetCapture_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CaptureServiceServer).GetCapture(ctx, req.(*GetCaptureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CaptureService_ListCaptures_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCapturesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CaptureServiceServer).ListCaptures(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CaptureService_ListCaptures_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CaptureServiceServer).ListCaptures(ctx, req.(*ListCapturesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CaptureService_WatchCapture_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCaptureRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CaptureServiceServer).WatchCapture(m, &grpc.GenericServerStream[WatchCaptureRequest, CaptureEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CaptureService_WatchCaptureServer = grpc.ServerStreamingServer[CaptureEvent]
+
+// CaptureService_ServiceDesc is the grpc.ServiceDesc for CaptureService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CaptureService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "harcapture.v1.CaptureService",
+	HandlerType: (*CaptureServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateCapture",
+			Handler:    _CaptureService_CreateCapture_Handler,
+		},
+		{
+			MethodName: "GetCapture",
+			Handler:    _CaptureService_GetCapture_Handler,
+		},
+		{
+			MethodName: "ListCaptures",
+			Handler:    _CaptureService_ListCaptures_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchCapture",
+			Handler:       _CaptureService_WatchCapture_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/capturepb/capture.proto",
+}
@@ -0,0 +1,5 @@
+// Package capturepb contains the protocol buffer messages and gRPC client
+// and server of the HAR capture API, generated from capture.proto.
+package capturepb
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative pkg/capturepb/capture.proto